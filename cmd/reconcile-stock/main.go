@@ -0,0 +1,51 @@
+// Command reconcile-stock compares the warehouse quantities that product
+// stock is summed from with the movement ledger and prints every mismatch.
+// With -apply the quantities are rebuilt from the ledger.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/ifs21014-itdel/concurrent-order-processor/config"
+	repo "github.com/ifs21014-itdel/concurrent-order-processor/internal/repository"
+	usecase "github.com/ifs21014-itdel/concurrent-order-processor/internal/usecase"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	apply := flag.Bool("apply", false, "overwrite warehouse quantities with the ledger totals")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("Warning: .env file not found, using system environment variables")
+	}
+
+	db, err := config.NewDB()
+	if err != nil {
+		log.Fatal("db:", err)
+	}
+	defer db.Close()
+
+	productUC := usecase.NewProductUsecase(repo.NewProductRepository(db), repo.NewWarehouseStockRepository(db), config.NewRedis())
+	discrepancies, err := productUC.ReconcileStock(context.Background(), *apply)
+	if err != nil {
+		log.Fatal("reconcile:", err)
+	}
+
+	for _, d := range discrepancies {
+		fmt.Printf("warehouse %d product %d: quantity=%d ledger=%d\n", d.WarehouseID, d.ProductID, d.Quantity, d.LedgerQuantity)
+	}
+	switch {
+	case len(discrepancies) == 0:
+		fmt.Println("warehouse stock is in sync with the ledger")
+	case *apply:
+		fmt.Printf("fixed %d stock row(s)\n", len(discrepancies))
+	default:
+		fmt.Printf("%d stock row(s) out of sync; run with -apply to fix\n", len(discrepancies))
+		os.Exit(1)
+	}
+}
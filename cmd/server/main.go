@@ -1,13 +1,21 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	nethttp "net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/ifs21014-itdel/concurrent-order-processor/config"
 	"github.com/ifs21014-itdel/concurrent-order-processor/internal/delivery/http"
 	repo "github.com/ifs21014-itdel/concurrent-order-processor/internal/repository"
 	usecase "github.com/ifs21014-itdel/concurrent-order-processor/internal/usecase"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/utils"
 	"github.com/joho/godotenv"
 )
 
@@ -43,23 +51,123 @@ func main() {
 	wareHouseStockRepo := repo.NewWarehouseStockRepository(db)
 	orderRepo := repo.NewOrderRepository(db)
 	orderItemRepo := repo.NewOrderItemRepository(db)
-	cartRepo := repo.NewCartRepository(db)
-	cartItemRepo := repo.NewCartItemRepository(db)
+	// CART_STORAGE=redis keeps carts in Redis and writes them to Postgres in the background
+	cartStorage, err := repo.NewCartStorage(config.GetString("CART_STORAGE", repo.CartStoragePostgres), db, redisClient)
+	if err != nil {
+		log.Fatal("cart storage:", err)
+	}
+	log.Println("cart storage:", cartStorage.Kind)
+	cartRepo := cartStorage.Carts
+	cartItemRepo := cartStorage.Items
+	cartAbandonmentRepo := repo.NewCartAbandonmentRepository(db)
+	wishlistRepo := repo.NewWishlistRepository(db)
+	transferRepo := repo.NewStockTransferRepository(db)
+	stockAlertRepo := repo.NewStockAlertRepository(db)
+	addressRepo := repo.NewAddressRepository(db)
+	stockLotRepo := repo.NewStockLotRepository(db)
+	stockCountRepo := repo.NewStockCountRepository(db)
+	supplierRepo := repo.NewSupplierRepository(db)
+	purchaseOrderRepo := repo.NewPurchaseOrderRepository(db)
+	locationRepo := repo.NewStorageLocationRepository(db)
+	snapshotRepo := repo.NewInventorySnapshotRepository(db)
+	replenishmentRepo := repo.NewReplenishmentRepository(db)
 
 	authUC := usecase.NewAuthUsecase(userRepo)
-	productUC := usecase.NewProductUsecase(productRepo, redisClient) // Pass Redis client
+	productUC := usecase.NewProductUsecase(productRepo, wareHouseStockRepo, redisClient) // Pass Redis client
 	wareHouseUC := usecase.NewWarehouseUsecase(wareHouseRepo)
-	wareHouseStockUC := usecase.NewWarehouseStockUsecase(wareHouseStockRepo, wareHouseRepo, productRepo)
-	orderUC := usecase.NewOrderUsecase(orderRepo, orderItemRepo, cartRepo, cartItemRepo, wareHouseStockRepo)
-	cartUC := usecase.NewCartUsecase(cartRepo, cartItemRepo, redisClient)
-	cartItemUC := usecase.NewCartItemUsecase(cartItemRepo)
+	wareHouseStockUC := usecase.NewWarehouseStockUsecase(wareHouseStockRepo, wareHouseRepo, productRepo, redisClient)
+	pricingUC := usecase.NewPricingUsecase(productRepo, wareHouseRepo, usecase.PricingOptions{
+		TaxRate: config.GetFloat("TAX_RATE", 0),
+	})
+	cartValidationUC := usecase.NewCartValidationUsecase(cartRepo, cartItemRepo, productRepo, wareHouseStockRepo)
+	orderUC := usecase.NewOrderUsecase(orderRepo, orderItemRepo, cartRepo, cartItemRepo, wareHouseStockRepo, wareHouseRepo, addressRepo, cartValidationUC, pricingUC, redisClient)
+	cartUC := usecase.NewCartUsecase(cartStorage, pricingUC, redisClient)
+	cartItemUC := usecase.NewCartItemUsecase(cartItemRepo, cartRepo, redisClient)
+	guestCartUC := usecase.NewGuestCartUsecase(cartUC, productRepo, redisClient)
+	wishlistUC := usecase.NewWishlistUsecase(wishlistRepo, productRepo, wareHouseRepo, wareHouseStockRepo, cartUC)
+	addressUC := usecase.NewAddressUsecase(addressRepo)
+	stockLotUC := usecase.NewStockLotUsecase(stockLotRepo, wareHouseRepo, productRepo, redisClient)
+	stockCountUC := usecase.NewStockCountUsecase(stockCountRepo, wareHouseRepo, productRepo, redisClient)
+	supplierUC := usecase.NewSupplierUsecase(supplierRepo)
+	purchaseOrderUC := usecase.NewPurchaseOrderUsecase(purchaseOrderRepo, supplierRepo, wareHouseRepo, productRepo, wareHouseStockRepo, stockLotRepo, redisClient)
+	locationUC := usecase.NewStorageLocationUsecase(locationRepo, wareHouseRepo, productRepo)
+	replenishmentUC := usecase.NewReplenishmentUsecase(replenishmentRepo, wareHouseRepo)
+	transferUC := usecase.NewStockTransferUsecase(transferRepo, wareHouseRepo, productRepo, wareHouseStockRepo, stockLotRepo, redisClient)
+
+	notifier := config.NewNotifier()
+	abandonedCartUC := usecase.NewAbandonedCartUsecase(cartAbandonmentRepo, userRepo, notifier, usecase.AbandonedCartOptions{
+		AbandonAfter:  config.GetDuration("ABANDONED_CART_AFTER", 24*time.Hour),
+		CheckInterval: config.GetDuration("ABANDONED_CART_CHECK_INTERVAL", time.Hour),
+		MaxReminders:  config.GetInt("ABANDONED_CART_MAX_REMINDERS", 3),
+		ReminderGap:   config.GetDuration("ABANDONED_CART_REMINDER_GAP", 24*time.Hour),
+	})
+	stockAlertUC := usecase.NewStockAlertUsecase(stockAlertRepo, wareHouseRepo, productRepo, config.NewStockAlertNotifier(), usecase.StockAlertOptions{
+		Recipient:     config.GetString("STOCK_ALERT_RECIPIENT", "purchasing@localhost"),
+		CheckInterval: config.GetDuration("STOCK_ALERT_CHECK_INTERVAL", time.Minute),
+	})
+	inventoryUC := usecase.NewInventorySnapshotUsecase(snapshotRepo, usecase.InventorySnapshotOptions{
+		CheckInterval: config.GetDuration("INVENTORY_SNAPSHOT_CHECK_INTERVAL", time.Hour),
+	})
+
+	// background jobs
+	jobCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	var jobs sync.WaitGroup
+	startJob := func(name string, fn func()) {
+		jobs.Add(1)
+		go func() {
+			defer jobs.Done()
+			utils.SafeGoroutine(name, fn)
+		}()
+	}
+	if config.GetBool("ABANDONED_CART_JOB_ENABLED", true) {
+		startJob("abandoned cart job", func() {
+			abandonedCartUC.Run(jobCtx)
+		})
+	}
+	if config.GetBool("STOCK_ALERT_JOB_ENABLED", true) {
+		startJob("stock alert job", func() {
+			stockAlertUC.Run(jobCtx)
+		})
+	}
+	if config.GetBool("INVENTORY_SNAPSHOT_JOB_ENABLED", true) {
+		startJob("inventory snapshot job", func() {
+			inventoryUC.Run(jobCtx)
+		})
+	}
+	if cartStorage.WriteBehind != nil {
+		interval := config.GetDuration("CART_FLUSH_INTERVAL", 5*time.Second)
+		startJob("cart write-behind", func() {
+			cartStorage.WriteBehind.Run(jobCtx, interval)
+		})
+	}
 
-	r := http.NewRouter(authUC, productUC, wareHouseUC, wareHouseStockUC, orderUC, cartUC, cartItemUC)
+	r := http.NewRouter(authUC, productUC, wareHouseUC, wareHouseStockUC, orderUC, cartUC, cartValidationUC, cartItemUC, guestCartUC, abandonedCartUC, wishlistUC, transferUC, stockAlertUC, addressUC, stockLotUC, stockCountUC, supplierUC, purchaseOrderUC, locationUC, inventoryUC, replenishmentUC)
 
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 	}
-	log.Println("listen on :", port)
-	r.Run(":" + port)
+	srv := &nethttp.Server{Addr: ":" + port, Handler: r}
+	go func() {
+		log.Println("listen on :", port)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, nethttp.ErrServerClosed) {
+			log.Fatal("server:", err)
+		}
+	}()
+
+	// graceful shutdown: stop accepting requests, then let the jobs finish
+	// (the cart write-behind does a last flush when it stops)
+	sigCtx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+	<-sigCtx.Done()
+	log.Println("shutting down...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Println("server shutdown:", err)
+	}
+	stopJobs()
+	jobs.Wait()
 }
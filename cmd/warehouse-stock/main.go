@@ -0,0 +1,154 @@
+// Command warehouse-stock imports stock counts from a CSV or NDJSON file and
+// exports the current stock in the same format.
+//
+//	warehouse-stock import -file counts.csv [-format csv|ndjson] [-apply] [-chunk 500]
+//	warehouse-stock export [-format csv|ndjson] [-warehouse 1] > stock.csv
+//
+// Import only prints the diff unless -apply is given.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ifs21014-itdel/concurrent-order-processor/config"
+	repo "github.com/ifs21014-itdel/concurrent-order-processor/internal/repository"
+	usecase "github.com/ifs21014-itdel/concurrent-order-processor/internal/usecase"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("Warning: .env file not found, using system environment variables")
+	}
+
+	switch os.Args[1] {
+	case "import":
+		runImport(os.Args[2:])
+	case "export":
+		runExport(os.Args[2:])
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: warehouse-stock import -file <path> [-format csv|ndjson] [-apply] [-chunk n] [-actor id] [-note text]")
+	fmt.Fprintln(os.Stderr, "       warehouse-stock export [-format csv|ndjson] [-warehouse id]")
+	os.Exit(2)
+}
+
+func newUsecase() *usecase.WarehouseStockUsecase {
+	db, err := config.NewDB()
+	if err != nil {
+		log.Fatal("db:", err)
+	}
+	return usecase.NewWarehouseStockUsecase(
+		repo.NewWarehouseStockRepository(db),
+		repo.NewWarehouseRepository(db),
+		repo.NewProductRepository(db),
+		config.NewRedis(), // imports drop the cached availability of the products they change
+	)
+}
+
+func runImport(args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	file := fs.String("file", "", "stock file to import, - for stdin")
+	format := fs.String("format", "", "csv or ndjson (default: from the file extension)")
+	apply := fs.Bool("apply", false, "write the counted quantities")
+	chunk := fs.Int("chunk", 0, "rows per transaction (default 500)")
+	actor := fs.Uint("actor", 0, "user ID recorded on the stock movements")
+	note := fs.String("note", "", "note recorded on the stock movements")
+	fs.Parse(args)
+
+	if *file == "" {
+		usage()
+	}
+	var in io.Reader = os.Stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		in = f
+	}
+	if *format == "" {
+		*format = formatFromExtension(*file)
+	}
+
+	rows, err := usecase.ParseStockImport(in, *format)
+	if err != nil {
+		log.Fatal(err)
+	}
+	report, err := newUsecase().ImportStock(context.Background(), *actor, rows, usecase.StockImportOptions{
+		Apply:     *apply,
+		ChunkSize: *chunk,
+		Note:      *note,
+	})
+	if err != nil {
+		log.Fatal("import:", err)
+	}
+
+	for _, r := range report.Rows {
+		switch r.Status {
+		case usecase.StockImportUnchanged:
+			continue
+		case usecase.StockImportFailed:
+			fmt.Printf("line %d: error: %s\n", r.Line, r.Error)
+		default:
+			current := "new"
+			if r.CurrentQuantity != nil {
+				current = fmt.Sprint(*r.CurrentQuantity)
+			}
+			fmt.Printf("line %d: warehouse %d product %d: %s -> %d (%+d)\n",
+				r.Line, r.WarehouseID, r.ProductID, current, *r.Quantity, r.Delta)
+		}
+	}
+	summary, _ := json.Marshal(map[string]any{
+		"applied":   report.Applied,
+		"total":     report.Total,
+		"created":   report.Created,
+		"updated":   report.Updated,
+		"unchanged": report.Unchanged,
+		"failed":    report.Failed,
+	})
+	fmt.Println(string(summary))
+	if !*apply {
+		fmt.Println("dry run; run with -apply to write the changes")
+	}
+	if report.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func runExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	format := fs.String("format", usecase.StockFormatCSV, "csv or ndjson")
+	warehouse := fs.Uint("warehouse", 0, "only export this warehouse")
+	fs.Parse(args)
+
+	if err := newUsecase().ExportStock(context.Background(), os.Stdout, *format, *warehouse); err != nil {
+		log.Fatal("export:", err)
+	}
+}
+
+func formatFromExtension(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ndjson", ".jsonl":
+		return usecase.StockFormatNDJSON
+	default:
+		return usecase.StockFormatCSV
+	}
+}
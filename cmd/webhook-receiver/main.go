@@ -0,0 +1,55 @@
+// Command webhook-receiver is a small local endpoint for NOTIFIER=webhook.
+// It prints every notification it receives and checks the signature when
+// WEBHOOK_SECRET is set.
+package main
+
+import (
+	"crypto/hmac"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/ifs21014-itdel/concurrent-order-processor/pkg/notifier"
+)
+
+func main() {
+	addr := os.Getenv("WEBHOOK_LISTEN")
+	if addr == "" {
+		addr = ":9090"
+	}
+	secret := os.Getenv("WEBHOOK_SECRET")
+
+	http.HandleFunc("/webhook", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			http.Error(w, "failed to read body", http.StatusBadRequest)
+			return
+		}
+
+		if secret != "" {
+			want := notifier.Sign(secret, body)
+			if !hmac.Equal([]byte(want), []byte(r.Header.Get(notifier.SignatureHeader))) {
+				log.Printf("rejected notification with bad signature from %s", r.RemoteAddr)
+				http.Error(w, "invalid signature", http.StatusUnauthorized)
+				return
+			}
+		}
+
+		var msg notifier.Message
+		if err := json.Unmarshal(body, &msg); err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+		log.Printf("📨 to=%s subject=%q tags=%v\n%s", msg.To, msg.Subject, msg.Tags, msg.Body)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	log.Println("webhook receiver listening on", addr)
+	log.Fatal(http.ListenAndServe(addr, nil))
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// GetString membaca env key, atau def jika kosong
+func GetString(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// GetInt membaca env key sebagai integer, atau def jika kosong/tidak valid
+func GetInt(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	parsed, err := strconv.Atoi(v)
+	if err != nil {
+		log.Printf("Warning: invalid %s=%q, using default %d", key, v, def)
+		return def
+	}
+	return parsed
+}
+
+// GetDuration membaca env key sebagai time.Duration (contoh: 30m, 24h)
+func GetDuration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	parsed, err := time.ParseDuration(v)
+	if err != nil {
+		log.Printf("Warning: invalid %s=%q, using default %s", key, v, def)
+		return def
+	}
+	return parsed
+}
+
+// GetBool membaca env key sebagai boolean, atau def jika kosong/tidak valid
+func GetBool(key string, def bool) bool {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	parsed, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Printf("Warning: invalid %s=%q, using default %t", key, v, def)
+		return def
+	}
+	return parsed
+}
+
+// GetFloat membaca env key sebagai float64, atau def jika kosong/tidak valid
+func GetFloat(key string, def float64) float64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	parsed, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		log.Printf("Warning: invalid %s=%q, using default %g", key, v, def)
+		return def
+	}
+	return parsed
+}
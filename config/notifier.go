@@ -0,0 +1,50 @@
+package config
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"github.com/ifs21014-itdel/concurrent-order-processor/pkg/notifier"
+)
+
+// NewNotifier memilih implementasi notifier dari env NOTIFIER (log, file, smtp, webhook).
+// Default-nya log sehingga aplikasi tetap jalan tanpa konfigurasi tambahan.
+func NewNotifier() notifier.Notifier {
+	return newNotifier("NOTIFIER", os.Getenv("NOTIFIER"))
+}
+
+// NewStockAlertNotifier memilih notifier untuk alert stok dari env
+// STOCK_ALERT_NOTIFIER; kalau kosong, sama dengan NOTIFIER.
+func NewStockAlertNotifier() notifier.Notifier {
+	if kind := os.Getenv("STOCK_ALERT_NOTIFIER"); kind != "" {
+		return newNotifier("STOCK_ALERT_NOTIFIER", kind)
+	}
+	return NewNotifier()
+}
+
+func newNotifier(key, kind string) notifier.Notifier {
+	switch kind {
+	case "", "log":
+		return notifier.NewLogNotifier()
+	case "file":
+		return notifier.NewFileNotifier(GetString("NOTIFIER_FILE_PATH", "notifications.log"))
+	case "smtp":
+		return notifier.NewSMTPNotifier(
+			GetString("SMTP_HOST", "localhost"),
+			GetString("SMTP_PORT", "1025"),
+			os.Getenv("SMTP_USERNAME"),
+			os.Getenv("SMTP_PASSWORD"),
+			GetString("SMTP_FROM", "no-reply@localhost"),
+		)
+	case "webhook":
+		return notifier.NewWebhookNotifier(
+			GetString("WEBHOOK_URL", "http://localhost:9090/webhook"),
+			os.Getenv("WEBHOOK_SECRET"),
+			GetDuration("WEBHOOK_TIMEOUT", 5*time.Second),
+		)
+	default:
+		log.Printf("Warning: unknown %s %q, falling back to log", key, kind)
+		return notifier.NewLogNotifier()
+	}
+}
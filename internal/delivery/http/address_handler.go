@@ -0,0 +1,141 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/repository"
+	uc "github.com/ifs21014-itdel/concurrent-order-processor/internal/usecase"
+	"github.com/ifs21014-itdel/concurrent-order-processor/pkg/jwt"
+)
+
+type AddressHandler struct {
+	usecase *uc.AddressUsecase
+}
+
+// NewAddressHandler registers the delivery address routes. Addresses belong
+// to the logged-in user; another user's address answers 404.
+func NewAddressHandler(rg *gin.RouterGroup, addressUC *uc.AddressUsecase) {
+	h := &AddressHandler{usecase: addressUC}
+
+	protected := rg.Group("/addresses")
+	protected.Use(jwt.AuthMiddleware())
+	protected.POST("/", h.Create)
+	protected.GET("/", h.GetAll)
+	protected.GET("/:id", h.GetByID)
+	protected.DELETE("/:id", h.Delete)
+}
+
+// addressInput uses pointers so that 0 is accepted as a coordinate while a
+// missing one is still rejected.
+type addressInput struct {
+	Label     *string  `json:"label"`
+	Address   string   `json:"address" binding:"required"`
+	Latitude  *float64 `json:"latitude" binding:"required"`
+	Longitude *float64 `json:"longitude" binding:"required"`
+}
+
+func (h *AddressHandler) Create(c *gin.Context) {
+	var input addressInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON input"})
+		return
+	}
+
+	userID, err := jwt.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	address := &domain.CustomerAddress{
+		UserID:    userID,
+		Label:     input.Label,
+		Address:   input.Address,
+		Latitude:  *input.Latitude,
+		Longitude: *input.Longitude,
+	}
+	if err := h.usecase.CreateAddress(c.Request.Context(), address); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  "success",
+		"message": "alamat berhasil dibuat",
+		"data":    address,
+	})
+}
+
+func (h *AddressHandler) GetAll(c *gin.Context) {
+	userID, err := jwt.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	addresses, err := h.usecase.GetAddresses(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   addresses,
+	})
+}
+
+func (h *AddressHandler) GetByID(c *gin.Context) {
+	id, ok := parseUintParam(c, "id", "invalid address ID")
+	if !ok {
+		return
+	}
+	userID, err := jwt.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	address, err := h.usecase.GetAddress(c.Request.Context(), userID, id)
+	if err != nil {
+		c.JSON(addressErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   address,
+	})
+}
+
+func (h *AddressHandler) Delete(c *gin.Context) {
+	id, ok := parseUintParam(c, "id", "invalid address ID")
+	if !ok {
+		return
+	}
+	userID, err := jwt.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.usecase.DeleteAddress(c.Request.Context(), userID, id); err != nil {
+		c.JSON(addressErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "alamat berhasil dihapus",
+	})
+}
+
+func addressErrorStatus(err error) int {
+	if errors.Is(err, repository.ErrAddressNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,26 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	uc "github.com/ifs21014-itdel/concurrent-order-processor/internal/usecase"
+	"github.com/ifs21014-itdel/concurrent-order-processor/pkg/jwt"
+)
+
+// adminOnly must run after jwt.AuthMiddleware; it lets only admins through.
+func adminOnly(authUC *uc.AuthUsecase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := jwt.GetUserIDFromContext(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+		ok, err := authUC.IsAdmin(userID)
+		if err != nil || !ok {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin only"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -1,6 +1,7 @@
 package http
 
 import (
+	"log"
 	"net/http"
 	"os"
 	"strconv"
@@ -10,11 +11,12 @@ import (
 )
 
 type AuthHandler struct {
-	uc *uc.AuthUsecase
+	uc          *uc.AuthUsecase
+	guestCartUC *uc.GuestCartUsecase
 }
 
-func NewAuthHandler(rg *gin.RouterGroup, uc *uc.AuthUsecase) {
-	h := &AuthHandler{uc: uc}
+func NewAuthHandler(rg *gin.RouterGroup, uc *uc.AuthUsecase, guestCartUC *uc.GuestCartUsecase) {
+	h := &AuthHandler{uc: uc, guestCartUC: guestCartUC}
 	rg.POST("/register", h.Register)
 	rg.POST("/login", h.Login)
 	rg.POST("/totp/setup/:id", h.SetupTOTP)   // id: user id (for demo)
@@ -43,9 +45,10 @@ func (h *AuthHandler) Register(c *gin.Context) {
 }
 
 type loginReq struct {
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required"`
-	TOTP     string `json:"totp"` // optional: required if user enabled
+	Email     string `json:"email" binding:"required,email"`
+	Password  string `json:"password" binding:"required"`
+	TOTP      string `json:"totp"`       // optional: required if user enabled
+	CartToken string `json:"cart_token"` // optional: guest cart to merge, falls back to X-Cart-Token
 }
 
 func (h *AuthHandler) Login(c *gin.Context) {
@@ -59,7 +62,23 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"token": token, "user": gin.H{"id": user.ID, "email": user.Email, "totp_enabled": user.TOTPEnabled}})
+	resp := gin.H{"token": token, "user": gin.H{"id": user.ID, "email": user.Email, "totp_enabled": user.TOTPEnabled}}
+
+	// merge guest cart; a failed merge must not block the login itself
+	cartToken := req.CartToken
+	if cartToken == "" {
+		cartToken = c.GetHeader(cartTokenHeader)
+	}
+	if cartToken != "" && h.guestCartUC != nil {
+		merged, err := h.guestCartUC.MergeIntoUser(c.Request.Context(), cartToken, user.ID)
+		if err != nil {
+			log.Printf("[WARN] Failed to merge guest cart for user %d: %v", user.ID, err)
+			resp["cart_merge_error"] = err.Error()
+		} else {
+			resp["cart_merge"] = merged
+		}
+	}
+	c.JSON(http.StatusOK, resp)
 }
 
 func (h *AuthHandler) SetupTOTP(c *gin.Context) {
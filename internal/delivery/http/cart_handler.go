@@ -11,12 +11,14 @@ import (
 )
 
 type CartHandler struct {
-	cartUsecase *uc.CartUsecase
+	cartUsecase       *uc.CartUsecase
+	validationUsecase *uc.CartValidationUsecase
 }
 
-func NewCartHandler(rg *gin.RouterGroup, cartUC *uc.CartUsecase) {
+func NewCartHandler(rg *gin.RouterGroup, cartUC *uc.CartUsecase, validationUC *uc.CartValidationUsecase) {
 	h := &CartHandler{
-		cartUsecase: cartUC,
+		cartUsecase:       cartUC,
+		validationUsecase: validationUC,
 	}
 
 	protected := rg.Group("/cart")
@@ -25,6 +27,7 @@ func NewCartHandler(rg *gin.RouterGroup, cartUC *uc.CartUsecase) {
 	protected.GET("/", h.GetCartsByUser)
 	protected.DELETE("/:id", h.DeleteCart)
 	protected.DELETE("/item/:id", h.DeleteCartItem)
+	protected.GET("/:id/validate", h.ValidateCart)
 }
 
 func (h *CartHandler) AddItemToCart(c *gin.Context) {
@@ -45,7 +48,7 @@ func (h *CartHandler) AddItemToCart(c *gin.Context) {
 	ctx := c.Request.Context()
 
 	if err := h.cartUsecase.AddItemToCart(ctx, req); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(cartErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -68,7 +71,7 @@ func (h *CartHandler) DeleteCart(c *gin.Context) {
 	}
 	ctx := c.Request.Context()
 	if err := h.cartUsecase.DeleteCart(ctx, id, userID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(cartErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -92,7 +95,7 @@ func (h *CartHandler) DeleteCartItem(c *gin.Context) {
 	}
 	ctx := c.Request.Context()
 	if err := h.cartUsecase.DeleteCartItem(ctx, id, userID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(cartErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -127,6 +130,31 @@ func (h *CartHandler) GetCartsByUser(c *gin.Context) {
 	})
 }
 
+func (h *CartHandler) ValidateCart(c *gin.Context) {
+	id, err := h.getIDFromParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cart ID"})
+		return
+	}
+	userID, err := h.getUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	result, err := h.validationUsecase.ValidateCart(ctx, userID, id)
+	if err != nil {
+		c.JSON(cartErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   result,
+	})
+}
+
 // Helper methods
 func (h *CartHandler) getUserIDFromContext(c *gin.Context) (uint, error) {
 	userIDValue, exists := c.Get("userID")
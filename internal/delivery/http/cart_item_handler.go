@@ -0,0 +1,140 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/repository"
+	uc "github.com/ifs21014-itdel/concurrent-order-processor/internal/usecase"
+	"github.com/ifs21014-itdel/concurrent-order-processor/pkg/jwt"
+)
+
+type CartItemHandler struct {
+	usecase *uc.CartItemUsecase
+}
+
+func NewCartItemHandler(rg *gin.RouterGroup, cartItemUC *uc.CartItemUsecase) {
+	h := &CartItemHandler{usecase: cartItemUC}
+
+	protected := rg.Group("/cart")
+	protected.Use(jwt.AuthMiddleware())
+	protected.PATCH("/item/:id", h.UpdateQuantity)
+	protected.DELETE("/:id/items", h.ClearCart)
+	protected.PUT("/items/batch", h.BatchUpdate)
+}
+
+type updateCartItemQuantityInput struct {
+	Quantity int32 `json:"quantity" binding:"required"`
+}
+
+func (h *CartItemHandler) UpdateQuantity(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cart item ID"})
+		return
+	}
+
+	var input updateCartItemQuantityInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON input"})
+		return
+	}
+	if input.Quantity <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "quantity must be greater than zero"})
+		return
+	}
+
+	userID, err := jwt.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	item, err := h.usecase.UpdateItemQuantity(ctx, userID, uint(id), input.Quantity)
+	if err != nil {
+		c.JSON(cartErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "quantity cart item berhasil diubah",
+		"data":    item,
+	})
+}
+
+func (h *CartItemHandler) ClearCart(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cart ID"})
+		return
+	}
+
+	userID, err := jwt.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.usecase.ClearCart(ctx, userID, uint(id)); err != nil {
+		c.JSON(cartErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "semua item di cart berhasil dihapus",
+	})
+}
+
+func (h *CartItemHandler) BatchUpdate(c *gin.Context) {
+	var updates []uc.CartItemQuantityUpdate
+	if err := c.ShouldBindJSON(&updates); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON input"})
+		return
+	}
+
+	userID, err := jwt.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	results, err := h.usecase.ConcurrentUpdateItems(ctx, userID, updates)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	status := "success"
+	for _, r := range results {
+		if !r.Success {
+			status = "partial"
+			break
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  status,
+		"results": results,
+	})
+}
+
+// cartErrorStatus maps cart usecase errors to HTTP status codes.
+func cartErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, uc.ErrInvalidCartInput):
+		return http.StatusBadRequest
+	case errors.Is(err, uc.ErrCartForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, repository.ErrCartNotFound), errors.Is(err, repository.ErrCartItemNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
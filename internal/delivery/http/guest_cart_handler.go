@@ -0,0 +1,95 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	uc "github.com/ifs21014-itdel/concurrent-order-processor/internal/usecase"
+)
+
+// cartTokenHeader carries the signed guest cart token between requests.
+const cartTokenHeader = "X-Cart-Token"
+
+type GuestCartHandler struct {
+	usecase *uc.GuestCartUsecase
+}
+
+// NewGuestCartHandler registers the anonymous cart routes. They are not
+// protected by jwt.AuthMiddleware; the signed cart token identifies the guest.
+func NewGuestCartHandler(rg *gin.RouterGroup, guestCartUC *uc.GuestCartUsecase) {
+	h := &GuestCartHandler{usecase: guestCartUC}
+
+	guest := rg.Group("/guest-cart")
+	guest.POST("/item", h.AddItem)
+	guest.GET("/", h.GetCart)
+	guest.DELETE("/item/:warehouseId/:productId", h.RemoveItem)
+}
+
+func (h *GuestCartHandler) AddItem(c *gin.Context) {
+	var req uc.AddItemToCartRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON input"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	token, err := h.usecase.AddItem(ctx, c.GetHeader(cartTokenHeader), req)
+	if err != nil {
+		c.JSON(guestCartErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header(cartTokenHeader, token)
+	c.JSON(http.StatusCreated, gin.H{
+		"status":     "success",
+		"message":    "item berhasil ditambahkan ke guest cart",
+		"cart_token": token,
+	})
+}
+
+func (h *GuestCartHandler) GetCart(c *gin.Context) {
+	ctx := c.Request.Context()
+	result, err := h.usecase.GetCart(ctx, c.GetHeader(cartTokenHeader))
+	if err != nil {
+		c.JSON(guestCartErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   result,
+	})
+}
+
+func (h *GuestCartHandler) RemoveItem(c *gin.Context) {
+	warehouseID, err := strconv.ParseUint(c.Param("warehouseId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse ID"})
+		return
+	}
+	productID, err := strconv.ParseUint(c.Param("productId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.usecase.RemoveItem(ctx, c.GetHeader(cartTokenHeader), uint(warehouseID), uint(productID)); err != nil {
+		c.JSON(guestCartErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "guest cart item berhasil dihapus",
+	})
+}
+
+func guestCartErrorStatus(err error) int {
+	if errors.Is(err, uc.ErrGuestCartUnavailable) {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusBadRequest
+}
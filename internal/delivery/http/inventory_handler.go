@@ -0,0 +1,154 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/repository"
+	uc "github.com/ifs21014-itdel/concurrent-order-processor/internal/usecase"
+	"github.com/ifs21014-itdel/concurrent-order-processor/pkg/jwt"
+)
+
+type InventoryHandler struct {
+	usecase *uc.InventorySnapshotUsecase
+}
+
+func NewInventoryHandler(rg *gin.RouterGroup, snapshotUC *uc.InventorySnapshotUsecase) {
+	h := &InventoryHandler{usecase: snapshotUC}
+
+	protected := rg.Group("/inventory")
+	protected.Use(jwt.AuthMiddleware())
+	{
+		protected.POST("/snapshots", h.TakeSnapshot)
+		protected.GET("/snapshots", h.ListSnapshots)
+		protected.GET("/snapshots/:id", h.GetSnapshot)
+		protected.GET("/levels", h.Levels)
+		protected.GET("/valuation", h.Valuation)
+	}
+}
+
+func (h *InventoryHandler) TakeSnapshot(c *gin.Context) {
+	userID, err := jwt.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	snapshot, err := h.usecase.Take(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(inventoryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  "success",
+		"message": "inventory snapshot taken",
+		"data":    snapshot,
+	})
+}
+
+func (h *InventoryHandler) ListSnapshots(c *gin.Context) {
+	limit := 0
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		limit = n
+	}
+
+	snapshots, err := h.usecase.List(c.Request.Context(), c.Query("kind"), limit)
+	if err != nil {
+		c.JSON(inventoryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": snapshots})
+}
+
+func (h *InventoryHandler) GetSnapshot(c *gin.Context) {
+	id, ok := parseUintParam(c, "id", "Invalid snapshot ID")
+	if !ok {
+		return
+	}
+
+	snapshot, err := h.usecase.Get(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(inventoryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": snapshot})
+}
+
+// Levels returns the stock at ?at= for an optional ?warehouse_id= and ?product_id=.
+func (h *InventoryHandler) Levels(c *gin.Context) {
+	at, ok := parseAtQuery(c)
+	if !ok {
+		return
+	}
+	warehouseID, ok := parseOptionalUintQuery(c, "warehouse_id", "Invalid warehouse ID")
+	if !ok {
+		return
+	}
+	productID, ok := parseOptionalUintQuery(c, "product_id", "Invalid product ID")
+	if !ok {
+		return
+	}
+
+	levels, err := h.usecase.Levels(c.Request.Context(), at, warehouseID, productID)
+	if err != nil {
+		c.JSON(inventoryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "at": at, "data": levels})
+}
+
+func (h *InventoryHandler) Valuation(c *gin.Context) {
+	at, ok := parseAtQuery(c)
+	if !ok {
+		return
+	}
+	warehouseID, ok := parseOptionalUintQuery(c, "warehouse_id", "Invalid warehouse ID")
+	if !ok {
+		return
+	}
+
+	report, err := h.usecase.Valuation(c.Request.Context(), at, warehouseID)
+	if err != nil {
+		c.JSON(inventoryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": report})
+}
+
+// parseAtQuery reads ?at= as RFC3339 or a plain date, which means the end of
+// that day, so ?at=2025-01-31 is the month-end stock. Empty means now.
+func parseAtQuery(c *gin.Context) (time.Time, bool) {
+	value := c.Query("at")
+	if value == "" {
+		return time.Now(), true
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, true
+	}
+	day, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid at, use RFC3339 or YYYY-MM-DD"})
+		return time.Time{}, false
+	}
+	return day.AddDate(0, 0, 1).Add(-time.Microsecond), true
+}
+
+// inventoryErrorStatus maps snapshot and history errors to HTTP status codes.
+func inventoryErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, uc.ErrInvalidInventoryQuery):
+		return http.StatusBadRequest
+	case errors.Is(err, repository.ErrInventorySnapshotNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -1,10 +1,12 @@
 package http
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/repository"
 	uc "github.com/ifs21014-itdel/concurrent-order-processor/internal/usecase"
 	"github.com/ifs21014-itdel/concurrent-order-processor/pkg/jwt"
 )
@@ -22,16 +24,23 @@ func NewOrderHandler(rg *gin.RouterGroup, orderUc *uc.OrderUsecase) {
 	protected.GET("/", h.GetOrderByUserId)
 	protected.GET("/status/:status", h.GetOrderByUserIdAndStatus)
 	protected.PATCH("/:id/status", h.UpdateOrderStatus)
+	protected.GET("/:id/lots", h.GetOrderLots)
+	protected.GET("/:id/picks", h.GetOrderPicks)
 }
 
+// CreateOrderInput does not take prices or shipping: the order is priced on
+// the server from the cart, the same way GET /api/cart previews it. With an
+// address_id the order is routed to the nearest warehouse that has stock.
 type CreateOrderInput struct {
-	CartID       uint    `json:"cart_id" binding:"required"`
-	Status       string  `json:"status" binding:"required"`
-	ShippingCost float64 `json:"shipping_cost"`
+	CartID         uint   `json:"cart_id" binding:"required"`
+	Status         string `json:"status" binding:"required"`
+	AddressID      uint   `json:"address_id"`
+	SingleShipment bool   `json:"single_shipment"`
 }
 
 type UpdateStatusInput struct {
-	Status string `json:"status" binding:"required"`
+	Status  string `json:"status" binding:"required"`
+	Version int32  `json:"version"`
 }
 
 func (h *OrderHandler) CreateOrder(c *gin.Context) {
@@ -48,14 +57,47 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 	userID, _ := c.Get("userID")
 
 	orderReq := uc.CreateOrderRequest{
-		UserID:       userID.(uint),
-		CartID:       input.CartID,
-		Status:       input.Status,
-		ShippingCost: input.ShippingCost,
+		UserID:         userID.(uint),
+		CartID:         input.CartID,
+		Status:         input.Status,
+		AddressID:      input.AddressID,
+		SingleShipment: input.SingleShipment,
 	}
 
 	ctx := c.Request.Context()
-	if err := h.usecase.CreateOrder(ctx, orderReq); err != nil {
+	order, err := h.usecase.CreateOrder(ctx, orderReq)
+	if err != nil {
+		var validationErr *uc.CartValidationError
+		if errors.As(err, &validationErr) {
+			c.JSON(http.StatusConflict, gin.H{
+				"status":     "error",
+				"message":    err.Error(),
+				"validation": validationErr.Result,
+			})
+			return
+		}
+		if errors.Is(err, uc.ErrCartForbidden) || errors.Is(err, repository.ErrCartNotFound) ||
+			errors.Is(err, uc.ErrInvalidCartInput) {
+			c.JSON(cartErrorStatus(err), gin.H{
+				"status":  "error",
+				"message": err.Error(),
+			})
+			return
+		}
+		if errors.Is(err, repository.ErrAddressNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"status":  "error",
+				"message": err.Error(),
+			})
+			return
+		}
+		if errors.Is(err, uc.ErrUnroutableOrder) {
+			c.JSON(http.StatusConflict, gin.H{
+				"status":  "error",
+				"message": err.Error(),
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status":  "error",
 			"message": "failed to create order",
@@ -67,6 +109,7 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 	c.JSON(http.StatusCreated, gin.H{
 		"status":  "success",
 		"message": "order created successfully from cart",
+		"data":    order,
 	})
 }
 
@@ -171,8 +214,21 @@ func (h *OrderHandler) UpdateOrderStatus(c *gin.Context) {
 		return
 	}
 
+	version, ok := requestVersion(c, input.Version)
+	if !ok {
+		return
+	}
+
 	ctx := c.Request.Context()
-	if err := h.usecase.UpdateOrderStatus(ctx, uint(id), input.Status); err != nil {
+	newVersion, err := h.usecase.UpdateOrderStatus(ctx, uint(id), input.Status, version)
+	if err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			c.JSON(http.StatusConflict, gin.H{
+				"status":  "error",
+				"message": err.Error(),
+			})
+			return
+		}
 		if err.Error() == "order not found" {
 			c.JSON(http.StatusNotFound, gin.H{
 				"status":  "error",
@@ -187,8 +243,79 @@ func (h *OrderHandler) UpdateOrderStatus(c *gin.Context) {
 		return
 	}
 
+	setETag(c, newVersion)
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "success",
 		"message": "order status updated successfully",
+		"version": newVersion,
+	})
+}
+
+// GetOrderLots lists the stock lots (batch and expiry) each item of the order
+// was picked from.
+func (h *OrderHandler) GetOrderLots(c *gin.Context) {
+	idParam := c.Param("id")
+	id, err := strconv.ParseUint(idParam, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "invalid order ID",
+		})
+		return
+	}
+	userID, _ := c.Get("userID")
+
+	ctx := c.Request.Context()
+	lots, err := h.usecase.GetOrderLots(ctx, userID.(uint), uint(id))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err.Error() == "order not found" {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   lots,
+	})
+}
+
+// GetOrderPicks returns the pick list of the order: the storage location each
+// item is picked from in each warehouse. Lines without a location come from
+// stock that was never put away.
+func (h *OrderHandler) GetOrderPicks(c *gin.Context) {
+	idParam := c.Param("id")
+	id, err := strconv.ParseUint(idParam, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "invalid order ID",
+		})
+		return
+	}
+	userID, _ := c.Get("userID")
+
+	ctx := c.Request.Context()
+	picks, err := h.usecase.GetOrderPicks(ctx, userID.(uint), uint(id))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err.Error() == "order not found" {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   picks,
 	})
 }
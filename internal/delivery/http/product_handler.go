@@ -1,29 +1,37 @@
 package http
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/repository"
 	uc "github.com/ifs21014-itdel/concurrent-order-processor/internal/usecase"
 	"github.com/ifs21014-itdel/concurrent-order-processor/pkg/jwt"
 )
 
 type ProductHandler struct {
-	usecase *uc.ProductUsecase
+	usecase      *uc.ProductUsecase
+	stockUsecase *uc.WarehouseStockUsecase
 }
 
-func NewProductHandler(rg *gin.RouterGroup, uc *uc.ProductUsecase) {
-	h := &ProductHandler{usecase: uc}
+func NewProductHandler(rg *gin.RouterGroup, uc *uc.ProductUsecase, stockUC *uc.WarehouseStockUsecase) {
+	h := &ProductHandler{usecase: uc, stockUsecase: stockUC}
 
 	protected := rg.Group("/products")
 	protected.Use(jwt.AuthMiddleware())
 
 	protected.POST("/", h.Create)
 	protected.GET("/", h.GetAll)
+	protected.POST("/reconcile-stock", h.ReconcileStock)
 	protected.GET("/:name", h.GetByName)
+	// gin allows one wildcard name per path segment, so the product ID of
+	// this route is read from :name
+	protected.GET("/:name/availability", h.Availability)
 	protected.PUT("/:id", h.Update)
 	protected.DELETE("/:id", h.Delete)
 }
@@ -42,7 +50,7 @@ func (h *ProductHandler) Create(c *gin.Context) {
 	fmt.Println("user Id:", userID)
 	input.UserID = userID.(uint)
 	if err := h.usecase.CreateProduct(ctx, &input); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -83,6 +91,30 @@ func (h *ProductHandler) GetByName(c *gin.Context) {
 	})
 }
 
+// Availability lists how much of a product each warehouse has on hand,
+// how much of it is reserved and how much is available.
+func (h *ProductHandler) Availability(c *gin.Context) {
+	id, ok := parseUintParam(c, "name", "invalid product ID")
+	if !ok {
+		return
+	}
+
+	availability, err := h.stockUsecase.ProductAvailability(c.Request.Context(), id)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, repository.ErrProductNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   availability,
+	})
+}
+
 func (h *ProductHandler) Update(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.ParseInt(idParam, 10, 64)
@@ -97,12 +129,18 @@ func (h *ProductHandler) Update(c *gin.Context) {
 		return
 	}
 	input.ID = uint(id)
+	version, ok := requestVersion(c, input.Version)
+	if !ok {
+		return
+	}
+	input.Version = version
 
 	ctx := c.Request.Context()
 	if err := h.usecase.UpdateProduct(ctx, &input); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
+	setETag(c, input.Version)
 
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "success",
@@ -130,3 +168,61 @@ func (h *ProductHandler) Delete(c *gin.Context) {
 		"message": "product deleted successfully",
 	})
 }
+
+// ReconcileStock reports warehouse quantities, which product stock is summed
+// from, that differ from the movement ledger; with ?apply=true they are
+// rebuilt from the ledger.
+func (h *ProductHandler) ReconcileStock(c *gin.Context) {
+	apply, err := strconv.ParseBool(c.DefaultQuery("apply", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid apply flag"})
+		return
+	}
+
+	discrepancies, err := h.usecase.ReconcileStock(c.Request.Context(), apply)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": fmt.Sprintf("%d stock row(s) out of sync with the ledger", len(discrepancies)),
+		"data": gin.H{
+			"applied":       apply,
+			"discrepancies": discrepancies,
+		},
+	})
+}
+
+// productErrorStatus maps product errors to HTTP status codes.
+func productErrorStatus(err error) int {
+	if errors.Is(err, repository.ErrDuplicateSKU) || errors.Is(err, repository.ErrVersionConflict) {
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// requestVersion returns the version an update is based on: the If-Match
+// header ("3", W/"3" or 3) or else the version field of the body. 0 means the
+// client sent neither and the update is not checked.
+func requestVersion(c *gin.Context, bodyVersion int32) (int32, bool) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return bodyVersion, true
+	}
+	n, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(header, "W/"), `"`), 10, 32)
+	if err != nil || n <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid If-Match header"})
+		return 0, false
+	}
+	if bodyVersion != 0 && bodyVersion != int32(n) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "If-Match and version do not match"})
+		return 0, false
+	}
+	return int32(n), true
+}
+
+// setETag exposes the row version so the client can send it back as If-Match.
+func setETag(c *gin.Context, version int32) {
+	c.Header("ETag", fmt.Sprintf(`"%d"`, version))
+}
@@ -0,0 +1,178 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/repository"
+	uc "github.com/ifs21014-itdel/concurrent-order-processor/internal/usecase"
+	"github.com/ifs21014-itdel/concurrent-order-processor/pkg/jwt"
+)
+
+type PurchaseOrderHandler struct {
+	usecase *uc.PurchaseOrderUsecase
+}
+
+func NewPurchaseOrderHandler(rg *gin.RouterGroup, purchaseOrderUC *uc.PurchaseOrderUsecase) {
+	h := &PurchaseOrderHandler{usecase: purchaseOrderUC}
+
+	protected := rg.Group("/purchase-orders")
+	protected.Use(jwt.AuthMiddleware())
+	{
+		protected.POST("/", h.Create)
+		protected.GET("/", h.List)
+		protected.GET("/:id", h.Get)
+		protected.DELETE("/:id", h.Delete)
+		protected.POST("/:id/send", h.Send)
+		protected.POST("/:id/receive", h.Receive)
+		protected.GET("/:id/receipts", h.Receipts)
+	}
+}
+
+func (h *PurchaseOrderHandler) Create(c *gin.Context) {
+	var input uc.CreatePurchaseOrderRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, err := jwt.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	po, err := h.usecase.Create(c.Request.Context(), userID, input)
+	if err != nil {
+		c.JSON(purchaseOrderErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  "success",
+		"message": "purchase order created successfully",
+		"data":    po,
+	})
+}
+
+func (h *PurchaseOrderHandler) List(c *gin.Context) {
+	supplierID, ok := parseOptionalUintQuery(c, "supplier_id", "Invalid supplier ID")
+	if !ok {
+		return
+	}
+	warehouseID, ok := parseOptionalUintQuery(c, "warehouse_id", "Invalid warehouse ID")
+	if !ok {
+		return
+	}
+
+	orders, err := h.usecase.List(c.Request.Context(), supplierID, warehouseID, c.Query("status"))
+	if err != nil {
+		c.JSON(purchaseOrderErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": orders})
+}
+
+func (h *PurchaseOrderHandler) Get(c *gin.Context) {
+	id, ok := parseUintParam(c, "id", "Invalid purchase order ID")
+	if !ok {
+		return
+	}
+
+	po, err := h.usecase.Get(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(purchaseOrderErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": po})
+}
+
+func (h *PurchaseOrderHandler) Delete(c *gin.Context) {
+	id, ok := parseUintParam(c, "id", "Invalid purchase order ID")
+	if !ok {
+		return
+	}
+
+	if err := h.usecase.Delete(c.Request.Context(), id); err != nil {
+		c.JSON(purchaseOrderErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "message": "purchase order deleted successfully"})
+}
+
+func (h *PurchaseOrderHandler) Send(c *gin.Context) {
+	id, ok := parseUintParam(c, "id", "Invalid purchase order ID")
+	if !ok {
+		return
+	}
+
+	po, err := h.usecase.Send(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(purchaseOrderErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "purchase order sent",
+		"data":    po,
+	})
+}
+
+func (h *PurchaseOrderHandler) Receive(c *gin.Context) {
+	id, ok := parseUintParam(c, "id", "Invalid purchase order ID")
+	if !ok {
+		return
+	}
+
+	var input uc.ReceivePurchaseOrderRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, err := jwt.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	po, err := h.usecase.Receive(c.Request.Context(), userID, id, input)
+	if err != nil {
+		c.JSON(purchaseOrderErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "purchase order receipt recorded",
+		"data":    po,
+	})
+}
+
+func (h *PurchaseOrderHandler) Receipts(c *gin.Context) {
+	id, ok := parseUintParam(c, "id", "Invalid purchase order ID")
+	if !ok {
+		return
+	}
+
+	receipts, err := h.usecase.Receipts(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(purchaseOrderErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": receipts})
+}
+
+// purchaseOrderErrorStatus maps purchase order errors to HTTP status codes.
+func purchaseOrderErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, uc.ErrInvalidPurchaseOrder):
+		return http.StatusBadRequest
+	case errors.Is(err, repository.ErrPurchaseOrderNotFound), errors.Is(err, repository.ErrPurchaseOrderLineNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, uc.ErrPurchaseOrderStatus), errors.Is(err, repository.ErrCapacityExceeded),
+		errors.Is(err, repository.ErrLotExpiryMismatch):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,74 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	uc "github.com/ifs21014-itdel/concurrent-order-processor/internal/usecase"
+	"github.com/ifs21014-itdel/concurrent-order-processor/pkg/jwt"
+)
+
+type ReplenishmentHandler struct {
+	usecase *uc.ReplenishmentUsecase
+}
+
+func NewReplenishmentHandler(rg *gin.RouterGroup, replenishmentUC *uc.ReplenishmentUsecase) {
+	h := &ReplenishmentHandler{usecase: replenishmentUC}
+
+	protected := rg.Group("/warehouses")
+	protected.Use(jwt.AuthMiddleware())
+	protected.GET("/:id/replenishment", h.Suggest)
+}
+
+// Suggest returns the replenishment suggestions of a warehouse as JSON, or
+// as a CSV download with ?format=csv.
+func (h *ReplenishmentHandler) Suggest(c *gin.Context) {
+	id, ok := parseUintParam(c, "id", "Invalid warehouse ID")
+	if !ok {
+		return
+	}
+	var params uc.ReplenishmentParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	format := strings.ToLower(c.DefaultQuery("format", "json"))
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or csv"})
+		return
+	}
+
+	report, err := h.usecase.Suggest(c.Request.Context(), id, params)
+	if err != nil {
+		c.JSON(replenishmentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	if format == "csv" {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="replenishment-warehouse-%d.csv"`, id))
+		c.Status(http.StatusOK)
+		if err := h.usecase.WriteCSV(c.Writer, report); err != nil {
+			log.Printf("replenishment export failed: %v", err)
+			c.Abort()
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": report})
+}
+
+// replenishmentErrorStatus maps replenishment errors to HTTP status codes.
+func replenishmentErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, uc.ErrInvalidReplenishment):
+		return http.StatusBadRequest
+	case errors.Is(err, uc.ErrReplenishmentWarehouse):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,68 @@
+package http
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	uc "github.com/ifs21014-itdel/concurrent-order-processor/internal/usecase"
+	"github.com/ifs21014-itdel/concurrent-order-processor/pkg/jwt"
+)
+
+type ReportHandler struct {
+	abandonedCartUC *uc.AbandonedCartUsecase
+}
+
+func NewReportHandler(rg *gin.RouterGroup, authUC *uc.AuthUsecase, abandonedCartUC *uc.AbandonedCartUsecase) {
+	h := &ReportHandler{abandonedCartUC: abandonedCartUC}
+
+	protected := rg.Group("/reports")
+	protected.Use(jwt.AuthMiddleware(), adminOnly(authUC))
+	protected.GET("/abandoned-carts", h.AbandonedCarts)
+	protected.POST("/abandoned-carts/run", h.RunAbandonedCartJob)
+}
+
+func (h *ReportHandler) AbandonedCarts(c *gin.Context) {
+	since, err := parseSince(c.Query("since"), 30*24*time.Hour)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid since, use RFC3339 or YYYY-MM-DD"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	stats, err := h.abandonedCartUC.Report(ctx, since)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   stats,
+	})
+}
+
+func (h *ReportHandler) RunAbandonedCartJob(c *gin.Context) {
+	ctx := c.Request.Context()
+	result, err := h.abandonedCartUC.RunOnce(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   result,
+	})
+}
+
+// parseSince accepts RFC3339 or a plain date; empty means now minus def.
+func parseSince(value string, def time.Duration) (time.Time, error) {
+	if value == "" {
+		return time.Now().Add(-def), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
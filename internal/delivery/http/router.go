@@ -12,7 +12,21 @@ func NewRouter(
 	warehouseStockUC *usecase.WarehouseStockUsecase,
 	orderUC *usecase.OrderUsecase,
 	cartUC *usecase.CartUsecase,
+	cartValidationUC *usecase.CartValidationUsecase,
 	cartItemUC *usecase.CartItemUsecase,
+	guestCartUC *usecase.GuestCartUsecase,
+	abandonedCartUC *usecase.AbandonedCartUsecase,
+	wishlistUC *usecase.WishlistUsecase,
+	transferUC *usecase.StockTransferUsecase,
+	stockAlertUC *usecase.StockAlertUsecase,
+	addressUC *usecase.AddressUsecase,
+	stockLotUC *usecase.StockLotUsecase,
+	stockCountUC *usecase.StockCountUsecase,
+	supplierUC *usecase.SupplierUsecase,
+	purchaseOrderUC *usecase.PurchaseOrderUsecase,
+	locationUC *usecase.StorageLocationUsecase,
+	inventoryUC *usecase.InventorySnapshotUsecase,
+	replenishmentUC *usecase.ReplenishmentUsecase,
 ) *gin.Engine {
 	r := gin.Default()
 
@@ -20,12 +34,26 @@ func NewRouter(
 	api := r.Group("/api")
 
 	// Initialize handlers
-	NewAuthHandler(api, authUC)
-	NewProductHandler(api, productUC)
+	NewAuthHandler(api, authUC, guestCartUC)
+	NewProductHandler(api, productUC, warehouseStockUC)
 	NewWarehouseHandler(api, wareHouseUC)
 	NewWarehouseStockHandler(api, warehouseStockUC)
 	NewOrderHandler(api, orderUC)
-	NewCartHandler(api, cartUC)
+	NewCartHandler(api, cartUC, cartValidationUC)
+	NewCartItemHandler(api, cartItemUC)
+	NewGuestCartHandler(api, guestCartUC)
+	NewReportHandler(api, authUC, abandonedCartUC)
+	NewWishlistHandler(api, wishlistUC)
+	NewStockTransferHandler(api, transferUC)
+	NewStockAlertHandler(api, stockAlertUC)
+	NewAddressHandler(api, addressUC)
+	NewStockLotHandler(api, stockLotUC)
+	NewStockCountHandler(api, stockCountUC)
+	NewSupplierHandler(api, supplierUC)
+	NewPurchaseOrderHandler(api, purchaseOrderUC)
+	NewStorageLocationHandler(api, locationUC)
+	NewInventoryHandler(api, inventoryUC)
+	NewReplenishmentHandler(api, replenishmentUC)
 
 	return r
 }
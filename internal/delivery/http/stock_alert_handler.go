@@ -0,0 +1,51 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	uc "github.com/ifs21014-itdel/concurrent-order-processor/internal/usecase"
+	"github.com/ifs21014-itdel/concurrent-order-processor/pkg/jwt"
+)
+
+type StockAlertHandler struct {
+	usecase *uc.StockAlertUsecase
+}
+
+func NewStockAlertHandler(rg *gin.RouterGroup, stockAlertUC *uc.StockAlertUsecase) {
+	h := &StockAlertHandler{usecase: stockAlertUC}
+
+	protected := rg.Group("/stock-alerts")
+	protected.Use(jwt.AuthMiddleware())
+	protected.GET("/", h.List)
+	protected.POST("/deliver", h.Deliver)
+}
+
+func (h *StockAlertHandler) List(c *gin.Context) {
+	warehouseID, ok := parseOptionalUintQuery(c, "warehouse_id", "Invalid warehouse ID")
+	if !ok {
+		return
+	}
+
+	alerts, err := h.usecase.List(c.Request.Context(), warehouseID, c.Query("status"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, uc.ErrInvalidStockAlertFilter) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": alerts})
+}
+
+// Deliver sends pending alerts now instead of waiting for the job.
+func (h *StockAlertHandler) Deliver(c *gin.Context) {
+	result, err := h.usecase.DeliverPending(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": result})
+}
@@ -0,0 +1,175 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/repository"
+	uc "github.com/ifs21014-itdel/concurrent-order-processor/internal/usecase"
+	"github.com/ifs21014-itdel/concurrent-order-processor/pkg/jwt"
+)
+
+type StockCountHandler struct {
+	usecase *uc.StockCountUsecase
+}
+
+func NewStockCountHandler(rg *gin.RouterGroup, countUC *uc.StockCountUsecase) {
+	h := &StockCountHandler{usecase: countUC}
+
+	protected := rg.Group("/stock-counts")
+	protected.Use(jwt.AuthMiddleware())
+	{
+		protected.POST("/", h.Create)
+		protected.GET("/", h.List)
+		protected.GET("/:id", h.Get)
+		protected.POST("/:id/counts", h.SubmitCounts)
+		protected.POST("/:id/approve", h.Approve)
+		protected.POST("/:id/cancel", h.Cancel)
+	}
+}
+
+func (h *StockCountHandler) Create(c *gin.Context) {
+	var input uc.CreateStockCountRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, err := jwt.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, err := h.usecase.Create(c.Request.Context(), userID, input)
+	if err != nil {
+		c.JSON(stockCountErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  "success",
+		"message": "stock count session opened",
+		"data":    session,
+	})
+}
+
+func (h *StockCountHandler) List(c *gin.Context) {
+	warehouseID, ok := parseOptionalUintQuery(c, "warehouse_id", "Invalid warehouse ID")
+	if !ok {
+		return
+	}
+
+	sessions, err := h.usecase.List(c.Request.Context(), warehouseID, c.Query("status"))
+	if err != nil {
+		c.JSON(stockCountErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": sessions})
+}
+
+func (h *StockCountHandler) Get(c *gin.Context) {
+	id, ok := parseUintParam(c, "id", "Invalid stock count ID")
+	if !ok {
+		return
+	}
+
+	session, err := h.usecase.Get(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(stockCountErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": session})
+}
+
+func (h *StockCountHandler) SubmitCounts(c *gin.Context) {
+	id, ok := parseUintParam(c, "id", "Invalid stock count ID")
+	if !ok {
+		return
+	}
+
+	var input uc.SubmitCountsRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, err := jwt.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, err := h.usecase.SubmitCounts(c.Request.Context(), userID, id, input)
+	if err != nil {
+		c.JSON(stockCountErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "counts recorded",
+		"data":    session,
+	})
+}
+
+func (h *StockCountHandler) Approve(c *gin.Context) {
+	id, ok := parseUintParam(c, "id", "Invalid stock count ID")
+	if !ok {
+		return
+	}
+
+	userID, err := jwt.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, err := h.usecase.Approve(c.Request.Context(), userID, id)
+	if err != nil {
+		c.JSON(stockCountErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "stock count approved and adjustments posted",
+		"data":    session,
+	})
+}
+
+func (h *StockCountHandler) Cancel(c *gin.Context) {
+	id, ok := parseUintParam(c, "id", "Invalid stock count ID")
+	if !ok {
+		return
+	}
+
+	userID, err := jwt.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, err := h.usecase.Cancel(c.Request.Context(), userID, id)
+	if err != nil {
+		c.JSON(stockCountErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "stock count cancelled",
+		"data":    session,
+	})
+}
+
+// stockCountErrorStatus maps stock count errors to HTTP status codes.
+func stockCountErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, uc.ErrInvalidStockCount), errors.Is(err, repository.ErrStockCountLineNotFound):
+		return http.StatusBadRequest
+	case errors.Is(err, repository.ErrStockCountNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, uc.ErrStockCountStatus), errors.Is(err, repository.ErrStockCountAlreadyOpen):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
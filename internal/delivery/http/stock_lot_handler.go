@@ -0,0 +1,117 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/repository"
+	uc "github.com/ifs21014-itdel/concurrent-order-processor/internal/usecase"
+	"github.com/ifs21014-itdel/concurrent-order-processor/pkg/jwt"
+)
+
+type StockLotHandler struct {
+	usecase *uc.StockLotUsecase
+}
+
+// NewStockLotHandler registers the lot routes under /warehouseStocks/lots,
+// next to the rest of the warehouse stock API.
+func NewStockLotHandler(rg *gin.RouterGroup, lotUC *uc.StockLotUsecase) {
+	h := &StockLotHandler{usecase: lotUC}
+
+	protected := rg.Group("/warehouseStocks/lots")
+	protected.Use(jwt.AuthMiddleware())
+	protected.POST("/", h.Receive)
+	protected.GET("/", h.List)
+	protected.GET("/expiring", h.Expiring)
+}
+
+func (h *StockLotHandler) Receive(c *gin.Context) {
+	var input uc.ReceiveLotRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, err := jwt.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	lot, result, err := h.usecase.Receive(c.Request.Context(), userID, input)
+	if err != nil {
+		c.JSON(stockLotErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  "success",
+		"message": "lot received successfully",
+		"data": gin.H{
+			"lot":    lot,
+			"change": result,
+		},
+	})
+}
+
+func (h *StockLotHandler) List(c *gin.Context) {
+	warehouseID, ok := parseOptionalUintQuery(c, "warehouse_id", "Invalid warehouse ID")
+	if !ok {
+		return
+	}
+	productID, ok := parseOptionalUintQuery(c, "product_id", "Invalid product ID")
+	if !ok {
+		return
+	}
+
+	lots, err := h.usecase.ListLots(c.Request.Context(), warehouseID, productID)
+	if err != nil {
+		c.JSON(stockLotErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   lots,
+	})
+}
+
+// Expiring reports lots that expire within ?days= (default 30), expired ones
+// included, optionally for one ?warehouse_id=.
+func (h *StockLotHandler) Expiring(c *gin.Context) {
+	warehouseID, ok := parseOptionalUintQuery(c, "warehouse_id", "Invalid warehouse ID")
+	if !ok {
+		return
+	}
+	days := 0
+	if v := c.Query("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid days"})
+			return
+		}
+		days = n
+	}
+
+	lots, err := h.usecase.ExpiringLots(c.Request.Context(), days, warehouseID)
+	if err != nil {
+		c.JSON(stockLotErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   lots,
+	})
+}
+
+func stockLotErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, uc.ErrInvalidStockLot):
+		return http.StatusBadRequest
+	case errors.Is(err, repository.ErrLotExpiryMismatch), errors.Is(err, repository.ErrCapacityExceeded):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
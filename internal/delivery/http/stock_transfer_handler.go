@@ -0,0 +1,218 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/repository"
+	uc "github.com/ifs21014-itdel/concurrent-order-processor/internal/usecase"
+	"github.com/ifs21014-itdel/concurrent-order-processor/pkg/jwt"
+)
+
+type StockTransferHandler struct {
+	usecase *uc.StockTransferUsecase
+}
+
+func NewStockTransferHandler(rg *gin.RouterGroup, transferUC *uc.StockTransferUsecase) {
+	h := &StockTransferHandler{usecase: transferUC}
+
+	protected := rg.Group("/transfers")
+	protected.Use(jwt.AuthMiddleware())
+	{
+		protected.POST("/", h.Create)
+		protected.GET("/", h.List)
+		protected.GET("/in-transit", h.InTransit)
+		protected.GET("/:id", h.Get)
+		protected.DELETE("/:id", h.Delete)
+		protected.POST("/:id/dispatch", h.Dispatch)
+		protected.POST("/:id/in-transit", h.MarkInTransit)
+		protected.POST("/:id/receive", h.Receive)
+	}
+}
+
+func (h *StockTransferHandler) Create(c *gin.Context) {
+	var input uc.CreateTransferRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, err := jwt.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	transfer, err := h.usecase.Create(c.Request.Context(), userID, input)
+	if err != nil {
+		c.JSON(transferErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  "success",
+		"message": "transfer created successfully",
+		"data":    transfer,
+	})
+}
+
+func (h *StockTransferHandler) List(c *gin.Context) {
+	warehouseID, ok := parseOptionalUintQuery(c, "warehouse_id", "Invalid warehouse ID")
+	if !ok {
+		return
+	}
+
+	transfers, err := h.usecase.List(c.Request.Context(), warehouseID, c.Query("status"))
+	if err != nil {
+		c.JSON(transferErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": transfers})
+}
+
+func (h *StockTransferHandler) InTransit(c *gin.Context) {
+	warehouseID, ok := parseOptionalUintQuery(c, "warehouse_id", "Invalid warehouse ID")
+	if !ok {
+		return
+	}
+
+	stock, err := h.usecase.InTransit(c.Request.Context(), warehouseID)
+	if err != nil {
+		c.JSON(transferErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": stock})
+}
+
+func (h *StockTransferHandler) Get(c *gin.Context) {
+	id, ok := parseUintParam(c, "id", "Invalid transfer ID")
+	if !ok {
+		return
+	}
+
+	transfer, err := h.usecase.Get(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(transferErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": transfer})
+}
+
+func (h *StockTransferHandler) Delete(c *gin.Context) {
+	id, ok := parseUintParam(c, "id", "Invalid transfer ID")
+	if !ok {
+		return
+	}
+
+	if err := h.usecase.Delete(c.Request.Context(), id); err != nil {
+		c.JSON(transferErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "message": "transfer deleted successfully"})
+}
+
+func (h *StockTransferHandler) Dispatch(c *gin.Context) {
+	id, ok := parseUintParam(c, "id", "Invalid transfer ID")
+	if !ok {
+		return
+	}
+
+	userID, err := jwt.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	transfer, err := h.usecase.Dispatch(c.Request.Context(), userID, id)
+	if err != nil {
+		c.JSON(transferErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "transfer dispatched",
+		"data":    transfer,
+	})
+}
+
+func (h *StockTransferHandler) MarkInTransit(c *gin.Context) {
+	id, ok := parseUintParam(c, "id", "Invalid transfer ID")
+	if !ok {
+		return
+	}
+
+	transfer, err := h.usecase.MarkInTransit(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(transferErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "transfer marked in transit",
+		"data":    transfer,
+	})
+}
+
+func (h *StockTransferHandler) Receive(c *gin.Context) {
+	id, ok := parseUintParam(c, "id", "Invalid transfer ID")
+	if !ok {
+		return
+	}
+
+	var input uc.ReceiveTransferRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, err := jwt.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	transfer, err := h.usecase.Receive(c.Request.Context(), userID, id, input)
+	if err != nil {
+		c.JSON(transferErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "transfer receipt recorded",
+		"data":    transfer,
+	})
+}
+
+// parseOptionalUintQuery reads an optional numeric query parameter; a
+// missing value is 0.
+func parseOptionalUintQuery(c *gin.Context, name, message string) (uint, bool) {
+	v := c.Query(name)
+	if v == "" {
+		return 0, true
+	}
+	n, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return 0, false
+	}
+	return uint(n), true
+}
+
+// transferErrorStatus maps stock transfer errors to HTTP status codes.
+func transferErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, uc.ErrInvalidTransfer):
+		return http.StatusBadRequest
+	case errors.Is(err, repository.ErrTransferNotFound), errors.Is(err, repository.ErrTransferLineNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, uc.ErrTransferStatus),
+		errors.Is(err, repository.ErrInsufficientStock),
+		errors.Is(err, repository.ErrWarehouseStockNotFound),
+		errors.Is(err, repository.ErrCapacityExceeded),
+		errors.Is(err, repository.ErrLotExpiryMismatch):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
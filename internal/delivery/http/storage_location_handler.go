@@ -0,0 +1,135 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/repository"
+	uc "github.com/ifs21014-itdel/concurrent-order-processor/internal/usecase"
+	"github.com/ifs21014-itdel/concurrent-order-processor/pkg/jwt"
+)
+
+type StorageLocationHandler struct {
+	usecase *uc.StorageLocationUsecase
+}
+
+// NewStorageLocationHandler registers the bin location routes under
+// /warehouseStocks/locations, next to the rest of the warehouse stock API.
+func NewStorageLocationHandler(rg *gin.RouterGroup, locationUC *uc.StorageLocationUsecase) {
+	h := &StorageLocationHandler{usecase: locationUC}
+
+	protected := rg.Group("/warehouseStocks/locations")
+	protected.Use(jwt.AuthMiddleware())
+	protected.POST("/", h.Create)
+	protected.GET("/", h.List)
+	protected.DELETE("/:id", h.Delete)
+	protected.GET("/stock", h.Stock)
+	protected.POST("/move", h.Move)
+}
+
+func (h *StorageLocationHandler) Create(c *gin.Context) {
+	var input uc.CreateLocationRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	location, err := h.usecase.Create(c.Request.Context(), input)
+	if err != nil {
+		c.JSON(locationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  "success",
+		"message": "location created successfully",
+		"data":    location,
+	})
+}
+
+func (h *StorageLocationHandler) List(c *gin.Context) {
+	warehouseID, ok := parseOptionalUintQuery(c, "warehouse_id", "Invalid warehouse ID")
+	if !ok {
+		return
+	}
+
+	locations, err := h.usecase.List(c.Request.Context(), warehouseID)
+	if err != nil {
+		c.JSON(locationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": locations})
+}
+
+func (h *StorageLocationHandler) Delete(c *gin.Context) {
+	id, ok := parseUintParam(c, "id", "Invalid location ID")
+	if !ok {
+		return
+	}
+
+	if err := h.usecase.Delete(c.Request.Context(), id); err != nil {
+		c.JSON(locationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "message": "location deleted successfully"})
+}
+
+// Stock lists what is put away where in ?warehouse_id=, optionally for one
+// ?product_id= or ?location_id=.
+func (h *StorageLocationHandler) Stock(c *gin.Context) {
+	warehouseID, ok := parseOptionalUintQuery(c, "warehouse_id", "Invalid warehouse ID")
+	if !ok {
+		return
+	}
+	productID, ok := parseOptionalUintQuery(c, "product_id", "Invalid product ID")
+	if !ok {
+		return
+	}
+	locationID, ok := parseOptionalUintQuery(c, "location_id", "Invalid location ID")
+	if !ok {
+		return
+	}
+
+	stock, err := h.usecase.Stock(c.Request.Context(), warehouseID, productID, locationID)
+	if err != nil {
+		c.JSON(locationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": stock})
+}
+
+func (h *StorageLocationHandler) Move(c *gin.Context) {
+	var input domain.LocationMove
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	stock, err := h.usecase.Move(c.Request.Context(), input)
+	if err != nil {
+		c.JSON(locationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "stock moved",
+		"data":    stock,
+	})
+}
+
+// locationErrorStatus maps storage location errors to HTTP status codes.
+func locationErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, uc.ErrInvalidLocation):
+		return http.StatusBadRequest
+	case errors.Is(err, repository.ErrLocationNotFound), errors.Is(err, repository.ErrWarehouseStockNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, repository.ErrLocationExists),
+		errors.Is(err, repository.ErrLocationNotEmpty),
+		errors.Is(err, repository.ErrInsufficientLocationStock):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,133 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/repository"
+	uc "github.com/ifs21014-itdel/concurrent-order-processor/internal/usecase"
+	"github.com/ifs21014-itdel/concurrent-order-processor/pkg/jwt"
+)
+
+type SupplierHandler struct {
+	usecase *uc.SupplierUsecase
+}
+
+func NewSupplierHandler(rg *gin.RouterGroup, supplierUC *uc.SupplierUsecase) {
+	h := &SupplierHandler{usecase: supplierUC}
+
+	protected := rg.Group("/suppliers")
+	protected.Use(jwt.AuthMiddleware())
+	protected.POST("/", h.Create)
+	protected.GET("/", h.GetAll)
+	protected.GET("/:id", h.GetByID)
+	protected.PUT("/:id", h.Update)
+	protected.DELETE("/:id", h.Delete)
+}
+
+type supplierInput struct {
+	Name    string  `json:"name" binding:"required"`
+	Email   *string `json:"email"`
+	Phone   *string `json:"phone"`
+	Address *string `json:"address"`
+}
+
+func (in supplierInput) supplier() *domain.Supplier {
+	return &domain.Supplier{Name: in.Name, Email: in.Email, Phone: in.Phone, Address: in.Address}
+}
+
+func (h *SupplierHandler) Create(c *gin.Context) {
+	var input supplierInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	supplier := input.supplier()
+	if err := h.usecase.Create(c.Request.Context(), supplier); err != nil {
+		c.JSON(supplierErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  "success",
+		"message": "supplier created successfully",
+		"data":    supplier,
+	})
+}
+
+func (h *SupplierHandler) GetAll(c *gin.Context) {
+	suppliers, err := h.usecase.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": suppliers})
+}
+
+func (h *SupplierHandler) GetByID(c *gin.Context) {
+	id, ok := parseUintParam(c, "id", "Invalid supplier ID")
+	if !ok {
+		return
+	}
+
+	supplier, err := h.usecase.Get(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(supplierErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": supplier})
+}
+
+func (h *SupplierHandler) Update(c *gin.Context) {
+	id, ok := parseUintParam(c, "id", "Invalid supplier ID")
+	if !ok {
+		return
+	}
+
+	var input supplierInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	supplier := input.supplier()
+	supplier.ID = id
+	if err := h.usecase.Update(c.Request.Context(), supplier); err != nil {
+		c.JSON(supplierErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "supplier updated successfully",
+		"data":    supplier,
+	})
+}
+
+func (h *SupplierHandler) Delete(c *gin.Context) {
+	id, ok := parseUintParam(c, "id", "Invalid supplier ID")
+	if !ok {
+		return
+	}
+
+	if err := h.usecase.Delete(c.Request.Context(), id); err != nil {
+		c.JSON(supplierErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "message": "supplier deleted successfully"})
+}
+
+// supplierErrorStatus maps supplier errors to HTTP status codes.
+func supplierErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, uc.ErrInvalidSupplier):
+		return http.StatusBadRequest
+	case errors.Is(err, repository.ErrSupplierNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, repository.ErrSupplierInUse):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -1,11 +1,13 @@
 package http
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/repository"
 	uc "github.com/ifs21014-itdel/concurrent-order-processor/internal/usecase"
 	"github.com/ifs21014-itdel/concurrent-order-processor/pkg/jwt"
 )
@@ -59,16 +61,27 @@ func (h *WarehouseHandler) Update(c *gin.Context) {
 	}
 
 	input.ID = uint(id)
+	version, ok := requestVersion(c, input.Version)
+	if !ok {
+		return
+	}
+	input.Version = version
+
 	ctx := c.Request.Context()
 	if err := h.usecase.UpdateWarehouse(ctx, &input); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		status := http.StatusInternalServerError
+		if errors.Is(err, repository.ErrVersionConflict) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
 
+	setETag(c, input.Version)
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "success",
 		"message": "warehouse updated successfully",
-		"data":    "input",
+		"data":    input,
 	})
 }
 
@@ -1,11 +1,16 @@
 package http
 
 import (
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/repository"
 	uc "github.com/ifs21014-itdel/concurrent-order-processor/internal/usecase"
 	"github.com/ifs21014-itdel/concurrent-order-processor/pkg/jwt"
 )
@@ -26,12 +31,21 @@ func NewWarehouseStockHandler(
 		protected.POST("/", h.Create)
 		protected.DELETE("/:id", h.Delete)
 		protected.GET("/", h.GetAll)
-		protected.PUT("/concurrent", h.ConcurrentUpdateQuantities)
+		protected.POST("/adjust", h.Adjust)
+		protected.PUT("/reorder-point", h.SetReorderPoint)
+		protected.PUT("/concurrent", h.ConcurrentAdjust)
+		protected.GET("/movements", h.ListMovements)
+		protected.POST("/rebuild", h.RebuildFromLedger)
+		protected.POST("/import", h.ImportStock)
+		protected.GET("/export", h.ExportStock)
 		protected.GET("/:warehouseId", h.GetByWareHouseId)
-		protected.PUT("/:id", h.UpdateQuantity)
+		protected.PUT("/:id", h.Stocktake)
 	}
 }
 
+// Create adds stock of a product to a warehouse. If the warehouse already
+// holds the product the existing row is changed instead (?mode=add, the
+// default, or ?mode=replace) and the response is 200 rather than 201.
 func (h *WarehouseStockHandler) Create(c *gin.Context) {
 	var input domain.WarehouseStock
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -39,10 +53,21 @@ func (h *WarehouseStockHandler) Create(c *gin.Context) {
 		return
 	}
 
+	userID, err := jwt.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
 	ctx := c.Request.Context()
-	res, err := h.usecase.Create(ctx, &input)
+	mode := domain.StockUpsertMode(c.Query("mode"))
+	res, created, err := h.usecase.Create(ctx, userID, &input, mode)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(warehouseStockErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if !created {
+		c.JSON(http.StatusOK, res)
 		return
 	}
 	c.JSON(http.StatusCreated, res)
@@ -55,8 +80,14 @@ func (h *WarehouseStockHandler) Delete(c *gin.Context) {
 		return
 	}
 
+	userID, err := jwt.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
 	ctx := c.Request.Context()
-	res, err := h.usecase.Delete(ctx, uint(id))
+	res, err := h.usecase.Delete(ctx, userID, uint(id))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -90,31 +121,338 @@ func (h *WarehouseStockHandler) GetByWareHouseId(c *gin.Context) {
 	c.JSON(http.StatusOK, res)
 }
 
-func (h *WarehouseStockHandler) UpdateQuantity(c *gin.Context) {
+// Adjust applies a signed delta to one stock row.
+func (h *WarehouseStockHandler) Adjust(c *gin.Context) {
+	var input uc.AdjustStockRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	var input domain.WarehouseStock
+	userID, err := jwt.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.usecase.Adjust(c.Request.Context(), userID, input)
+	if err != nil {
+		c.JSON(warehouseStockErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "stock adjusted successfully",
+		"data":    result,
+	})
+}
+
+// Stocktake sets the counted quantity of one stock row. The request carries
+// the quantity the count started from and/or the row version (If-Match); if
+// the row changed meanwhile the response is 409 with the current quantity and
+// version, and the count has to be checked again.
+func (h *WarehouseStockHandler) Stocktake(c *gin.Context) {
+	var input uc.StocktakeRequest
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	version, ok := requestVersion(c, input.Version)
+	if !ok {
+		return
+	}
+	input.Version = version
 
-	ctx := c.Request.Context()
-	res, err := h.usecase.UpdateQuantity(ctx, input.WarehouseID, input.ProductID, input.Quantity)
+	userID, err := jwt.GetUserIDFromContext(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, res)
+
+	result, err := h.usecase.Stocktake(c.Request.Context(), userID, input)
+	if err != nil {
+		body := gin.H{"error": err.Error()}
+		if result != nil {
+			body["current_quantity"] = result.QuantityAfter
+			body["current_version"] = result.Version
+		}
+		c.JSON(warehouseStockErrorStatus(err), body)
+		return
+	}
+	setETag(c, result.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "stocktake applied successfully",
+		"data":    result,
+	})
+}
+
+// SetReorderPoint sets or clears the low-stock threshold of one stock row.
+func (h *WarehouseStockHandler) SetReorderPoint(c *gin.Context) {
+	var input uc.ReorderPointRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	version, ok := requestVersion(c, input.Version)
+	if !ok {
+		return
+	}
+	input.Version = version
+
+	stock, err := h.usecase.SetReorderPoint(c.Request.Context(), input)
+	if err != nil {
+		c.JSON(warehouseStockErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	setETag(c, stock.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "reorder point updated successfully",
+		"data":    stock,
+	})
 }
 
-func (h *WarehouseStockHandler) ConcurrentUpdateQuantities(c *gin.Context) {
-	var updates []domain.WarehouseStock
+// ConcurrentAdjust applies a batch of adjustments and reports the outcome of
+// each one. By default they run in parallel and independently; with
+// ?atomic=true they are applied in one transaction, all or nothing.
+func (h *WarehouseStockHandler) ConcurrentAdjust(c *gin.Context) {
+	atomic, err := strconv.ParseBool(c.DefaultQuery("atomic", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid atomic flag"})
+		return
+	}
+
+	var updates []uc.AdjustStockRequest
 	if err := c.ShouldBindJSON(&updates); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if len(updates) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "at least one adjustment is required"})
+		return
+	}
 
-	ctx := c.Request.Context()
-	res := h.usecase.ConcurrentUpdateQuantities(ctx, updates)
+	userID, err := jwt.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	if atomic {
+		results, err := h.usecase.AtomicAdjust(c.Request.Context(), userID, updates)
+		if err != nil {
+			c.JSON(warehouseStockErrorStatus(err), gin.H{"error": err.Error(), "results": results})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "done",
+			"message": fmt.Sprintf("all %d adjustments applied", len(results)),
+			"results": results,
+		})
+		return
+	}
+
+	results := h.usecase.ConcurrentAdjust(c.Request.Context(), userID, updates)
+	failed := 0
+	for _, r := range results {
+		if !r.Success {
+			failed++
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "done",
+		"message": fmt.Sprintf("%d of %d adjustments applied", len(results)-failed, len(results)),
+		"results": results,
+	})
+}
+
+// ListMovements pages through the stock ledger of a warehouse, newest first.
+// Pass the returned next_before_id as before_id to get the next page.
+func (h *WarehouseStockHandler) ListMovements(c *gin.Context) {
+	var filter domain.StockMovementFilter
+
+	warehouseID, err := strconv.ParseUint(c.Query("warehouse_id"), 10, 64)
+	if err != nil || warehouseID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid warehouse ID"})
+		return
+	}
+	filter.WarehouseID = uint(warehouseID)
+
+	if v := c.Query("product_id"); v != "" {
+		productID, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+			return
+		}
+		filter.ProductID = uint(productID)
+	}
+	if v := c.Query("before_id"); v != "" {
+		beforeID, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid before_id"})
+			return
+		}
+		filter.BeforeID = beforeID
+	}
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		filter.Limit = limit
+	}
+
+	res, err := h.usecase.ListMovements(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(warehouseStockErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// RebuildFromLedger reports quantities that differ from the ledger; with
+// ?apply=true they are overwritten with the ledger values.
+func (h *WarehouseStockHandler) RebuildFromLedger(c *gin.Context) {
+	apply, err := strconv.ParseBool(c.DefaultQuery("apply", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid apply flag"})
+		return
+	}
+
+	res, err := h.usecase.RebuildFromLedger(c.Request.Context(), apply)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 	c.JSON(http.StatusOK, res)
 }
+
+// maxStockImportBytes limits the size of an uploaded stock file.
+const maxStockImportBytes = 10 << 20
+
+// ImportStock reads a stock count file (CSV or NDJSON) from the request body
+// and sets every listed quantity. Without ?apply=true it only returns the
+// diff. Invalid rows are reported per line and do not stop the valid ones.
+func (h *WarehouseStockHandler) ImportStock(c *gin.Context) {
+	format, ok := stockFileFormat(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or ndjson"})
+		return
+	}
+	apply, err := strconv.ParseBool(c.DefaultQuery("apply", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid apply flag"})
+		return
+	}
+	chunkSize, ok := parseOptionalUintQuery(c, "chunk_size", "Invalid chunk_size")
+	if !ok {
+		return
+	}
+
+	userID, err := jwt.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, maxStockImportBytes)
+	rows, err := uc.ParseStockImport(body, format)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "stock file is too large"})
+			return
+		}
+		c.JSON(warehouseStockErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := h.usecase.ImportStock(c.Request.Context(), userID, rows, uc.StockImportOptions{
+		Apply:     apply,
+		ChunkSize: int(chunkSize),
+		Note:      c.Query("note"),
+	})
+	if err != nil {
+		c.JSON(warehouseStockErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	status := "success"
+	if report.Failed > 0 {
+		status = "partial"
+	}
+	verb := "would change"
+	if apply {
+		verb = "changed"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  status,
+		"message": fmt.Sprintf("%d of %d rows %s, %d failed", report.Created+report.Updated, report.Total, verb, report.Failed),
+		"data":    report,
+	})
+}
+
+// ExportStock streams the current stock in the import format, so an export
+// can be edited and imported again.
+func (h *WarehouseStockHandler) ExportStock(c *gin.Context) {
+	format, ok := stockFileFormat(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or ndjson"})
+		return
+	}
+	warehouseID, ok := parseOptionalUintQuery(c, "warehouse_id", "Invalid warehouse ID")
+	if !ok {
+		return
+	}
+
+	contentType := "text/csv; charset=utf-8"
+	if format == uc.StockFormatNDJSON {
+		contentType = "application/x-ndjson"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="warehouse-stock.%s"`, format))
+	c.Status(http.StatusOK)
+
+	if err := h.usecase.ExportStock(c.Request.Context(), c.Writer, format, warehouseID); err != nil {
+		// header sudah terkirim; yang bisa dilakukan hanya memutus stream
+		log.Printf("stock export failed: %v", err)
+		c.Abort()
+	}
+}
+
+// stockFileFormat reads ?format=, falling back to the request Content-Type
+// and then to CSV.
+func stockFileFormat(c *gin.Context) (string, bool) {
+	format := strings.ToLower(c.Query("format"))
+	if format == "" {
+		switch ct := c.ContentType(); {
+		case strings.Contains(ct, "ndjson"), strings.Contains(ct, "jsonl"):
+			format = uc.StockFormatNDJSON
+		default:
+			format = uc.StockFormatCSV
+		}
+	}
+	if format == "jsonl" {
+		format = uc.StockFormatNDJSON
+	}
+	return format, format == uc.StockFormatCSV || format == uc.StockFormatNDJSON
+}
+
+// warehouseStockErrorStatus maps warehouse stock errors to HTTP status codes.
+func warehouseStockErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, uc.ErrInvalidMovementFilter), errors.Is(err, uc.ErrInvalidStockImport):
+		return http.StatusBadRequest
+	case errors.Is(err, repository.ErrWarehouseStockNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, uc.ErrInvalidStockAdjustment):
+		return http.StatusBadRequest
+	case errors.Is(err, repository.ErrInsufficientStock), errors.Is(err, repository.ErrStockChanged),
+		errors.Is(err, repository.ErrVersionConflict), errors.Is(err, repository.ErrCapacityExceeded):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,382 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/repository"
+	uc "github.com/ifs21014-itdel/concurrent-order-processor/internal/usecase"
+	"github.com/ifs21014-itdel/concurrent-order-processor/pkg/jwt"
+)
+
+type WishlistHandler struct {
+	usecase *uc.WishlistUsecase
+}
+
+// NewWishlistHandler registers the wishlist routes. Everything is protected
+// except GET /wishlists/shared/:token, which serves shared wishlists publicly.
+func NewWishlistHandler(rg *gin.RouterGroup, wishlistUC *uc.WishlistUsecase) {
+	h := &WishlistHandler{usecase: wishlistUC}
+
+	rg.GET("/wishlists/shared/:token", h.GetShared)
+
+	protected := rg.Group("/wishlists")
+	protected.Use(jwt.AuthMiddleware())
+	protected.POST("/", h.Create)
+	protected.GET("/", h.GetAll)
+	protected.GET("/:id", h.GetByID)
+	protected.PATCH("/:id", h.Rename)
+	protected.DELETE("/:id", h.Delete)
+	protected.POST("/:id/share", h.Share)
+	protected.DELETE("/:id/share", h.Unshare)
+	protected.POST("/:id/items", h.AddItem)
+	protected.DELETE("/:id/items/:itemId", h.RemoveItem)
+	protected.POST("/:id/items/:itemId/move-to-cart", h.MoveToCart)
+
+	cart := rg.Group("/cart")
+	cart.Use(jwt.AuthMiddleware())
+	cart.POST("/item/:id/move-to-wishlist", h.MoveFromCart)
+}
+
+type wishlistNameInput struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type wishlistItemInput struct {
+	ProductID uint `json:"product_id" binding:"required"`
+}
+
+type moveToWishlistInput struct {
+	WishlistID uint `json:"wishlist_id" binding:"required"`
+}
+
+func (h *WishlistHandler) Create(c *gin.Context) {
+	var input wishlistNameInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON input"})
+		return
+	}
+
+	userID, err := jwt.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	wishlist, err := h.usecase.CreateWishlist(c.Request.Context(), userID, input.Name)
+	if err != nil {
+		c.JSON(wishlistErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  "success",
+		"message": "wishlist berhasil dibuat",
+		"data":    wishlist,
+	})
+}
+
+func (h *WishlistHandler) GetAll(c *gin.Context) {
+	userID, err := jwt.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	wishlists, err := h.usecase.GetWishlists(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   wishlists,
+	})
+}
+
+func (h *WishlistHandler) GetByID(c *gin.Context) {
+	id, ok := parseUintParam(c, "id", "invalid wishlist ID")
+	if !ok {
+		return
+	}
+	userID, err := jwt.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	detail, err := h.usecase.GetWishlist(c.Request.Context(), userID, id)
+	if err != nil {
+		c.JSON(wishlistErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   detail,
+	})
+}
+
+func (h *WishlistHandler) GetShared(c *gin.Context) {
+	detail, err := h.usecase.GetSharedWishlist(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		c.JSON(wishlistErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	// the owner's ID and the token itself are not part of the public view
+	detail.Wishlist.UserID = 0
+	detail.Wishlist.ShareToken = nil
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   detail,
+	})
+}
+
+func (h *WishlistHandler) Rename(c *gin.Context) {
+	id, ok := parseUintParam(c, "id", "invalid wishlist ID")
+	if !ok {
+		return
+	}
+	var input wishlistNameInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON input"})
+		return
+	}
+	userID, err := jwt.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.usecase.RenameWishlist(c.Request.Context(), userID, id, input.Name); err != nil {
+		c.JSON(wishlistErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "wishlist berhasil diubah",
+	})
+}
+
+func (h *WishlistHandler) Delete(c *gin.Context) {
+	id, ok := parseUintParam(c, "id", "invalid wishlist ID")
+	if !ok {
+		return
+	}
+	userID, err := jwt.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.usecase.DeleteWishlist(c.Request.Context(), userID, id); err != nil {
+		c.JSON(wishlistErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "wishlist berhasil dihapus",
+	})
+}
+
+func (h *WishlistHandler) Share(c *gin.Context) {
+	id, ok := parseUintParam(c, "id", "invalid wishlist ID")
+	if !ok {
+		return
+	}
+	userID, err := jwt.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, err := h.usecase.Share(c.Request.Context(), userID, id)
+	if err != nil {
+		c.JSON(wishlistErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "wishlist berhasil dibagikan",
+		"data": gin.H{
+			"share_token": token,
+			"share_path":  "/api/wishlists/shared/" + token,
+		},
+	})
+}
+
+func (h *WishlistHandler) Unshare(c *gin.Context) {
+	id, ok := parseUintParam(c, "id", "invalid wishlist ID")
+	if !ok {
+		return
+	}
+	userID, err := jwt.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.usecase.Unshare(c.Request.Context(), userID, id); err != nil {
+		c.JSON(wishlistErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "wishlist tidak lagi dibagikan",
+	})
+}
+
+func (h *WishlistHandler) AddItem(c *gin.Context) {
+	id, ok := parseUintParam(c, "id", "invalid wishlist ID")
+	if !ok {
+		return
+	}
+	var input wishlistItemInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON input"})
+		return
+	}
+	userID, err := jwt.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	item, err := h.usecase.AddItem(c.Request.Context(), userID, id, input.ProductID)
+	if err != nil {
+		c.JSON(wishlistErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  "success",
+		"message": "item berhasil ditambahkan ke wishlist",
+		"data":    item,
+	})
+}
+
+func (h *WishlistHandler) RemoveItem(c *gin.Context) {
+	id, ok := parseUintParam(c, "id", "invalid wishlist ID")
+	if !ok {
+		return
+	}
+	itemID, ok := parseUintParam(c, "itemId", "invalid wishlist item ID")
+	if !ok {
+		return
+	}
+	userID, err := jwt.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.usecase.RemoveItem(c.Request.Context(), userID, id, itemID); err != nil {
+		c.JSON(wishlistErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "item wishlist berhasil dihapus",
+	})
+}
+
+func (h *WishlistHandler) MoveToCart(c *gin.Context) {
+	id, ok := parseUintParam(c, "id", "invalid wishlist ID")
+	if !ok {
+		return
+	}
+	itemID, ok := parseUintParam(c, "itemId", "invalid wishlist item ID")
+	if !ok {
+		return
+	}
+	var req uc.MoveToCartRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON input"})
+		return
+	}
+	userID, err := jwt.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	cartItem, err := h.usecase.MoveWishlistItemToCart(c.Request.Context(), userID, id, itemID, req)
+	if err != nil {
+		c.JSON(wishlistErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "item berhasil dipindahkan ke cart",
+		"data":    cartItem,
+	})
+}
+
+func (h *WishlistHandler) MoveFromCart(c *gin.Context) {
+	cartItemID, ok := parseUintParam(c, "id", "invalid cart item ID")
+	if !ok {
+		return
+	}
+	var input moveToWishlistInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON input"})
+		return
+	}
+	userID, err := jwt.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	item, err := h.usecase.MoveCartItemToWishlist(c.Request.Context(), userID, cartItemID, input.WishlistID)
+	if err != nil {
+		c.JSON(wishlistErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "item berhasil dipindahkan ke wishlist",
+		"data":    item,
+	})
+}
+
+// parseUintParam reads a numeric path parameter, writing a 400 response when
+// it is not a valid ID.
+func parseUintParam(c *gin.Context, name, message string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// wishlistErrorStatus maps wishlist usecase errors to HTTP status codes.
+func wishlistErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, uc.ErrWishlistForbidden), errors.Is(err, uc.ErrCartForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, repository.ErrWishlistNotFound),
+		errors.Is(err, repository.ErrWishlistItemNotFound),
+		errors.Is(err, repository.ErrProductNotFound),
+		errors.Is(err, repository.ErrCartItemNotFound),
+		errors.Is(err, repository.ErrCartNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, repository.ErrWishlistNameTaken), errors.Is(err, uc.ErrProductUnavailable):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
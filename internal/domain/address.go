@@ -0,0 +1,20 @@
+package domain
+
+import "time"
+
+// CustomerAddress adalah alamat kirim milik user, dengan koordinat untuk
+// memilih gudang terdekat.
+type CustomerAddress struct {
+	ID        uint      `json:"id"`
+	UserID    uint      `json:"user_id"`
+	Label     *string   `json:"label,omitempty"`
+	Address   string    `json:"address"`
+	Latitude  float64   `json:"latitude"`
+	Longitude float64   `json:"longitude"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Point mengembalikan koordinat alamat.
+func (a CustomerAddress) Point() GeoPoint {
+	return GeoPoint{Latitude: a.Latitude, Longitude: a.Longitude}
+}
@@ -0,0 +1,52 @@
+package domain
+
+import "time"
+
+// CartAbandonment mencatat cart yang ditinggalkan dan reminder yang sudah dikirim.
+type CartAbandonment struct {
+	ID             uint       `json:"id"`
+	CartID         uint       `json:"cart_id"`
+	UserID         uint       `json:"user_id"`
+	LastActivityAt time.Time  `json:"last_activity_at"`
+	DetectedAt     time.Time  `json:"detected_at"`
+	RemindersSent  int        `json:"reminders_sent"`
+	LastRemindedAt *time.Time `json:"last_reminded_at,omitempty"`
+	RecoveredAt    *time.Time `json:"recovered_at,omitempty"`
+}
+
+// OpenCartAbandonment is an abandonment that has not been recovered yet, with
+// the current activity of its cart so the job can decide what to do with it.
+// CartRemindersSent counts the reminders over every abandonment of the cart.
+type OpenCartAbandonment struct {
+	CartAbandonment
+	CartRemindersSent int       `json:"cart_reminders_sent"`
+	CurrentActivityAt time.Time `json:"current_activity_at"`
+	HasItems          bool      `json:"has_items"`
+	OrderedSince      bool      `json:"ordered_since"`
+}
+
+// StaleCart is a cart with items that has seen no activity for a while.
+// RemindersSent counts the reminders of its earlier, recovered abandonments.
+type StaleCart struct {
+	CartID         uint      `json:"cart_id"`
+	UserID         uint      `json:"user_id"`
+	ItemCount      int       `json:"item_count"`
+	LastActivityAt time.Time `json:"last_activity_at"`
+	RemindersSent  int       `json:"reminders_sent"`
+}
+
+// CartAbandonmentStats is the aggregate used by the abandonment report.
+// CartsCreated and CartsAbandoned cover the carts created since Since, so
+// their ratio is the abandonment rate. Abandoned and the counts after it
+// cover the abandonments detected since Since.
+type CartAbandonmentStats struct {
+	Since                  time.Time `json:"since"`
+	CartsCreated           int       `json:"carts_created"`
+	CartsAbandoned         int       `json:"carts_abandoned"`
+	Abandoned              int       `json:"abandoned"`
+	Recovered              int       `json:"recovered"`
+	RecoveredAfterReminder int       `json:"recovered_after_reminder"`
+	RemindersSent          int       `json:"reminders_sent"`
+	AbandonmentRate        float64   `json:"abandonment_rate"`
+	RecoveryRate           float64   `json:"recovery_rate"`
+}
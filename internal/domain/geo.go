@@ -0,0 +1,25 @@
+package domain
+
+import "math"
+
+const earthRadiusKm = 6371.0
+
+// GeoPoint adalah koordinat dalam derajat desimal.
+type GeoPoint struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Valid melaporkan apakah koordinat ada di rentang lintang/bujur yang sah.
+func (p GeoPoint) Valid() bool {
+	return p.Latitude >= -90 && p.Latitude <= 90 && p.Longitude >= -180 && p.Longitude <= 180
+}
+
+// DistanceKm adalah jarak great-circle (haversine) ke q dalam kilometer.
+func (p GeoPoint) DistanceKm(q GeoPoint) float64 {
+	lat1, lat2 := p.Latitude*math.Pi/180, q.Latitude*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (q.Longitude - p.Longitude) * math.Pi / 180
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
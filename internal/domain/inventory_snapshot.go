@@ -0,0 +1,59 @@
+package domain
+
+import "time"
+
+const (
+	InventorySnapshotScheduled = "scheduled"
+	InventorySnapshotManual    = "manual"
+)
+
+// InventorySnapshot adalah salinan warehouse_stock pada satu waktu.
+// Lines hanya diisi saat snapshot diambil satu per satu.
+type InventorySnapshot struct {
+	ID            uint                    `json:"id"`
+	Kind          string                  `json:"kind"`
+	TakenBy       *uint                   `json:"taken_by,omitempty"`
+	LineCount     int                     `json:"line_count"`
+	TotalQuantity int64                   `json:"total_quantity"`
+	TakenAt       time.Time               `json:"taken_at"`
+	Lines         []InventorySnapshotLine `json:"lines,omitempty"`
+}
+
+// InventorySnapshotLine adalah quantity satu produk di satu gudang, dengan
+// harga produk saat snapshot diambil.
+type InventorySnapshotLine struct {
+	WarehouseID uint    `json:"warehouse_id"`
+	ProductID   uint    `json:"product_id"`
+	Quantity    int32   `json:"quantity"`
+	UnitPrice   float64 `json:"unit_price"`
+}
+
+// InventoryLevel adalah stok satu produk di satu gudang pada waktu tertentu,
+// dinilai dengan harga produk yang berlaku saat itu. Nama kosong berarti
+// gudang atau produknya sudah dihapus.
+type InventoryLevel struct {
+	WarehouseID   uint    `json:"warehouse_id"`
+	WarehouseName string  `json:"warehouse_name"`
+	ProductID     uint    `json:"product_id"`
+	ProductName   string  `json:"product_name"`
+	Quantity      int32   `json:"quantity"`
+	UnitPrice     float64 `json:"unit_price"`
+	Value         float64 `json:"value"`
+}
+
+// WarehouseValuation adalah total nilai stok satu gudang.
+type WarehouseValuation struct {
+	WarehouseID   uint    `json:"warehouse_id"`
+	WarehouseName string  `json:"warehouse_name"`
+	Quantity      int64   `json:"quantity"`
+	Value         float64 `json:"value"`
+}
+
+// InventoryValuation adalah laporan nilai stok pada waktu At.
+type InventoryValuation struct {
+	At            time.Time            `json:"at"`
+	TotalQuantity int64                `json:"total_quantity"`
+	TotalValue    float64              `json:"total_value"`
+	Warehouses    []WarehouseValuation `json:"warehouses"`
+	Lines         []InventoryLevel     `json:"lines"`
+}
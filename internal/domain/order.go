@@ -4,11 +4,28 @@ import "time"
 
 // Order menyimpan data pesanan pelanggan.
 type Order struct {
-	ID           uint      `json:"id"`
-	UserID       uint      `json:"user_id"`
-	Status       string    `json:"status"` // pending / processed / shipped
-	TotalPrice   float64   `json:"total_price"`
-	ShippingCost float64   `json:"shipping_cost"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID              uint              `json:"id"`
+	UserID          uint              `json:"user_id"`
+	Status          string            `json:"status"` // pending / processed / shipped
+	TotalPrice      float64           `json:"total_price"`
+	ShippingCost    float64           `json:"shipping_cost"`
+	TaxAmount       float64           `json:"tax_amount"`
+	AddressID       *uint             `json:"address_id,omitempty"`
+	RoutingDecision *string           `json:"routing_decision,omitempty"` // gudang yang dipilih dan alasannya
+	Allocations     []OrderAllocation `json:"allocations,omitempty"`
+	Picks           []OrderItemPick   `json:"picks,omitempty"` // pick list, hanya diisi saat order dibuat
+	Version         int32             `json:"version"`         // naik setiap update; dikirim balik untuk cek konflik
+	CreatedAt       time.Time         `json:"created_at"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+}
+
+// OrderAllocation adalah jumlah satu produk yang dikirim dari satu gudang.
+// Pesanan yang di-split punya beberapa alokasi untuk produk yang sama.
+type OrderAllocation struct {
+	ID          uint     `json:"id"`
+	OrderID     uint     `json:"order_id"`
+	WarehouseID uint     `json:"warehouse_id"`
+	ProductID   uint     `json:"product_id"`
+	Quantity    int32    `json:"quantity"`
+	DistanceKm  *float64 `json:"distance_km,omitempty"`
 }
@@ -5,10 +5,13 @@ import "time"
 type Product struct {
 	ID        uint      `json:"id"`
 	Name      string    `json:"name"`
+	SKU       *string   `json:"sku,omitempty"` // kode unik opsional, dipakai import/export stok
 	UserID    uint      `json:"user_id"`
 	Price     float64   `json:"price"`
-	Stock     int32     `json:"stock"`            // optional, bisa dihapus jika stok per gudang
+	Stock     int32     `json:"stock"`            // jumlah stok di semua gudang, dihitung saat dibaca
 	Weight    *float64  `json:"weight,omitempty"` // bisa NULL
+	Volume    *float64  `json:"volume,omitempty"` // m³ per unit, bisa NULL
+	Version   int32     `json:"version"`          // naik setiap update; dikirim balik untuk cek konflik
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
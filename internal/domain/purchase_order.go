@@ -0,0 +1,68 @@
+package domain
+
+import "time"
+
+// Status purchase order ke supplier.
+const (
+	PurchaseOrderDraft             = "draft"              // masih bisa dihapus, belum dikirim ke supplier
+	PurchaseOrderSent              = "sent"               // sudah dikirim, menunggu barang
+	PurchaseOrderPartiallyReceived = "partially_received" // sebagian line sudah diterima
+	PurchaseOrderReceived          = "received"           // semua quantity sudah diterima
+)
+
+// Supplier adalah pemasok barang untuk purchase order.
+type Supplier struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	Email     *string   `json:"email,omitempty"`
+	Phone     *string   `json:"phone,omitempty"`
+	Address   *string   `json:"address,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PurchaseOrder adalah pesanan barang ke satu supplier yang akan diterima di
+// satu gudang.
+type PurchaseOrder struct {
+	ID          uint                `json:"id"`
+	SupplierID  uint                `json:"supplier_id"`
+	WarehouseID uint                `json:"warehouse_id"`
+	Status      string              `json:"status"`
+	Note        *string             `json:"note,omitempty"`
+	CreatedBy   *uint               `json:"created_by,omitempty"`
+	SentAt      *time.Time          `json:"sent_at,omitempty"`
+	ReceivedAt  *time.Time          `json:"received_at,omitempty"`
+	CreatedAt   time.Time           `json:"created_at"`
+	UpdatedAt   time.Time           `json:"updated_at"`
+	Lines       []PurchaseOrderLine `json:"lines"`
+}
+
+// PurchaseOrderLine adalah satu produk yang dipesan beserta harga belinya.
+type PurchaseOrderLine struct {
+	ID               uint    `json:"id"`
+	PurchaseOrderID  uint    `json:"purchase_order_id"`
+	ProductID        uint    `json:"product_id"`
+	Quantity         int32   `json:"quantity"`
+	UnitCost         float64 `json:"unit_cost"`
+	ReceivedQuantity int32   `json:"received_quantity"`
+	// ReceivedValue diisi saat dibaca: jumlah quantity * landed cost semua penerimaan.
+	ReceivedValue float64 `json:"received_value"`
+}
+
+// Outstanding adalah quantity yang belum diterima.
+func (l PurchaseOrderLine) Outstanding() int32 {
+	return l.Quantity - l.ReceivedQuantity
+}
+
+// PurchaseReceipt adalah satu penerimaan barang untuk satu line.
+type PurchaseReceipt struct {
+	ID                  uint       `json:"id"`
+	PurchaseOrderLineID uint       `json:"purchase_order_line_id"`
+	ProductID           uint       `json:"product_id"`
+	Quantity            int32      `json:"quantity"`
+	LandedUnitCost      float64    `json:"landed_unit_cost"`
+	BatchNumber         *string    `json:"batch_number,omitempty"` // diisi kalau barang diterima ke sebuah lot
+	ExpiryDate          *time.Time `json:"expiry_date,omitempty"`
+	ReceivedBy          *uint      `json:"received_by,omitempty"`
+	ReceivedAt          time.Time  `json:"received_at"`
+}
@@ -0,0 +1,54 @@
+package domain
+
+import "time"
+
+const (
+	DemandMovingAverage        = "sma" // rata-rata harian sederhana selama window
+	DemandExponentialSmoothing = "ses" // exponential smoothing, hari terbaru paling berat
+)
+
+// DailyDemand adalah jumlah unit satu produk yang terjual dari satu gudang
+// dalam satu hari.
+type DailyDemand struct {
+	ProductID uint      `json:"product_id"`
+	Day       time.Time `json:"day"`
+	Quantity  int64     `json:"quantity"`
+}
+
+// StockPosition adalah stok di gudang dan yang masih dalam perjalanan dari
+// purchase order yang sudah dikirim.
+type StockPosition struct {
+	ProductID   uint    `json:"product_id"`
+	ProductName string  `json:"product_name"`
+	SKU         *string `json:"sku,omitempty"`
+	OnHand      int32   `json:"on_hand"`
+	OnOrder     int32   `json:"on_order"`
+}
+
+// ReplenishmentSuggestion adalah saran pembelian untuk satu produk di satu
+// gudang. DaysOfCover nil berarti tidak ada permintaan dalam window.
+type ReplenishmentSuggestion struct {
+	StockPosition
+	AvgDailyDemand    float64  `json:"avg_daily_demand"`
+	DemandStdDev      float64  `json:"demand_std_dev"`
+	DaysOfCover       *float64 `json:"days_of_cover"`
+	SafetyStock       int32    `json:"safety_stock"`
+	ReorderPoint      int32    `json:"reorder_point"`
+	TargetLevel       int32    `json:"target_level"`
+	SuggestedQuantity int32    `json:"suggested_quantity"`
+}
+
+// ReplenishmentReport adalah saran pembelian satu gudang beserta parameter
+// yang dipakai menghitungnya. Demand dihitung dari hari From sampai sebelum To.
+type ReplenishmentReport struct {
+	WarehouseID  uint                      `json:"warehouse_id"`
+	Method       string                    `json:"method"`
+	WindowDays   int                       `json:"window_days"`
+	Alpha        float64                   `json:"alpha,omitempty"`
+	LeadTimeDays int                       `json:"lead_time_days"`
+	ReviewDays   int                       `json:"review_days"`
+	ServiceLevel float64                   `json:"service_level"`
+	From         time.Time                 `json:"from"`
+	To           time.Time                 `json:"to"`
+	Suggestions  []ReplenishmentSuggestion `json:"suggestions"`
+}
@@ -0,0 +1,23 @@
+package domain
+
+import "time"
+
+// Status alert stok rendah.
+const (
+	StockAlertOpen     = "open"
+	StockAlertResolved = "resolved"
+)
+
+// StockAlert dibuat saat stok turun sampai atau di bawah reorder point, dan
+// ditutup (resolved) setelah stok diisi kembali di atas reorder point.
+type StockAlert struct {
+	ID           uint       `json:"id"`
+	WarehouseID  uint       `json:"warehouse_id"`
+	ProductID    uint       `json:"product_id"`
+	ReorderPoint int32      `json:"reorder_point"`
+	Quantity     int32      `json:"quantity"` // quantity saat alert dibuat
+	Status       string     `json:"status"`
+	NotifiedAt   *time.Time `json:"notified_at,omitempty"`
+	ResolvedAt   *time.Time `json:"resolved_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
@@ -0,0 +1,61 @@
+package domain
+
+import "time"
+
+// Status sesi hitung stok.
+const (
+	StockCountOpen      = "open"      // hitungan masih boleh dikirim
+	StockCountApproved  = "approved"  // selisih sudah diposting ke warehouse_stock
+	StockCountCancelled = "cancelled" // ditutup tanpa mengubah stok
+)
+
+// StockCountSession adalah satu sesi stocktake / cycle count di satu gudang.
+// Penjualan tetap jalan selama sesi terbuka.
+type StockCountSession struct {
+	ID          uint   `json:"id"`
+	WarehouseID uint   `json:"warehouse_id"`
+	Status      string `json:"status"`
+	// FullWarehouse false berarti sesi dibatasi ke produk yang dipilih saat dibuka.
+	FullWarehouse bool             `json:"full_warehouse"`
+	Note          *string          `json:"note,omitempty"`
+	CreatedBy     *uint            `json:"created_by,omitempty"`
+	ClosedBy      *uint            `json:"closed_by,omitempty"`
+	ClosedAt      *time.Time       `json:"closed_at,omitempty"`
+	CreatedAt     time.Time        `json:"created_at"`
+	UpdatedAt     time.Time        `json:"updated_at"`
+	Lines         []StockCountLine `json:"lines"`
+}
+
+// StockCountLine adalah satu produk dalam sesi. ExpectedQuantity adalah
+// snapshot saat sesi dibuka; SystemQuantity adalah quantity saat hitungan
+// dikirim, dan selisih dihitung terhadap angka itu.
+type StockCountLine struct {
+	ID               uint       `json:"id"`
+	SessionID        uint       `json:"session_id"`
+	ProductID        uint       `json:"product_id"`
+	ExpectedQuantity int32      `json:"expected_quantity"`
+	CountedQuantity  *int32     `json:"counted_quantity"`
+	SystemQuantity   *int32     `json:"system_quantity,omitempty"`
+	CountedBy        *uint      `json:"counted_by,omitempty"`
+	CountedAt        *time.Time `json:"counted_at,omitempty"`
+	// Variance diisi saat dibaca: counted - system, nil kalau belum dihitung.
+	Variance *int32 `json:"variance"`
+	// Adjustment adalah delta yang diposting saat approve.
+	Adjustment *int32 `json:"adjustment,omitempty"`
+}
+
+// ComputeVariance mengisi Variance dari hasil hitung.
+func (l *StockCountLine) ComputeVariance() {
+	if l.CountedQuantity == nil || l.SystemQuantity == nil {
+		l.Variance = nil
+		return
+	}
+	v := *l.CountedQuantity - *l.SystemQuantity
+	l.Variance = &v
+}
+
+// StockCountEntry adalah satu hasil hitung yang dikirim petugas.
+type StockCountEntry struct {
+	ProductID uint   `json:"product_id" binding:"required"`
+	Counted   *int32 `json:"counted_quantity" binding:"required"`
+}
@@ -0,0 +1,45 @@
+package domain
+
+import "time"
+
+// StockLot adalah satu batch produk di satu gudang. Jumlah quantity semua lot
+// tidak pernah melebihi quantity warehouse_stock; sisanya stok tanpa lot.
+type StockLot struct {
+	ID          uint       `json:"id"`
+	WarehouseID uint       `json:"warehouse_id"`
+	ProductID   uint       `json:"product_id"`
+	BatchNumber string     `json:"batch_number"`
+	ExpiryDate  *time.Time `json:"expiry_date,omitempty"` // nil untuk barang yang tidak kedaluwarsa
+	Quantity    int32      `json:"quantity"`
+	ReceivedAt  time.Time  `json:"received_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// LotConsumption adalah bagian dari pengurangan stok yang diambil dari satu lot.
+type LotConsumption struct {
+	LotID       uint       `json:"lot_id"`
+	BatchNumber string     `json:"batch_number"`
+	ExpiryDate  *time.Time `json:"expiry_date,omitempty"`
+	Quantity    int32      `json:"quantity"`
+}
+
+// OrderItemLot mencatat lot asal sebuah order item. LotID nil kalau lotnya
+// sudah dihapus bersama baris stoknya.
+type OrderItemLot struct {
+	ID          uint       `json:"id"`
+	OrderItemID uint       `json:"order_item_id"`
+	ProductID   uint       `json:"product_id"`
+	LotID       *uint      `json:"lot_id,omitempty"`
+	BatchNumber string     `json:"batch_number"`
+	ExpiryDate  *time.Time `json:"expiry_date,omitempty"`
+	Quantity    int32      `json:"quantity"`
+}
+
+// ExpiringLot adalah baris laporan lot yang mendekati (atau sudah lewat)
+// tanggal kedaluwarsa.
+type ExpiringLot struct {
+	StockLot
+	WarehouseName string `json:"warehouse_name"`
+	ProductName   string `json:"product_name"`
+	DaysLeft      int    `json:"days_left"` // negatif kalau sudah kedaluwarsa
+}
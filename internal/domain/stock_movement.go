@@ -0,0 +1,94 @@
+package domain
+
+import "time"
+
+// Alasan perubahan stok yang dicatat di stock_movements.
+const (
+	MovementReasonSale       = "sale"
+	MovementReasonRestock    = "restock"
+	MovementReasonAdjustment = "adjustment"
+	MovementReasonTransfer   = "transfer"
+	MovementReasonReturn     = "return"
+)
+
+// Jenis dokumen yang bisa dirujuk oleh sebuah movement.
+const (
+	MovementRefOrder         = "order"
+	MovementRefTransfer      = "transfer"
+	MovementRefStockCount    = "stock_count"
+	MovementRefPurchaseOrder = "purchase_order"
+)
+
+// StockMovement adalah satu baris ledger stok. Baris tidak pernah diubah atau
+// dihapus; jumlah Delta per gudang/produk sama dengan quantity saat ini.
+type StockMovement struct {
+	ID            uint64    `json:"id"`
+	WarehouseID   uint      `json:"warehouse_id"`
+	ProductID     uint      `json:"product_id"`
+	Delta         int32     `json:"delta"`
+	QuantityAfter int32     `json:"quantity_after"`
+	Reason        string    `json:"reason"`
+	ReferenceType *string   `json:"reference_type,omitempty"`
+	ReferenceID   *uint     `json:"reference_id,omitempty"`
+	ActorID       *uint     `json:"actor_id,omitempty"`
+	Note          *string   `json:"note,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// StockReference menjelaskan kenapa dan oleh siapa stok berubah.
+type StockReference struct {
+	Reason        string
+	ReferenceType string
+	ReferenceID   uint
+	ActorID       uint
+	Note          string
+}
+
+// StockChange adalah perubahan relatif pada satu baris warehouse_stock.
+type StockChange struct {
+	WarehouseID uint
+	ProductID   uint
+	Delta       int32
+	StockReference
+}
+
+// StockChangeResult adalah hasil perubahan stok beserta movement yang tercatat.
+type StockChangeResult struct {
+	WarehouseID    uint           `json:"warehouse_id"`
+	ProductID      uint           `json:"product_id"`
+	QuantityBefore int32          `json:"quantity_before"`
+	QuantityAfter  int32          `json:"quantity_after"`
+	Version        int32          `json:"version"` // versi baris setelah perubahan
+	Movement       *StockMovement `json:"movement,omitempty"`
+	// Lots berisi lot yang diambil oleh pengurangan stok, urut FEFO.
+	Lots []LotConsumption `json:"lots,omitempty"`
+	// Picks berisi lokasi yang diambil oleh pengurangan stok.
+	Picks []LocationPick `json:"picks,omitempty"`
+	// CapacityWarning diisi jika penambahan stok melebihi kapasitas gudang
+	// tetapi tetap dicatat (policy warn, atau hasil stock count).
+	CapacityWarning string `json:"capacity_warning,omitempty"`
+}
+
+// StockExpectation adalah kondisi yang harus dipenuhi baris stok sebelum
+// ditimpa. Quantity nil dan Version 0 berarti tidak dicek.
+type StockExpectation struct {
+	Quantity *int32
+	Version  int32
+}
+
+// StockMovementFilter memilih movement untuk satu gudang (dan opsional satu
+// produk). Paging memakai cursor BeforeID: hanya movement dengan id lebih kecil.
+type StockMovementFilter struct {
+	WarehouseID uint
+	ProductID   uint
+	BeforeID    uint64
+	Limit       int
+}
+
+// StockDiscrepancy adalah selisih antara quantity tersimpan dan hasil ledger.
+type StockDiscrepancy struct {
+	WarehouseID    uint  `json:"warehouse_id"`
+	ProductID      uint  `json:"product_id"`
+	Quantity       int32 `json:"quantity"`
+	LedgerQuantity int32 `json:"ledger_quantity"`
+}
@@ -0,0 +1,65 @@
+package domain
+
+import "time"
+
+// Status transfer stok antar gudang.
+const (
+	TransferStatusDraft      = "draft"      // masih bisa diubah, stok belum bergerak
+	TransferStatusDispatched = "dispatched" // stok sudah keluar dari gudang asal
+	TransferStatusInTransit  = "in_transit" // dalam perjalanan / sudah diterima sebagian
+	TransferStatusReceived   = "received"   // semua line sudah diterima atau dicatat selisihnya
+)
+
+// StockTransfer memindahkan stok dari SourceWarehouseID ke DestinationWarehouseID.
+type StockTransfer struct {
+	ID                     uint                `json:"id"`
+	SourceWarehouseID      uint                `json:"source_warehouse_id"`
+	DestinationWarehouseID uint                `json:"destination_warehouse_id"`
+	Status                 string              `json:"status"`
+	Note                   *string             `json:"note,omitempty"`
+	CreatedBy              *uint               `json:"created_by,omitempty"`
+	DispatchedAt           *time.Time          `json:"dispatched_at,omitempty"`
+	ReceivedAt             *time.Time          `json:"received_at,omitempty"`
+	CreatedAt              time.Time           `json:"created_at"`
+	UpdatedAt              time.Time           `json:"updated_at"`
+	Lines                  []StockTransferLine `json:"lines"`
+}
+
+// StockTransferLine adalah satu produk dalam transfer. Quantity yang belum
+// diterima dan belum dicatat sebagai selisih masih dalam perjalanan.
+type StockTransferLine struct {
+	ID                  uint    `json:"id"`
+	TransferID          uint    `json:"transfer_id"`
+	ProductID           uint    `json:"product_id"`
+	Quantity            int32   `json:"quantity"`
+	ReceivedQuantity    int32   `json:"received_quantity"`
+	DiscrepancyQuantity int32   `json:"discrepancy_quantity"`
+	DiscrepancyNote     *string `json:"discrepancy_note,omitempty"`
+	// InTransitQuantity diisi saat dibaca: Outstanding() setelah dispatch, 0 untuk draft.
+	InTransitQuantity int32 `json:"in_transit_quantity"`
+	// Lots adalah lot gudang asal yang diambil saat dispatch; sisanya stok tanpa lot.
+	Lots []StockTransferLineLot `json:"lots,omitempty"`
+}
+
+// StockTransferLineLot adalah bagian line transfer yang berasal dari satu lot.
+// Saat diterima, quantity-nya masuk ke batch yang sama di gudang tujuan.
+type StockTransferLineLot struct {
+	ID               uint       `json:"id"`
+	TransferLineID   uint       `json:"transfer_line_id"`
+	BatchNumber      string     `json:"batch_number"`
+	ExpiryDate       *time.Time `json:"expiry_date,omitempty"`
+	Quantity         int32      `json:"quantity"`
+	ReceivedQuantity int32      `json:"received_quantity"`
+}
+
+// Outstanding adalah quantity yang belum diterima maupun dicatat sebagai selisih.
+func (l StockTransferLine) Outstanding() int32 {
+	return l.Quantity - l.ReceivedQuantity - l.DiscrepancyQuantity
+}
+
+// InTransitStock adalah total quantity dalam perjalanan per gudang tujuan dan produk.
+type InTransitStock struct {
+	DestinationWarehouseID uint  `json:"destination_warehouse_id"`
+	ProductID              uint  `json:"product_id"`
+	Quantity               int32 `json:"quantity"`
+}
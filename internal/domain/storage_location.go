@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// StorageLocation adalah satu tempat penyimpanan di dalam gudang. Aisle,
+// Shelf dan Bin boleh kosong, misalnya untuk area lantai di satu zone.
+type StorageLocation struct {
+	ID          uint      `json:"id"`
+	WarehouseID uint      `json:"warehouse_id"`
+	Zone        string    `json:"zone"`
+	Aisle       string    `json:"aisle"`
+	Shelf       string    `json:"shelf"`
+	Bin         string    `json:"bin"`
+	Code        string    `json:"code"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// LocationCode menggabungkan bagian lokasi yang terisi, misalnya "A-03-2-B".
+func LocationCode(zone, aisle, shelf, bin string) string {
+	parts := make([]string, 0, 4)
+	for _, p := range []string{zone, aisle, shelf, bin} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+// LocationStock adalah quantity satu produk di satu lokasi. Jumlah semua
+// lokasi tidak pernah melebihi quantity warehouse_stock; sisanya unassigned.
+type LocationStock struct {
+	LocationID   uint      `json:"location_id"`
+	LocationCode string    `json:"location_code"`
+	WarehouseID  uint      `json:"warehouse_id"`
+	ProductID    uint      `json:"product_id"`
+	Quantity     int32     `json:"quantity"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// LocationMove memindahkan stok antar lokasi di satu gudang. Lokasi 0 berarti
+// stok unassigned: dari 0 berarti put-away, ke 0 berarti melepas dari lokasi.
+type LocationMove struct {
+	WarehouseID    uint  `json:"warehouse_id" binding:"required"`
+	ProductID      uint  `json:"product_id" binding:"required"`
+	FromLocationID uint  `json:"from_location_id"`
+	ToLocationID   uint  `json:"to_location_id"`
+	Quantity       int32 `json:"quantity" binding:"required"`
+}
+
+// LocationPick adalah bagian dari pengurangan stok yang diambil dari satu lokasi.
+type LocationPick struct {
+	LocationID   uint   `json:"location_id"`
+	LocationCode string `json:"location_code"`
+	Quantity     int32  `json:"quantity"`
+}
+
+// OrderItemPick adalah satu baris pick list pesanan. LocationID dan
+// LocationCode nil berarti diambil dari stok unassigned.
+type OrderItemPick struct {
+	ID           uint    `json:"id"`
+	OrderItemID  uint    `json:"order_item_id"`
+	ProductID    uint    `json:"product_id"`
+	WarehouseID  uint    `json:"warehouse_id"`
+	LocationID   *uint   `json:"location_id,omitempty"`
+	LocationCode *string `json:"location_code,omitempty"`
+	Quantity     int32   `json:"quantity"`
+}
@@ -2,6 +2,10 @@ package domain
 
 import "time"
 
+// UserRoleAdmin adalah role yang boleh membuka laporan dan menjalankan job.
+// Role hanya diisi langsung di database, register tidak pernah mengisinya.
+const UserRoleAdmin = "admin"
+
 type User struct {
 	ID           uint      `gorm:"primaryKey" json:"id"`
 	Email        string    `gorm:"uniqueIndex;not null" json:"email"`
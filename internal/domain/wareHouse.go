@@ -3,9 +3,41 @@ package domain
 import "time"
 
 type Warehouse struct {
-	ID        uint      `json:"id"`
-	Name      string    `json:"name"`
-	Location  string    `json:"location"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID                uint      `json:"id"`
+	Name              string    `json:"name"`
+	Location          string    `json:"location"`
+	ShippingBaseCost  float64   `json:"shipping_base_cost"`   // biaya dasar per pengiriman
+	ShippingCostPerKg float64   `json:"shipping_cost_per_kg"` // biaya tambahan per kg berat barang
+	Latitude          *float64  `json:"latitude,omitempty"`   // nil berarti tidak ikut routing jarak
+	Longitude         *float64  `json:"longitude,omitempty"`
+	MaxWeightKg       *float64  `json:"max_weight_kg,omitempty"` // kapasitas berat; nil berarti tanpa batas
+	MaxVolumeM3       *float64  `json:"max_volume_m3,omitempty"` // kapasitas volume dalam m³; nil berarti tanpa batas
+	CapacityPolicy    string    `json:"capacity_policy"`         // reject / warn saat stok melebihi kapasitas
+	Version           int32     `json:"version"`                 // naik setiap update; dikirim balik untuk cek konflik
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+const (
+	CapacityPolicyReject = "reject" // penambahan stok yang melebihi kapasitas ditolak
+	CapacityPolicyWarn   = "warn"   // penambahan tetap dicatat, dengan peringatan
+)
+
+// WarehouseUtilisation adalah berat dan volume stok gudang dibanding
+// kapasitasnya. Percent nil berarti kapasitas itu tidak dibatasi.
+type WarehouseUtilisation struct {
+	WeightKg      float64  `json:"weight_kg"`
+	MaxWeightKg   *float64 `json:"max_weight_kg,omitempty"`
+	WeightPercent *float64 `json:"weight_percent,omitempty"`
+	VolumeM3      float64  `json:"volume_m3"`
+	MaxVolumeM3   *float64 `json:"max_volume_m3,omitempty"`
+	VolumePercent *float64 `json:"volume_percent,omitempty"`
+}
+
+// Point mengembalikan koordinat gudang; ok false kalau belum diisi.
+func (w Warehouse) Point() (GeoPoint, bool) {
+	if w.Latitude == nil || w.Longitude == nil {
+		return GeoPoint{}, false
+	}
+	return GeoPoint{Latitude: *w.Latitude, Longitude: *w.Longitude}, true
 }
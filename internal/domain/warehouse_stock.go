@@ -3,10 +3,58 @@ package domain
 import "time"
 
 type WarehouseStock struct {
-	ID          uint      `json:"id"`
-	WarehouseID uint      `json:"warehouse_id"`
-	ProductID   uint      `json:"product_id"`
-	Quantity    int32     `json:"quantity"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          uint  `json:"id"`
+	WarehouseID uint  `json:"warehouse_id"`
+	ProductID   uint  `json:"product_id"`
+	Quantity    int32 `json:"quantity"`
+	// ReorderPoint memicu alert stok rendah; nil berarti tanpa alert
+	ReorderPoint *int32    `json:"reorder_point,omitempty"`
+	Version      int32     `json:"version"` // naik setiap perubahan baris
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	// CapacityWarning hanya diisi oleh Create, lihat StockChangeResult.
+	CapacityWarning string `json:"capacity_warning,omitempty"`
+}
+
+// StockUpsertMode menentukan apa yang dilakukan Create kalau gudang sudah
+// punya baris stok untuk produk itu.
+type StockUpsertMode string
+
+const (
+	StockUpsertAdd     StockUpsertMode = "add"     // quantity ditambahkan ke stok yang ada
+	StockUpsertReplace StockUpsertMode = "replace" // quantity menggantikan stok yang ada
+)
+
+// StockKey mengidentifikasi satu baris warehouse_stock.
+type StockKey struct {
+	WarehouseID uint `json:"warehouse_id"`
+	ProductID   uint `json:"product_id"`
+}
+
+// StockExportRow adalah satu baris export stok, dengan SKU produk kalau ada.
+type StockExportRow struct {
+	WarehouseID uint    `json:"warehouse_id"`
+	ProductID   uint    `json:"product_id"`
+	SKU         *string `json:"sku,omitempty"`
+	Quantity    int32   `json:"quantity"`
+}
+
+// StockAvailability adalah stok satu produk di satu gudang. Reserved adalah
+// quantity yang sudah dijanjikan ke transfer draft dari gudang ini.
+type StockAvailability struct {
+	WarehouseID   uint   `json:"warehouse_id"`
+	WarehouseName string `json:"warehouse_name"`
+	Location      string `json:"location"`
+	OnHand        int32  `json:"on_hand"`
+	Reserved      int32  `json:"reserved"`
+	Available     int32  `json:"available"` // on_hand - reserved, minimal 0
+}
+
+// ProductAvailability menjawab "di gudang mana produk ini ada, dan berapa".
+type ProductAvailability struct {
+	ProductID  uint                `json:"product_id"`
+	OnHand     int32               `json:"on_hand"`
+	Reserved   int32               `json:"reserved"`
+	Available  int32               `json:"available"`
+	Warehouses []StockAvailability `json:"warehouses"`
 }
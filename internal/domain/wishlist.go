@@ -0,0 +1,21 @@
+package domain
+
+import "time"
+
+// Wishlist adalah daftar simpan-untuk-nanti milik user. Satu user bisa punya
+// beberapa wishlist dengan nama berbeda.
+type Wishlist struct {
+	ID         uint      `json:"id"`
+	UserID     uint      `json:"user_id"`
+	Name       string    `json:"name"`
+	ShareToken *string   `json:"share_token,omitempty"` // NULL jika tidak dibagikan
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type WishlistItem struct {
+	ID         uint      `json:"id"`
+	WishlistID uint      `json:"wishlist_id"`
+	ProductID  uint      `json:"product_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+)
+
+// ErrAddressNotFound is returned when an address does not exist or belongs to
+// another user.
+var ErrAddressNotFound = errors.New("address not found")
+
+type AddressRepository interface {
+	Create(ctx context.Context, address *domain.CustomerAddress) error
+	// GetByID returns the address only if it belongs to userID.
+	GetByID(ctx context.Context, id uint, userID uint) (*domain.CustomerAddress, error)
+	GetByUserID(ctx context.Context, userID uint) ([]domain.CustomerAddress, error)
+	Delete(ctx context.Context, id uint, userID uint) error
+}
+
+type addressRepo struct {
+	db *sql.DB
+}
+
+func NewAddressRepository(db *sql.DB) AddressRepository {
+	return &addressRepo{db: db}
+}
+
+const addressColumns = `id, user_id, label, address, latitude, longitude, created_at`
+
+func scanAddress(row rowScanner) (*domain.CustomerAddress, error) {
+	var a domain.CustomerAddress
+	if err := row.Scan(&a.ID, &a.UserID, &a.Label, &a.Address, &a.Latitude, &a.Longitude, &a.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *addressRepo) Create(ctx context.Context, address *domain.CustomerAddress) error {
+	query := `
+		INSERT INTO customer_addresses (user_id, label, address, latitude, longitude, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		RETURNING id, created_at
+	`
+	err := r.db.QueryRowContext(ctx, query, address.UserID, address.Label, address.Address, address.Latitude, address.Longitude).
+		Scan(&address.ID, &address.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create address: %w", err)
+	}
+	return nil
+}
+
+func (r *addressRepo) GetByID(ctx context.Context, id uint, userID uint) (*domain.CustomerAddress, error) {
+	query := `SELECT ` + addressColumns + ` FROM customer_addresses WHERE id = $1 AND user_id = $2`
+	a, err := scanAddress(r.db.QueryRowContext(ctx, query, id, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAddressNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query address: %w", err)
+	}
+	return a, nil
+}
+
+func (r *addressRepo) GetByUserID(ctx context.Context, userID uint) ([]domain.CustomerAddress, error) {
+	query := `SELECT ` + addressColumns + ` FROM customer_addresses WHERE user_id = $1 ORDER BY id`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query addresses: %w", err)
+	}
+	defer rows.Close()
+
+	addresses := []domain.CustomerAddress{}
+	for rows.Next() {
+		a, err := scanAddress(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan address: %w", err)
+		}
+		addresses = append(addresses, *a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating addresses: %w", err)
+	}
+	return addresses, nil
+}
+
+func (r *addressRepo) Delete(ctx context.Context, id uint, userID uint) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM customer_addresses WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete address: %w", err)
+	}
+	return expectAffected(res, ErrAddressNotFound)
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddressRepository_Create(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := NewAddressRepository(db)
+	ctx := context.Background()
+	now := time.Now()
+
+	a := &domain.CustomerAddress{UserID: 1, Address: "Jl. Sisingamangaraja No. 1", Latitude: 2.3833, Longitude: 99.1500}
+	mock.ExpectQuery("INSERT INTO customer_addresses").
+		WithArgs(a.UserID, a.Label, a.Address, a.Latitude, a.Longitude).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(3, now))
+
+	err := repo.Create(ctx, a)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(3), a.ID)
+}
+
+func TestAddressRepository_GetByID(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := NewAddressRepository(db)
+	ctx := context.Background()
+	now := time.Now()
+
+	t.Run("Success", func(t *testing.T) {
+		mock.ExpectQuery("SELECT (.+) FROM customer_addresses WHERE id = \\$1 AND user_id = \\$2").
+			WithArgs(3, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "label", "address", "latitude", "longitude", "created_at"}).
+				AddRow(3, 1, "Rumah", "Jl. Sisingamangaraja No. 1", 2.3833, 99.15, now))
+
+		a, err := repo.GetByID(ctx, 3, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, domain.GeoPoint{Latitude: 2.3833, Longitude: 99.15}, a.Point())
+	})
+
+	t.Run("OtherUser", func(t *testing.T) {
+		mock.ExpectQuery("SELECT (.+) FROM customer_addresses WHERE id = \\$1 AND user_id = \\$2").
+			WithArgs(3, 2).
+			WillReturnError(sql.ErrNoRows)
+
+		_, err := repo.GetByID(ctx, 3, 2)
+		assert.ErrorIs(t, err, ErrAddressNotFound)
+	})
+}
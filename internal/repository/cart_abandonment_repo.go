@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+)
+
+type CartAbandonmentRepository interface {
+	FindStaleCarts(ctx context.Context, inactiveSince time.Time) ([]domain.StaleCart, error)
+	Create(ctx context.Context, abandonment *domain.CartAbandonment) error
+	ListOpen(ctx context.Context) ([]domain.OpenCartAbandonment, error)
+	MarkReminded(ctx context.Context, id uint, at time.Time) error
+	MarkRecovered(ctx context.Context, id uint, at time.Time) error
+	Stats(ctx context.Context, since time.Time) (*domain.CartAbandonmentStats, error)
+}
+
+type cartAbandonmentRepo struct {
+	db *sql.DB
+}
+
+func NewCartAbandonmentRepository(db *sql.DB) CartAbandonmentRepository {
+	return &cartAbandonmentRepo{db: db}
+}
+
+// cartActivitySubquery computes the last activity of every cart that has items:
+// the latest of the cart's own update and any item change.
+const cartActivitySubquery = `
+	SELECT c.id AS cart_id, c.user_id, COUNT(ci.id) AS item_count,
+	       GREATEST(c.updated_at, MAX(ci.updated_at)) AS last_activity_at
+	FROM carts c
+	JOIN cart_items ci ON ci.cart_id = c.id
+	GROUP BY c.id, c.user_id, c.updated_at
+`
+
+// FindStaleCarts returns carts idle since before inactiveSince that are not
+// already tracked, and whose owner has not placed an order since, with the
+// reminders their earlier abandonments got.
+func (r *cartAbandonmentRepo) FindStaleCarts(ctx context.Context, inactiveSince time.Time) ([]domain.StaleCart, error) {
+	query := `
+		SELECT a.cart_id, a.user_id, a.item_count, a.last_activity_at,
+		       (SELECT COALESCE(SUM(x.reminders_sent), 0)::int
+		        FROM cart_abandonments x WHERE x.cart_id = a.cart_id)
+		FROM (` + cartActivitySubquery + `) a
+		WHERE a.last_activity_at < $1
+		  AND NOT EXISTS (
+			SELECT 1 FROM cart_abandonments ca
+			WHERE ca.cart_id = a.cart_id
+			  AND (ca.recovered_at IS NULL OR ca.last_activity_at >= a.last_activity_at)
+		  )
+		  AND NOT EXISTS (
+			SELECT 1 FROM orders o
+			WHERE o.user_id = a.user_id AND o.created_at >= a.last_activity_at
+		  )
+		ORDER BY a.last_activity_at ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, inactiveSince)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stale carts: %w", err)
+	}
+	defer rows.Close()
+
+	var carts []domain.StaleCart
+	for rows.Next() {
+		var c domain.StaleCart
+		if err := rows.Scan(&c.CartID, &c.UserID, &c.ItemCount, &c.LastActivityAt, &c.RemindersSent); err != nil {
+			return nil, fmt.Errorf("failed to scan stale cart: %w", err)
+		}
+		carts = append(carts, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating stale carts: %w", err)
+	}
+	return carts, nil
+}
+
+func (r *cartAbandonmentRepo) Create(ctx context.Context, abandonment *domain.CartAbandonment) error {
+	query := `
+		INSERT INTO cart_abandonments (cart_id, user_id, last_activity_at, detected_at)
+		VALUES ($1, $2, $3, NOW())
+		RETURNING id, detected_at
+	`
+	err := r.db.QueryRowContext(ctx, query, abandonment.CartID, abandonment.UserID, abandonment.LastActivityAt).
+		Scan(&abandonment.ID, &abandonment.DetectedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create cart abandonment: %w", err)
+	}
+	return nil
+}
+
+// ListOpen returns every abandonment that is not recovered yet, together with
+// the cart's current activity, whether the owner ordered since detection and
+// the reminders sent for the cart over all its abandonments.
+func (r *cartAbandonmentRepo) ListOpen(ctx context.Context) ([]domain.OpenCartAbandonment, error) {
+	query := `
+		SELECT ca.id, ca.cart_id, ca.user_id, ca.last_activity_at, ca.detected_at,
+		       ca.reminders_sent, ca.last_reminded_at,
+		       (SELECT COALESCE(SUM(x.reminders_sent), 0)::int
+		        FROM cart_abandonments x WHERE x.cart_id = ca.cart_id) AS cart_reminders_sent,
+		       COALESCE(a.last_activity_at, ca.last_activity_at) AS current_activity_at,
+		       a.cart_id IS NOT NULL AS has_items,
+		       EXISTS (
+				SELECT 1 FROM orders o
+				WHERE o.user_id = ca.user_id AND o.created_at >= ca.detected_at
+		       ) AS ordered_since
+		FROM cart_abandonments ca
+		LEFT JOIN (` + cartActivitySubquery + `) a ON a.cart_id = ca.cart_id
+		WHERE ca.recovered_at IS NULL
+		ORDER BY ca.detected_at ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query open cart abandonments: %w", err)
+	}
+	defer rows.Close()
+
+	var result []domain.OpenCartAbandonment
+	for rows.Next() {
+		var a domain.OpenCartAbandonment
+		if err := rows.Scan(&a.ID, &a.CartID, &a.UserID, &a.LastActivityAt, &a.DetectedAt,
+			&a.RemindersSent, &a.LastRemindedAt, &a.CartRemindersSent, &a.CurrentActivityAt, &a.HasItems, &a.OrderedSince); err != nil {
+			return nil, fmt.Errorf("failed to scan cart abandonment: %w", err)
+		}
+		result = append(result, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating cart abandonments: %w", err)
+	}
+	return result, nil
+}
+
+func (r *cartAbandonmentRepo) MarkReminded(ctx context.Context, id uint, at time.Time) error {
+	query := `UPDATE cart_abandonments SET reminders_sent = reminders_sent + 1, last_reminded_at = $1 WHERE id = $2`
+	res, err := r.db.ExecContext(ctx, query, at, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark cart abandonment reminded: %w", err)
+	}
+	rowsAffected, _ := res.RowsAffected()
+	if rowsAffected == 0 {
+		return errors.New("cart abandonment not found")
+	}
+	return nil
+}
+
+func (r *cartAbandonmentRepo) MarkRecovered(ctx context.Context, id uint, at time.Time) error {
+	query := `UPDATE cart_abandonments SET recovered_at = $1 WHERE id = $2 AND recovered_at IS NULL`
+	res, err := r.db.ExecContext(ctx, query, at, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark cart abandonment recovered: %w", err)
+	}
+	rowsAffected, _ := res.RowsAffected()
+	if rowsAffected == 0 {
+		return errors.New("cart abandonment not found")
+	}
+	return nil
+}
+
+func (r *cartAbandonmentRepo) Stats(ctx context.Context, since time.Time) (*domain.CartAbandonmentStats, error) {
+	query := `
+		SELECT
+			(SELECT COUNT(*) FROM carts WHERE created_at >= $1),
+			(SELECT COUNT(*) FROM carts c
+			 WHERE c.created_at >= $1
+			   AND EXISTS (SELECT 1 FROM cart_abandonments a WHERE a.cart_id = c.id)),
+			COUNT(*),
+			COUNT(*) FILTER (WHERE recovered_at IS NOT NULL),
+			COUNT(*) FILTER (WHERE recovered_at IS NOT NULL AND reminders_sent > 0),
+			COALESCE(SUM(reminders_sent), 0)
+		FROM cart_abandonments
+		WHERE detected_at >= $1
+	`
+	stats := &domain.CartAbandonmentStats{Since: since}
+	err := r.db.QueryRowContext(ctx, query, since).Scan(
+		&stats.CartsCreated,
+		&stats.CartsAbandoned,
+		&stats.Abandoned,
+		&stats.Recovered,
+		&stats.RecoveredAfterReminder,
+		&stats.RemindersSent,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cart abandonment stats: %w", err)
+	}
+	return stats, nil
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCartAbandonmentRepository_Create(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := NewCartAbandonmentRepository(db)
+	ctx := context.Background()
+
+	lastActivity := time.Now().Add(-48 * time.Hour)
+	detectedAt := time.Now()
+	a := &domain.CartAbandonment{CartID: 3, UserID: 1, LastActivityAt: lastActivity}
+
+	mock.ExpectQuery("INSERT INTO cart_abandonments").
+		WithArgs(a.CartID, a.UserID, lastActivity).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "detected_at"}).AddRow(9, detectedAt))
+
+	err := repo.Create(ctx, a)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(9), a.ID)
+	assert.Equal(t, detectedAt, a.DetectedAt)
+}
+
+func TestCartAbandonmentRepository_MarkReminded(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := NewCartAbandonmentRepository(db)
+	ctx := context.Background()
+	now := time.Now()
+
+	mock.ExpectExec("UPDATE cart_abandonments SET reminders_sent = reminders_sent \\+ 1").
+		WithArgs(now, 9).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := repo.MarkReminded(ctx, 9, now)
+	assert.NoError(t, err)
+}
+
+func TestCartAbandonmentRepository_Stats(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := NewCartAbandonmentRepository(db)
+	ctx := context.Background()
+	since := time.Now().Add(-30 * 24 * time.Hour)
+
+	mock.ExpectQuery("SELECT (.+) FROM cart_abandonments WHERE detected_at >=").
+		WithArgs(since).
+		WillReturnRows(sqlmock.NewRows([]string{"carts", "carts_abandoned", "abandoned", "recovered", "recovered_after", "reminders"}).
+			AddRow(40, 9, 10, 4, 3, 17))
+
+	stats, err := repo.Stats(ctx, since)
+	assert.NoError(t, err)
+	assert.Equal(t, 40, stats.CartsCreated)
+	assert.Equal(t, 9, stats.CartsAbandoned)
+	assert.Equal(t, 10, stats.Abandoned)
+	assert.Equal(t, 4, stats.Recovered)
+	assert.Equal(t, 17, stats.RemindersSent)
+}
@@ -9,10 +9,14 @@ import (
 	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
 )
 
+// ErrCartItemNotFound is returned when a cart item lookup matches no row.
+var ErrCartItemNotFound = errors.New("cart item not found")
+
 type CartItemRepository interface {
 	AddCartItem(ctx context.Context, item *domain.CartItem) error
 	UpdateCartItem(ctx context.Context, item *domain.CartItem) error
 	DeleteCartItem(ctx context.Context, id uint) error
+	GetCartItemByID(ctx context.Context, id uint) (*domain.CartItem, error)
 	GetCartItemsByCartID(ctx context.Context, cartID uint) ([]domain.CartItem, error)
 	ClearCart(ctx context.Context, cartID uint) error
 }
@@ -52,7 +56,7 @@ func (r *cartItemRepo) UpdateCartItem(ctx context.Context, item *domain.CartItem
 
 	rowsAffected, _ := res.RowsAffected()
 	if rowsAffected == 0 {
-		return errors.New("cart item not found")
+		return ErrCartItemNotFound
 	}
 	return nil
 }
@@ -66,11 +70,36 @@ func (r *cartItemRepo) DeleteCartItem(ctx context.Context, id uint) error {
 
 	rowsAffected, _ := res.RowsAffected()
 	if rowsAffected == 0 {
-		return errors.New("cart item not found")
+		return ErrCartItemNotFound
 	}
 	return nil
 }
 
+func (r *cartItemRepo) GetCartItemByID(ctx context.Context, id uint) (*domain.CartItem, error) {
+	query := `
+		SELECT id, cart_id, product_id, quantity, sub_total, created_at, updated_at
+		FROM cart_items
+		WHERE id = $1
+	`
+	var i domain.CartItem
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&i.ID,
+		&i.CartID,
+		&i.ProductID,
+		&i.Quantity,
+		&i.SubTotal,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCartItemNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cart item: %w", err)
+	}
+	return &i, nil
+}
+
 func (r *cartItemRepo) GetCartItemsByCartID(ctx context.Context, cartID uint) ([]domain.CartItem, error) {
 	query := `
 		SELECT id, cart_id, product_id, quantity, sub_total, created_at, updated_at
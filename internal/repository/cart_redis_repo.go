@@ -0,0 +1,727 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/lib/pq"
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis-primary cart storage. Carts live in Redis hashes and are written to
+// Postgres asynchronously by CartWriteBehind:
+//
+//	cartstore:cart:<cartID>        hash  user_id, warehouse_id, created_at, updated_at
+//	cartstore:cart:<cartID>:items  hash  <itemID> -> JSON domain.CartItem
+//	cartstore:user:<userID>        hash  <warehouseID> -> cartID, plus "_loaded"
+//	cartstore:item:<itemID>        string cartID
+//	cartstore:dirty                set   cart IDs waiting to be flushed
+//	cartstore:deleted              set   cart IDs waiting to be deleted in Postgres
+//
+// IDs come from the Postgres sequences so rows can be flushed with their
+// final ID. A user's carts are loaded from Postgres the first time they are
+// touched (the "_loaded" marker), so switching storage keeps existing carts.
+// The marker is checked and the load written in one script, so only the
+// first of concurrent loads is stored.
+const (
+	cartStorePrefix     = "cartstore:"
+	cartStoreDirtyKey   = cartStorePrefix + "dirty"
+	cartStoreDeletedKey = cartStorePrefix + "deleted"
+	cartStoreLoadedFlag = "_loaded"
+)
+
+func cartStoreCartKey(cartID uint) string {
+	return fmt.Sprintf("%scart:%d", cartStorePrefix, cartID)
+}
+
+func cartStoreItemsKey(cartID uint) string {
+	return fmt.Sprintf("%scart:%d:items", cartStorePrefix, cartID)
+}
+
+func cartStoreUserKey(userID uint) string {
+	return fmt.Sprintf("%suser:%d", cartStorePrefix, userID)
+}
+
+func cartStoreItemKey(itemID uint) string {
+	return fmt.Sprintf("%sitem:%d", cartStorePrefix, itemID)
+}
+
+// redisCartStore holds the state shared by the Redis cart and cart item
+// repositories. The Postgres repositories are used to load existing carts.
+type redisCartStore struct {
+	db      *sql.DB
+	rdb     *redis.Client
+	pgCarts CartRepository
+	pgItems CartItemRepository
+}
+
+func newRedisCartStore(db *sql.DB, rdb *redis.Client) *redisCartStore {
+	return &redisCartStore{
+		db:      db,
+		rdb:     rdb,
+		pgCarts: NewCartRepository(db),
+		pgItems: NewCartItemRepository(db),
+	}
+}
+
+func (s *redisCartStore) nextID(ctx context.Context, sequence string) (uint, error) {
+	var id uint
+	if err := s.db.QueryRowContext(ctx, `SELECT nextval($1)`, sequence).Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to allocate id from %s: %w", sequence, err)
+	}
+	return id, nil
+}
+
+// ensureUserLoaded copies the user's carts and items from Postgres into Redis
+// unless that already happened.
+func (s *redisCartStore) ensureUserLoaded(ctx context.Context, userID uint) error {
+	loaded, err := s.rdb.HExists(ctx, cartStoreUserKey(userID), cartStoreLoadedFlag).Result()
+	if err != nil {
+		return fmt.Errorf("failed to check cart store: %w", err)
+	}
+	if loaded {
+		return nil
+	}
+
+	carts, err := s.pgCarts.GetCartByUserId(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to load carts: %w", err)
+	}
+	items := make(map[uint][]domain.CartItem, len(carts))
+	for _, cart := range carts {
+		cartItems, err := s.pgItems.GetCartItemsByCartID(ctx, cart.ID)
+		if err != nil {
+			return fmt.Errorf("failed to load cart items: %w", err)
+		}
+		items[cart.ID] = cartItems
+	}
+	_, err = s.storeLoaded(ctx, userID, carts, items)
+	return err
+}
+
+// cartStoreLoadScript writes a user's carts unless the user is loaded
+// already. Checking the marker and writing in one script keeps a slow load
+// from overwriting changes made after a faster one, or from restoring a
+// cart deleted meanwhile. ARGV[2] is a JSON list of writes: {k, f, v} is
+// HSET k f v, {k, v} is SET k v.
+var cartStoreLoadScript = redis.NewScript(`
+if redis.call('HEXISTS', KEYS[1], ARGV[1]) == 1 then
+	return 0
+end
+for _, op in ipairs(cjson.decode(ARGV[2])) do
+	if op.f then
+		redis.call('HSET', op.k, op.f, op.v)
+	else
+		redis.call('SET', op.k, op.v)
+	end
+end
+redis.call('HSET', KEYS[1], ARGV[1], 1)
+return 1
+`)
+
+type cartStoreWrite struct {
+	Key   string `json:"k"`
+	Field string `json:"f,omitempty"`
+	Value string `json:"v"`
+}
+
+// storeLoaded writes the carts read from Postgres and marks the user loaded.
+// It returns false, writing nothing, when another load finished first.
+func (s *redisCartStore) storeLoaded(ctx context.Context, userID uint, carts []domain.Cart, items map[uint][]domain.CartItem) (bool, error) {
+	userKey := cartStoreUserKey(userID)
+	writes := []cartStoreWrite{}
+	for _, cart := range carts {
+		for field, value := range cartFields(&cart) {
+			writes = append(writes, cartStoreWrite{Key: cartStoreCartKey(cart.ID), Field: field, Value: fmt.Sprint(value)})
+		}
+		for _, item := range items[cart.ID] {
+			data, err := json.Marshal(item)
+			if err != nil {
+				return false, err
+			}
+			writes = append(writes,
+				cartStoreWrite{Key: cartStoreItemsKey(cart.ID), Field: strconv.FormatUint(uint64(item.ID), 10), Value: string(data)},
+				cartStoreWrite{Key: cartStoreItemKey(item.ID), Value: strconv.FormatUint(uint64(cart.ID), 10)})
+		}
+		writes = append(writes, cartStoreWrite{
+			Key: userKey, Field: strconv.FormatUint(uint64(cart.WarehouseID), 10), Value: strconv.FormatUint(uint64(cart.ID), 10),
+		})
+	}
+	payload, err := json.Marshal(writes)
+	if err != nil {
+		return false, err
+	}
+
+	stored, err := cartStoreLoadScript.Run(ctx, s.rdb, []string{userKey}, cartStoreLoadedFlag, payload).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to load carts into redis: %w", err)
+	}
+	return stored == 1, nil
+}
+
+// getCart reads a cart from Redis, loading its owner's carts from Postgres
+// when the cart is not there yet.
+func (s *redisCartStore) getCart(ctx context.Context, cartID uint) (*domain.Cart, error) {
+	cart, err := s.readCart(ctx, cartID)
+	if err != nil || cart != nil {
+		return cart, err
+	}
+
+	pending, err := s.rdb.SIsMember(ctx, cartStoreDeletedKey, cartID).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check cart store: %w", err)
+	}
+	if pending {
+		return nil, ErrCartNotFound
+	}
+
+	pgCart, err := s.pgCarts.GetCartByID(ctx, cartID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ensureUserLoaded(ctx, pgCart.UserID); err != nil {
+		return nil, err
+	}
+
+	cart, err = s.readCart(ctx, cartID)
+	if err != nil {
+		return nil, err
+	}
+	if cart == nil {
+		// the user was already loaded, so Redis is authoritative
+		return nil, ErrCartNotFound
+	}
+	return cart, nil
+}
+
+func (s *redisCartStore) readCart(ctx context.Context, cartID uint) (*domain.Cart, error) {
+	fields, err := s.rdb.HGetAll(ctx, cartStoreCartKey(cartID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cart: %w", err)
+	}
+	// a bare updated_at left behind by a write racing a delete is not a cart
+	if len(fields) == 0 || fields["user_id"] == "" {
+		return nil, nil
+	}
+	return parseCartFields(cartID, fields)
+}
+
+func (s *redisCartStore) readItems(ctx context.Context, cartID uint) ([]domain.CartItem, error) {
+	raw, err := s.rdb.HGetAll(ctx, cartStoreItemsKey(cartID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cart items: %w", err)
+	}
+
+	items := make([]domain.CartItem, 0, len(raw))
+	for field, value := range raw {
+		var item domain.CartItem
+		if err := json.Unmarshal([]byte(value), &item); err != nil {
+			log.Printf("[WARN] Skipping malformed cart item %s of cart %d: %v", field, cartID, err)
+			continue
+		}
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+	return items, nil
+}
+
+// markDirty queues a cart for the next flush and bumps its updated_at.
+func markDirty(ctx context.Context, pipe redis.Pipeliner, cartID uint, now time.Time) {
+	pipe.HSet(ctx, cartStoreCartKey(cartID), "updated_at", now.Format(time.RFC3339Nano))
+	pipe.SAdd(ctx, cartStoreDirtyKey, cartID)
+}
+
+func cartFields(cart *domain.Cart) map[string]interface{} {
+	return map[string]interface{}{
+		"user_id":      cart.UserID,
+		"warehouse_id": cart.WarehouseID,
+		"created_at":   cart.CreatedAt.Format(time.RFC3339Nano),
+		"updated_at":   cart.UpdatedAt.Format(time.RFC3339Nano),
+	}
+}
+
+func parseCartFields(cartID uint, fields map[string]string) (*domain.Cart, error) {
+	userID, err := strconv.ParseUint(fields["user_id"], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("corrupt cart %d in redis: %w", cartID, err)
+	}
+	warehouseID, err := strconv.ParseUint(fields["warehouse_id"], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("corrupt cart %d in redis: %w", cartID, err)
+	}
+	cart := &domain.Cart{ID: cartID, UserID: uint(userID), WarehouseID: uint(warehouseID)}
+	cart.CreatedAt, _ = time.Parse(time.RFC3339Nano, fields["created_at"])
+	cart.UpdatedAt, _ = time.Parse(time.RFC3339Nano, fields["updated_at"])
+	return cart, nil
+}
+
+type redisCartRepo struct {
+	store *redisCartStore
+}
+
+func (r *redisCartRepo) CreateCart(ctx context.Context, cart *domain.Cart) error {
+	if err := r.store.ensureUserLoaded(ctx, cart.UserID); err != nil {
+		return err
+	}
+
+	id, err := r.store.nextID(ctx, "carts_id_seq")
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	cart.ID = id
+	cart.CreatedAt = now
+	cart.UpdatedAt = now
+
+	userKey := cartStoreUserKey(cart.UserID)
+	warehouseField := strconv.FormatUint(uint64(cart.WarehouseID), 10)
+
+	// same rule as the carts_user_id_warehouse_id_unique constraint
+	claimed, err := r.store.rdb.HSetNX(ctx, userKey, warehouseField, id).Result()
+	if err != nil {
+		return fmt.Errorf("failed to create cart: %w", err)
+	}
+	if !claimed {
+		return fmt.Errorf("cart for user %d and warehouse %d already exists", cart.UserID, cart.WarehouseID)
+	}
+
+	pipe := r.store.rdb.TxPipeline()
+	pipe.HSet(ctx, cartStoreCartKey(id), cartFields(cart))
+	pipe.SAdd(ctx, cartStoreDirtyKey, id)
+	if _, err := pipe.Exec(ctx); err != nil {
+		r.store.rdb.HDel(ctx, userKey, warehouseField)
+		return fmt.Errorf("failed to create cart: %w", err)
+	}
+	return nil
+}
+
+func (r *redisCartRepo) DeleteCart(ctx context.Context, id uint) error {
+	cart, err := r.store.getCart(ctx, id)
+	if err != nil {
+		return err
+	}
+	itemIDs, err := r.store.rdb.HKeys(ctx, cartStoreItemsKey(id)).Result()
+	if err != nil {
+		return fmt.Errorf("failed to delete cart: %w", err)
+	}
+
+	pipe := r.store.rdb.TxPipeline()
+	for _, itemID := range itemIDs {
+		pipe.Del(ctx, cartStorePrefix+"item:"+itemID)
+	}
+	pipe.Del(ctx, cartStoreCartKey(id), cartStoreItemsKey(id))
+	pipe.HDel(ctx, cartStoreUserKey(cart.UserID), strconv.FormatUint(uint64(cart.WarehouseID), 10))
+	pipe.SRem(ctx, cartStoreDirtyKey, id)
+	pipe.SAdd(ctx, cartStoreDeletedKey, id)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to delete cart: %w", err)
+	}
+	return nil
+}
+
+func (r *redisCartRepo) GetCartByID(ctx context.Context, id uint) (*domain.Cart, error) {
+	return r.store.getCart(ctx, id)
+}
+
+func (r *redisCartRepo) GetCartByUserId(ctx context.Context, userId uint) ([]domain.Cart, error) {
+	if err := r.store.ensureUserLoaded(ctx, userId); err != nil {
+		return nil, err
+	}
+
+	index, err := r.store.rdb.HGetAll(ctx, cartStoreUserKey(userId)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read carts: %w", err)
+	}
+
+	var carts []domain.Cart
+	for field, value := range index {
+		if field == cartStoreLoadedFlag {
+			continue
+		}
+		cartID, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			continue
+		}
+		cart, err := r.store.readCart(ctx, uint(cartID))
+		if err != nil {
+			return nil, err
+		}
+		if cart != nil {
+			carts = append(carts, *cart)
+		}
+	}
+	sort.Slice(carts, func(i, j int) bool { return carts[i].ID < carts[j].ID })
+	return carts, nil
+}
+
+func (r *redisCartRepo) FindByUserAndWarehouse(ctx context.Context, userId uint, warehouseID uint) (*domain.Cart, error) {
+	if err := r.store.ensureUserLoaded(ctx, userId); err != nil {
+		return nil, err
+	}
+
+	value, err := r.store.rdb.HGet(ctx, cartStoreUserKey(userId), strconv.FormatUint(uint64(warehouseID), 10)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrCartNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cart: %w", err)
+	}
+	cartID, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("corrupt cart index for user %d: %w", userId, err)
+	}
+
+	cart, err := r.store.readCart(ctx, uint(cartID))
+	if err != nil {
+		return nil, err
+	}
+	if cart == nil {
+		return nil, ErrCartNotFound
+	}
+	return cart, nil
+}
+
+type redisCartItemRepo struct {
+	store *redisCartStore
+}
+
+func (r *redisCartItemRepo) AddCartItem(ctx context.Context, item *domain.CartItem) error {
+	if _, err := r.store.getCart(ctx, item.CartID); err != nil {
+		return err
+	}
+
+	id, err := r.store.nextID(ctx, "cart_items_id_seq")
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	item.ID = id
+	item.CreatedAt = now
+	item.UpdatedAt = now
+
+	return r.writeItem(ctx, item, now)
+}
+
+func (r *redisCartItemRepo) UpdateCartItem(ctx context.Context, item *domain.CartItem) error {
+	existing, err := r.GetCartItemByID(ctx, item.ID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	existing.Quantity = item.Quantity
+	existing.SubTotal = item.SubTotal
+	existing.UpdatedAt = now
+	return r.writeItem(ctx, existing, now)
+}
+
+func (r *redisCartItemRepo) writeItem(ctx context.Context, item *domain.CartItem, now time.Time) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	pipe := r.store.rdb.TxPipeline()
+	pipe.HSet(ctx, cartStoreItemsKey(item.CartID), strconv.FormatUint(uint64(item.ID), 10), data)
+	pipe.Set(ctx, cartStoreItemKey(item.ID), item.CartID, 0)
+	markDirty(ctx, pipe, item.CartID, now)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to save cart item: %w", err)
+	}
+	return nil
+}
+
+func (r *redisCartItemRepo) DeleteCartItem(ctx context.Context, id uint) error {
+	item, err := r.GetCartItemByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	pipe := r.store.rdb.TxPipeline()
+	pipe.HDel(ctx, cartStoreItemsKey(item.CartID), strconv.FormatUint(uint64(id), 10))
+	pipe.Del(ctx, cartStoreItemKey(id))
+	markDirty(ctx, pipe, item.CartID, time.Now())
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to delete cart item: %w", err)
+	}
+	return nil
+}
+
+func (r *redisCartItemRepo) GetCartItemByID(ctx context.Context, id uint) (*domain.CartItem, error) {
+	item, err := r.readItem(ctx, id)
+	if err != nil || item != nil {
+		return item, err
+	}
+
+	// not in Redis yet: find the owning cart in Postgres and load it
+	pgItem, err := r.store.pgItems.GetCartItemByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.store.getCart(ctx, pgItem.CartID); err != nil {
+		if errors.Is(err, ErrCartNotFound) {
+			return nil, ErrCartItemNotFound
+		}
+		return nil, err
+	}
+
+	item, err = r.readItem(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return nil, ErrCartItemNotFound
+	}
+	return item, nil
+}
+
+func (r *redisCartItemRepo) readItem(ctx context.Context, id uint) (*domain.CartItem, error) {
+	cartID, err := r.store.rdb.Get(ctx, cartStoreItemKey(id)).Uint64()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cart item: %w", err)
+	}
+
+	raw, err := r.store.rdb.HGet(ctx, cartStoreItemsKey(uint(cartID)), strconv.FormatUint(uint64(id), 10)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cart item: %w", err)
+	}
+
+	var item domain.CartItem
+	if err := json.Unmarshal([]byte(raw), &item); err != nil {
+		return nil, fmt.Errorf("corrupt cart item %d in redis: %w", id, err)
+	}
+	return &item, nil
+}
+
+func (r *redisCartItemRepo) GetCartItemsByCartID(ctx context.Context, cartID uint) ([]domain.CartItem, error) {
+	if _, err := r.store.getCart(ctx, cartID); err != nil {
+		if errors.Is(err, ErrCartNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return r.store.readItems(ctx, cartID)
+}
+
+func (r *redisCartItemRepo) ClearCart(ctx context.Context, cartID uint) error {
+	if _, err := r.store.getCart(ctx, cartID); err != nil {
+		return err
+	}
+	itemIDs, err := r.store.rdb.HKeys(ctx, cartStoreItemsKey(cartID)).Result()
+	if err != nil {
+		return fmt.Errorf("failed to clear cart: %w", err)
+	}
+
+	pipe := r.store.rdb.TxPipeline()
+	for _, itemID := range itemIDs {
+		pipe.Del(ctx, cartStorePrefix+"item:"+itemID)
+	}
+	pipe.Del(ctx, cartStoreItemsKey(cartID))
+	markDirty(ctx, pipe, cartID, time.Now())
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to clear cart: %w", err)
+	}
+	return nil
+}
+
+// CartFlushResult summarises one write-behind pass.
+type CartFlushResult struct {
+	Flushed int `json:"flushed"`
+	Deleted int `json:"deleted"`
+	Failed  int `json:"failed"`
+}
+
+// CartWriteBehind persists Redis carts to Postgres. The queue of dirty and
+// deleted carts is kept in Redis, so nothing is lost when the process restarts
+// between a change and its flush.
+type CartWriteBehind struct {
+	store *redisCartStore
+}
+
+// Flush writes every queued cart to Postgres. Each cart is written in its own
+// transaction; its items in Postgres are replaced by the ones in Redis.
+func (w *CartWriteBehind) Flush(ctx context.Context) (*CartFlushResult, error) {
+	result := &CartFlushResult{}
+	rdb := w.store.rdb
+
+	// deletions first, so a cart recreated for the same warehouse does not
+	// hit the user/warehouse unique constraint
+	deleted, err := rdb.SMembers(ctx, cartStoreDeletedKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read deleted carts: %w", err)
+	}
+	if len(deleted) > 0 {
+		if _, err := w.store.db.ExecContext(ctx, `DELETE FROM carts WHERE id = ANY($1::int[])`, pq.Array(deleted)); err != nil {
+			return nil, fmt.Errorf("failed to delete carts: %w", err)
+		}
+		members := make([]interface{}, len(deleted))
+		for i, id := range deleted {
+			members[i] = id
+		}
+		rdb.SRem(ctx, cartStoreDeletedKey, members...)
+		result.Deleted = len(deleted)
+	}
+
+	dirty, err := rdb.SMembers(ctx, cartStoreDirtyKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dirty carts: %w", err)
+	}
+	for _, member := range dirty {
+		cartID, err := strconv.ParseUint(member, 10, 64)
+		if err != nil {
+			rdb.SRem(ctx, cartStoreDirtyKey, member)
+			continue
+		}
+
+		// remove before reading: a change made while we flush re-queues the cart
+		if err := rdb.SRem(ctx, cartStoreDirtyKey, member).Err(); err != nil {
+			return nil, fmt.Errorf("failed to dequeue cart %d: %w", cartID, err)
+		}
+		if err := w.flushCart(ctx, uint(cartID)); err != nil {
+			log.Printf("[ERROR] Failed to flush cart %d: %v", cartID, err)
+			rdb.SAdd(ctx, cartStoreDirtyKey, member)
+			result.Failed++
+			continue
+		}
+		result.Flushed++
+	}
+	return result, nil
+}
+
+func (w *CartWriteBehind) flushCart(ctx context.Context, cartID uint) error {
+	cart, err := w.store.readCart(ctx, cartID)
+	if err != nil {
+		return err
+	}
+	if cart == nil {
+		// deleted after it was queued; the deleted set takes care of it
+		return nil
+	}
+	items, err := w.store.readItems(ctx, cartID)
+	if err != nil {
+		return err
+	}
+
+	tx, err := w.store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO carts (id, user_id, warehouse_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (id) DO UPDATE SET updated_at = EXCLUDED.updated_at
+	`, cart.ID, cart.UserID, cart.WarehouseID, cart.CreatedAt, cart.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to upsert cart: %w", err)
+	}
+
+	keep := make([]int64, 0, len(items))
+	for _, item := range items {
+		// products deleted meanwhile are skipped instead of failing on the FK
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO cart_items (id, cart_id, product_id, quantity, sub_total, created_at, updated_at)
+			SELECT $1, $2, $3, $4, $5, $6, $7
+			WHERE EXISTS (SELECT 1 FROM products WHERE id = $3)
+			ON CONFLICT (id) DO UPDATE
+			SET quantity = EXCLUDED.quantity, sub_total = EXCLUDED.sub_total, updated_at = EXCLUDED.updated_at
+		`, item.ID, item.CartID, item.ProductID, item.Quantity, item.SubTotal, item.CreatedAt, item.UpdatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to upsert cart item %d: %w", item.ID, err)
+		}
+		keep = append(keep, int64(item.ID))
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM cart_items WHERE cart_id = $1 AND NOT (id = ANY($2::int[]))`,
+		cartID, pq.Array(keep)); err != nil {
+		return fmt.Errorf("failed to delete removed cart items: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// Run flushes every interval until ctx is cancelled, then flushes once more.
+func (w *CartWriteBehind) Run(ctx context.Context, interval time.Duration) {
+	log.Printf("[JOB] Cart write-behind started (interval=%s)", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// final flush with a fresh context, the job context is already cancelled
+			flushCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			if _, err := w.Flush(flushCtx); err != nil {
+				log.Printf("[ERROR] Final cart flush failed: %v", err)
+			}
+			cancel()
+			log.Println("[JOB] Cart write-behind stopped")
+			return
+		case <-ticker.C:
+			result, err := w.Flush(ctx)
+			if err != nil {
+				log.Printf("[ERROR] Cart flush failed: %v", err)
+				continue
+			}
+			if result.Flushed+result.Deleted+result.Failed > 0 {
+				log.Printf("[JOB] Cart flush: flushed=%d deleted=%d failed=%d",
+					result.Flushed, result.Deleted, result.Failed)
+			}
+		}
+	}
+}
+
+const (
+	CartStoragePostgres = "postgres"
+	CartStorageRedis    = "redis"
+)
+
+// CartStorage bundles the cart repositories of one storage strategy.
+// WriteBehind is nil for Postgres storage.
+type CartStorage struct {
+	Kind        string
+	Carts       CartRepository
+	Items       CartItemRepository
+	WriteBehind *CartWriteBehind
+}
+
+// NewCartStorage returns the cart repositories for kind ("postgres" or
+// "redis"). Redis storage needs a Redis client.
+func NewCartStorage(kind string, db *sql.DB, rdb *redis.Client) (*CartStorage, error) {
+	switch kind {
+	case "", CartStoragePostgres:
+		return &CartStorage{
+			Kind:  CartStoragePostgres,
+			Carts: NewCartRepository(db),
+			Items: NewCartItemRepository(db),
+		}, nil
+	case CartStorageRedis:
+		if rdb == nil {
+			return nil, errors.New("redis cart storage requires a redis connection")
+		}
+		store := newRedisCartStore(db, rdb)
+		return &CartStorage{
+			Kind:        CartStorageRedis,
+			Carts:       &redisCartRepo{store: store},
+			Items:       &redisCartItemRepo{store: store},
+			WriteBehind: &CartWriteBehind{store: store},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown cart storage %q", kind)
+	}
+}
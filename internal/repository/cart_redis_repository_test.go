@@ -0,0 +1,260 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCartStorage(t *testing.T) {
+	db, _, _ := sqlmock.New()
+	defer db.Close()
+
+	t.Run("DefaultsToPostgres", func(t *testing.T) {
+		storage, err := NewCartStorage("", db, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, CartStoragePostgres, storage.Kind)
+		assert.Nil(t, storage.WriteBehind)
+	})
+
+	t.Run("RedisRequiresClient", func(t *testing.T) {
+		storage, err := NewCartStorage(CartStorageRedis, db, nil)
+		assert.Error(t, err)
+		assert.Nil(t, storage)
+	})
+
+	t.Run("Redis", func(t *testing.T) {
+		rdb := redis.NewClient(&redis.Options{Addr: "localhost:0"})
+		defer rdb.Close()
+
+		storage, err := NewCartStorage(CartStorageRedis, db, rdb)
+		assert.NoError(t, err)
+		assert.Equal(t, CartStorageRedis, storage.Kind)
+		assert.NotNil(t, storage.WriteBehind)
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		_, err := NewCartStorage("memcached", db, nil)
+		assert.Error(t, err)
+	})
+}
+
+func newTestRedisCartStorage(t *testing.T) (*CartStorage, sqlmock.Sqlmock, *miniredis.Miniredis) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	storage, err := NewCartStorage(CartStorageRedis, db, rdb)
+	assert.NoError(t, err)
+	return storage, mock, mr
+}
+
+var (
+	pgCartColumns = []string{"id", "user_id", "warehouse_id", "created_at", "updated_at"}
+	pgItemColumns = []string{"id", "cart_id", "product_id", "quantity", "sub_total", "created_at", "updated_at"}
+)
+
+// expectUserLoad expects the Postgres reads of a first load of user 1, who
+// has cart 5 in warehouse 2 holding item 9.
+func expectUserLoad(mock sqlmock.Sqlmock) {
+	now := time.Now()
+	mock.ExpectQuery("SELECT \\* FROM carts WHERE user_id = \\$1").
+		WithArgs(uint(1)).
+		WillReturnRows(sqlmock.NewRows(pgCartColumns).AddRow(5, 1, 2, now, now))
+	mock.ExpectQuery("SELECT id, cart_id, product_id, quantity, sub_total, created_at, updated_at FROM cart_items").
+		WithArgs(uint(5)).
+		WillReturnRows(sqlmock.NewRows(pgItemColumns).AddRow(9, 5, 3, 1, 10000, now, now))
+}
+
+func TestRedisCartStore_Load(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("LoadsUserOnce", func(t *testing.T) {
+		storage, mock, mr := newTestRedisCartStorage(t)
+		expectUserLoad(mock)
+
+		carts, err := storage.Carts.GetCartByUserId(ctx, 1)
+		assert.NoError(t, err)
+		assert.Len(t, carts, 1)
+		assert.Equal(t, uint(2), carts[0].WarehouseID)
+		got, _ := mr.Get(cartStoreItemKey(9))
+		assert.Equal(t, "5", got)
+
+		// loaded: served from Redis without touching Postgres
+		cart, err := storage.Carts.FindByUserAndWarehouse(ctx, 1, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(5), cart.ID)
+		items, err := storage.Items.GetCartItemsByCartID(ctx, 5)
+		assert.NoError(t, err)
+		assert.Len(t, items, 1)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("SlowLoadDoesNotOverwrite", func(t *testing.T) {
+		storage, mock, mr := newTestRedisCartStorage(t)
+		store := storage.Carts.(*redisCartRepo).store
+		expectUserLoad(mock)
+
+		// a second request read the same rows from Postgres but writes last
+		now := time.Now()
+		stale := []domain.Cart{{ID: 5, UserID: 1, WarehouseID: 2, CreatedAt: now, UpdatedAt: now}}
+		staleItems := map[uint][]domain.CartItem{5: {{ID: 9, CartID: 5, ProductID: 3, Quantity: 1}}}
+
+		_, err := storage.Carts.FindByUserAndWarehouse(ctx, 1, 2)
+		assert.NoError(t, err)
+		err = storage.Items.UpdateCartItem(ctx, &domain.CartItem{ID: 9, Quantity: 4, SubTotal: 40000})
+		assert.NoError(t, err)
+
+		stored, err := store.storeLoaded(ctx, 1, stale, staleItems)
+		assert.NoError(t, err)
+		assert.False(t, stored)
+		item, err := storage.Items.GetCartItemByID(ctx, 9)
+		assert.NoError(t, err)
+		assert.Equal(t, int32(4), item.Quantity)
+
+		// nor does it bring back a cart deleted after the first load
+		assert.NoError(t, storage.Carts.DeleteCart(ctx, 5))
+		stored, err = store.storeLoaded(ctx, 1, stale, staleItems)
+		assert.NoError(t, err)
+		assert.False(t, stored)
+		assert.False(t, mr.Exists(cartStoreCartKey(5)))
+		assert.False(t, mr.Exists(cartStoreItemKey(9)))
+		_, err = storage.Carts.FindByUserAndWarehouse(ctx, 1, 2)
+		assert.ErrorIs(t, err, ErrCartNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRedisCartStore_Items(t *testing.T) {
+	storage, mock, mr := newTestRedisCartStorage(t)
+	ctx := context.Background()
+
+	mock.ExpectQuery("SELECT \\* FROM carts WHERE user_id = \\$1").
+		WithArgs(uint(1)).
+		WillReturnRows(sqlmock.NewRows(pgCartColumns))
+	mock.ExpectQuery("SELECT nextval").
+		WithArgs("carts_id_seq").
+		WillReturnRows(sqlmock.NewRows([]string{"nextval"}).AddRow(11))
+	cart := &domain.Cart{UserID: 1, WarehouseID: 2}
+	assert.NoError(t, storage.Carts.CreateCart(ctx, cart))
+	assert.Equal(t, uint(11), cart.ID)
+
+	t.Run("OneCartPerWarehouse", func(t *testing.T) {
+		mock.ExpectQuery("SELECT nextval").
+			WithArgs("carts_id_seq").
+			WillReturnRows(sqlmock.NewRows([]string{"nextval"}).AddRow(12))
+		err := storage.Carts.CreateCart(ctx, &domain.Cart{UserID: 1, WarehouseID: 2})
+		assert.Error(t, err)
+		assert.False(t, mr.Exists(cartStoreCartKey(12)))
+	})
+
+	t.Run("AddUpdateRemove", func(t *testing.T) {
+		for _, id := range []int{21, 22} {
+			mock.ExpectQuery("SELECT nextval").
+				WithArgs("cart_items_id_seq").
+				WillReturnRows(sqlmock.NewRows([]string{"nextval"}).AddRow(id))
+		}
+		first := &domain.CartItem{CartID: 11, ProductID: 3, Quantity: 1, SubTotal: 100}
+		second := &domain.CartItem{CartID: 11, ProductID: 4, Quantity: 2, SubTotal: 50}
+		assert.NoError(t, storage.Items.AddCartItem(ctx, first))
+		assert.NoError(t, storage.Items.AddCartItem(ctx, second))
+		assert.Equal(t, uint(21), first.ID)
+
+		assert.NoError(t, storage.Items.UpdateCartItem(ctx, &domain.CartItem{ID: 21, Quantity: 3, SubTotal: 300}))
+		assert.NoError(t, storage.Items.DeleteCartItem(ctx, 22))
+
+		items, err := storage.Items.GetCartItemsByCartID(ctx, 11)
+		assert.NoError(t, err)
+		if assert.Len(t, items, 1) {
+			assert.Equal(t, int32(3), items[0].Quantity)
+			assert.Equal(t, uint(3), items[0].ProductID)
+		}
+		_, err = storage.Items.GetCartItemByID(ctx, 22)
+		assert.Error(t, err)
+		assert.False(t, mr.Exists(cartStoreItemKey(22)))
+
+		dirty, _ := mr.Members(cartStoreDirtyKey)
+		assert.Equal(t, []string{"11"}, dirty)
+	})
+
+	t.Run("ItemOfUnknownCart", func(t *testing.T) {
+		mock.ExpectQuery("SELECT id, user_id, warehouse_id, created_at, updated_at FROM carts WHERE id = \\$1").
+			WithArgs(uint(99)).
+			WillReturnRows(sqlmock.NewRows(pgCartColumns))
+		err := storage.Items.AddCartItem(ctx, &domain.CartItem{CartID: 99, ProductID: 3, Quantity: 1})
+		assert.Error(t, err)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCartWriteBehind_Flush(t *testing.T) {
+	storage, mock, mr := newTestRedisCartStorage(t)
+	ctx := context.Background()
+
+	mock.ExpectQuery("SELECT \\* FROM carts WHERE user_id = \\$1").
+		WithArgs(uint(1)).
+		WillReturnRows(sqlmock.NewRows(pgCartColumns))
+	mock.ExpectQuery("SELECT nextval").WithArgs("carts_id_seq").
+		WillReturnRows(sqlmock.NewRows([]string{"nextval"}).AddRow(11))
+	mock.ExpectQuery("SELECT nextval").WithArgs("cart_items_id_seq").
+		WillReturnRows(sqlmock.NewRows([]string{"nextval"}).AddRow(21))
+	assert.NoError(t, storage.Carts.CreateCart(ctx, &domain.Cart{UserID: 1, WarehouseID: 2}))
+	assert.NoError(t, storage.Items.AddCartItem(ctx, &domain.CartItem{CartID: 11, ProductID: 3, Quantity: 2, SubTotal: 200}))
+
+	t.Run("FailedCartStaysQueued", func(t *testing.T) {
+		mock.ExpectBegin().WillReturnError(errors.New("connection refused"))
+
+		result, err := storage.WriteBehind.Flush(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, result.Failed)
+		dirty, _ := mr.Members(cartStoreDirtyKey)
+		assert.Equal(t, []string{"11"}, dirty)
+	})
+
+	t.Run("WritesDirtyCart", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO carts (.+) ON CONFLICT \\(id\\) DO UPDATE").
+			WithArgs(uint(11), uint(1), uint(2), sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO cart_items (.+) ON CONFLICT \\(id\\) DO UPDATE").
+			WithArgs(uint(21), uint(11), uint(3), int32(2), 200.0, sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("DELETE FROM cart_items WHERE cart_id = \\$1").
+			WithArgs(uint(11), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		result, err := storage.WriteBehind.Flush(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, &CartFlushResult{Flushed: 1}, result)
+		assert.False(t, mr.Exists(cartStoreDirtyKey))
+	})
+
+	t.Run("DeletesCart", func(t *testing.T) {
+		assert.NoError(t, storage.Carts.DeleteCart(ctx, 11))
+		assert.False(t, mr.Exists(cartStoreItemKey(21)))
+
+		mock.ExpectExec("DELETE FROM carts WHERE id = ANY").
+			WithArgs(sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		result, err := storage.WriteBehind.Flush(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, &CartFlushResult{Deleted: 1}, result)
+		assert.False(t, mr.Exists(cartStoreDeletedKey))
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
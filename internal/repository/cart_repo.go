@@ -9,9 +9,13 @@ import (
 	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
 )
 
+// ErrCartNotFound is returned when a cart lookup matches no row.
+var ErrCartNotFound = errors.New("cart not found")
+
 type CartRepository interface {
 	CreateCart(ctx context.Context, cart *domain.Cart) error
 	DeleteCart(ctx context.Context, id uint) error
+	GetCartByID(ctx context.Context, id uint) (*domain.Cart, error)
 	GetCartByUserId(ctx context.Context, userId uint) ([]domain.Cart, error)
 	FindByUserAndWarehouse(ctx context.Context, userId uint, warehouseID uint) (*domain.Cart, error)
 }
@@ -27,7 +31,7 @@ func (c *cartRepo) FindByUserAndWarehouse(ctx context.Context, userId uint, ware
 	var cart domain.Cart
 	err := row.Scan(&cart.ID, &cart.UserID, &cart.WarehouseID, &cart.CreatedAt)
 	if err == sql.ErrNoRows {
-		return nil, errors.New("chart not found")
+		return nil, ErrCartNotFound
 	}
 
 	if err != nil {
@@ -56,11 +60,27 @@ func (c *cartRepo) DeleteCart(ctx context.Context, id uint) error {
 		return fmt.Errorf("could not check affecred rows : %w", err)
 	}
 	if rowAffected == 0 {
-		return fmt.Errorf("cart not found")
+		return ErrCartNotFound
 	}
 	return nil
 }
 
+// GetCartByID implements CartRepository.
+func (c *cartRepo) GetCartByID(ctx context.Context, id uint) (*domain.Cart, error) {
+	query := `SELECT id, user_id, warehouse_id, created_at, updated_at FROM carts WHERE id = $1`
+	row := c.db.QueryRowContext(ctx, query, id)
+
+	var cart domain.Cart
+	err := row.Scan(&cart.ID, &cart.UserID, &cart.WarehouseID, &cart.CreatedAt, &cart.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrCartNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cart: %w", err)
+	}
+	return &cart, nil
+}
+
 // GetCartByUserId implements CartRepository.
 func (c *cartRepo) GetCartByUserId(ctx context.Context, userId uint) ([]domain.Cart, error) {
 	query := `SELECT * FROM carts WHERE user_id = $1`
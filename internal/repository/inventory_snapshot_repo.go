@@ -0,0 +1,254 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+)
+
+// ErrInventorySnapshotNotFound is returned when a snapshot lookup matches no row.
+var ErrInventorySnapshotNotFound = errors.New("inventory snapshot not found")
+
+// InventorySnapshotRepository stores point-in-time copies of warehouse_stock
+// and answers stock levels as of any moment from them and the movement ledger.
+type InventorySnapshotRepository interface {
+	// Create copies every non-zero warehouse_stock row, with the current
+	// product price, and records the last stock movement the copy includes.
+	Create(ctx context.Context, kind string, takenBy *uint) (*domain.InventorySnapshot, error)
+	// GetByID returns the snapshot with its lines.
+	GetByID(ctx context.Context, id uint) (*domain.InventorySnapshot, error)
+	// List returns snapshot headers, newest first. An empty kind lists all.
+	List(ctx context.Context, kind string, limit int) ([]domain.InventorySnapshot, error)
+	// LatestTakenAt returns when the newest snapshot of the kind was taken,
+	// or nil when there is none.
+	LatestTakenAt(ctx context.Context, kind string) (*time.Time, error)
+	// LevelsAt returns the non-zero stock levels at the given moment, valued
+	// at the product prices in effect then. Zero IDs disable the filters.
+	LevelsAt(ctx context.Context, at time.Time, warehouseID, productID uint) ([]domain.InventoryLevel, error)
+}
+
+type inventorySnapshotRepo struct {
+	db *sql.DB
+}
+
+func NewInventorySnapshotRepository(db *sql.DB) InventorySnapshotRepository {
+	return &inventorySnapshotRepo{db: db}
+}
+
+func (r *inventorySnapshotRepo) Create(ctx context.Context, kind string, takenBy *uint) (*domain.InventorySnapshot, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Block new movements until the snapshot commits. Taking the lock waits
+	// for every transaction that has already written one, so the ledger up to
+	// the watermark is exactly what the copy below sees; stock changes that
+	// are still in flight commit their movements after the watermark.
+	if _, err := tx.ExecContext(ctx, `LOCK TABLE stock_movements IN SHARE MODE`); err != nil {
+		return nil, fmt.Errorf("failed to lock stock movements: %w", err)
+	}
+
+	snapshot := &domain.InventorySnapshot{Kind: kind, TakenBy: takenBy}
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO inventory_snapshots (kind, taken_by, taken_at, last_movement_id)
+		VALUES ($1, $2, NOW(), (SELECT COALESCE(MAX(id), 0) FROM stock_movements))
+		RETURNING id, taken_at
+	`, kind, takenBy).Scan(&snapshot.ID, &snapshot.TakenAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create inventory snapshot: %w", err)
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		WITH lines AS (
+			INSERT INTO inventory_snapshot_lines (snapshot_id, warehouse_id, product_id, quantity, unit_price)
+			SELECT $1, s.warehouse_id, s.product_id, s.quantity, p.price
+			FROM warehouse_stock s
+			JOIN products p ON p.id = s.product_id
+			WHERE s.quantity <> 0
+			RETURNING quantity
+		)
+		UPDATE inventory_snapshots
+		SET line_count = (SELECT COUNT(*) FROM lines),
+		    total_quantity = (SELECT COALESCE(SUM(quantity), 0) FROM lines)
+		WHERE id = $1
+		RETURNING line_count, total_quantity
+	`, snapshot.ID).Scan(&snapshot.LineCount, &snapshot.TotalQuantity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy warehouse stock: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit inventory snapshot: %w", err)
+	}
+	return snapshot, nil
+}
+
+const inventorySnapshotColumns = `id, kind, taken_by, line_count, total_quantity, taken_at`
+
+func scanInventorySnapshot(row rowScanner) (*domain.InventorySnapshot, error) {
+	var s domain.InventorySnapshot
+	var takenBy sql.NullInt64
+	if err := row.Scan(&s.ID, &s.Kind, &takenBy, &s.LineCount, &s.TotalQuantity, &s.TakenAt); err != nil {
+		return nil, err
+	}
+	if takenBy.Valid {
+		id := uint(takenBy.Int64)
+		s.TakenBy = &id
+	}
+	return &s, nil
+}
+
+func (r *inventorySnapshotRepo) GetByID(ctx context.Context, id uint) (*domain.InventorySnapshot, error) {
+	query := `SELECT ` + inventorySnapshotColumns + ` FROM inventory_snapshots WHERE id = $1`
+	snapshot, err := scanInventorySnapshot(r.db.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInventorySnapshotNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query inventory snapshot: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT warehouse_id, product_id, quantity, unit_price
+		FROM inventory_snapshot_lines
+		WHERE snapshot_id = $1
+		ORDER BY warehouse_id, product_id
+	`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query inventory snapshot lines: %w", err)
+	}
+	defer rows.Close()
+
+	snapshot.Lines = []domain.InventorySnapshotLine{}
+	for rows.Next() {
+		var l domain.InventorySnapshotLine
+		if err := rows.Scan(&l.WarehouseID, &l.ProductID, &l.Quantity, &l.UnitPrice); err != nil {
+			return nil, fmt.Errorf("failed to scan inventory snapshot line: %w", err)
+		}
+		snapshot.Lines = append(snapshot.Lines, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating inventory snapshot lines: %w", err)
+	}
+	return snapshot, nil
+}
+
+func (r *inventorySnapshotRepo) List(ctx context.Context, kind string, limit int) ([]domain.InventorySnapshot, error) {
+	query := `SELECT ` + inventorySnapshotColumns + ` FROM inventory_snapshots
+		WHERE ($1 = '' OR kind = $1)
+		ORDER BY taken_at DESC, id DESC
+		LIMIT $2`
+	rows, err := r.db.QueryContext(ctx, query, kind, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query inventory snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	snapshots := []domain.InventorySnapshot{}
+	for rows.Next() {
+		s, err := scanInventorySnapshot(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan inventory snapshot: %w", err)
+		}
+		snapshots = append(snapshots, *s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating inventory snapshots: %w", err)
+	}
+	return snapshots, nil
+}
+
+func (r *inventorySnapshotRepo) LatestTakenAt(ctx context.Context, kind string) (*time.Time, error) {
+	var takenAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, `SELECT MAX(taken_at) FROM inventory_snapshots WHERE kind = $1`, kind).Scan(&takenAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query latest inventory snapshot: %w", err)
+	}
+	if !takenAt.Valid {
+		return nil, nil
+	}
+	return &takenAt.Time, nil
+}
+
+// LevelsAt starts from the latest snapshot taken at or before the moment and
+// replays the ledger after it: a pair's level is the quantity_after of its
+// last movement up to the moment, or its snapshot quantity when it did not
+// move since. Movements after the snapshot are those past its
+// last_movement_id; created_at cannot tell, since both timestamps are
+// transaction-start times. Without an earlier snapshot the whole ledger is used, which
+// starts with the opening balances.
+//
+// The price is the product's price history entry in effect at the moment,
+// then the price copied into the snapshot, then the current price.
+func (r *inventorySnapshotRepo) LevelsAt(ctx context.Context, at time.Time, warehouseID, productID uint) ([]domain.InventoryLevel, error) {
+	query := `
+		WITH snap AS (
+			SELECT id, last_movement_id FROM inventory_snapshots
+			WHERE taken_at <= $1
+			ORDER BY taken_at DESC, id DESC
+			LIMIT 1
+		),
+		pairs AS (
+			SELECT l.warehouse_id, l.product_id
+			FROM inventory_snapshot_lines l
+			JOIN snap ON snap.id = l.snapshot_id
+			UNION
+			SELECT m.warehouse_id, m.product_id
+			FROM stock_movements m
+			WHERE m.created_at <= $1
+			  AND m.id > COALESCE((SELECT last_movement_id FROM snap), 0)
+		)
+		SELECT k.warehouse_id, COALESCE(w.name, ''), k.product_id, COALESCE(p.name, ''),
+		       COALESCE(m.quantity_after, l.quantity, 0),
+		       COALESCE(h.price, l.unit_price, p.price, 0)
+		FROM pairs k
+		LEFT JOIN inventory_snapshot_lines l
+		       ON l.snapshot_id = (SELECT id FROM snap)
+		      AND l.warehouse_id = k.warehouse_id AND l.product_id = k.product_id
+		LEFT JOIN LATERAL (
+			SELECT quantity_after FROM stock_movements
+			WHERE warehouse_id = k.warehouse_id AND product_id = k.product_id
+			  AND created_at <= $1
+			  AND id > COALESCE((SELECT last_movement_id FROM snap), 0)
+			ORDER BY id DESC
+			LIMIT 1
+		) m ON TRUE
+		LEFT JOIN LATERAL (
+			SELECT price FROM product_price_history
+			WHERE product_id = k.product_id AND effective_from <= $1
+			ORDER BY effective_from DESC, id DESC
+			LIMIT 1
+		) h ON TRUE
+		LEFT JOIN warehouses w ON w.id = k.warehouse_id
+		LEFT JOIN products p ON p.id = k.product_id
+		WHERE ($2 = 0 OR k.warehouse_id = $2)
+		  AND ($3 = 0 OR k.product_id = $3)
+		  AND COALESCE(m.quantity_after, l.quantity, 0) <> 0
+		ORDER BY k.warehouse_id, k.product_id
+	`
+	rows, err := r.db.QueryContext(ctx, query, at, warehouseID, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query inventory levels: %w", err)
+	}
+	defer rows.Close()
+
+	levels := []domain.InventoryLevel{}
+	for rows.Next() {
+		var l domain.InventoryLevel
+		if err := rows.Scan(&l.WarehouseID, &l.WarehouseName, &l.ProductID, &l.ProductName, &l.Quantity, &l.UnitPrice); err != nil {
+			return nil, fmt.Errorf("failed to scan inventory level: %w", err)
+		}
+		l.Value = float64(l.Quantity) * l.UnitPrice
+		levels = append(levels, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating inventory levels: %w", err)
+	}
+	return levels, nil
+}
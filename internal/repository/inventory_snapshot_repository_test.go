@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInventorySnapshotRepository(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := NewInventorySnapshotRepository(db)
+	ctx := context.Background()
+
+	t.Run("Create_CopiesWarehouseStock", func(t *testing.T) {
+		takenAt := time.Date(2025, 1, 31, 23, 59, 0, 0, time.UTC)
+		userID := uint(7)
+
+		mock.ExpectBegin()
+		mock.ExpectExec("LOCK TABLE stock_movements IN SHARE MODE").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("INSERT INTO inventory_snapshots (.+)SELECT COALESCE\\(MAX\\(id\\), 0\\) FROM stock_movements").
+			WithArgs(domain.InventorySnapshotManual, &userID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "taken_at"}).AddRow(4, takenAt))
+		mock.ExpectQuery("INSERT INTO inventory_snapshot_lines (.+) FROM warehouse_stock s (.+) UPDATE inventory_snapshots").
+			WithArgs(uint(4)).
+			WillReturnRows(sqlmock.NewRows([]string{"line_count", "total_quantity"}).AddRow(3, 250))
+		mock.ExpectCommit()
+
+		snapshot, err := repo.Create(ctx, domain.InventorySnapshotManual, &userID)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(4), snapshot.ID)
+		assert.Equal(t, takenAt, snapshot.TakenAt)
+		assert.Equal(t, 3, snapshot.LineCount)
+		assert.Equal(t, int64(250), snapshot.TotalQuantity)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("LevelsAt_ValuesWithHistoricPrice", func(t *testing.T) {
+		at := time.Date(2025, 1, 31, 23, 59, 59, 0, time.UTC)
+
+		mock.ExpectQuery("WITH snap AS \\(\\s*SELECT id, last_movement_id FROM inventory_snapshots (.+) m.id > COALESCE\\(\\(SELECT last_movement_id FROM snap\\), 0\\) (.+) product_price_history").
+			WithArgs(at, uint(1), uint(0)).
+			WillReturnRows(sqlmock.NewRows([]string{"warehouse_id", "warehouse_name", "product_id", "product_name", "quantity", "price"}).
+				AddRow(1, "Gudang Jakarta", 5, "Susu UHT 1L", 12, 15000.0).
+				AddRow(1, "Gudang Jakarta", 9, "", 3, 2500.5))
+
+		levels, err := repo.LevelsAt(ctx, at, 1, 0)
+		assert.NoError(t, err)
+		assert.Len(t, levels, 2)
+		assert.Equal(t, 180000.0, levels[0].Value)
+		assert.Equal(t, 7501.5, levels[1].Value)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("GetByID_NotFound", func(t *testing.T) {
+		mock.ExpectQuery("SELECT (.+) FROM inventory_snapshots WHERE id = \\$1").
+			WithArgs(uint(99)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "kind", "taken_by", "line_count", "total_quantity", "taken_at"}))
+
+		_, err := repo.GetByID(ctx, 99)
+		assert.ErrorIs(t, err, ErrInventorySnapshotNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -15,6 +15,14 @@ type OrderItemRepository interface {
 	DeleteOrderItem(ctx context.Context, id uint) error
 	GetOrderItemByIdOrder(ctx context.Context, id uint) ([]domain.OrderItem, error)
 	CreateOrderItemTx(ctx context.Context, tx *sql.Tx, item *domain.OrderItem) error
+	// CreateLotsTx records the lots an order item was picked from.
+	CreateLotsTx(ctx context.Context, tx *sql.Tx, orderItemID uint, lots []domain.LotConsumption) error
+	// GetLotsByOrderID returns the lots of every item of an order of userID.
+	GetLotsByOrderID(ctx context.Context, orderID uint, userID uint) ([]domain.OrderItemLot, error)
+	// CreatePicksTx records the pick list lines of an order item and fills their IDs.
+	CreatePicksTx(ctx context.Context, tx *sql.Tx, picks []domain.OrderItemPick) error
+	// GetPicksByOrderID returns the pick list of an order of userID.
+	GetPicksByOrderID(ctx context.Context, orderID uint, userID uint) ([]domain.OrderItemPick, error)
 }
 
 type orderItemRepo struct {
@@ -98,6 +106,111 @@ func (r *orderItemRepo) CreateOrderItemTx(ctx context.Context, tx *sql.Tx, item
 	return nil
 }
 
+func (r *orderItemRepo) CreateLotsTx(ctx context.Context, tx *sql.Tx, orderItemID uint, lots []domain.LotConsumption) error {
+	query := `
+		INSERT INTO order_item_lots (order_item_id, lot_id, batch_number, expiry_date, quantity)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	for _, l := range lots {
+		if _, err := tx.ExecContext(ctx, query, orderItemID, l.LotID, l.BatchNumber, l.ExpiryDate, l.Quantity); err != nil {
+			return fmt.Errorf("failed to record order item lot: %w", err)
+		}
+	}
+	return nil
+}
+
+func (r *orderItemRepo) GetLotsByOrderID(ctx context.Context, orderID uint, userID uint) ([]domain.OrderItemLot, error) {
+	if err := r.checkOrderOwner(ctx, orderID, userID); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT l.id, l.order_item_id, i.product_id, l.lot_id, l.batch_number, l.expiry_date, l.quantity
+		FROM order_item_lots l
+		JOIN order_items i ON i.id = l.order_item_id
+		WHERE i.order_id = $1
+		ORDER BY l.order_item_id, l.id
+	`
+	rows, err := r.db.QueryContext(ctx, query, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query order item lots: %w", err)
+	}
+	defer rows.Close()
+
+	lots := []domain.OrderItemLot{}
+	for rows.Next() {
+		var l domain.OrderItemLot
+		if err := rows.Scan(&l.ID, &l.OrderItemID, &l.ProductID, &l.LotID, &l.BatchNumber, &l.ExpiryDate, &l.Quantity); err != nil {
+			return nil, fmt.Errorf("failed to scan order item lot: %w", err)
+		}
+		lots = append(lots, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating order item lots: %w", err)
+	}
+	return lots, nil
+}
+
+func (r *orderItemRepo) CreatePicksTx(ctx context.Context, tx *sql.Tx, picks []domain.OrderItemPick) error {
+	query := `
+		INSERT INTO order_item_picks (order_item_id, warehouse_id, location_id, location_code, quantity)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`
+	for i := range picks {
+		p := &picks[i]
+		if err := tx.QueryRowContext(ctx, query, p.OrderItemID, p.WarehouseID, p.LocationID, p.LocationCode, p.Quantity).Scan(&p.ID); err != nil {
+			return fmt.Errorf("failed to record order item pick: %w", err)
+		}
+	}
+	return nil
+}
+
+func (r *orderItemRepo) GetPicksByOrderID(ctx context.Context, orderID uint, userID uint) ([]domain.OrderItemPick, error) {
+	if err := r.checkOrderOwner(ctx, orderID, userID); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT p.id, p.order_item_id, i.product_id, p.warehouse_id, p.location_id, p.location_code, p.quantity
+		FROM order_item_picks p
+		JOIN order_items i ON i.id = p.order_item_id
+		WHERE i.order_id = $1
+		ORDER BY p.warehouse_id, p.location_code NULLS LAST, p.id
+	`
+	rows, err := r.db.QueryContext(ctx, query, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query order item picks: %w", err)
+	}
+	defer rows.Close()
+
+	picks := []domain.OrderItemPick{}
+	for rows.Next() {
+		var p domain.OrderItemPick
+		if err := rows.Scan(&p.ID, &p.OrderItemID, &p.ProductID, &p.WarehouseID, &p.LocationID, &p.LocationCode, &p.Quantity); err != nil {
+			return nil, fmt.Errorf("failed to scan order item pick: %w", err)
+		}
+		picks = append(picks, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating order item picks: %w", err)
+	}
+	return picks, nil
+}
+
+// checkOrderOwner returns "order not found" unless the order belongs to userID.
+func (r *orderItemRepo) checkOrderOwner(ctx context.Context, orderID uint, userID uint) error {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM orders WHERE id = $1 AND user_id = $2)`, orderID, userID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check order: %w", err)
+	}
+	if !exists {
+		return errors.New("order not found")
+	}
+	return nil
+}
+
 func NewOrderItemRepository(db *sql.DB) OrderItemRepository {
 	return &orderItemRepo{db: db}
 }
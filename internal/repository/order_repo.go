@@ -7,6 +7,7 @@ import (
 	"fmt"
 
 	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/lib/pq"
 )
 
 type OrderRepository interface {
@@ -14,10 +15,16 @@ type OrderRepository interface {
 	UpdateShippingCostOrder(ctx context.Context, id uint, shipping_cost float64) error
 	GetOrderByUserId(ctx context.Context, id uint) ([]domain.Order, error)
 	GetOrderByUserIdAndStatus(ctx context.Context, userID uint, status string) ([]domain.Order, error)
-	UpdateOrderStatus(ctx context.Context, id uint, status string) error
+	// UpdateOrderStatus sets the status and returns the new version. A non-zero
+	// version must match the stored one (ErrVersionConflict).
+	UpdateOrderStatus(ctx context.Context, id uint, status string, version int32) (int32, error)
 	Delete(ctx context.Context, id uint) error
 	BeginTx(ctx context.Context) (*sql.Tx, error)
 	CreateOrderTx(ctx context.Context, tx *sql.Tx, order *domain.Order) error
+	// CreateAllocationsTx records which warehouse ships which part of the order.
+	CreateAllocationsTx(ctx context.Context, tx *sql.Tx, orderID uint, allocations []domain.OrderAllocation) error
+	// GetAllocations returns the allocations of the given orders keyed by order ID.
+	GetAllocations(ctx context.Context, orderIDs []uint) (map[uint][]domain.OrderAllocation, error)
 }
 
 type orderRepo struct {
@@ -25,7 +32,7 @@ type orderRepo struct {
 }
 
 func (o *orderRepo) UpdatePriceOrder(ctx context.Context, id uint, price float64) error {
-	query := `UPDATE orders SET total_price = $1, updated_at = NOW() WHERE id = $2`
+	query := `UPDATE orders SET total_price = $1, version = version + 1, updated_at = NOW() WHERE id = $2`
 	result, err := o.db.ExecContext(ctx, query, price, id)
 	if err != nil {
 		return err
@@ -38,7 +45,7 @@ func (o *orderRepo) UpdatePriceOrder(ctx context.Context, id uint, price float64
 }
 
 func (o *orderRepo) UpdateShippingCostOrder(ctx context.Context, id uint, shipping_cost float64) error {
-	query := `UPDATE orders SET shipping_cost = $1, updated_at = NOW() WHERE id = $2`
+	query := `UPDATE orders SET shipping_cost = $1, version = version + 1, updated_at = NOW() WHERE id = $2`
 	result, err := o.db.ExecContext(ctx, query, shipping_cost, id)
 	if err != nil {
 		return err
@@ -67,7 +74,7 @@ func (o *orderRepo) Delete(ctx context.Context, id uint) error {
 }
 
 func (o *orderRepo) GetOrderByUserId(ctx context.Context, id uint) ([]domain.Order, error) {
-	query := `SELECT id, user_id, status, total_price, shipping_cost, created_at, updated_at 
+	query := `SELECT id, user_id, status, total_price, shipping_cost, tax_amount, address_id, routing_decision, version, created_at, updated_at 
 	          FROM orders WHERE user_id = $1 ORDER BY created_at DESC`
 	rows, err := o.db.QueryContext(ctx, query, id)
 	if err != nil {
@@ -79,7 +86,7 @@ func (o *orderRepo) GetOrderByUserId(ctx context.Context, id uint) ([]domain.Ord
 	for rows.Next() {
 		var order domain.Order
 		if err := rows.Scan(&order.ID, &order.UserID, &order.Status, &order.TotalPrice,
-			&order.ShippingCost, &order.CreatedAt, &order.UpdatedAt); err != nil {
+			&order.ShippingCost, &order.TaxAmount, &order.AddressID, &order.RoutingDecision, &order.Version, &order.CreatedAt, &order.UpdatedAt); err != nil {
 			return nil, err
 		}
 		orders = append(orders, order)
@@ -88,7 +95,7 @@ func (o *orderRepo) GetOrderByUserId(ctx context.Context, id uint) ([]domain.Ord
 }
 
 func (o *orderRepo) GetOrderByUserIdAndStatus(ctx context.Context, userID uint, status string) ([]domain.Order, error) {
-	query := `SELECT id, user_id, status, total_price, shipping_cost, created_at, updated_at 
+	query := `SELECT id, user_id, status, total_price, shipping_cost, tax_amount, address_id, routing_decision, version, created_at, updated_at 
 	          FROM orders WHERE user_id = $1 AND status = $2 ORDER BY created_at DESC`
 	rows, err := o.db.QueryContext(ctx, query, userID, status)
 	if err != nil {
@@ -100,7 +107,7 @@ func (o *orderRepo) GetOrderByUserIdAndStatus(ctx context.Context, userID uint,
 	for rows.Next() {
 		var order domain.Order
 		if err := rows.Scan(&order.ID, &order.UserID, &order.Status, &order.TotalPrice,
-			&order.ShippingCost, &order.CreatedAt, &order.UpdatedAt); err != nil {
+			&order.ShippingCost, &order.TaxAmount, &order.AddressID, &order.RoutingDecision, &order.Version, &order.CreatedAt, &order.UpdatedAt); err != nil {
 			return nil, err
 		}
 		orders = append(orders, order)
@@ -108,20 +115,19 @@ func (o *orderRepo) GetOrderByUserIdAndStatus(ctx context.Context, userID uint,
 	return orders, nil
 }
 
-func (o *orderRepo) UpdateOrderStatus(ctx context.Context, id uint, status string) error {
-	query := `UPDATE orders SET status = $1, updated_at = NOW() WHERE id = $2`
-	res, err := o.db.ExecContext(ctx, query, status, id)
-	if err != nil {
-		return fmt.Errorf("failed to update order status: %w", err)
+func (o *orderRepo) UpdateOrderStatus(ctx context.Context, id uint, status string, version int32) (int32, error) {
+	query := `UPDATE orders SET status = $1, version = version + 1, updated_at = NOW()
+	          WHERE id = $2 AND ($3 = 0 OR version = $3)
+	          RETURNING version`
+	var newVersion int32
+	err := o.db.QueryRowContext(ctx, query, status, id, version).Scan(&newVersion)
+	if err == sql.ErrNoRows {
+		return 0, conflictOrNotFound(ctx, o.db, "orders", id, errors.New("order not found"))
 	}
-	rowsAffected, err := res.RowsAffected()
 	if err != nil {
-		return fmt.Errorf("could not check affected rows: %w", err)
+		return 0, fmt.Errorf("failed to update order status: %w", err)
 	}
-	if rowsAffected == 0 {
-		return errors.New("order not found")
-	}
-	return nil
+	return newVersion, nil
 }
 
 func (o *orderRepo) BeginTx(ctx context.Context) (*sql.Tx, error) {
@@ -129,9 +135,54 @@ func (o *orderRepo) BeginTx(ctx context.Context) (*sql.Tx, error) {
 }
 
 func (o *orderRepo) CreateOrderTx(ctx context.Context, tx *sql.Tx, order *domain.Order) error {
-	query := `INSERT INTO orders (user_id, status, total_price, shipping_cost, created_at, updated_at)
-	          VALUES ($1, $2, $3, $4, NOW(), NOW()) RETURNING id`
-	return tx.QueryRowContext(ctx, query, order.UserID, order.Status, order.TotalPrice, order.ShippingCost).Scan(&order.ID)
+	query := `INSERT INTO orders (user_id, status, total_price, shipping_cost, tax_amount, address_id, routing_decision, created_at, updated_at)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW()) RETURNING id, version, created_at, updated_at`
+	return tx.QueryRowContext(ctx, query, order.UserID, order.Status, order.TotalPrice, order.ShippingCost, order.TaxAmount,
+		order.AddressID, order.RoutingDecision).Scan(&order.ID, &order.Version, &order.CreatedAt, &order.UpdatedAt)
+}
+
+func (o *orderRepo) CreateAllocationsTx(ctx context.Context, tx *sql.Tx, orderID uint, allocations []domain.OrderAllocation) error {
+	query := `INSERT INTO order_allocations (order_id, warehouse_id, product_id, quantity, distance_km)
+	          VALUES ($1, $2, $3, $4, $5) RETURNING id`
+	for i := range allocations {
+		a := &allocations[i]
+		a.OrderID = orderID
+		if err := tx.QueryRowContext(ctx, query, orderID, a.WarehouseID, a.ProductID, a.Quantity, a.DistanceKm).Scan(&a.ID); err != nil {
+			return fmt.Errorf("failed to create order allocation: %w", err)
+		}
+	}
+	return nil
+}
+
+func (o *orderRepo) GetAllocations(ctx context.Context, orderIDs []uint) (map[uint][]domain.OrderAllocation, error) {
+	allocations := make(map[uint][]domain.OrderAllocation, len(orderIDs))
+	if len(orderIDs) == 0 {
+		return allocations, nil
+	}
+	ids := make([]int64, len(orderIDs))
+	for i, id := range orderIDs {
+		ids[i] = int64(id)
+	}
+
+	query := `SELECT id, order_id, warehouse_id, product_id, quantity, distance_km
+	          FROM order_allocations WHERE order_id = ANY($1) ORDER BY order_id, id`
+	rows, err := o.db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query order allocations: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a domain.OrderAllocation
+		if err := rows.Scan(&a.ID, &a.OrderID, &a.WarehouseID, &a.ProductID, &a.Quantity, &a.DistanceKm); err != nil {
+			return nil, fmt.Errorf("failed to scan order allocation: %w", err)
+		}
+		allocations[a.OrderID] = append(allocations[a.OrderID], a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating order allocations: %w", err)
+	}
+	return allocations, nil
 }
 
 func NewOrderRepository(db *sql.DB) OrderRepository {
@@ -7,6 +7,14 @@ import (
 	"fmt"
 
 	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrProductNotFound is returned by FindById when the product does not exist.
+	ErrProductNotFound = errors.New("id product not found")
+	// ErrDuplicateSKU is returned when another product already uses the SKU.
+	ErrDuplicateSKU = errors.New("sku is already used by another product")
 )
 
 type ProductRepository interface {
@@ -16,8 +24,19 @@ type ProductRepository interface {
 	FindByName(ctx context.Context, name string) (*domain.Product, error)
 	FindById(ctx context.Context, id uint) (*domain.Product, error)
 	GetAll(ctx context.Context) ([]domain.Product, error)
+	// FindIDsBySKU maps each known SKU to its product ID; unknown SKUs are left out.
+	FindIDsBySKU(ctx context.Context, skus []string) (map[string]uint, error)
+	// StockTotals returns the current stock of each product, the sum of its
+	// warehouse_stock rows. Products without stock rows are left out.
+	StockTotals(ctx context.Context, ids []uint) (map[uint]int32, error)
 }
 
+// productColumns selects a product; stock is not stored but summed from
+// warehouse_stock, so it is always current.
+const productColumns = `p.id, p.name, p.sku, p.price,
+	(SELECT COALESCE(SUM(s.quantity), 0)::int FROM warehouse_stock s WHERE s.product_id = p.id),
+	p.weight, p.volume, p.version`
+
 type productRepo struct {
 	db *sql.DB
 }
@@ -26,31 +45,59 @@ func NewProductRepository(db *sql.DB) ProductRepository {
 	return &productRepo{db: db}
 }
 
-// Create a new product
+// Create a new product. Stock starts at 0; it only changes through warehouse_stock.
+// The price opens the product's price history.
 func (p *productRepo) Create(ctx context.Context, product *domain.Product) error {
-	query := `INSERT INTO products (name, price, stock, weight,user_id)
-	          VALUES ($1, $2, $3, $4, $5) RETURNING id`
-	return p.db.QueryRowContext(ctx, query,
-		product.Name, product.Price, product.Stock, product.Weight, product.UserID).Scan(&product.ID)
+	query := `WITH p AS (
+	              INSERT INTO products (name, sku, price, weight, volume, user_id)
+	              VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, version, price
+	          ), h AS (
+	              INSERT INTO product_price_history (product_id, price, effective_from)
+	              SELECT id, price, NOW() FROM p
+	          )
+	          SELECT id, version FROM p`
+	err := p.db.QueryRowContext(ctx, query,
+		product.Name, product.SKU, product.Price, product.Weight, product.Volume, product.UserID).Scan(&product.ID, &product.Version)
+	if isUniqueViolation(err) {
+		return ErrDuplicateSKU
+	}
+	product.Stock = 0
+	return err
 }
 
-// Update product data
+// Update product data. Stock is not writable here; the current value is
+// returned into product.Stock. A non-zero product.Version must match the
+// stored version (ErrVersionConflict); on success it holds the new version.
+// A changed price is appended to the price history in the same statement.
 func (p *productRepo) Update(ctx context.Context, product *domain.Product) error {
-	query := `UPDATE products
-	          SET name = $1, price = $2, stock = $3, weight = $4, user_id = $5
-	          WHERE id = $6`
-	res, err := p.db.ExecContext(ctx, query,
-		product.Name, product.Price, product.Stock, product.Weight, product.UserID, product.ID)
-	if err != nil {
-		return fmt.Errorf("failed to update product: %w", err)
+	query := `WITH p AS (
+	              UPDATE products
+	              SET name = $1, sku = $2, price = $3, weight = $4, volume = $5, user_id = $6,
+	                  version = version + 1, updated_at = NOW()
+	              WHERE id = $7 AND ($8 = 0 OR version = $8)
+	              RETURNING id, version, price
+	          ), h AS (
+	              INSERT INTO product_price_history (product_id, price, effective_from)
+	              SELECT id, price, NOW() FROM p
+	              WHERE price IS DISTINCT FROM (
+	                  SELECT price FROM product_price_history
+	                  WHERE product_id = p.id
+	                  ORDER BY effective_from DESC, id DESC
+	                  LIMIT 1
+	              )
+	          )
+	          SELECT (SELECT COALESCE(SUM(s.quantity), 0)::int FROM warehouse_stock s WHERE s.product_id = p.id), version FROM p`
+	err := p.db.QueryRowContext(ctx, query,
+		product.Name, product.SKU, product.Price, product.Weight, product.Volume, product.UserID, product.ID, product.Version).
+		Scan(&product.Stock, &product.Version)
+	if err == sql.ErrNoRows {
+		return conflictOrNotFound(ctx, p.db, "products", product.ID, errors.New("product not found"))
 	}
-
-	rowsAffected, err := res.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("could not check affected rows: %w", err)
+	if isUniqueViolation(err) {
+		return ErrDuplicateSKU
 	}
-	if rowsAffected == 0 {
-		return errors.New("product not found")
+	if err != nil {
+		return fmt.Errorf("failed to update product: %w", err)
 	}
 	return nil
 }
@@ -74,7 +121,7 @@ func (p *productRepo) Delete(ctx context.Context, id int64) error {
 }
 
 func (p *productRepo) GetAll(ctx context.Context) ([]domain.Product, error) {
-	query := `SELECT id, name, price, stock, weight FROM products ORDER BY id ASC`
+	query := `SELECT ` + productColumns + ` FROM products p ORDER BY p.id ASC`
 	rows, err := p.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query products: %w", err)
@@ -84,7 +131,7 @@ func (p *productRepo) GetAll(ctx context.Context) ([]domain.Product, error) {
 	var products []domain.Product
 	for rows.Next() {
 		var product domain.Product
-		if err := rows.Scan(&product.ID, &product.Name, &product.Price, &product.Stock, &product.Weight); err != nil {
+		if err := rows.Scan(&product.ID, &product.Name, &product.SKU, &product.Price, &product.Stock, &product.Weight, &product.Volume, &product.Version); err != nil {
 			return nil, fmt.Errorf("failed to scan product: %w", err)
 		}
 		products = append(products, product)
@@ -99,11 +146,11 @@ func (p *productRepo) GetAll(ctx context.Context) ([]domain.Product, error) {
 
 // Find product by name
 func (p *productRepo) FindByName(ctx context.Context, name string) (*domain.Product, error) {
-	query := `SELECT id, name, price, stock, weight FROM products WHERE name = $1`
+	query := `SELECT ` + productColumns + ` FROM products p WHERE p.name = $1`
 	row := p.db.QueryRowContext(ctx, query, name)
 
 	var product domain.Product
-	err := row.Scan(&product.ID, &product.Name, &product.Price, &product.Stock, &product.Weight)
+	err := row.Scan(&product.ID, &product.Name, &product.SKU, &product.Price, &product.Stock, &product.Weight, &product.Volume, &product.Version)
 	if err == sql.ErrNoRows {
 		return nil, errors.New("product not found")
 	}
@@ -114,16 +161,73 @@ func (p *productRepo) FindByName(ctx context.Context, name string) (*domain.Prod
 }
 
 func (p *productRepo) FindById(ctx context.Context, id uint) (*domain.Product, error) {
-	query := `SELECT id, name, price, stock, weight FROM products WHERE id = $1`
+	query := `SELECT ` + productColumns + ` FROM products p WHERE p.id = $1`
 	row := p.db.QueryRowContext(ctx, query, id)
 
 	var product domain.Product
-	err := row.Scan(&product.ID, &product.Name, &product.Price, &product.Stock, &product.Weight)
+	err := row.Scan(&product.ID, &product.Name, &product.SKU, &product.Price, &product.Stock, &product.Weight, &product.Volume, &product.Version)
 	if err == sql.ErrNoRows {
-		return nil, errors.New("id product not found")
+		return nil, ErrProductNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to query product: %w", err)
 	}
 	return &product, nil
 }
+
+func (p *productRepo) FindIDsBySKU(ctx context.Context, skus []string) (map[string]uint, error) {
+	ids := make(map[string]uint, len(skus))
+	if len(skus) == 0 {
+		return ids, nil
+	}
+
+	rows, err := p.db.QueryContext(ctx, `SELECT sku, id FROM products WHERE sku = ANY($1)`, pq.Array(skus))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query products by sku: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var sku string
+		var id uint
+		if err := rows.Scan(&sku, &id); err != nil {
+			return nil, fmt.Errorf("failed to scan product sku: %w", err)
+		}
+		ids[sku] = id
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return ids, nil
+}
+
+func (p *productRepo) StockTotals(ctx context.Context, ids []uint) (map[uint]int32, error) {
+	totals := make(map[uint]int32, len(ids))
+	if len(ids) == 0 {
+		return totals, nil
+	}
+
+	keys := make([]int64, len(ids))
+	for i, id := range ids {
+		keys[i] = int64(id)
+	}
+	rows, err := p.db.QueryContext(ctx,
+		`SELECT product_id, SUM(quantity)::int FROM warehouse_stock WHERE product_id = ANY($1) GROUP BY product_id`, pq.Array(keys))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query product stock: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uint
+		var total int32
+		if err := rows.Scan(&id, &total); err != nil {
+			return nil, fmt.Errorf("failed to scan product stock: %w", err)
+		}
+		totals[id] = total
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return totals, nil
+}
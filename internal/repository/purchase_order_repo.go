@@ -0,0 +1,283 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrPurchaseOrderNotFound is returned when a purchase order lookup matches no row.
+	ErrPurchaseOrderNotFound = errors.New("purchase order not found")
+	// ErrPurchaseOrderLineNotFound is returned when a line does not belong to the purchase order.
+	ErrPurchaseOrderLineNotFound = errors.New("purchase order line not found")
+)
+
+// PurchaseOrderRepository stores purchase orders, their lines and receipts.
+// Stock is raised by the usecase through WarehouseStockRepository, inside the
+// transaction returned by BeginTx.
+type PurchaseOrderRepository interface {
+	BeginTx(ctx context.Context) (*sql.Tx, error)
+	// Create inserts a draft purchase order together with its lines.
+	Create(ctx context.Context, po *domain.PurchaseOrder) error
+	GetByID(ctx context.Context, id uint) (*domain.PurchaseOrder, error)
+	// GetByIDForUpdateTx locks the purchase order row until tx ends.
+	GetByIDForUpdateTx(ctx context.Context, tx *sql.Tx, id uint) (*domain.PurchaseOrder, error)
+	// List returns purchase orders newest first. Zero values disable the filters.
+	List(ctx context.Context, supplierID uint, warehouseID uint, status string) ([]domain.PurchaseOrder, error)
+	UpdateStatusTx(ctx context.Context, tx *sql.Tx, id uint, status string) error
+	// RecordReceiptTx adds quantity to the line's received quantity and stores
+	// the receipt with its landed unit cost.
+	RecordReceiptTx(ctx context.Context, tx *sql.Tx, receipt *domain.PurchaseReceipt) error
+	// ListReceipts returns every receipt of the purchase order, oldest first.
+	ListReceipts(ctx context.Context, purchaseOrderID uint) ([]domain.PurchaseReceipt, error)
+	// DeleteDraft removes a purchase order that has not been sent yet.
+	DeleteDraft(ctx context.Context, id uint) error
+}
+
+type purchaseOrderRepo struct {
+	db *sql.DB
+}
+
+func NewPurchaseOrderRepository(db *sql.DB) PurchaseOrderRepository {
+	return &purchaseOrderRepo{db: db}
+}
+
+const purchaseOrderColumns = `id, supplier_id, warehouse_id, status, note, created_by, sent_at, received_at, created_at, updated_at`
+
+func scanPurchaseOrder(row rowScanner) (*domain.PurchaseOrder, error) {
+	var po domain.PurchaseOrder
+	err := row.Scan(&po.ID, &po.SupplierID, &po.WarehouseID, &po.Status, &po.Note, &po.CreatedBy,
+		&po.SentAt, &po.ReceivedAt, &po.CreatedAt, &po.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &po, nil
+}
+
+func (r *purchaseOrderRepo) BeginTx(ctx context.Context) (*sql.Tx, error) {
+	return r.db.BeginTx(ctx, nil)
+}
+
+func (r *purchaseOrderRepo) Create(ctx context.Context, po *domain.PurchaseOrder) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	po.Status = domain.PurchaseOrderDraft
+	query := `
+		INSERT INTO purchase_orders (supplier_id, warehouse_id, status, note, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+		RETURNING id, created_at, updated_at
+	`
+	err = tx.QueryRowContext(ctx, query, po.SupplierID, po.WarehouseID, po.Status, po.Note, po.CreatedBy).
+		Scan(&po.ID, &po.CreatedAt, &po.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create purchase order: %w", err)
+	}
+
+	lineQuery := `
+		INSERT INTO purchase_order_lines (purchase_order_id, product_id, quantity, unit_cost)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`
+	for i := range po.Lines {
+		line := &po.Lines[i]
+		line.PurchaseOrderID = po.ID
+		if err := tx.QueryRowContext(ctx, lineQuery, line.PurchaseOrderID, line.ProductID, line.Quantity, line.UnitCost).Scan(&line.ID); err != nil {
+			return fmt.Errorf("failed to create purchase order line: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit purchase order: %w", err)
+	}
+	return nil
+}
+
+func (r *purchaseOrderRepo) GetByID(ctx context.Context, id uint) (*domain.PurchaseOrder, error) {
+	return r.getByID(ctx, r.db, id, "")
+}
+
+func (r *purchaseOrderRepo) GetByIDForUpdateTx(ctx context.Context, tx *sql.Tx, id uint) (*domain.PurchaseOrder, error) {
+	return r.getByID(ctx, tx, id, " FOR UPDATE")
+}
+
+func (r *purchaseOrderRepo) getByID(ctx context.Context, q queryer, id uint, lock string) (*domain.PurchaseOrder, error) {
+	query := `SELECT ` + purchaseOrderColumns + ` FROM purchase_orders WHERE id = $1` + lock
+	po, err := scanPurchaseOrder(q.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPurchaseOrderNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get purchase order: %w", err)
+	}
+
+	orders := []domain.PurchaseOrder{*po}
+	if err := r.loadLines(ctx, q, orders); err != nil {
+		return nil, err
+	}
+	return &orders[0], nil
+}
+
+func (r *purchaseOrderRepo) List(ctx context.Context, supplierID uint, warehouseID uint, status string) ([]domain.PurchaseOrder, error) {
+	query := `
+		SELECT ` + purchaseOrderColumns + `
+		FROM purchase_orders
+		WHERE ($1 = 0 OR supplier_id = $1)
+		  AND ($2 = 0 OR warehouse_id = $2)
+		  AND ($3 = '' OR status = $3)
+		ORDER BY id DESC
+	`
+	rows, err := r.db.QueryContext(ctx, query, supplierID, warehouseID, status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query purchase orders: %w", err)
+	}
+	defer rows.Close()
+
+	orders := []domain.PurchaseOrder{}
+	for rows.Next() {
+		po, err := scanPurchaseOrder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan purchase order: %w", err)
+		}
+		orders = append(orders, *po)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating purchase orders: %w", err)
+	}
+
+	if err := r.loadLines(ctx, r.db, orders); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// loadLines fills the lines of all purchase orders with a single query,
+// including the landed value of what each line has received so far.
+func (r *purchaseOrderRepo) loadLines(ctx context.Context, q queryer, orders []domain.PurchaseOrder) error {
+	if len(orders) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(orders))
+	index := make(map[uint]int, len(orders))
+	for i := range orders {
+		ids[i] = int64(orders[i].ID)
+		index[orders[i].ID] = i
+		orders[i].Lines = []domain.PurchaseOrderLine{}
+	}
+
+	query := `
+		SELECT l.id, l.purchase_order_id, l.product_id, l.quantity, l.unit_cost, l.received_quantity,
+		       COALESCE((SELECT SUM(rc.quantity * rc.landed_unit_cost) FROM purchase_receipts rc
+		                 WHERE rc.purchase_order_line_id = l.id), 0)
+		FROM purchase_order_lines l
+		WHERE l.purchase_order_id = ANY($1)
+		ORDER BY l.id
+	`
+	rows, err := q.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("failed to query purchase order lines: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var l domain.PurchaseOrderLine
+		if err := rows.Scan(&l.ID, &l.PurchaseOrderID, &l.ProductID, &l.Quantity, &l.UnitCost,
+			&l.ReceivedQuantity, &l.ReceivedValue); err != nil {
+			return fmt.Errorf("failed to scan purchase order line: %w", err)
+		}
+		po := &orders[index[l.PurchaseOrderID]]
+		po.Lines = append(po.Lines, l)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating purchase order lines: %w", err)
+	}
+	return nil
+}
+
+func (r *purchaseOrderRepo) UpdateStatusTx(ctx context.Context, tx *sql.Tx, id uint, status string) error {
+	query := `
+		UPDATE purchase_orders
+		SET status = $1,
+		    sent_at = CASE WHEN $1 = 'sent' THEN NOW() ELSE sent_at END,
+		    received_at = CASE WHEN $1 = 'received' THEN NOW() ELSE received_at END,
+		    updated_at = NOW()
+		WHERE id = $2
+	`
+	res, err := tx.ExecContext(ctx, query, status, id)
+	if err != nil {
+		return fmt.Errorf("failed to update purchase order status: %w", err)
+	}
+	return expectAffected(res, ErrPurchaseOrderNotFound)
+}
+
+func (r *purchaseOrderRepo) RecordReceiptTx(ctx context.Context, tx *sql.Tx, receipt *domain.PurchaseReceipt) error {
+	res, err := tx.ExecContext(ctx,
+		`UPDATE purchase_order_lines SET received_quantity = received_quantity + $1 WHERE id = $2`,
+		receipt.Quantity, receipt.PurchaseOrderLineID)
+	if err != nil {
+		return fmt.Errorf("failed to update purchase order line: %w", err)
+	}
+	if err := expectAffected(res, ErrPurchaseOrderLineNotFound); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO purchase_receipts
+			(purchase_order_line_id, quantity, landed_unit_cost, batch_number, expiry_date, received_by, received_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		RETURNING id, received_at
+	`
+	err = tx.QueryRowContext(ctx, query, receipt.PurchaseOrderLineID, receipt.Quantity, receipt.LandedUnitCost,
+		receipt.BatchNumber, receipt.ExpiryDate, receipt.ReceivedBy).
+		Scan(&receipt.ID, &receipt.ReceivedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record purchase receipt: %w", err)
+	}
+	return nil
+}
+
+func (r *purchaseOrderRepo) ListReceipts(ctx context.Context, purchaseOrderID uint) ([]domain.PurchaseReceipt, error) {
+	query := `
+		SELECT rc.id, rc.purchase_order_line_id, l.product_id, rc.quantity, rc.landed_unit_cost,
+		       rc.batch_number, rc.expiry_date, rc.received_by, rc.received_at
+		FROM purchase_receipts rc
+		JOIN purchase_order_lines l ON l.id = rc.purchase_order_line_id
+		WHERE l.purchase_order_id = $1
+		ORDER BY rc.id
+	`
+	rows, err := r.db.QueryContext(ctx, query, purchaseOrderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query purchase receipts: %w", err)
+	}
+	defer rows.Close()
+
+	receipts := []domain.PurchaseReceipt{}
+	for rows.Next() {
+		var rc domain.PurchaseReceipt
+		if err := rows.Scan(&rc.ID, &rc.PurchaseOrderLineID, &rc.ProductID, &rc.Quantity, &rc.LandedUnitCost,
+			&rc.BatchNumber, &rc.ExpiryDate, &rc.ReceivedBy, &rc.ReceivedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan purchase receipt: %w", err)
+		}
+		receipts = append(receipts, rc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating purchase receipts: %w", err)
+	}
+	return receipts, nil
+}
+
+func (r *purchaseOrderRepo) DeleteDraft(ctx context.Context, id uint) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM purchase_orders WHERE id = $1 AND status = 'draft'`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete purchase order: %w", err)
+	}
+	return expectAffected(res, ErrPurchaseOrderNotFound)
+}
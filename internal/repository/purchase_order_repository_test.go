@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPurchaseOrderRepository(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := NewPurchaseOrderRepository(db)
+	ctx := context.Background()
+
+	t.Run("GetByID_WithReceivedValue", func(t *testing.T) {
+		mock.ExpectQuery("SELECT (.+) FROM purchase_orders WHERE id = \\$1").
+			WithArgs(uint(3)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "supplier_id", "warehouse_id", "status", "note", "created_by",
+				"sent_at", "received_at", "created_at", "updated_at"}).
+				AddRow(3, 2, 1, domain.PurchaseOrderPartiallyReceived, nil, 1, time.Now(), nil, time.Now(), time.Now()))
+		mock.ExpectQuery("SELECT (.+) FROM purchase_order_lines l").
+			WithArgs(pq.Array([]int64{3})).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "purchase_order_id", "product_id", "quantity", "unit_cost",
+				"received_quantity", "received_value"}).
+				AddRow(7, 3, 5, 100, 12.5, 40, 520.0))
+
+		po, err := repo.GetByID(ctx, 3)
+		assert.NoError(t, err)
+		assert.Len(t, po.Lines, 1)
+		assert.Equal(t, int32(60), po.Lines[0].Outstanding())
+		assert.Equal(t, 520.0, po.Lines[0].ReceivedValue)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("RecordReceipt", func(t *testing.T) {
+		actor := uint(1)
+		batch := "B-01"
+		expiry := time.Date(2027, 3, 1, 0, 0, 0, 0, time.UTC)
+		receipt := &domain.PurchaseReceipt{PurchaseOrderLineID: 7, ProductID: 5, Quantity: 10, LandedUnitCost: 13.1,
+			BatchNumber: &batch, ExpiryDate: &expiry, ReceivedBy: &actor}
+
+		mock.ExpectBegin()
+		tx, err := db.Begin()
+		assert.NoError(t, err)
+		mock.ExpectExec("UPDATE purchase_order_lines SET received_quantity = received_quantity \\+ \\$1").
+			WithArgs(int32(10), uint(7)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("INSERT INTO purchase_receipts").
+			WithArgs(uint(7), int32(10), 13.1, &batch, &expiry, &actor).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "received_at"}).AddRow(9, time.Now()))
+
+		err = repo.RecordReceiptTx(ctx, tx, receipt)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(9), receipt.ID)
+
+		mock.ExpectRollback()
+		_ = tx.Rollback()
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("RecordReceipt_LineNotFound", func(t *testing.T) {
+		receipt := &domain.PurchaseReceipt{PurchaseOrderLineID: 99, Quantity: 1}
+
+		mock.ExpectBegin()
+		tx, err := db.Begin()
+		assert.NoError(t, err)
+		mock.ExpectExec("UPDATE purchase_order_lines").
+			WithArgs(int32(1), uint(99)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err = repo.RecordReceiptTx(ctx, tx, receipt)
+		assert.ErrorIs(t, err, ErrPurchaseOrderLineNotFound)
+
+		mock.ExpectRollback()
+		_ = tx.Rollback()
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestSupplierRepository_Delete(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := NewSupplierRepository(db)
+
+	mock.ExpectExec("DELETE FROM suppliers WHERE id = \\$1").
+		WithArgs(uint(2)).
+		WillReturnError(&pq.Error{Code: "23503"})
+
+	err := repo.Delete(context.Background(), 2)
+	assert.ErrorIs(t, err, ErrSupplierInUse)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
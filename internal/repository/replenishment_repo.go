@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+)
+
+// ReplenishmentRepository reads what the replenishment suggestions are
+// computed from: the demand history of a warehouse and its stock position.
+type ReplenishmentRepository interface {
+	// DailyDemand sums the sold quantity per product and day for sales booked
+	// in [from, to). Cancelled orders are left out; days without sales have
+	// no row.
+	DailyDemand(ctx context.Context, warehouseID uint, from, to time.Time) ([]domain.DailyDemand, error)
+	// StockPositions returns every product with a stock row in the warehouse
+	// or still on order for it, with the quantity on hand and on order.
+	StockPositions(ctx context.Context, warehouseID uint) ([]domain.StockPosition, error)
+}
+
+type replenishmentRepo struct {
+	db *sql.DB
+}
+
+func NewReplenishmentRepository(db *sql.DB) ReplenishmentRepository {
+	return &replenishmentRepo{db: db}
+}
+
+// DailyDemand reads the sale movements of the ledger. They are booked per
+// warehouse for every order, also for orders older than the order
+// allocations, and a split item counts only the warehouse's share. A sale
+// of a deleted order still counts: the stock did leave.
+func (r *replenishmentRepo) DailyDemand(ctx context.Context, warehouseID uint, from, to time.Time) ([]domain.DailyDemand, error) {
+	query := `
+		SELECT m.product_id, m.created_at::date AS day, SUM(-m.delta)
+		FROM stock_movements m
+		LEFT JOIN orders o ON o.id = m.reference_id
+		WHERE m.warehouse_id = $1
+		  AND m.reason = 'sale' AND m.reference_type = 'order'
+		  AND m.created_at >= $2 AND m.created_at < $3
+		  AND o.status IS DISTINCT FROM 'cancelled'
+		GROUP BY m.product_id, day
+		ORDER BY m.product_id, day
+	`
+	rows, err := r.db.QueryContext(ctx, query, warehouseID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query demand history: %w", err)
+	}
+	defer rows.Close()
+
+	demand := []domain.DailyDemand{}
+	for rows.Next() {
+		var d domain.DailyDemand
+		if err := rows.Scan(&d.ProductID, &d.Day, &d.Quantity); err != nil {
+			return nil, fmt.Errorf("failed to scan demand history: %w", err)
+		}
+		demand = append(demand, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating demand history: %w", err)
+	}
+	return demand, nil
+}
+
+func (r *replenishmentRepo) StockPositions(ctx context.Context, warehouseID uint) ([]domain.StockPosition, error) {
+	query := `
+		WITH on_order AS (
+			SELECT l.product_id, SUM(l.quantity - l.received_quantity) AS quantity
+			FROM purchase_order_lines l
+			JOIN purchase_orders po ON po.id = l.purchase_order_id
+			WHERE po.warehouse_id = $1 AND po.status IN ('sent', 'partially_received')
+			GROUP BY l.product_id
+		)
+		SELECT p.id, p.name, p.sku, COALESCE(s.quantity, 0), COALESCE(o.quantity, 0)
+		FROM (
+			SELECT product_id FROM warehouse_stock WHERE warehouse_id = $1
+			UNION
+			SELECT product_id FROM on_order
+		) k
+		JOIN products p ON p.id = k.product_id
+		LEFT JOIN warehouse_stock s ON s.warehouse_id = $1 AND s.product_id = k.product_id
+		LEFT JOIN on_order o ON o.product_id = k.product_id
+		ORDER BY p.id
+	`
+	rows, err := r.db.QueryContext(ctx, query, warehouseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stock positions: %w", err)
+	}
+	defer rows.Close()
+
+	positions := []domain.StockPosition{}
+	for rows.Next() {
+		var p domain.StockPosition
+		if err := rows.Scan(&p.ProductID, &p.ProductName, &p.SKU, &p.OnHand, &p.OnOrder); err != nil {
+			return nil, fmt.Errorf("failed to scan stock position: %w", err)
+		}
+		positions = append(positions, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating stock positions: %w", err)
+	}
+	return positions, nil
+}
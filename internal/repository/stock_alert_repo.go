@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+)
+
+// ErrStockAlertNotFound is returned when an alert lookup matches no row.
+var ErrStockAlertNotFound = errors.New("stock alert not found")
+
+// StockAlertRepository reads low-stock alerts. Alerts are raised and resolved
+// by WarehouseStockRepository in the same transaction as the stock change, so
+// they work as an outbox: the alert job delivers the rows with notified_at
+// still NULL.
+type StockAlertRepository interface {
+	// List returns alerts newest first. Zero values disable the filters.
+	List(ctx context.Context, warehouseID uint, status string) ([]domain.StockAlert, error)
+	// ListUnnotified returns open alerts that have not been delivered yet, oldest first.
+	ListUnnotified(ctx context.Context, limit int) ([]domain.StockAlert, error)
+	MarkNotified(ctx context.Context, id uint, at time.Time) error
+}
+
+type stockAlertRepo struct {
+	db *sql.DB
+}
+
+func NewStockAlertRepository(db *sql.DB) StockAlertRepository {
+	return &stockAlertRepo{db: db}
+}
+
+const stockAlertColumns = `id, warehouse_id, product_id, reorder_point, quantity, status, notified_at, resolved_at, created_at`
+
+func (r *stockAlertRepo) List(ctx context.Context, warehouseID uint, status string) ([]domain.StockAlert, error) {
+	query := `
+		SELECT ` + stockAlertColumns + `
+		FROM stock_alerts
+		WHERE ($1 = 0 OR warehouse_id = $1)
+		  AND ($2 = '' OR status = $2)
+		ORDER BY id DESC
+	`
+	return r.query(ctx, query, warehouseID, status)
+}
+
+func (r *stockAlertRepo) ListUnnotified(ctx context.Context, limit int) ([]domain.StockAlert, error) {
+	query := `
+		SELECT ` + stockAlertColumns + `
+		FROM stock_alerts
+		WHERE notified_at IS NULL AND status = 'open'
+		ORDER BY id
+		LIMIT $1
+	`
+	return r.query(ctx, query, limit)
+}
+
+func (r *stockAlertRepo) query(ctx context.Context, query string, args ...interface{}) ([]domain.StockAlert, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stock alerts: %w", err)
+	}
+	defer rows.Close()
+
+	alerts := []domain.StockAlert{}
+	for rows.Next() {
+		var a domain.StockAlert
+		if err := rows.Scan(&a.ID, &a.WarehouseID, &a.ProductID, &a.ReorderPoint, &a.Quantity, &a.Status,
+			&a.NotifiedAt, &a.ResolvedAt, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan stock alert: %w", err)
+		}
+		alerts = append(alerts, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating stock alerts: %w", err)
+	}
+	return alerts, nil
+}
+
+func (r *stockAlertRepo) MarkNotified(ctx context.Context, id uint, at time.Time) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE stock_alerts SET notified_at = $1 WHERE id = $2`, at, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark stock alert notified: %w", err)
+	}
+	return expectAffected(res, ErrStockAlertNotFound)
+}
+
+// raiseStockAlertTx opens a low-stock alert unless one is already open for
+// the warehouse/product (the partial unique index does the deduplication).
+func raiseStockAlertTx(ctx context.Context, tx *sql.Tx, warehouseID, productID uint, reorderPoint, quantity int32) error {
+	query := `
+		INSERT INTO stock_alerts (warehouse_id, product_id, reorder_point, quantity, status, created_at)
+		VALUES ($1, $2, $3, $4, 'open', NOW())
+		ON CONFLICT (warehouse_id, product_id) WHERE status = 'open' DO NOTHING
+	`
+	if _, err := tx.ExecContext(ctx, query, warehouseID, productID, reorderPoint, quantity); err != nil {
+		return fmt.Errorf("failed to raise stock alert: %w", err)
+	}
+	return nil
+}
+
+// resolveStockAlertsTx closes the open alert of a warehouse/product, if any.
+func resolveStockAlertsTx(ctx context.Context, tx *sql.Tx, warehouseID, productID uint) error {
+	query := `
+		UPDATE stock_alerts SET status = 'resolved', resolved_at = NOW()
+		WHERE warehouse_id = $1 AND product_id = $2 AND status = 'open'
+	`
+	if _, err := tx.ExecContext(ctx, query, warehouseID, productID); err != nil {
+		return fmt.Errorf("failed to resolve stock alert: %w", err)
+	}
+	return nil
+}
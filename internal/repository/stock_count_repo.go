@@ -0,0 +1,318 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrStockCountNotFound is returned when a count session lookup matches no row.
+	ErrStockCountNotFound = errors.New("stock count session not found")
+	// ErrStockCountLineNotFound is returned when a product is not part of a
+	// session limited to selected products.
+	ErrStockCountLineNotFound = errors.New("product is not part of the stock count session")
+	// ErrStockCountAlreadyOpen is returned when the warehouse already has an open session.
+	ErrStockCountAlreadyOpen = errors.New("warehouse already has an open stock count session")
+)
+
+// StockCountRepository stores count sessions and their lines. Counts never
+// touch warehouse_stock; PostAdjustmentTx does, once the session is approved.
+type StockCountRepository interface {
+	BeginTx(ctx context.Context) (*sql.Tx, error)
+	// Create inserts an open session and snapshots the expected quantities:
+	// every stock row of the warehouse, or only productIDs when the session
+	// is not FullWarehouse (products without a row are expected at zero).
+	Create(ctx context.Context, session *domain.StockCountSession, productIDs []uint) error
+	GetByID(ctx context.Context, id uint) (*domain.StockCountSession, error)
+	// GetByIDForUpdateTx locks the session row until tx ends.
+	GetByIDForUpdateTx(ctx context.Context, tx *sql.Tx, id uint) (*domain.StockCountSession, error)
+	// List returns sessions newest first. Zero values disable the filters.
+	List(ctx context.Context, warehouseID uint, status string) ([]domain.StockCountSession, error)
+	// RecordCountTx stores a counted quantity together with the current
+	// warehouse quantity, replacing an earlier count of the same product. A
+	// product without a line gets one only in FullWarehouse sessions.
+	RecordCountTx(ctx context.Context, tx *sql.Tx, session *domain.StockCountSession, productID uint, counted int32, actorID uint) error
+	// PostAdjustmentTx applies the variance of a counted line to warehouse_stock
+	// and records the delta actually posted on the line.
+	PostAdjustmentTx(ctx context.Context, tx *sql.Tx, session *domain.StockCountSession, line *domain.StockCountLine, ref domain.StockReference) (*domain.StockChangeResult, error)
+	// CloseTx moves an open session to approved or cancelled.
+	CloseTx(ctx context.Context, tx *sql.Tx, id uint, status string, actorID uint) error
+}
+
+type stockCountRepo struct {
+	db *sql.DB
+}
+
+func NewStockCountRepository(db *sql.DB) StockCountRepository {
+	return &stockCountRepo{db: db}
+}
+
+const stockCountColumns = `id, warehouse_id, status, full_warehouse, note, created_by, closed_by, closed_at, created_at, updated_at`
+
+const stockCountLineColumns = `id, session_id, product_id, expected_quantity, counted_quantity, system_quantity,
+	counted_by, counted_at, adjustment`
+
+func scanStockCount(row rowScanner) (*domain.StockCountSession, error) {
+	var s domain.StockCountSession
+	err := row.Scan(&s.ID, &s.WarehouseID, &s.Status, &s.FullWarehouse, &s.Note, &s.CreatedBy,
+		&s.ClosedBy, &s.ClosedAt, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *stockCountRepo) BeginTx(ctx context.Context) (*sql.Tx, error) {
+	return r.db.BeginTx(ctx, nil)
+}
+
+func (r *stockCountRepo) Create(ctx context.Context, session *domain.StockCountSession, productIDs []uint) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	session.Status = domain.StockCountOpen
+	query := `
+		INSERT INTO stock_count_sessions (warehouse_id, status, full_warehouse, note, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+		RETURNING id, created_at, updated_at
+	`
+	err = tx.QueryRowContext(ctx, query, session.WarehouseID, session.Status, session.FullWarehouse, session.Note, session.CreatedBy).
+		Scan(&session.ID, &session.CreatedAt, &session.UpdatedAt)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("%w: warehouse_id=%d", ErrStockCountAlreadyOpen, session.WarehouseID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create stock count session: %w", err)
+	}
+
+	// One statement per snapshot, so all expected quantities are read at the
+	// same instant even while sales go on.
+	if session.FullWarehouse {
+		query = `
+			INSERT INTO stock_count_lines (session_id, product_id, expected_quantity)
+			SELECT $1, product_id, quantity FROM warehouse_stock WHERE warehouse_id = $2
+		`
+		_, err = tx.ExecContext(ctx, query, session.ID, session.WarehouseID)
+	} else {
+		ids := make([]int64, len(productIDs))
+		for i, id := range productIDs {
+			ids[i] = int64(id)
+		}
+		query = `
+			INSERT INTO stock_count_lines (session_id, product_id, expected_quantity)
+			SELECT $1, p.id, COALESCE(ws.quantity, 0)
+			FROM unnest($3::int[]) AS p(id)
+			LEFT JOIN warehouse_stock ws ON ws.warehouse_id = $2 AND ws.product_id = p.id
+		`
+		_, err = tx.ExecContext(ctx, query, session.ID, session.WarehouseID, pq.Array(ids))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to snapshot stock count lines: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit stock count session: %w", err)
+	}
+	return nil
+}
+
+func (r *stockCountRepo) GetByID(ctx context.Context, id uint) (*domain.StockCountSession, error) {
+	return r.getByID(ctx, r.db, id, "")
+}
+
+func (r *stockCountRepo) GetByIDForUpdateTx(ctx context.Context, tx *sql.Tx, id uint) (*domain.StockCountSession, error) {
+	return r.getByID(ctx, tx, id, " FOR UPDATE")
+}
+
+func (r *stockCountRepo) getByID(ctx context.Context, q queryer, id uint, lock string) (*domain.StockCountSession, error) {
+	query := `SELECT ` + stockCountColumns + ` FROM stock_count_sessions WHERE id = $1` + lock
+	s, err := scanStockCount(q.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrStockCountNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stock count session: %w", err)
+	}
+
+	sessions := []domain.StockCountSession{*s}
+	if err := r.loadLines(ctx, q, sessions); err != nil {
+		return nil, err
+	}
+	return &sessions[0], nil
+}
+
+func (r *stockCountRepo) List(ctx context.Context, warehouseID uint, status string) ([]domain.StockCountSession, error) {
+	query := `
+		SELECT ` + stockCountColumns + `
+		FROM stock_count_sessions
+		WHERE ($1 = 0 OR warehouse_id = $1)
+		  AND ($2 = '' OR status = $2)
+		ORDER BY id DESC
+	`
+	rows, err := r.db.QueryContext(ctx, query, warehouseID, status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stock count sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []domain.StockCountSession{}
+	for rows.Next() {
+		s, err := scanStockCount(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan stock count session: %w", err)
+		}
+		sessions = append(sessions, *s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating stock count sessions: %w", err)
+	}
+
+	if err := r.loadLines(ctx, r.db, sessions); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// loadLines fills the lines of all sessions with a single query.
+func (r *stockCountRepo) loadLines(ctx context.Context, q queryer, sessions []domain.StockCountSession) error {
+	if len(sessions) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(sessions))
+	index := make(map[uint]int, len(sessions))
+	for i := range sessions {
+		ids[i] = int64(sessions[i].ID)
+		index[sessions[i].ID] = i
+		sessions[i].Lines = []domain.StockCountLine{}
+	}
+
+	query := `SELECT ` + stockCountLineColumns + ` FROM stock_count_lines WHERE session_id = ANY($1) ORDER BY product_id`
+	rows, err := q.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("failed to query stock count lines: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var l domain.StockCountLine
+		if err := rows.Scan(&l.ID, &l.SessionID, &l.ProductID, &l.ExpectedQuantity, &l.CountedQuantity,
+			&l.SystemQuantity, &l.CountedBy, &l.CountedAt, &l.Adjustment); err != nil {
+			return fmt.Errorf("failed to scan stock count line: %w", err)
+		}
+		l.ComputeVariance()
+		s := &sessions[index[l.SessionID]]
+		s.Lines = append(s.Lines, l)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating stock count lines: %w", err)
+	}
+	return nil
+}
+
+func (r *stockCountRepo) RecordCountTx(ctx context.Context, tx *sql.Tx, session *domain.StockCountSession, productID uint, counted int32, actorID uint) error {
+	// system_quantity is read when the count arrives: sales after this point
+	// are already in warehouse_stock when the variance is posted, and sales
+	// before it are already out of system_quantity.
+	if session.FullWarehouse {
+		query := `
+			INSERT INTO stock_count_lines (session_id, product_id, expected_quantity, counted_quantity, system_quantity, counted_by, counted_at)
+			VALUES ($1, $2, 0, $3,
+			        COALESCE((SELECT quantity FROM warehouse_stock WHERE warehouse_id = $4 AND product_id = $2), 0),
+			        $5, NOW())
+			ON CONFLICT (session_id, product_id) DO UPDATE
+			SET counted_quantity = EXCLUDED.counted_quantity,
+			    system_quantity = EXCLUDED.system_quantity,
+			    counted_by = EXCLUDED.counted_by,
+			    counted_at = EXCLUDED.counted_at
+		`
+		if _, err := tx.ExecContext(ctx, query, session.ID, productID, counted, session.WarehouseID, actorID); err != nil {
+			return fmt.Errorf("failed to record stock count: %w", err)
+		}
+		return nil
+	}
+
+	query := `
+		UPDATE stock_count_lines
+		SET counted_quantity = $1,
+		    system_quantity = COALESCE((SELECT quantity FROM warehouse_stock WHERE warehouse_id = $2 AND product_id = $3), 0),
+		    counted_by = $4,
+		    counted_at = NOW()
+		WHERE session_id = $5 AND product_id = $3
+	`
+	res, err := tx.ExecContext(ctx, query, counted, session.WarehouseID, productID, actorID, session.ID)
+	if err != nil {
+		return fmt.Errorf("failed to record stock count: %w", err)
+	}
+	return expectAffected(res, fmt.Errorf("%w: product_id=%d", ErrStockCountLineNotFound, productID))
+}
+
+func (r *stockCountRepo) PostAdjustmentTx(ctx context.Context, tx *sql.Tx, session *domain.StockCountSession, line *domain.StockCountLine, ref domain.StockReference) (*domain.StockChangeResult, error) {
+	if line.Variance == nil {
+		return nil, fmt.Errorf("product_id=%d has not been counted", line.ProductID)
+	}
+	if ref.Reason == "" {
+		ref.Reason = domain.MovementReasonAdjustment
+	}
+
+	delta := *line.Variance
+	result := &domain.StockChangeResult{WarehouseID: session.WarehouseID, ProductID: line.ProductID}
+	if delta > 0 {
+		if err := ensureStockRowTx(ctx, tx, session.WarehouseID, line.ProductID); err != nil {
+			return nil, err
+		}
+	}
+	if delta != 0 {
+		current, err := lockStock(ctx, tx, session.WarehouseID, line.ProductID)
+		switch {
+		case errors.Is(err, ErrWarehouseStockNotFound):
+			// the row was deleted after counting; nothing left to write off
+			delta = 0
+		case err != nil:
+			return nil, err
+		default:
+			// Sales made after the count may already have taken some of the
+			// missing units; the row cannot go below zero.
+			delta = max(delta, -current.Quantity)
+			result, err = applyLockedChange(ctx, tx, domain.StockChange{
+				WarehouseID:    session.WarehouseID,
+				ProductID:      line.ProductID,
+				Delta:          delta,
+				StockReference: ref,
+			}, current)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	res, err := tx.ExecContext(ctx, `UPDATE stock_count_lines SET adjustment = $1 WHERE id = $2`, delta, line.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record stock count adjustment: %w", err)
+	}
+	if err := expectAffected(res, ErrStockCountLineNotFound); err != nil {
+		return nil, err
+	}
+	line.Adjustment = &delta
+	return result, nil
+}
+
+func (r *stockCountRepo) CloseTx(ctx context.Context, tx *sql.Tx, id uint, status string, actorID uint) error {
+	query := `
+		UPDATE stock_count_sessions
+		SET status = $1, closed_by = $2, closed_at = NOW(), updated_at = NOW()
+		WHERE id = $3 AND status = 'open'
+	`
+	res, err := tx.ExecContext(ctx, query, status, actorID, id)
+	if err != nil {
+		return fmt.Errorf("failed to close stock count session: %w", err)
+	}
+	return expectAffected(res, ErrStockCountNotFound)
+}
@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStockCountRepository(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := NewStockCountRepository(db)
+	ctx := context.Background()
+	ref := domain.StockReference{Reason: domain.MovementReasonAdjustment, ReferenceType: domain.MovementRefStockCount, ReferenceID: 5, Note: "stock count #5"}
+
+	t.Run("Create_AlreadyOpen", func(t *testing.T) {
+		session := &domain.StockCountSession{WarehouseID: 1, FullWarehouse: true}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO stock_count_sessions").
+			WillReturnError(&pq.Error{Code: "23505"})
+		mock.ExpectRollback()
+
+		err := repo.Create(ctx, session, nil)
+		assert.ErrorIs(t, err, ErrStockCountAlreadyOpen)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Create_SelectedProducts", func(t *testing.T) {
+		session := &domain.StockCountSession{WarehouseID: 1}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO stock_count_sessions").
+			WithArgs(uint(1), domain.StockCountOpen, false, nil, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(5, time.Now(), time.Now()))
+		mock.ExpectExec("INSERT INTO stock_count_lines (.+) FROM unnest").
+			WithArgs(uint(5), uint(1), pq.Array([]int64{2, 3})).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		err := repo.Create(ctx, session, []uint{2, 3})
+		assert.NoError(t, err)
+		assert.Equal(t, uint(5), session.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("RecordCount_NotInSession", func(t *testing.T) {
+		session := &domain.StockCountSession{ID: 5, WarehouseID: 1}
+
+		mock.ExpectBegin()
+		tx, err := db.Begin()
+		assert.NoError(t, err)
+		mock.ExpectExec("UPDATE stock_count_lines").
+			WithArgs(int32(4), uint(1), uint(9), uint(3), uint(5)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err = repo.RecordCountTx(ctx, tx, session, 9, 4, 3)
+		assert.ErrorIs(t, err, ErrStockCountLineNotFound)
+
+		mock.ExpectRollback()
+		_ = tx.Rollback()
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("PostAdjustment_ClampedBySalesAfterCount", func(t *testing.T) {
+		// counted 4 against 10 on the shelf, then 8 were sold: only 2 remain
+		counted, system := int32(4), int32(10)
+		line := &domain.StockCountLine{ID: 11, ProductID: 1, CountedQuantity: &counted, SystemQuantity: &system}
+		line.ComputeVariance()
+		session := &domain.StockCountSession{ID: 5, WarehouseID: 1}
+
+		mock.ExpectBegin()
+		tx, err := db.Begin()
+		assert.NoError(t, err)
+		mock.ExpectQuery("SELECT quantity, reorder_point, version FROM warehouse_stock").
+			WithArgs(uint(1), uint(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"quantity", "reorder_point", "version"}).AddRow(2, nil, 7))
+		mock.ExpectQuery("SELECT (.+) FROM stock_lots (.+) FOR UPDATE").
+			WithArgs(uint(1), uint(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "batch_number", "expiry_date", "quantity", "expired"}))
+		mock.ExpectQuery("SELECT (.+) FROM location_stock (.+) FOR UPDATE").
+			WithArgs(uint(1), uint(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"location_id", "code", "quantity"}))
+		mock.ExpectExec("UPDATE warehouse_stock SET quantity").
+			WithArgs(int32(0), uint(1), uint(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("INSERT INTO stock_movements").
+			WithArgs(uint(1), uint(1), int32(-2), int32(0), domain.MovementReasonAdjustment, domain.MovementRefStockCount, uint(5), nil, "stock count #5").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(30, time.Now()))
+		mock.ExpectExec("UPDATE stock_count_lines SET adjustment").
+			WithArgs(int32(-2), uint(11)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		result, err := repo.PostAdjustmentTx(ctx, tx, session, line, ref)
+		assert.NoError(t, err)
+		assert.Equal(t, int32(0), result.QuantityAfter)
+		assert.Equal(t, int32(-2), *line.Adjustment)
+
+		mock.ExpectRollback()
+		_ = tx.Rollback()
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("PostAdjustment_NoVariance", func(t *testing.T) {
+		counted := int32(6)
+		line := &domain.StockCountLine{ID: 12, ProductID: 2, CountedQuantity: &counted, SystemQuantity: &counted}
+		line.ComputeVariance()
+		session := &domain.StockCountSession{ID: 5, WarehouseID: 1}
+
+		mock.ExpectBegin()
+		tx, err := db.Begin()
+		assert.NoError(t, err)
+		mock.ExpectExec("UPDATE stock_count_lines SET adjustment").
+			WithArgs(int32(0), uint(12)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		_, err = repo.PostAdjustmentTx(ctx, tx, session, line, ref)
+		assert.NoError(t, err)
+
+		mock.ExpectRollback()
+		_ = tx.Rollback()
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Close_NotOpen", func(t *testing.T) {
+		mock.ExpectBegin()
+		tx, err := db.Begin()
+		assert.NoError(t, err)
+		mock.ExpectExec("UPDATE stock_count_sessions").
+			WithArgs(domain.StockCountCancelled, uint(3), uint(5)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err = repo.CloseTx(ctx, tx, 5, domain.StockCountCancelled, 3)
+		assert.ErrorIs(t, err, ErrStockCountNotFound)
+
+		mock.ExpectRollback()
+		_ = tx.Rollback()
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
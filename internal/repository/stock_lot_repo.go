@@ -0,0 +1,270 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+)
+
+// ErrLotExpiryMismatch is returned when stock is received into an existing
+// batch with a different expiry date.
+var ErrLotExpiryMismatch = errors.New("batch already exists with a different expiry date")
+
+// StockLotRepository manages the lots (batches) of warehouse stock. Lots are
+// a breakdown of warehouse_stock.quantity: receiving into a lot also raises
+// the quantity, and every decrease of the quantity takes from the lots in the
+// same transaction (see drawLotsTx), so the lots never hold more than the row.
+type StockLotRepository interface {
+	// Receive adds lot.Quantity to the lot's batch, creating the batch and the
+	// stock row if needed, and records the increase as a movement.
+	Receive(ctx context.Context, lot *domain.StockLot, ref domain.StockReference) (*domain.StockChangeResult, error)
+	// ReceiveTx adds lot.Quantity to the lot's batch inside tx. The caller
+	// must already have raised the stock row by at least as much in tx, which
+	// also locks the row before the lot.
+	ReceiveTx(ctx context.Context, tx *sql.Tx, lot *domain.StockLot) error
+	// List returns the non-empty lots in FEFO order. Zero IDs disable the filters.
+	List(ctx context.Context, warehouseID uint, productID uint) ([]domain.StockLot, error)
+	// ListExpiring returns non-empty lots that expire within the given number
+	// of days, already expired ones included, soonest first.
+	ListExpiring(ctx context.Context, days int, warehouseID uint) ([]domain.ExpiringLot, error)
+}
+
+type stockLotRepo struct {
+	db *sql.DB
+}
+
+func NewStockLotRepository(db *sql.DB) StockLotRepository {
+	return &stockLotRepo{db: db}
+}
+
+const stockLotColumns = `l.id, l.warehouse_id, l.product_id, l.batch_number, l.expiry_date, l.quantity, l.received_at, l.updated_at`
+
+func (r *stockLotRepo) Receive(ctx context.Context, lot *domain.StockLot, ref domain.StockReference) (*domain.StockChangeResult, error) {
+	if lot.Quantity <= 0 {
+		return nil, fmt.Errorf("quantity to receive must be greater than zero")
+	}
+	if ref.Reason == "" {
+		ref.Reason = domain.MovementReasonRestock
+	}
+	if ref.Note == "" {
+		ref.Note = "lot " + lot.BatchNumber
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockCapacityTx(ctx, tx, lot.WarehouseID); err != nil {
+		return nil, err
+	}
+	if err := ensureStockRowTx(ctx, tx, lot.WarehouseID, lot.ProductID); err != nil {
+		return nil, err
+	}
+	// The stock row is locked before the lot, the same order drawLotsTx uses.
+	current, err := lockStock(ctx, tx, lot.WarehouseID, lot.ProductID)
+	if err != nil {
+		return nil, err
+	}
+
+	received := lot.Quantity
+	if err := receiveLotTx(ctx, tx, lot); err != nil {
+		return nil, err
+	}
+
+	result, err := applyLockedChange(ctx, tx, domain.StockChange{
+		WarehouseID:    lot.WarehouseID,
+		ProductID:      lot.ProductID,
+		Delta:          received,
+		StockReference: ref,
+	}, current)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit lot receipt: %w", err)
+	}
+	return result, nil
+}
+
+func (r *stockLotRepo) ReceiveTx(ctx context.Context, tx *sql.Tx, lot *domain.StockLot) error {
+	return receiveLotTx(ctx, tx, lot)
+}
+
+// receiveLotTx adds lot.Quantity to its batch, creating the batch if needed,
+// and fills lot with the resulting row. A batch that already exists with a
+// different expiry date fails with ErrLotExpiryMismatch.
+func receiveLotTx(ctx context.Context, tx *sql.Tx, lot *domain.StockLot) error {
+	query := `
+		INSERT INTO stock_lots (warehouse_id, product_id, batch_number, expiry_date, quantity, received_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+		ON CONFLICT (warehouse_id, product_id, batch_number) DO UPDATE
+		SET quantity = stock_lots.quantity + EXCLUDED.quantity, updated_at = NOW()
+		WHERE stock_lots.expiry_date IS NOT DISTINCT FROM EXCLUDED.expiry_date
+		RETURNING id, quantity, received_at, updated_at
+	`
+	err := tx.QueryRowContext(ctx, query, lot.WarehouseID, lot.ProductID, lot.BatchNumber, lot.ExpiryDate, lot.Quantity).
+		Scan(&lot.ID, &lot.Quantity, &lot.ReceivedAt, &lot.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: batch %q", ErrLotExpiryMismatch, lot.BatchNumber)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to receive lot: %w", err)
+	}
+	return nil
+}
+
+func (r *stockLotRepo) List(ctx context.Context, warehouseID uint, productID uint) ([]domain.StockLot, error) {
+	query := `
+		SELECT ` + stockLotColumns + `
+		FROM stock_lots l
+		WHERE l.quantity > 0
+		  AND ($1 = 0 OR l.warehouse_id = $1)
+		  AND ($2 = 0 OR l.product_id = $2)
+		ORDER BY l.warehouse_id, l.product_id, l.expiry_date NULLS LAST, l.received_at, l.id
+	`
+	rows, err := r.db.QueryContext(ctx, query, warehouseID, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stock lots: %w", err)
+	}
+	defer rows.Close()
+
+	lots := []domain.StockLot{}
+	for rows.Next() {
+		var l domain.StockLot
+		if err := rows.Scan(&l.ID, &l.WarehouseID, &l.ProductID, &l.BatchNumber, &l.ExpiryDate, &l.Quantity, &l.ReceivedAt, &l.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan stock lot: %w", err)
+		}
+		lots = append(lots, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating stock lots: %w", err)
+	}
+	return lots, nil
+}
+
+func (r *stockLotRepo) ListExpiring(ctx context.Context, days int, warehouseID uint) ([]domain.ExpiringLot, error) {
+	query := `
+		SELECT ` + stockLotColumns + `, w.name, p.name, l.expiry_date - CURRENT_DATE
+		FROM stock_lots l
+		JOIN warehouses w ON w.id = l.warehouse_id
+		JOIN products p ON p.id = l.product_id
+		WHERE l.quantity > 0
+		  AND l.expiry_date <= CURRENT_DATE + $1::int
+		  AND ($2 = 0 OR l.warehouse_id = $2)
+		ORDER BY l.expiry_date, l.warehouse_id, l.product_id, l.id
+	`
+	rows, err := r.db.QueryContext(ctx, query, days, warehouseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query expiring lots: %w", err)
+	}
+	defer rows.Close()
+
+	lots := []domain.ExpiringLot{}
+	for rows.Next() {
+		var l domain.ExpiringLot
+		if err := rows.Scan(&l.ID, &l.WarehouseID, &l.ProductID, &l.BatchNumber, &l.ExpiryDate, &l.Quantity, &l.ReceivedAt, &l.UpdatedAt,
+			&l.WarehouseName, &l.ProductName, &l.DaysLeft); err != nil {
+			return nil, fmt.Errorf("failed to scan expiring lot: %w", err)
+		}
+		lots = append(lots, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating expiring lots: %w", err)
+	}
+	return lots, nil
+}
+
+// fefoLot is a lot read under FOR UPDATE by drawLotsTx.
+type fefoLot struct {
+	domain.LotConsumption
+	available int32
+	expired   bool
+}
+
+// drawLotsTx takes -change.Delta units out of the lots of a stock row already
+// locked by lockStock, so the lots never hold more than the new quantity.
+// Lots are taken first-expiry-first-out; lots without an expiry date come
+// last. onHand is the row's quantity before the change.
+//
+// A sale takes from unexpired lots first and only then from untracked stock,
+// and fails with ErrInsufficientStock rather than sell expired lots. Any other
+// decrease (adjustments, transfers, stocktakes) takes untracked stock first
+// and then the lots, expired ones first, which is how write-offs of expired
+// batches come out of the right lot.
+func drawLotsTx(ctx context.Context, tx *sql.Tx, change domain.StockChange, onHand int32) ([]domain.LotConsumption, error) {
+	query := `
+		SELECT id, batch_number, expiry_date, quantity, COALESCE(expiry_date < CURRENT_DATE, FALSE)
+		FROM stock_lots
+		WHERE warehouse_id = $1 AND product_id = $2 AND quantity > 0
+		ORDER BY expiry_date NULLS LAST, received_at, id
+		FOR UPDATE
+	`
+	rows, err := tx.QueryContext(ctx, query, change.WarehouseID, change.ProductID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock stock lots: %w", err)
+	}
+	var lots []fefoLot
+	var lotted int32
+	for rows.Next() {
+		var l fefoLot
+		if err := rows.Scan(&l.LotID, &l.BatchNumber, &l.ExpiryDate, &l.available, &l.expired); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan stock lot: %w", err)
+		}
+		lots = append(lots, l)
+		lotted += l.available
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating stock lots: %w", err)
+	}
+
+	need := -change.Delta
+	untracked := max(onHand-lotted, 0)
+	take := func(l *fefoLot) {
+		n := min(l.available, need)
+		l.Quantity += n
+		l.available -= n
+		need -= n
+	}
+
+	if change.Reason == domain.MovementReasonSale {
+		for i := range lots {
+			if need == 0 {
+				break
+			}
+			if !lots[i].expired {
+				take(&lots[i])
+			}
+		}
+		if need > untracked {
+			return nil, fmt.Errorf("%w for product_id=%d in warehouse_id=%d: the rest is expired", ErrInsufficientStock, change.ProductID, change.WarehouseID)
+		}
+	} else {
+		need -= min(need, untracked)
+		for i := range lots {
+			if need == 0 {
+				break
+			}
+			take(&lots[i])
+		}
+	}
+
+	var taken []domain.LotConsumption
+	for _, l := range lots {
+		if l.Quantity == 0 {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE stock_lots SET quantity = quantity - $1, updated_at = NOW() WHERE id = $2`, l.Quantity, l.LotID); err != nil {
+			return nil, fmt.Errorf("failed to update stock lot: %w", err)
+		}
+		taken = append(taken, l.LotConsumption)
+	}
+	return taken, nil
+}
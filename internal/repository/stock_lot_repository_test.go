@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStockLotRepository_Receive(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := NewStockLotRepository(db)
+	ctx := context.Background()
+	expiry := time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)
+
+	t.Run("AddsToBatch", func(t *testing.T) {
+		lot := &domain.StockLot{WarehouseID: 1, ProductID: 2, BatchNumber: "B-0325", ExpiryDate: &expiry, Quantity: 6}
+
+		mock.ExpectBegin()
+		expectCapacityLock(mock, 1)
+		mock.ExpectExec("INSERT INTO warehouse_stock (.+) ON CONFLICT").
+			WithArgs(uint(1), uint(2)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("SELECT quantity, reorder_point, version FROM warehouse_stock (.+) FOR UPDATE").
+			WithArgs(uint(1), uint(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"quantity", "reorder_point", "version"}).AddRow(10, nil, 2))
+		mock.ExpectQuery("INSERT INTO stock_lots (.+) ON CONFLICT (.+) DO UPDATE").
+			WithArgs(uint(1), uint(2), "B-0325", &expiry, int32(6)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "quantity", "received_at", "updated_at"}).AddRow(4, 9, time.Now(), time.Now()))
+		expectNoCapacityLimit(mock, 1)
+		mock.ExpectExec("UPDATE warehouse_stock SET quantity").
+			WithArgs(int32(16), uint(1), uint(2)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("INSERT INTO stock_movements").
+			WithArgs(uint(1), uint(2), int32(6), int32(16), domain.MovementReasonRestock, nil, nil, nil, "lot B-0325").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(12, time.Now()))
+		mock.ExpectCommit()
+
+		result, err := repo.Receive(ctx, lot, domain.StockReference{})
+		assert.NoError(t, err)
+		assert.Equal(t, int32(16), result.QuantityAfter)
+		assert.Equal(t, uint(4), lot.ID)
+		assert.Equal(t, int32(9), lot.Quantity)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("ExpiryMismatch", func(t *testing.T) {
+		lot := &domain.StockLot{WarehouseID: 1, ProductID: 2, BatchNumber: "B-0325", Quantity: 6}
+
+		mock.ExpectBegin()
+		expectCapacityLock(mock, 1)
+		mock.ExpectExec("INSERT INTO warehouse_stock").
+			WithArgs(uint(1), uint(2)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("SELECT quantity, reorder_point, version FROM warehouse_stock").
+			WithArgs(uint(1), uint(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"quantity", "reorder_point", "version"}).AddRow(16, nil, 3))
+		mock.ExpectQuery("INSERT INTO stock_lots").
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		_, err := repo.Receive(ctx, lot, domain.StockReference{})
+		assert.ErrorIs(t, err, ErrLotExpiryMismatch)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
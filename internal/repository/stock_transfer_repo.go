@@ -0,0 +1,384 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrTransferNotFound is returned when a transfer lookup matches no row.
+	ErrTransferNotFound = errors.New("stock transfer not found")
+	// ErrTransferLineNotFound is returned when a line does not belong to the transfer.
+	ErrTransferLineNotFound = errors.New("stock transfer line not found")
+)
+
+// StockTransferRepository stores transfers and their lines. Stock itself is
+// moved by the usecase through WarehouseStockRepository, inside the
+// transaction returned by BeginTx.
+type StockTransferRepository interface {
+	BeginTx(ctx context.Context) (*sql.Tx, error)
+	// Create inserts a draft transfer together with its lines.
+	Create(ctx context.Context, transfer *domain.StockTransfer) error
+	GetByID(ctx context.Context, id uint) (*domain.StockTransfer, error)
+	// GetByIDForUpdateTx locks the transfer row until tx ends.
+	GetByIDForUpdateTx(ctx context.Context, tx *sql.Tx, id uint) (*domain.StockTransfer, error)
+	// List returns transfers touching warehouseID (as source or destination),
+	// newest first. Zero values disable the filters.
+	List(ctx context.Context, warehouseID uint, status string) ([]domain.StockTransfer, error)
+	UpdateStatusTx(ctx context.Context, tx *sql.Tx, id uint, status string) error
+	// RecordReceiptTx adds received and discrepancy quantities to a line.
+	RecordReceiptTx(ctx context.Context, tx *sql.Tx, lineID uint, received, discrepancy int32, note *string) error
+	// RecordLineLotsTx stores the source lots a line took when it was dispatched.
+	RecordLineLotsTx(ctx context.Context, tx *sql.Tx, lineID uint, lots []domain.LotConsumption) error
+	// ReceiveLineLotsTx marks up to quantity units of the line's lots as
+	// received, earliest expiry first, and returns the batches to put them
+	// in (batch number, expiry and quantity only). Units beyond the lots were
+	// untracked stock.
+	ReceiveLineLotsTx(ctx context.Context, tx *sql.Tx, lineID uint, quantity int32) ([]domain.StockLot, error)
+	// InTransit sums the outstanding quantities of dispatched transfers per
+	// destination and product. Zero destinationID returns every warehouse.
+	InTransit(ctx context.Context, destinationID uint) ([]domain.InTransitStock, error)
+	// DeleteDraft removes a transfer that has not been dispatched yet.
+	DeleteDraft(ctx context.Context, id uint) error
+}
+
+type stockTransferRepo struct {
+	db *sql.DB
+}
+
+func NewStockTransferRepository(db *sql.DB) StockTransferRepository {
+	return &stockTransferRepo{db: db}
+}
+
+const stockTransferColumns = `id, source_warehouse_id, destination_warehouse_id, status, note, created_by,
+	dispatched_at, received_at, created_at, updated_at`
+
+const stockTransferLineColumns = `id, transfer_id, product_id, quantity, received_quantity, discrepancy_quantity, discrepancy_note`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanStockTransfer(row rowScanner) (*domain.StockTransfer, error) {
+	var t domain.StockTransfer
+	err := row.Scan(&t.ID, &t.SourceWarehouseID, &t.DestinationWarehouseID, &t.Status, &t.Note, &t.CreatedBy,
+		&t.DispatchedAt, &t.ReceivedAt, &t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *stockTransferRepo) BeginTx(ctx context.Context) (*sql.Tx, error) {
+	return r.db.BeginTx(ctx, nil)
+}
+
+func (r *stockTransferRepo) Create(ctx context.Context, transfer *domain.StockTransfer) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	transfer.Status = domain.TransferStatusDraft
+	query := `
+		INSERT INTO stock_transfers (source_warehouse_id, destination_warehouse_id, status, note, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+		RETURNING id, created_at, updated_at
+	`
+	err = tx.QueryRowContext(ctx, query, transfer.SourceWarehouseID, transfer.DestinationWarehouseID,
+		transfer.Status, transfer.Note, transfer.CreatedBy).
+		Scan(&transfer.ID, &transfer.CreatedAt, &transfer.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create stock transfer: %w", err)
+	}
+
+	lineQuery := `
+		INSERT INTO stock_transfer_lines (transfer_id, product_id, quantity)
+		VALUES ($1, $2, $3)
+		RETURNING id
+	`
+	for i := range transfer.Lines {
+		line := &transfer.Lines[i]
+		line.TransferID = transfer.ID
+		if err := tx.QueryRowContext(ctx, lineQuery, line.TransferID, line.ProductID, line.Quantity).Scan(&line.ID); err != nil {
+			return fmt.Errorf("failed to create stock transfer line: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit stock transfer: %w", err)
+	}
+	return nil
+}
+
+func (r *stockTransferRepo) GetByID(ctx context.Context, id uint) (*domain.StockTransfer, error) {
+	return r.getByID(ctx, r.db, id, "")
+}
+
+func (r *stockTransferRepo) GetByIDForUpdateTx(ctx context.Context, tx *sql.Tx, id uint) (*domain.StockTransfer, error) {
+	return r.getByID(ctx, tx, id, " FOR UPDATE")
+}
+
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func (r *stockTransferRepo) getByID(ctx context.Context, q queryer, id uint, lock string) (*domain.StockTransfer, error) {
+	query := `SELECT ` + stockTransferColumns + ` FROM stock_transfers WHERE id = $1` + lock
+	t, err := scanStockTransfer(q.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTransferNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stock transfer: %w", err)
+	}
+
+	transfers := []domain.StockTransfer{*t}
+	if err := r.loadLines(ctx, q, transfers); err != nil {
+		return nil, err
+	}
+	return &transfers[0], nil
+}
+
+func (r *stockTransferRepo) List(ctx context.Context, warehouseID uint, status string) ([]domain.StockTransfer, error) {
+	query := `
+		SELECT ` + stockTransferColumns + `
+		FROM stock_transfers
+		WHERE ($1 = 0 OR source_warehouse_id = $1 OR destination_warehouse_id = $1)
+		  AND ($2 = '' OR status = $2)
+		ORDER BY id DESC
+	`
+	rows, err := r.db.QueryContext(ctx, query, warehouseID, status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stock transfers: %w", err)
+	}
+	defer rows.Close()
+
+	transfers := []domain.StockTransfer{}
+	for rows.Next() {
+		t, err := scanStockTransfer(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan stock transfer: %w", err)
+		}
+		transfers = append(transfers, *t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating stock transfers: %w", err)
+	}
+
+	if err := r.loadLines(ctx, r.db, transfers); err != nil {
+		return nil, err
+	}
+	return transfers, nil
+}
+
+// loadLines fills the lines of all transfers with a single query.
+func (r *stockTransferRepo) loadLines(ctx context.Context, q queryer, transfers []domain.StockTransfer) error {
+	if len(transfers) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(transfers))
+	index := make(map[uint]int, len(transfers))
+	for i := range transfers {
+		ids[i] = int64(transfers[i].ID)
+		index[transfers[i].ID] = i
+		transfers[i].Lines = []domain.StockTransferLine{}
+	}
+
+	query := `SELECT ` + stockTransferLineColumns + ` FROM stock_transfer_lines WHERE transfer_id = ANY($1) ORDER BY id`
+	rows, err := q.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("failed to query stock transfer lines: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var l domain.StockTransferLine
+		if err := rows.Scan(&l.ID, &l.TransferID, &l.ProductID, &l.Quantity, &l.ReceivedQuantity,
+			&l.DiscrepancyQuantity, &l.DiscrepancyNote); err != nil {
+			return fmt.Errorf("failed to scan stock transfer line: %w", err)
+		}
+		t := &transfers[index[l.TransferID]]
+		if t.Status != domain.TransferStatusDraft {
+			l.InTransitQuantity = l.Outstanding()
+		}
+		t.Lines = append(t.Lines, l)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating stock transfer lines: %w", err)
+	}
+	return r.loadLineLots(ctx, q, transfers, ids)
+}
+
+// loadLineLots fills the lots of the lines loaded by loadLines.
+func (r *stockTransferRepo) loadLineLots(ctx context.Context, q queryer, transfers []domain.StockTransfer, transferIDs []int64) error {
+	lines := map[uint]*domain.StockTransferLine{}
+	for i := range transfers {
+		for j := range transfers[i].Lines {
+			lines[transfers[i].Lines[j].ID] = &transfers[i].Lines[j]
+		}
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+
+	query := `
+		SELECT ll.id, ll.transfer_line_id, ll.batch_number, ll.expiry_date, ll.quantity, ll.received_quantity
+		FROM stock_transfer_line_lots ll
+		JOIN stock_transfer_lines l ON l.id = ll.transfer_line_id
+		WHERE l.transfer_id = ANY($1)
+		ORDER BY ll.transfer_line_id, ll.expiry_date NULLS LAST, ll.id
+	`
+	rows, err := q.QueryContext(ctx, query, pq.Array(transferIDs))
+	if err != nil {
+		return fmt.Errorf("failed to query stock transfer line lots: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ll domain.StockTransferLineLot
+		if err := rows.Scan(&ll.ID, &ll.TransferLineID, &ll.BatchNumber, &ll.ExpiryDate, &ll.Quantity, &ll.ReceivedQuantity); err != nil {
+			return fmt.Errorf("failed to scan stock transfer line lot: %w", err)
+		}
+		if line, ok := lines[ll.TransferLineID]; ok {
+			line.Lots = append(line.Lots, ll)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating stock transfer line lots: %w", err)
+	}
+	return nil
+}
+
+func (r *stockTransferRepo) UpdateStatusTx(ctx context.Context, tx *sql.Tx, id uint, status string) error {
+	query := `
+		UPDATE stock_transfers
+		SET status = $1,
+		    dispatched_at = CASE WHEN $1 = 'dispatched' THEN NOW() ELSE dispatched_at END,
+		    received_at = CASE WHEN $1 = 'received' THEN NOW() ELSE received_at END,
+		    updated_at = NOW()
+		WHERE id = $2
+	`
+	res, err := tx.ExecContext(ctx, query, status, id)
+	if err != nil {
+		return fmt.Errorf("failed to update stock transfer status: %w", err)
+	}
+	return expectAffected(res, ErrTransferNotFound)
+}
+
+func (r *stockTransferRepo) RecordReceiptTx(ctx context.Context, tx *sql.Tx, lineID uint, received, discrepancy int32, note *string) error {
+	query := `
+		UPDATE stock_transfer_lines
+		SET received_quantity = received_quantity + $1,
+		    discrepancy_quantity = discrepancy_quantity + $2,
+		    discrepancy_note = COALESCE($3, discrepancy_note)
+		WHERE id = $4
+	`
+	res, err := tx.ExecContext(ctx, query, received, discrepancy, note, lineID)
+	if err != nil {
+		return fmt.Errorf("failed to record stock transfer receipt: %w", err)
+	}
+	return expectAffected(res, ErrTransferLineNotFound)
+}
+
+func (r *stockTransferRepo) RecordLineLotsTx(ctx context.Context, tx *sql.Tx, lineID uint, lots []domain.LotConsumption) error {
+	query := `
+		INSERT INTO stock_transfer_line_lots (transfer_line_id, batch_number, expiry_date, quantity)
+		VALUES ($1, $2, $3, $4)
+	`
+	for _, l := range lots {
+		if _, err := tx.ExecContext(ctx, query, lineID, l.BatchNumber, l.ExpiryDate, l.Quantity); err != nil {
+			return fmt.Errorf("failed to record stock transfer line lot: %w", err)
+		}
+	}
+	return nil
+}
+
+func (r *stockTransferRepo) ReceiveLineLotsTx(ctx context.Context, tx *sql.Tx, lineID uint, quantity int32) ([]domain.StockLot, error) {
+	query := `
+		SELECT id, transfer_line_id, batch_number, expiry_date, quantity, received_quantity
+		FROM stock_transfer_line_lots
+		WHERE transfer_line_id = $1 AND received_quantity < quantity
+		ORDER BY expiry_date NULLS LAST, id
+		FOR UPDATE
+	`
+	rows, err := tx.QueryContext(ctx, query, lineID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock stock transfer line lots: %w", err)
+	}
+	var lots []domain.StockTransferLineLot
+	for rows.Next() {
+		var ll domain.StockTransferLineLot
+		if err := rows.Scan(&ll.ID, &ll.TransferLineID, &ll.BatchNumber, &ll.ExpiryDate, &ll.Quantity, &ll.ReceivedQuantity); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan stock transfer line lot: %w", err)
+		}
+		lots = append(lots, ll)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating stock transfer line lots: %w", err)
+	}
+
+	var received []domain.StockLot
+	for _, ll := range lots {
+		if quantity == 0 {
+			break
+		}
+		n := min(ll.Quantity-ll.ReceivedQuantity, quantity)
+		quantity -= n
+		_, err := tx.ExecContext(ctx, `UPDATE stock_transfer_line_lots SET received_quantity = received_quantity + $1 WHERE id = $2`, n, ll.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to receive stock transfer line lot: %w", err)
+		}
+		received = append(received, domain.StockLot{BatchNumber: ll.BatchNumber, ExpiryDate: ll.ExpiryDate, Quantity: n})
+	}
+	return received, nil
+}
+
+func (r *stockTransferRepo) InTransit(ctx context.Context, destinationID uint) ([]domain.InTransitStock, error) {
+	query := `
+		SELECT t.destination_warehouse_id, l.product_id,
+		       SUM(l.quantity - l.received_quantity - l.discrepancy_quantity)::int
+		FROM stock_transfers t
+		JOIN stock_transfer_lines l ON l.transfer_id = t.id
+		WHERE t.status IN ('dispatched', 'in_transit')
+		  AND ($1 = 0 OR t.destination_warehouse_id = $1)
+		GROUP BY t.destination_warehouse_id, l.product_id
+		HAVING SUM(l.quantity - l.received_quantity - l.discrepancy_quantity) > 0
+		ORDER BY t.destination_warehouse_id, l.product_id
+	`
+	rows, err := r.db.QueryContext(ctx, query, destinationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query in-transit stock: %w", err)
+	}
+	defer rows.Close()
+
+	result := []domain.InTransitStock{}
+	for rows.Next() {
+		var s domain.InTransitStock
+		if err := rows.Scan(&s.DestinationWarehouseID, &s.ProductID, &s.Quantity); err != nil {
+			return nil, fmt.Errorf("failed to scan in-transit stock: %w", err)
+		}
+		result = append(result, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating in-transit stock: %w", err)
+	}
+	return result, nil
+}
+
+func (r *stockTransferRepo) DeleteDraft(ctx context.Context, id uint) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM stock_transfers WHERE id = $1 AND status = 'draft'`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete stock transfer: %w", err)
+	}
+	return expectAffected(res, ErrTransferNotFound)
+}
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStockTransferRepository_Create(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := NewStockTransferRepository(db)
+	ctx := context.Background()
+	now := time.Now()
+
+	transfer := &domain.StockTransfer{
+		SourceWarehouseID:      1,
+		DestinationWarehouseID: 2,
+		Lines: []domain.StockTransferLine{
+			{ProductID: 5, Quantity: 10},
+			{ProductID: 6, Quantity: 3},
+		},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO stock_transfers").
+		WithArgs(uint(1), uint(2), domain.TransferStatusDraft, nil, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(7, now, now))
+	mock.ExpectQuery("INSERT INTO stock_transfer_lines").
+		WithArgs(uint(7), uint(5), int32(10)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery("INSERT INTO stock_transfer_lines").
+		WithArgs(uint(7), uint(6), int32(3)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectCommit()
+
+	err := repo.Create(ctx, transfer)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(7), transfer.ID)
+	assert.Equal(t, uint(7), transfer.Lines[1].TransferID)
+	assert.Equal(t, uint(2), transfer.Lines[1].ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStockTransferRepository_GetByID(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := NewStockTransferRepository(db)
+	ctx := context.Background()
+	now := time.Now()
+
+	mock.ExpectQuery("SELECT (.+) FROM stock_transfers WHERE id =").
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "source_warehouse_id", "destination_warehouse_id", "status", "note",
+			"created_by", "dispatched_at", "received_at", "created_at", "updated_at"}).
+			AddRow(7, 1, 2, "in_transit", nil, 3, now, nil, now, now))
+	mock.ExpectQuery("SELECT (.+) FROM stock_transfer_lines WHERE transfer_id = ANY").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "transfer_id", "product_id", "quantity", "received_quantity",
+			"discrepancy_quantity", "discrepancy_note"}).
+			AddRow(1, 7, 5, 10, 6, 1, "1 damaged"))
+	mock.ExpectQuery("SELECT (.+) FROM stock_transfer_line_lots ll JOIN stock_transfer_lines l (.+) WHERE l.transfer_id = ANY").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "transfer_line_id", "batch_number", "expiry_date", "quantity", "received_quantity"}).
+			AddRow(1, 1, "B-01", now, 4, 4).
+			AddRow(2, 1, "B-02", nil, 6, 2))
+
+	transfer, err := repo.GetByID(ctx, 7)
+	assert.NoError(t, err)
+	assert.Len(t, transfer.Lines, 1)
+	assert.Equal(t, int32(3), transfer.Lines[0].InTransitQuantity)
+	assert.Len(t, transfer.Lines[0].Lots, 2)
+	assert.Equal(t, "B-02", transfer.Lines[0].Lots[1].BatchNumber)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStockTransferRepository_ReceiveLineLotsTx(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := NewStockTransferRepository(db)
+	ctx := context.Background()
+	expiry := time.Date(2027, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	tx, err := db.Begin()
+	assert.NoError(t, err)
+
+	// lot pertama sudah diterima 1 dari 4, jadi 5 unit habiskan sisa 3 lalu 2 dari lot kedua
+	mock.ExpectQuery("SELECT (.+) FROM stock_transfer_line_lots WHERE transfer_line_id = \\$1 (.+) FOR UPDATE").
+		WithArgs(uint(3)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "transfer_line_id", "batch_number", "expiry_date", "quantity", "received_quantity"}).
+			AddRow(10, 3, "B-01", expiry, 4, 1).
+			AddRow(11, 3, "B-02", nil, 6, 0))
+	mock.ExpectExec("UPDATE stock_transfer_line_lots SET received_quantity = received_quantity \\+ \\$1").
+		WithArgs(int32(3), uint(10)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE stock_transfer_line_lots SET received_quantity = received_quantity \\+ \\$1").
+		WithArgs(int32(2), uint(11)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	lots, err := repo.ReceiveLineLotsTx(ctx, tx, 3, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.StockLot{
+		{BatchNumber: "B-01", ExpiryDate: &expiry, Quantity: 3},
+		{BatchNumber: "B-02", Quantity: 2},
+	}, lots)
+
+	mock.ExpectRollback()
+	_ = tx.Rollback()
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStockTransferRepository_RecordReceiptTx_NotFound(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := NewStockTransferRepository(db)
+	ctx := context.Background()
+
+	mock.ExpectBegin()
+	tx, err := db.Begin()
+	assert.NoError(t, err)
+
+	mock.ExpectExec("UPDATE stock_transfer_lines").
+		WithArgs(int32(2), int32(0), nil, uint(99)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = repo.RecordReceiptTx(ctx, tx, 99, 2, 0, nil)
+	assert.ErrorIs(t, err, ErrTransferLineNotFound)
+
+	mock.ExpectRollback()
+	_ = tx.Rollback()
+}
@@ -0,0 +1,310 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+)
+
+var (
+	// ErrLocationNotFound is returned when a location lookup matches no row,
+	// or the location belongs to another warehouse.
+	ErrLocationNotFound = errors.New("storage location not found")
+	// ErrLocationExists is returned when the warehouse already has a location with the same code.
+	ErrLocationExists = errors.New("storage location already exists")
+	// ErrLocationNotEmpty is returned when deleting a location that still holds stock.
+	ErrLocationNotEmpty = errors.New("storage location still holds stock")
+	// ErrInsufficientLocationStock is returned when a move takes more than the
+	// source location (or the unassigned stock) holds.
+	ErrInsufficientLocationStock = errors.New("not enough stock at location")
+)
+
+// StorageLocationRepository manages the locations of a warehouse and the
+// stock put away in them. Location stock is a breakdown of
+// warehouse_stock.quantity: moves only shift stock between locations, and
+// every decrease of the quantity takes from the locations in the same
+// transaction (see drawLocationsTx).
+type StorageLocationRepository interface {
+	Create(ctx context.Context, location *domain.StorageLocation) error
+	GetByID(ctx context.Context, id uint) (*domain.StorageLocation, error)
+	// List returns the locations of a warehouse ordered by code.
+	List(ctx context.Context, warehouseID uint) ([]domain.StorageLocation, error)
+	// Delete removes an empty location.
+	Delete(ctx context.Context, id uint) error
+	// ListStock returns the non-empty location rows. Zero IDs disable the
+	// product and location filters.
+	ListStock(ctx context.Context, warehouseID, productID, locationID uint) ([]domain.LocationStock, error)
+	// Move shifts stock between two locations of the warehouse, or between a
+	// location and the unassigned stock. The warehouse quantity is unchanged.
+	Move(ctx context.Context, move domain.LocationMove) ([]domain.LocationStock, error)
+}
+
+type storageLocationRepo struct {
+	db *sql.DB
+}
+
+func NewStorageLocationRepository(db *sql.DB) StorageLocationRepository {
+	return &storageLocationRepo{db: db}
+}
+
+const storageLocationColumns = `id, warehouse_id, zone, aisle, shelf, bin, code, created_at`
+
+func scanStorageLocation(row rowScanner) (*domain.StorageLocation, error) {
+	var l domain.StorageLocation
+	if err := row.Scan(&l.ID, &l.WarehouseID, &l.Zone, &l.Aisle, &l.Shelf, &l.Bin, &l.Code, &l.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+func (r *storageLocationRepo) Create(ctx context.Context, location *domain.StorageLocation) error {
+	location.Code = domain.LocationCode(location.Zone, location.Aisle, location.Shelf, location.Bin)
+	query := `
+		INSERT INTO storage_locations (warehouse_id, zone, aisle, shelf, bin, code, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		RETURNING id, created_at
+	`
+	err := r.db.QueryRowContext(ctx, query, location.WarehouseID, location.Zone, location.Aisle, location.Shelf, location.Bin, location.Code).
+		Scan(&location.ID, &location.CreatedAt)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("%w: %s", ErrLocationExists, location.Code)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create storage location: %w", err)
+	}
+	return nil
+}
+
+func (r *storageLocationRepo) GetByID(ctx context.Context, id uint) (*domain.StorageLocation, error) {
+	query := `SELECT ` + storageLocationColumns + ` FROM storage_locations WHERE id = $1`
+	l, err := scanStorageLocation(r.db.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrLocationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query storage location: %w", err)
+	}
+	return l, nil
+}
+
+func (r *storageLocationRepo) List(ctx context.Context, warehouseID uint) ([]domain.StorageLocation, error) {
+	query := `SELECT ` + storageLocationColumns + ` FROM storage_locations WHERE warehouse_id = $1 ORDER BY code`
+	rows, err := r.db.QueryContext(ctx, query, warehouseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query storage locations: %w", err)
+	}
+	defer rows.Close()
+
+	locations := []domain.StorageLocation{}
+	for rows.Next() {
+		l, err := scanStorageLocation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan storage location: %w", err)
+		}
+		locations = append(locations, *l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating storage locations: %w", err)
+	}
+	return locations, nil
+}
+
+func (r *storageLocationRepo) Delete(ctx context.Context, id uint) error {
+	query := `
+		DELETE FROM storage_locations
+		WHERE id = $1
+		  AND NOT EXISTS (SELECT 1 FROM location_stock WHERE location_id = $1 AND quantity > 0)
+	`
+	res, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete storage location: %w", err)
+	}
+	if err := expectAffected(res, ErrLocationNotFound); err != nil {
+		// tell a missing location apart from one that still holds stock
+		if _, getErr := r.GetByID(ctx, id); getErr == nil {
+			return ErrLocationNotEmpty
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *storageLocationRepo) ListStock(ctx context.Context, warehouseID, productID, locationID uint) ([]domain.LocationStock, error) {
+	return listLocationStock(ctx, r.db, warehouseID, productID, locationID)
+}
+
+func listLocationStock(ctx context.Context, q queryer, warehouseID, productID, locationID uint) ([]domain.LocationStock, error) {
+	query := `
+		SELECT s.location_id, l.code, s.warehouse_id, s.product_id, s.quantity, s.updated_at
+		FROM location_stock s
+		JOIN storage_locations l ON l.id = s.location_id
+		WHERE s.warehouse_id = $1 AND s.quantity > 0
+		  AND ($2 = 0 OR s.product_id = $2)
+		  AND ($3 = 0 OR s.location_id = $3)
+		ORDER BY l.code, s.product_id
+	`
+	rows, err := q.QueryContext(ctx, query, warehouseID, productID, locationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query location stock: %w", err)
+	}
+	defer rows.Close()
+
+	stock := []domain.LocationStock{}
+	for rows.Next() {
+		var s domain.LocationStock
+		if err := rows.Scan(&s.LocationID, &s.LocationCode, &s.WarehouseID, &s.ProductID, &s.Quantity, &s.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan location stock: %w", err)
+		}
+		stock = append(stock, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating location stock: %w", err)
+	}
+	return stock, nil
+}
+
+func (r *storageLocationRepo) Move(ctx context.Context, move domain.LocationMove) ([]domain.LocationStock, error) {
+	if move.Quantity <= 0 {
+		return nil, fmt.Errorf("quantity to move must be greater than zero")
+	}
+	if move.FromLocationID == move.ToLocationID {
+		return nil, fmt.Errorf("source and destination location must differ")
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// The stock row lock serialises moves with decreases, which also take
+	// from the locations (drawLocationsTx).
+	current, err := lockStock(ctx, tx, move.WarehouseID, move.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range []uint{move.FromLocationID, move.ToLocationID} {
+		if id == 0 {
+			continue
+		}
+		var exists bool
+		err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM storage_locations WHERE id = $1 AND warehouse_id = $2)`, id, move.WarehouseID).Scan(&exists)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check storage location: %w", err)
+		}
+		if !exists {
+			return nil, fmt.Errorf("%w: location_id=%d in warehouse_id=%d", ErrLocationNotFound, id, move.WarehouseID)
+		}
+	}
+
+	if move.FromLocationID == 0 {
+		var located int32
+		err := tx.QueryRowContext(ctx, `SELECT COALESCE(SUM(quantity), 0) FROM location_stock WHERE warehouse_id = $1 AND product_id = $2`,
+			move.WarehouseID, move.ProductID).Scan(&located)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sum location stock: %w", err)
+		}
+		if unassigned := current.Quantity - located; unassigned < move.Quantity {
+			return nil, fmt.Errorf("%w: only %d unassigned", ErrInsufficientLocationStock, max(unassigned, 0))
+		}
+	} else {
+		res, err := tx.ExecContext(ctx, `
+			UPDATE location_stock SET quantity = quantity - $1, updated_at = NOW()
+			WHERE location_id = $2 AND product_id = $3 AND quantity >= $1
+		`, move.Quantity, move.FromLocationID, move.ProductID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to take location stock: %w", err)
+		}
+		if err := expectAffected(res, fmt.Errorf("%w: location_id=%d", ErrInsufficientLocationStock, move.FromLocationID)); err != nil {
+			return nil, err
+		}
+	}
+
+	if move.ToLocationID != 0 {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO location_stock (location_id, warehouse_id, product_id, quantity, updated_at)
+			VALUES ($1, $2, $3, $4, NOW())
+			ON CONFLICT (location_id, product_id) DO UPDATE
+			SET quantity = location_stock.quantity + EXCLUDED.quantity, updated_at = NOW()
+		`, move.ToLocationID, move.WarehouseID, move.ProductID, move.Quantity)
+		if err != nil {
+			return nil, fmt.Errorf("failed to put away location stock: %w", err)
+		}
+	}
+
+	stock, err := listLocationStock(ctx, tx, move.WarehouseID, move.ProductID, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit location move: %w", err)
+	}
+	return stock, nil
+}
+
+// pickLocation is a location row read under FOR UPDATE by drawLocationsTx.
+type pickLocation struct {
+	domain.LocationPick
+	available int32
+}
+
+// drawLocationsTx takes -change.Delta units out of the locations of a stock
+// row already locked by lockStock, so the locations never hold more than the
+// new quantity. onHand is the row's quantity before the change.
+//
+// A sale is picked from locations first, fullest first so an order line
+// needs as few bins as possible, and only then from unassigned stock. Any
+// other decrease takes unassigned stock first, which is where stock of
+// unknown whereabouts is written off.
+func drawLocationsTx(ctx context.Context, tx *sql.Tx, change domain.StockChange, onHand int32) ([]domain.LocationPick, error) {
+	query := `
+		SELECT s.location_id, l.code, s.quantity
+		FROM location_stock s
+		JOIN storage_locations l ON l.id = s.location_id
+		WHERE s.warehouse_id = $1 AND s.product_id = $2 AND s.quantity > 0
+		ORDER BY s.quantity DESC, l.code
+		FOR UPDATE OF s
+	`
+	rows, err := tx.QueryContext(ctx, query, change.WarehouseID, change.ProductID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock location stock: %w", err)
+	}
+	var locations []pickLocation
+	var located int32
+	for rows.Next() {
+		var l pickLocation
+		if err := rows.Scan(&l.LocationID, &l.LocationCode, &l.available); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan location stock: %w", err)
+		}
+		locations = append(locations, l)
+		located += l.available
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating location stock: %w", err)
+	}
+
+	need := -change.Delta
+	if change.Reason != domain.MovementReasonSale {
+		need -= min(need, max(onHand-located, 0))
+	}
+	var picks []domain.LocationPick
+	for _, l := range locations {
+		if need == 0 {
+			break
+		}
+		n := min(l.available, need)
+		need -= n
+		if _, err := tx.ExecContext(ctx, `UPDATE location_stock SET quantity = quantity - $1, updated_at = NOW() WHERE location_id = $2 AND product_id = $3`,
+			n, l.LocationID, change.ProductID); err != nil {
+			return nil, fmt.Errorf("failed to update location stock: %w", err)
+		}
+		l.Quantity = n
+		picks = append(picks, l.LocationPick)
+	}
+	return picks, nil
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorageLocationRepository(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := NewStorageLocationRepository(db)
+	ctx := context.Background()
+
+	lockColumns := []string{"quantity", "reorder_point", "version"}
+
+	t.Run("Move_PutAwayWithinUnassigned", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT quantity, reorder_point, version FROM warehouse_stock WHERE warehouse_id = \\$1 AND product_id = \\$2 FOR UPDATE").
+			WithArgs(uint(1), uint(5)).
+			WillReturnRows(sqlmock.NewRows(lockColumns).AddRow(10, nil, 3))
+		mock.ExpectQuery("SELECT EXISTS (.+) FROM storage_locations").
+			WithArgs(uint(2), uint(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectQuery("SELECT COALESCE\\(SUM\\(quantity\\), 0\\) FROM location_stock").
+			WithArgs(uint(1), uint(5)).
+			WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(4))
+		mock.ExpectExec("INSERT INTO location_stock (.+) ON CONFLICT").
+			WithArgs(uint(2), uint(1), uint(5), int32(6)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT (.+) FROM location_stock s").
+			WithArgs(uint(1), uint(5), uint(0)).
+			WillReturnRows(sqlmock.NewRows([]string{"location_id", "code", "warehouse_id", "product_id", "quantity", "updated_at"}).
+				AddRow(2, "A-01", 1, 5, 6, time.Now()).
+				AddRow(3, "A-02", 1, 5, 4, time.Now()))
+		mock.ExpectCommit()
+
+		stock, err := repo.Move(ctx, domain.LocationMove{WarehouseID: 1, ProductID: 5, ToLocationID: 2, Quantity: 6})
+		assert.NoError(t, err)
+		assert.Len(t, stock, 2)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Move_PutAwayMoreThanUnassigned", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT quantity, reorder_point, version FROM warehouse_stock WHERE warehouse_id = \\$1 AND product_id = \\$2 FOR UPDATE").
+			WithArgs(uint(1), uint(5)).
+			WillReturnRows(sqlmock.NewRows(lockColumns).AddRow(10, nil, 3))
+		mock.ExpectQuery("SELECT EXISTS (.+) FROM storage_locations").
+			WithArgs(uint(2), uint(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectQuery("SELECT COALESCE\\(SUM\\(quantity\\), 0\\) FROM location_stock").
+			WithArgs(uint(1), uint(5)).
+			WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(8))
+		mock.ExpectRollback()
+
+		_, err := repo.Move(ctx, domain.LocationMove{WarehouseID: 1, ProductID: 5, ToLocationID: 2, Quantity: 3})
+		assert.ErrorIs(t, err, ErrInsufficientLocationStock)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Move_BinToBinShortSource", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT quantity, reorder_point, version FROM warehouse_stock WHERE warehouse_id = \\$1 AND product_id = \\$2 FOR UPDATE").
+			WithArgs(uint(1), uint(5)).
+			WillReturnRows(sqlmock.NewRows(lockColumns).AddRow(10, nil, 3))
+		mock.ExpectQuery("SELECT EXISTS (.+) FROM storage_locations").
+			WithArgs(uint(2), uint(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectQuery("SELECT EXISTS (.+) FROM storage_locations").
+			WithArgs(uint(3), uint(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectExec("UPDATE location_stock SET quantity = quantity - \\$1").
+			WithArgs(int32(7), uint(2), uint(5)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		_, err := repo.Move(ctx, domain.LocationMove{WarehouseID: 1, ProductID: 5, FromLocationID: 2, ToLocationID: 3, Quantity: 7})
+		assert.ErrorIs(t, err, ErrInsufficientLocationStock)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Delete_NotEmpty", func(t *testing.T) {
+		mock.ExpectExec("DELETE FROM storage_locations").
+			WithArgs(uint(2)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("SELECT (.+) FROM storage_locations WHERE id = \\$1").
+			WithArgs(uint(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "warehouse_id", "zone", "aisle", "shelf", "bin", "code", "created_at"}).
+				AddRow(2, 1, "A", "01", "", "", "A-01", time.Now()))
+
+		err := repo.Delete(ctx, 2)
+		assert.ErrorIs(t, err, ErrLocationNotEmpty)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+)
+
+var (
+	// ErrSupplierNotFound is returned when a supplier lookup matches no row.
+	ErrSupplierNotFound = errors.New("supplier not found")
+	// ErrSupplierInUse is returned when deleting a supplier that has purchase orders.
+	ErrSupplierInUse = errors.New("supplier has purchase orders")
+)
+
+type SupplierRepository interface {
+	Create(ctx context.Context, supplier *domain.Supplier) error
+	GetByID(ctx context.Context, id uint) (*domain.Supplier, error)
+	GetAll(ctx context.Context) ([]domain.Supplier, error)
+	Update(ctx context.Context, supplier *domain.Supplier) error
+	Delete(ctx context.Context, id uint) error
+}
+
+type supplierRepo struct {
+	db *sql.DB
+}
+
+func NewSupplierRepository(db *sql.DB) SupplierRepository {
+	return &supplierRepo{db: db}
+}
+
+const supplierColumns = `id, name, email, phone, address, created_at, updated_at`
+
+func scanSupplier(row rowScanner) (*domain.Supplier, error) {
+	var s domain.Supplier
+	if err := row.Scan(&s.ID, &s.Name, &s.Email, &s.Phone, &s.Address, &s.CreatedAt, &s.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *supplierRepo) Create(ctx context.Context, supplier *domain.Supplier) error {
+	query := `
+		INSERT INTO suppliers (name, email, phone, address, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, NOW(), NOW())
+		RETURNING id, created_at, updated_at
+	`
+	err := r.db.QueryRowContext(ctx, query, supplier.Name, supplier.Email, supplier.Phone, supplier.Address).
+		Scan(&supplier.ID, &supplier.CreatedAt, &supplier.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create supplier: %w", err)
+	}
+	return nil
+}
+
+func (r *supplierRepo) GetByID(ctx context.Context, id uint) (*domain.Supplier, error) {
+	query := `SELECT ` + supplierColumns + ` FROM suppliers WHERE id = $1`
+	s, err := scanSupplier(r.db.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSupplierNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query supplier: %w", err)
+	}
+	return s, nil
+}
+
+func (r *supplierRepo) GetAll(ctx context.Context) ([]domain.Supplier, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+supplierColumns+` FROM suppliers ORDER BY name, id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query suppliers: %w", err)
+	}
+	defer rows.Close()
+
+	suppliers := []domain.Supplier{}
+	for rows.Next() {
+		s, err := scanSupplier(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan supplier: %w", err)
+		}
+		suppliers = append(suppliers, *s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating suppliers: %w", err)
+	}
+	return suppliers, nil
+}
+
+func (r *supplierRepo) Update(ctx context.Context, supplier *domain.Supplier) error {
+	query := `
+		UPDATE suppliers
+		SET name = $1, email = $2, phone = $3, address = $4, updated_at = NOW()
+		WHERE id = $5
+		RETURNING created_at, updated_at
+	`
+	err := r.db.QueryRowContext(ctx, query, supplier.Name, supplier.Email, supplier.Phone, supplier.Address, supplier.ID).
+		Scan(&supplier.CreatedAt, &supplier.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrSupplierNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update supplier: %w", err)
+	}
+	return nil
+}
+
+func (r *supplierRepo) Delete(ctx context.Context, id uint) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM suppliers WHERE id = $1`, id)
+	if isForeignKeyViolation(err) {
+		return ErrSupplierInUse
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete supplier: %w", err)
+	}
+	return expectAffected(res, ErrSupplierNotFound)
+}
@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrVersionConflict is returned when an update carries a version that no
+// longer matches the stored row, i.e. someone else changed it first.
+var ErrVersionConflict = errors.New("version conflict: the record was changed by someone else")
+
+// conflictOrNotFound explains why an update guarded by
+// "id = $n AND ($m = 0 OR version = $m)" matched no row.
+func conflictOrNotFound(ctx context.Context, q queryer, table string, id uint, notFound error) error {
+	var exists bool
+	err := q.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM `+table+` WHERE id = $1)`, id).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check %s: %w", table, err)
+	}
+	if exists {
+		return ErrVersionConflict
+	}
+	return notFound
+}
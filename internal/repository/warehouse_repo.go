@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"math"
 
 	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
 )
@@ -15,6 +16,9 @@ type WarehouseRepository interface {
 	Delete(ctx context.Context, id int64) error
 	GetAll(ctx context.Context) ([]domain.Warehouse, error)
 	GetById(ctx context.Context, id uint) (*domain.Warehouse, error)
+	// Utilisation returns the weight and volume of the warehouse's stock
+	// against its capacity.
+	Utilisation(ctx context.Context, warehouse *domain.Warehouse) (*domain.WarehouseUtilisation, error)
 }
 
 type warehouseRepo struct {
@@ -27,9 +31,12 @@ func NewWarehouseRepository(db *sql.DB) WarehouseRepository {
 
 // Create implements WarehouseRepository.
 func (w *warehouseRepo) Create(ctx context.Context, warehouse *domain.Warehouse) error {
-	query := `INSERT INTO warehouses(name, location)
-			VALUES($1, $2) RETURNING id`
-	return w.db.QueryRowContext(ctx, query, warehouse.Name, warehouse.Location).Scan(&warehouse.ID)
+	query := `INSERT INTO warehouses(name, location, shipping_base_cost, shipping_cost_per_kg, latitude, longitude,
+			max_weight_kg, max_volume_m3, capacity_policy)
+			VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id, version`
+	return w.db.QueryRowContext(ctx, query, warehouse.Name, warehouse.Location,
+		warehouse.ShippingBaseCost, warehouse.ShippingCostPerKg, warehouse.Latitude, warehouse.Longitude,
+		warehouse.MaxWeightKg, warehouse.MaxVolumeM3, warehouse.CapacityPolicy).Scan(&warehouse.ID, &warehouse.Version)
 
 }
 
@@ -52,7 +59,8 @@ func (w *warehouseRepo) Delete(ctx context.Context, id int64) error {
 
 // GetAll implements WarehouseRepository.
 func (w *warehouseRepo) GetAll(ctx context.Context) ([]domain.Warehouse, error) {
-	query := `SELECT id, name, location from warehouses ORDER BY id asc`
+	query := `SELECT id, name, location, shipping_base_cost, shipping_cost_per_kg, latitude, longitude,
+			max_weight_kg, max_volume_m3, capacity_policy, version from warehouses ORDER BY id asc`
 	rows, err := w.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query warehouses: %w", err)
@@ -60,7 +68,9 @@ func (w *warehouseRepo) GetAll(ctx context.Context) ([]domain.Warehouse, error)
 	var warehouses []domain.Warehouse
 	for rows.Next() {
 		var warehouse domain.Warehouse
-		if err := rows.Scan(&warehouse.ID, &warehouse.Name, &warehouse.Location); err != nil {
+		if err := rows.Scan(&warehouse.ID, &warehouse.Name, &warehouse.Location,
+			&warehouse.ShippingBaseCost, &warehouse.ShippingCostPerKg, &warehouse.Latitude, &warehouse.Longitude,
+			&warehouse.MaxWeightKg, &warehouse.MaxVolumeM3, &warehouse.CapacityPolicy, &warehouse.Version); err != nil {
 			return nil, fmt.Errorf("failed to scan warehouses: %w", err)
 		}
 		warehouses = append(warehouses, warehouse)
@@ -72,29 +82,37 @@ func (w *warehouseRepo) GetAll(ctx context.Context) ([]domain.Warehouse, error)
 	return warehouses, nil
 }
 
-// Update implements WarehouseRepository.
+// Update implements WarehouseRepository. A non-zero warehouse.Version must
+// match the stored version (ErrVersionConflict); on success it holds the new
+// version.
 func (w *warehouseRepo) Update(ctx context.Context, warehouse *domain.Warehouse) error {
-	query := `UPDATE warehouses SET name = $1, location = $2 WHERE id = $3`
-	res, err := w.db.ExecContext(ctx, query, warehouse.Name, warehouse.Location, warehouse.ID)
-	if err != nil {
-		return fmt.Errorf("failed to update wareHouse : %w", err)
+	query := `UPDATE warehouses SET name = $1, location = $2, shipping_base_cost = $3, shipping_cost_per_kg = $4,
+			latitude = $5, longitude = $6, max_weight_kg = $7, max_volume_m3 = $8, capacity_policy = $9,
+			version = version + 1, updated_at = NOW()
+			WHERE id = $10 AND ($11 = 0 OR version = $11)
+			RETURNING version`
+	err := w.db.QueryRowContext(ctx, query, warehouse.Name, warehouse.Location,
+		warehouse.ShippingBaseCost, warehouse.ShippingCostPerKg, warehouse.Latitude, warehouse.Longitude,
+		warehouse.MaxWeightKg, warehouse.MaxVolumeM3, warehouse.CapacityPolicy,
+		warehouse.ID, warehouse.Version).Scan(&warehouse.Version)
+	if err == sql.ErrNoRows {
+		return conflictOrNotFound(ctx, w.db, "warehouses", warehouse.ID, errors.New("warehouse not found"))
 	}
-	rowAffected, err := res.RowsAffected()
 	if err != nil {
-		return fmt.Errorf("could not check effected rows : %w", err)
-	}
-	if rowAffected == 0 {
-		return errors.New("warehouse not found")
+		return fmt.Errorf("failed to update wareHouse : %w", err)
 	}
 	return nil
 }
 
 func (w *warehouseRepo) GetById(ctx context.Context, id uint) (*domain.Warehouse, error) {
-	query := `SELECT id, name, location FROM warehouses WHERE id = $1`
+	query := `SELECT id, name, location, shipping_base_cost, shipping_cost_per_kg, latitude, longitude,
+			max_weight_kg, max_volume_m3, capacity_policy, version FROM warehouses WHERE id = $1`
 	row := w.db.QueryRowContext(ctx, query, id)
 
 	var warehouse domain.Warehouse
-	err := row.Scan(&warehouse.ID, &warehouse.Name, &warehouse.Location)
+	err := row.Scan(&warehouse.ID, &warehouse.Name, &warehouse.Location,
+		&warehouse.ShippingBaseCost, &warehouse.ShippingCostPerKg, &warehouse.Latitude, &warehouse.Longitude,
+		&warehouse.MaxWeightKg, &warehouse.MaxVolumeM3, &warehouse.CapacityPolicy, &warehouse.Version)
 	if err == sql.ErrNoRows {
 		return nil, errors.New("id warehouse not found")
 	}
@@ -103,3 +121,41 @@ func (w *warehouseRepo) GetById(ctx context.Context, id uint) (*domain.Warehouse
 	}
 	return &warehouse, nil
 }
+
+func (w *warehouseRepo) Utilisation(ctx context.Context, warehouse *domain.Warehouse) (*domain.WarehouseUtilisation, error) {
+	weight, volume, err := warehouseLoad(ctx, w.db, warehouse.ID)
+	if err != nil {
+		return nil, err
+	}
+	u := &domain.WarehouseUtilisation{
+		WeightKg:    math.Round(weight*100) / 100,
+		MaxWeightKg: warehouse.MaxWeightKg,
+		VolumeM3:    math.Round(volume*1000) / 1000,
+		MaxVolumeM3: warehouse.MaxVolumeM3,
+	}
+	if m := warehouse.MaxWeightKg; m != nil {
+		p := math.Round(weight / *m * 10000) / 100
+		u.WeightPercent = &p
+	}
+	if m := warehouse.MaxVolumeM3; m != nil {
+		p := math.Round(volume / *m * 10000) / 100
+		u.VolumePercent = &p
+	}
+	return u, nil
+}
+
+// warehouseLoad sums the weight (kg) and volume (m3) of the stock held in a
+// warehouse. Products without a weight or volume count as zero.
+func warehouseLoad(ctx context.Context, q queryer, warehouseID uint) (float64, float64, error) {
+	var weight, volume float64
+	err := q.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(s.quantity * p.weight), 0), COALESCE(SUM(s.quantity * p.volume), 0)
+		FROM warehouse_stock s
+		JOIN products p ON p.id = s.product_id
+		WHERE s.warehouse_id = $1
+	`, warehouseID).Scan(&weight, &volume)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to sum warehouse load: %w", err)
+	}
+	return weight, volume, nil
+}
@@ -23,8 +23,8 @@ func TestWarehouseRepository_Create(t *testing.T) {
 
 	// Mock behavior
 	mock.ExpectQuery("INSERT INTO warehouses").
-		WithArgs(warehouse.Name, warehouse.Location).
-		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		WithArgs(warehouse.Name, warehouse.Location, warehouse.ShippingBaseCost, warehouse.ShippingCostPerKg, nil, nil, nil, nil, "").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "version"}).AddRow(1, 1))
 
 	err := repo.Create(ctx, warehouse)
 	assert.NoError(t, err)
@@ -38,17 +38,21 @@ func TestWarehouseRepository_GetAll(t *testing.T) {
 	repo := NewWarehouseRepository(db)
 	ctx := context.Background()
 
-	rows := sqlmock.NewRows([]string{"id", "name", "location"}).
-		AddRow(1, "Warehouse A", "Jakarta").
-		AddRow(2, "Warehouse B", "Bandung")
+	rows := sqlmock.NewRows([]string{"id", "name", "location", "shipping_base_cost", "shipping_cost_per_kg", "latitude", "longitude", "max_weight_kg", "max_volume_m3", "capacity_policy", "version"}).
+		AddRow(1, "Warehouse A", "Jakarta", 10000, 2000, -6.2, 106.8, 5000, nil, "reject", 1).
+		AddRow(2, "Warehouse B", "Bandung", 8000, 2500, nil, nil, nil, nil, "warn", 1)
 
-	mock.ExpectQuery("SELECT id, name, location from warehouses").
+	mock.ExpectQuery("SELECT id, name, location, (.+) from warehouses").
 		WillReturnRows(rows)
 
 	result, err := repo.GetAll(ctx)
 	assert.NoError(t, err)
 	assert.Len(t, result, 2)
 	assert.Equal(t, "Warehouse B", result[1].Name)
+	assert.Nil(t, result[1].Latitude)
+	assert.Equal(t, 5000.0, *result[0].MaxWeightKg)
+	assert.Nil(t, result[1].MaxWeightKg)
+	assert.Equal(t, domain.CapacityPolicyWarn, result[1].CapacityPolicy)
 }
 
 func TestWarehouseRepository_GetById(t *testing.T) {
@@ -58,16 +62,20 @@ func TestWarehouseRepository_GetById(t *testing.T) {
 	repo := NewWarehouseRepository(db)
 	ctx := context.Background()
 
-	rows := sqlmock.NewRows([]string{"id", "name", "location"}).
-		AddRow(1, "Warehouse A", "Jakarta")
+	rows := sqlmock.NewRows([]string{"id", "name", "location", "shipping_base_cost", "shipping_cost_per_kg", "latitude", "longitude", "max_weight_kg", "max_volume_m3", "capacity_policy", "version"}).
+		AddRow(1, "Warehouse A", "Jakarta", 10000, 2000, -6.2, 106.8, nil, nil, "reject", 1)
 
-	mock.ExpectQuery("SELECT id, name, location FROM warehouses WHERE id =").
+	mock.ExpectQuery("SELECT id, name, location, (.+) FROM warehouses WHERE id =").
 		WithArgs(1).
 		WillReturnRows(rows)
 
 	w, err := repo.GetById(ctx, 1)
 	assert.NoError(t, err)
 	assert.Equal(t, "Warehouse A", w.Name)
+	assert.Equal(t, 2000.0, w.ShippingCostPerKg)
+	point, ok := w.Point()
+	assert.True(t, ok)
+	assert.Equal(t, -6.2, point.Latitude)
 }
 
 func TestWarehouseRepository_Update(t *testing.T) {
@@ -83,12 +91,39 @@ func TestWarehouseRepository_Update(t *testing.T) {
 		Location: "Jakarta",
 	}
 
-	mock.ExpectExec("UPDATE warehouses SET name =").
-		WithArgs(warehouse.Name, warehouse.Location, warehouse.ID).
-		WillReturnResult(sqlmock.NewResult(0, 1)) // 1 row affected
+	mock.ExpectQuery("UPDATE warehouses SET name =").
+		WithArgs(warehouse.Name, warehouse.Location, warehouse.ShippingBaseCost, warehouse.ShippingCostPerKg, nil, nil, nil, nil, "", warehouse.ID, int32(0)).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(2))
 
 	err := repo.Update(ctx, warehouse)
 	assert.NoError(t, err)
+	assert.Equal(t, int32(2), warehouse.Version)
+}
+
+func TestWarehouseRepository_Update_VersionConflict(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := NewWarehouseRepository(db)
+	ctx := context.Background()
+
+	warehouse := &domain.Warehouse{
+		ID:       1,
+		Name:     "Warehouse Updated",
+		Location: "Jakarta",
+		Version:  3,
+	}
+
+	mock.ExpectQuery("UPDATE warehouses SET name =").
+		WithArgs(warehouse.Name, warehouse.Location, warehouse.ShippingBaseCost, warehouse.ShippingCostPerKg, nil, nil, nil, nil, "", warehouse.ID, int32(3)).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}))
+	mock.ExpectQuery("SELECT EXISTS").
+		WithArgs(uint(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	err := repo.Update(ctx, warehouse)
+	assert.ErrorIs(t, err, ErrVersionConflict)
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestWarehouseRepository_Delete(t *testing.T) {
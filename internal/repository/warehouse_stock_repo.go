@@ -7,15 +7,76 @@ import (
 	"fmt"
 
 	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/lib/pq"
 )
 
+var (
+	// ErrWarehouseStockNotFound is returned when no stock row exists for a warehouse/product pair.
+	ErrWarehouseStockNotFound = errors.New("warehouse stock not found")
+	// ErrInsufficientStock is returned when a change would make a quantity negative.
+	ErrInsufficientStock = errors.New("not enough stock")
+	// ErrStockChanged is returned by Stocktake when the quantity no longer
+	// matches the value the count was based on.
+	ErrStockChanged = errors.New("stock quantity changed since it was read")
+	// ErrCapacityExceeded is returned when an increase would take a warehouse
+	// with the reject policy over its weight or volume capacity.
+	ErrCapacityExceeded = errors.New("warehouse capacity exceeded")
+)
+
+// WarehouseStockRepository manages warehouse_stock. Every change to a quantity
+// locks the row, updates it and appends a stock_movements row in one
+// transaction (see applyStockChange). Product stock is summed from these rows
+// on read, so no product row is locked. Only RebuildFromLedger writes quantities
+// without a movement, because it copies them from the ledger.
 type WarehouseStockRepository interface {
-	Create(ctx context.Context, stock *domain.WarehouseStock) error
+	// Create upserts the row of stock's warehouse/product (see the method).
+	Create(ctx context.Context, stock *domain.WarehouseStock, mode domain.StockUpsertMode, ref domain.StockReference) (bool, error)
 	GetAll(ctx context.Context) ([]domain.WarehouseStock, error)
 	GetByWarehouseID(ctx context.Context, warehouseID uint) ([]domain.WarehouseStock, error)
-	UpdateQuantity(ctx context.Context, warehouseID uint, productID uint, quantity int32) error
-	Delete(ctx context.Context, stockID uint) error
-	SafeDecreaseQuantity(ctx context.Context, tx *sql.Tx, warehouseID uint, productID uint, qtyToDecrease int32) error
+	GetByWarehouseAndProduct(ctx context.Context, warehouseID uint, productID uint) (*domain.WarehouseStock, error)
+	GetByProductID(ctx context.Context, productID uint) ([]domain.WarehouseStock, error)
+	// GetAvailability returns the on-hand and reserved quantity of a product in
+	// every warehouse that has a stock row for it. Reserved is what draft
+	// transfers from that warehouse will take once they are dispatched.
+	GetAvailability(ctx context.Context, productID uint) ([]domain.StockAvailability, error)
+	// Stocktake sets an absolute quantity, but only if the row still matches
+	// expect: a different quantity fails with ErrStockChanged, a different
+	// version with ErrVersionConflict.
+	Stocktake(ctx context.Context, warehouseID uint, productID uint, counted int32, expect domain.StockExpectation, ref domain.StockReference) (*domain.StockChangeResult, error)
+	Delete(ctx context.Context, stockID uint, ref domain.StockReference) error
+	// SetReorderPoint changes the low-stock threshold (nil removes it) and
+	// raises or resolves the alert for the current quantity. A non-zero
+	// version must match the row (ErrVersionConflict).
+	SetReorderPoint(ctx context.Context, warehouseID uint, productID uint, reorderPoint *int32, version int32) (*domain.WarehouseStock, error)
+	SafeDecreaseQuantity(ctx context.Context, tx *sql.Tx, warehouseID uint, productID uint, qtyToDecrease int32, ref domain.StockReference) (*domain.StockChangeResult, error)
+
+	// ApplyChange applies a relative change in its own transaction.
+	ApplyChange(ctx context.Context, change domain.StockChange) (*domain.StockChangeResult, error)
+	// ApplyChangeTx applies a relative change inside the caller's transaction.
+	ApplyChangeTx(ctx context.Context, tx *sql.Tx, change domain.StockChange) (*domain.StockChangeResult, error)
+	// LockCapacityTx locks the given warehouses that enforce a capacity under
+	// the reject policy, in id order. A transaction that changes several rows
+	// calls it before touching any of them (see lockCapacityTx).
+	LockCapacityTx(ctx context.Context, tx *sql.Tx, warehouseIDs []uint) error
+	// EnsureStockRowTx creates an empty stock row for the pair if none exists,
+	// so stock can be received into a warehouse that never held the product.
+	EnsureStockRowTx(ctx context.Context, tx *sql.Tx, warehouseID uint, productID uint) error
+
+	ListMovements(ctx context.Context, filter domain.StockMovementFilter) ([]domain.StockMovement, error)
+	// BeginTx starts a transaction for the *Tx methods.
+	BeginTx(ctx context.Context) (*sql.Tx, error)
+	// SetQuantityTx locks an existing row and sets its quantity, recording the
+	// difference as a movement.
+	SetQuantityTx(ctx context.Context, tx *sql.Tx, warehouseID uint, productID uint, quantity int32, ref domain.StockReference) (*domain.StockChangeResult, error)
+	// GetQuantities returns the current quantity of every given row that exists.
+	GetQuantities(ctx context.Context, keys []domain.StockKey) (map[domain.StockKey]int32, error)
+	// Export calls fn for every stock row, ordered by warehouse and product,
+	// without loading them all into memory. warehouseID 0 exports all warehouses.
+	Export(ctx context.Context, warehouseID uint, fn func(domain.StockExportRow) error) error
+	// RebuildFromLedger compares every quantity with the sum of its movements.
+	// With apply set, the quantities are overwritten with the ledger values and
+	// low-stock alerts raised or resolved as a stock change would.
+	RebuildFromLedger(ctx context.Context, apply bool) ([]domain.StockDiscrepancy, error)
 }
 
 type warehouseStockRepo struct {
@@ -26,21 +87,106 @@ func NewWarehouseStockRepository(db *sql.DB) WarehouseStockRepository {
 	return &warehouseStockRepo{db: db}
 }
 
-func (r *warehouseStockRepo) Create(ctx context.Context, stock *domain.WarehouseStock) error {
+// Create inserts the stock row of a warehouse/product or, when the warehouse
+// already holds the product, changes that row: StockUpsertAdd adds
+// stock.Quantity to it, StockUpsertReplace sets it. A reorder point, if given,
+// replaces the existing one. The quantity difference is recorded as a
+// movement. stock is filled with the resulting row; created reports whether
+// a new row was inserted.
+func (r *warehouseStockRepo) Create(ctx context.Context, stock *domain.WarehouseStock, mode domain.StockUpsertMode, ref domain.StockReference) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockCapacityTx(ctx, tx, stock.WarehouseID); err != nil {
+		return false, err
+	}
+
+	created := true
 	query := `
-		INSERT INTO warehouse_stock (warehouse_id, product_id, quantity, created_at, updated_at)
-		VALUES ($1, $2, $3, NOW(), NOW())
+		INSERT INTO warehouse_stock (warehouse_id, product_id, quantity, reorder_point, created_at, updated_at)
+		VALUES ($1, $2, 0, $3, NOW(), NOW())
+		ON CONFLICT (warehouse_id, product_id) DO NOTHING
 		RETURNING id
 	`
-	err := r.db.QueryRowContext(ctx, query, stock.WarehouseID, stock.ProductID, stock.Quantity).Scan(&stock.ID)
+	err = tx.QueryRowContext(ctx, query, stock.WarehouseID, stock.ProductID, stock.ReorderPoint).Scan(&stock.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		created = false
+	} else if err != nil {
+		return false, fmt.Errorf("failed to create warehouse stock: %w", err)
+	}
+
+	current, err := lockStock(ctx, tx, stock.WarehouseID, stock.ProductID)
 	if err != nil {
-		return fmt.Errorf("failed to create warehouse stock: %w", err)
+		return false, err
 	}
-	return nil
+
+	reorderChanged := false
+	rp := stock.ReorderPoint
+	if !created && rp != nil && (current.ReorderPoint == nil || *current.ReorderPoint != *rp) {
+		queryRP := `UPDATE warehouse_stock SET reorder_point = $1, version = version + 1, updated_at = NOW() WHERE warehouse_id = $2 AND product_id = $3`
+		if _, err := tx.ExecContext(ctx, queryRP, rp, stock.WarehouseID, stock.ProductID); err != nil {
+			return false, fmt.Errorf("failed to update reorder point: %w", err)
+		}
+		current.ReorderPoint = rp
+		current.Version++
+		reorderChanged = true
+	}
+
+	delta := stock.Quantity
+	if mode == domain.StockUpsertReplace {
+		delta = stock.Quantity - current.Quantity
+	}
+	if ref.Reason == "" {
+		ref.Reason = domain.MovementReasonRestock
+		if delta < 0 {
+			ref.Reason = domain.MovementReasonAdjustment
+		}
+	}
+	result, err := applyLockedChange(ctx, tx, domain.StockChange{
+		WarehouseID:    stock.WarehouseID,
+		ProductID:      stock.ProductID,
+		Delta:          delta,
+		StockReference: ref,
+	}, current)
+	if err != nil {
+		return false, err
+	}
+	stock.CapacityWarning = result.CapacityWarning
+
+	// applyLockedChange only reacts when the quantity crosses the reorder
+	// point; a new row or a new reorder point that leaves the quantity on the
+	// same side is checked here.
+	if rp != nil && (created || reorderChanged) {
+		before, after := result.QuantityBefore, result.QuantityAfter
+		switch {
+		case before <= *rp && after <= *rp:
+			err = raiseStockAlertTx(ctx, tx, stock.WarehouseID, stock.ProductID, *rp, after)
+		case before > *rp && after > *rp && reorderChanged:
+			err = resolveStockAlertsTx(ctx, tx, stock.WarehouseID, stock.ProductID)
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+
+	querySelect := `SELECT id, warehouse_id, product_id, quantity, reorder_point, version, created_at, updated_at FROM warehouse_stock WHERE warehouse_id = $1 AND product_id = $2`
+	err = tx.QueryRowContext(ctx, querySelect, stock.WarehouseID, stock.ProductID).
+		Scan(&stock.ID, &stock.WarehouseID, &stock.ProductID, &stock.Quantity, &stock.ReorderPoint, &stock.Version, &stock.CreatedAt, &stock.UpdatedAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to query warehouse stock: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit warehouse stock: %w", err)
+	}
+	return created, nil
 }
 
 func (r *warehouseStockRepo) GetAll(ctx context.Context) ([]domain.WarehouseStock, error) {
-	query := `SELECT id, warehouse_id, product_id, quantity, created_at, updated_at FROM warehouse_stock`
+	query := `SELECT id, warehouse_id, product_id, quantity, reorder_point, version, created_at, updated_at FROM warehouse_stock`
 	rows, err := r.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query warehouse stocks: %w", err)
@@ -50,7 +196,7 @@ func (r *warehouseStockRepo) GetAll(ctx context.Context) ([]domain.WarehouseStoc
 	var stocks []domain.WarehouseStock
 	for rows.Next() {
 		var s domain.WarehouseStock
-		if err := rows.Scan(&s.ID, &s.WarehouseID, &s.ProductID, &s.Quantity, &s.CreatedAt, &s.UpdatedAt); err != nil {
+		if err := rows.Scan(&s.ID, &s.WarehouseID, &s.ProductID, &s.Quantity, &s.ReorderPoint, &s.Version, &s.CreatedAt, &s.UpdatedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan warehouse stock: %w", err)
 		}
 		stocks = append(stocks, s)
@@ -62,7 +208,7 @@ func (r *warehouseStockRepo) GetAll(ctx context.Context) ([]domain.WarehouseStoc
 }
 
 func (r *warehouseStockRepo) GetByWarehouseID(ctx context.Context, warehouseID uint) ([]domain.WarehouseStock, error) {
-	query := `SELECT id, warehouse_id, product_id, quantity, created_at, updated_at FROM warehouse_stock WHERE warehouse_id = $1`
+	query := `SELECT id, warehouse_id, product_id, quantity, reorder_point, version, created_at, updated_at FROM warehouse_stock WHERE warehouse_id = $1`
 	rows, err := r.db.QueryContext(ctx, query, warehouseID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query warehouse stock by warehouse_id: %w", err)
@@ -72,7 +218,7 @@ func (r *warehouseStockRepo) GetByWarehouseID(ctx context.Context, warehouseID u
 	var stocks []domain.WarehouseStock
 	for rows.Next() {
 		var s domain.WarehouseStock
-		if err := rows.Scan(&s.ID, &s.WarehouseID, &s.ProductID, &s.Quantity, &s.CreatedAt, &s.UpdatedAt); err != nil {
+		if err := rows.Scan(&s.ID, &s.WarehouseID, &s.ProductID, &s.Quantity, &s.ReorderPoint, &s.Version, &s.CreatedAt, &s.UpdatedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan warehouse stock: %w", err)
 		}
 		stocks = append(stocks, s)
@@ -83,56 +229,708 @@ func (r *warehouseStockRepo) GetByWarehouseID(ctx context.Context, warehouseID u
 	return stocks, nil
 }
 
-func (r *warehouseStockRepo) UpdateQuantity(ctx context.Context, warehouseID uint, productID uint, quantity int32) error {
-	query := `UPDATE warehouse_stock SET quantity = $1, updated_at = NOW() WHERE warehouse_id = $2 and product_id =$3`
-	result, err := r.db.ExecContext(ctx, query, quantity, warehouseID, productID)
+func (r *warehouseStockRepo) GetByWarehouseAndProduct(ctx context.Context, warehouseID uint, productID uint) (*domain.WarehouseStock, error) {
+	query := `SELECT id, warehouse_id, product_id, quantity, reorder_point, version, created_at, updated_at FROM warehouse_stock WHERE warehouse_id = $1 AND product_id = $2`
+	var s domain.WarehouseStock
+	err := r.db.QueryRowContext(ctx, query, warehouseID, productID).Scan(&s.ID, &s.WarehouseID, &s.ProductID, &s.Quantity, &s.ReorderPoint, &s.Version, &s.CreatedAt, &s.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWarehouseStockNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query warehouse stock: %w", err)
+	}
+	return &s, nil
+}
 
+// GetByProductID returns the stock of one product in every warehouse that holds it.
+func (r *warehouseStockRepo) GetByProductID(ctx context.Context, productID uint) ([]domain.WarehouseStock, error) {
+	query := `SELECT id, warehouse_id, product_id, quantity, reorder_point, version, created_at, updated_at FROM warehouse_stock WHERE product_id = $1 ORDER BY warehouse_id`
+	rows, err := r.db.QueryContext(ctx, query, productID)
 	if err != nil {
-		return fmt.Errorf("failed to update warehouse stock quantity: %w", err)
+		return nil, fmt.Errorf("failed to query warehouse stock by product_id: %w", err)
+	}
+	defer rows.Close()
+
+	var stocks []domain.WarehouseStock
+	for rows.Next() {
+		var s domain.WarehouseStock
+		if err := rows.Scan(&s.ID, &s.WarehouseID, &s.ProductID, &s.Quantity, &s.ReorderPoint, &s.Version, &s.CreatedAt, &s.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan warehouse stock: %w", err)
+		}
+		stocks = append(stocks, s)
 	}
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
-		return fmt.Errorf("no warehouse stock updated for warehouse_id=%d product_id=%d", warehouseID, productID)
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating warehouse stock rows: %w", err)
 	}
+	return stocks, nil
+}
 
-	return nil
+func (r *warehouseStockRepo) GetAvailability(ctx context.Context, productID uint) ([]domain.StockAvailability, error) {
+	query := `
+		SELECT s.warehouse_id, w.name, w.location, s.quantity, COALESCE(d.reserved, 0)
+		FROM warehouse_stock s
+		JOIN warehouses w ON w.id = s.warehouse_id
+		LEFT JOIN (
+			SELECT t.source_warehouse_id, SUM(l.quantity) AS reserved
+			FROM stock_transfers t
+			JOIN stock_transfer_lines l ON l.transfer_id = t.id
+			WHERE t.status = 'draft' AND l.product_id = $1
+			GROUP BY t.source_warehouse_id
+		) d ON d.source_warehouse_id = s.warehouse_id
+		WHERE s.product_id = $1
+		ORDER BY s.warehouse_id
+	`
+	rows, err := r.db.QueryContext(ctx, query, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stock availability: %w", err)
+	}
+	defer rows.Close()
+
+	availability := []domain.StockAvailability{}
+	for rows.Next() {
+		var a domain.StockAvailability
+		if err := rows.Scan(&a.WarehouseID, &a.WarehouseName, &a.Location, &a.OnHand, &a.Reserved); err != nil {
+			return nil, fmt.Errorf("failed to scan stock availability: %w", err)
+		}
+		a.Available = max(a.OnHand-a.Reserved, 0)
+		availability = append(availability, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating stock availability: %w", err)
+	}
+	return availability, nil
 }
 
-func (r *warehouseStockRepo) Delete(ctx context.Context, stockID uint) error {
-	query := `DELETE FROM warehouse_stock WHERE id = $1`
-	result, err := r.db.ExecContext(ctx, query, stockID)
+func (r *warehouseStockRepo) Stocktake(ctx context.Context, warehouseID uint, productID uint, counted int32, expect domain.StockExpectation, ref domain.StockReference) (*domain.StockChangeResult, error) {
+	if counted < 0 {
+		return nil, fmt.Errorf("quantity cannot be negative")
+	}
+	if ref.Reason == "" {
+		ref.Reason = domain.MovementReasonAdjustment
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("failed to delete warehouse stock: %w", err)
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockCapacityTx(ctx, tx, warehouseID); err != nil {
+		return nil, err
+	}
+	current, err := lockStock(ctx, tx, warehouseID, productID)
+	if err != nil {
+		return nil, err
+	}
+	unchanged := &domain.StockChangeResult{
+		WarehouseID:    warehouseID,
+		ProductID:      productID,
+		QuantityBefore: current.Quantity,
+		QuantityAfter:  current.Quantity,
+		Version:        current.Version,
+	}
+	if expect.Version != 0 && current.Version != expect.Version {
+		return unchanged, fmt.Errorf("%w: expected version %d, current %d", ErrVersionConflict, expect.Version, current.Version)
 	}
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
+	if expect.Quantity != nil && current.Quantity != *expect.Quantity {
+		return unchanged, fmt.Errorf("%w: expected %d, current %d", ErrStockChanged, *expect.Quantity, current.Quantity)
+	}
+
+	result, err := applyLockedChange(ctx, tx, domain.StockChange{
+		WarehouseID:    warehouseID,
+		ProductID:      productID,
+		Delta:          counted - current.Quantity,
+		StockReference: ref,
+	}, current)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit stocktake: %w", err)
+	}
+	return result, nil
+}
+
+func (r *warehouseStockRepo) SetReorderPoint(ctx context.Context, warehouseID uint, productID uint, reorderPoint *int32, version int32) (*domain.WarehouseStock, error) {
+	if reorderPoint != nil && *reorderPoint < 0 {
+		return nil, fmt.Errorf("reorder point cannot be negative")
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	current, err := lockStock(ctx, tx, warehouseID, productID)
+	if err != nil {
+		return nil, err
+	}
+	if version != 0 && current.Version != version {
+		return nil, fmt.Errorf("%w: expected version %d, current %d", ErrVersionConflict, version, current.Version)
+	}
+
+	var s domain.WarehouseStock
+	query := `
+		UPDATE warehouse_stock SET reorder_point = $1, version = version + 1, updated_at = NOW()
+		WHERE warehouse_id = $2 AND product_id = $3
+		RETURNING id, warehouse_id, product_id, quantity, reorder_point, version, created_at, updated_at
+	`
+	err = tx.QueryRowContext(ctx, query, reorderPoint, warehouseID, productID).
+		Scan(&s.ID, &s.WarehouseID, &s.ProductID, &s.Quantity, &s.ReorderPoint, &s.Version, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update reorder point: %w", err)
+	}
+
+	if reorderPoint != nil && current.Quantity <= *reorderPoint {
+		err = raiseStockAlertTx(ctx, tx, warehouseID, productID, *reorderPoint, current.Quantity)
+	} else {
+		err = resolveStockAlertsTx(ctx, tx, warehouseID, productID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit reorder point: %w", err)
+	}
+	return &s, nil
+}
+
+// Delete removes a stock row. A remaining quantity is written off in the
+// ledger first, so rebuilding does not bring it back.
+func (r *warehouseStockRepo) Delete(ctx context.Context, stockID uint, ref domain.StockReference) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var warehouseID, productID uint
+	var quantity int32
+	err = tx.QueryRowContext(ctx, `SELECT warehouse_id, product_id, quantity FROM warehouse_stock WHERE id = $1 FOR UPDATE`, stockID).
+		Scan(&warehouseID, &productID, &quantity)
+	if errors.Is(err, sql.ErrNoRows) {
 		return errors.New("no warehouse stock deleted")
 	}
+	if err != nil {
+		return fmt.Errorf("failed to delete warehouse stock: %w", err)
+	}
+
+	if quantity != 0 {
+		if ref.Reason == "" {
+			ref.Reason = domain.MovementReasonAdjustment
+		}
+		change := domain.StockChange{
+			WarehouseID:    warehouseID,
+			ProductID:      productID,
+			Delta:          -quantity,
+			StockReference: ref,
+		}
+		if _, err := insertMovement(ctx, tx, change, 0); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM warehouse_stock WHERE id = $1`, stockID); err != nil {
+		return fmt.Errorf("failed to delete warehouse stock: %w", err)
+	}
+	if err := resolveStockAlertsTx(ctx, tx, warehouseID, productID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SafeDecreaseQuantity takes qtyToDecrease units out of stock inside tx and
+// fails with ErrInsufficientStock instead of going negative.
+func (r *warehouseStockRepo) SafeDecreaseQuantity(ctx context.Context, tx *sql.Tx, warehouseID uint, productID uint, qtyToDecrease int32, ref domain.StockReference) (*domain.StockChangeResult, error) {
+	if qtyToDecrease <= 0 {
+		return nil, fmt.Errorf("quantity to decrease must be greater than zero")
+	}
+	if ref.Reason == "" {
+		ref.Reason = domain.MovementReasonSale
+	}
+	return applyStockChange(ctx, tx, domain.StockChange{
+		WarehouseID:    warehouseID,
+		ProductID:      productID,
+		Delta:          -qtyToDecrease,
+		StockReference: ref,
+	})
+}
+
+func (r *warehouseStockRepo) ApplyChange(ctx context.Context, change domain.StockChange) (*domain.StockChangeResult, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := applyStockChange(ctx, tx, change)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit stock change: %w", err)
+	}
+	return result, nil
+}
+
+func (r *warehouseStockRepo) ApplyChangeTx(ctx context.Context, tx *sql.Tx, change domain.StockChange) (*domain.StockChangeResult, error) {
+	return applyStockChange(ctx, tx, change)
+}
+
+func (r *warehouseStockRepo) LockCapacityTx(ctx context.Context, tx *sql.Tx, warehouseIDs []uint) error {
+	return lockCapacityTx(ctx, tx, warehouseIDs...)
+}
+
+func (r *warehouseStockRepo) BeginTx(ctx context.Context) (*sql.Tx, error) {
+	return r.db.BeginTx(ctx, nil)
+}
+
+func (r *warehouseStockRepo) SetQuantityTx(ctx context.Context, tx *sql.Tx, warehouseID uint, productID uint, quantity int32, ref domain.StockReference) (*domain.StockChangeResult, error) {
+	if quantity < 0 {
+		return nil, fmt.Errorf("quantity cannot be negative")
+	}
+	if ref.Reason == "" {
+		ref.Reason = domain.MovementReasonAdjustment
+	}
+
+	if err := lockCapacityTx(ctx, tx, warehouseID); err != nil {
+		return nil, err
+	}
+	current, err := lockStock(ctx, tx, warehouseID, productID)
+	if err != nil {
+		return nil, err
+	}
+	return applyLockedChange(ctx, tx, domain.StockChange{
+		WarehouseID:    warehouseID,
+		ProductID:      productID,
+		Delta:          quantity - current.Quantity,
+		StockReference: ref,
+	}, current)
+}
+
+func (r *warehouseStockRepo) GetQuantities(ctx context.Context, keys []domain.StockKey) (map[domain.StockKey]int32, error) {
+	quantities := make(map[domain.StockKey]int32, len(keys))
+	if len(keys) == 0 {
+		return quantities, nil
+	}
+
+	warehouseIDs := make([]int64, len(keys))
+	productIDs := make([]int64, len(keys))
+	for i, k := range keys {
+		warehouseIDs[i] = int64(k.WarehouseID)
+		productIDs[i] = int64(k.ProductID)
+	}
+
+	query := `
+		SELECT s.warehouse_id, s.product_id, s.quantity
+		FROM warehouse_stock s
+		JOIN unnest($1::bigint[], $2::bigint[]) AS k(warehouse_id, product_id)
+		  ON k.warehouse_id = s.warehouse_id AND k.product_id = s.product_id
+	`
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(warehouseIDs), pq.Array(productIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stock quantities: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var k domain.StockKey
+		var qty int32
+		if err := rows.Scan(&k.WarehouseID, &k.ProductID, &qty); err != nil {
+			return nil, fmt.Errorf("failed to scan stock quantity: %w", err)
+		}
+		quantities[k] = qty
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating stock quantities: %w", err)
+	}
+	return quantities, nil
+}
+
+func (r *warehouseStockRepo) Export(ctx context.Context, warehouseID uint, fn func(domain.StockExportRow) error) error {
+	query := `
+		SELECT s.warehouse_id, s.product_id, p.sku, s.quantity
+		FROM warehouse_stock s
+		LEFT JOIN products p ON p.id = s.product_id
+		WHERE $1 = 0 OR s.warehouse_id = $1
+		ORDER BY s.warehouse_id, s.product_id
+	`
+	rows, err := r.db.QueryContext(ctx, query, warehouseID)
+	if err != nil {
+		return fmt.Errorf("failed to query warehouse stock: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row domain.StockExportRow
+		if err := rows.Scan(&row.WarehouseID, &row.ProductID, &row.SKU, &row.Quantity); err != nil {
+			return fmt.Errorf("failed to scan warehouse stock: %w", err)
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating warehouse stock rows: %w", err)
+	}
 	return nil
 }
 
-func (r *warehouseStockRepo) SafeDecreaseQuantity(ctx context.Context, tx *sql.Tx, warehouseID uint, productID uint, qtyToDecrease int32) error {
+func (r *warehouseStockRepo) EnsureStockRowTx(ctx context.Context, tx *sql.Tx, warehouseID uint, productID uint) error {
+	return ensureStockRowTx(ctx, tx, warehouseID, productID)
+}
 
-	querySelect := `SELECT quantity FROM warehouse_stock WHERE warehouse_id = $1 AND product_id = $2 FOR UPDATE`
-	var currentQty int32
-	err := tx.QueryRowContext(ctx, querySelect, warehouseID, productID).Scan(&currentQty)
+func ensureStockRowTx(ctx context.Context, tx *sql.Tx, warehouseID uint, productID uint) error {
+	query := `
+		INSERT INTO warehouse_stock (warehouse_id, product_id, quantity, created_at, updated_at)
+		VALUES ($1, $2, 0, NOW(), NOW())
+		ON CONFLICT (warehouse_id, product_id) DO NOTHING
+	`
+	if _, err := tx.ExecContext(ctx, query, warehouseID, productID); err != nil {
+		return fmt.Errorf("failed to create warehouse stock: %w", err)
+	}
+	return nil
+}
+
+// applyStockChange locks the row, applies the delta and appends the movement,
+// all in tx. An increase first locks the warehouse for the capacity check.
+func applyStockChange(ctx context.Context, tx *sql.Tx, change domain.StockChange) (*domain.StockChangeResult, error) {
+	if change.Reason == "" {
+		return nil, fmt.Errorf("stock change reason is required")
+	}
+
+	if change.Delta > 0 && change.ReferenceType != domain.MovementRefStockCount {
+		if err := lockCapacityTx(ctx, tx, change.WarehouseID); err != nil {
+			return nil, err
+		}
+	}
+	current, err := lockStock(ctx, tx, change.WarehouseID, change.ProductID)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return fmt.Errorf("no stock found for warehouse_id=%d and product_id=%d", warehouseID, productID)
+		return nil, err
+	}
+	return applyLockedChange(ctx, tx, change, current)
+}
+
+// applyLockedChange applies change to a row already locked by lockStock. A
+// decrease also takes from the row's lots (drawLotsTx) and storage locations
+// (drawLocationsTx). When the quantity crosses the reorder point a low-stock
+// alert is raised, or the open one is resolved, in the same transaction.
+func applyLockedChange(ctx context.Context, tx *sql.Tx, change domain.StockChange, current lockedStock) (*domain.StockChangeResult, error) {
+	newQty := current.Quantity + change.Delta
+	if newQty < 0 {
+		return nil, fmt.Errorf("%w for product_id=%d in warehouse_id=%d", ErrInsufficientStock, change.ProductID, change.WarehouseID)
+	}
+
+	result := &domain.StockChangeResult{
+		WarehouseID:    change.WarehouseID,
+		ProductID:      change.ProductID,
+		QuantityBefore: current.Quantity,
+		QuantityAfter:  newQty,
+		Version:        current.Version,
+	}
+	if change.Delta == 0 {
+		return result, nil
+	}
+	if change.Delta > 0 {
+		warning, err := checkCapacityTx(ctx, tx, change)
+		if err != nil {
+			return nil, err
+		}
+		result.CapacityWarning = warning
+	}
+	if change.Delta < 0 {
+		lots, err := drawLotsTx(ctx, tx, change, current.Quantity)
+		if err != nil {
+			return nil, err
+		}
+		result.Lots = lots
+		picks, err := drawLocationsTx(ctx, tx, change, current.Quantity)
+		if err != nil {
+			return nil, err
 		}
-		return fmt.Errorf("failed to get current stock: %w", err)
+		result.Picks = picks
+	}
+
+	queryUpdate := `UPDATE warehouse_stock SET quantity = $1, version = version + 1, updated_at = NOW() WHERE warehouse_id = $2 AND product_id = $3`
+	if _, err := tx.ExecContext(ctx, queryUpdate, newQty, change.WarehouseID, change.ProductID); err != nil {
+		return nil, fmt.Errorf("failed to update stock: %w", err)
+	}
+	result.Version++
+
+	movement, err := insertMovement(ctx, tx, change, newQty)
+	if err != nil {
+		return nil, err
+	}
+	result.Movement = movement
+
+	if err := crossReorderPointTx(ctx, tx, change.WarehouseID, change.ProductID, current.ReorderPoint, current.Quantity, newQty); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// crossReorderPointTx raises a low-stock alert when a quantity drops from
+// above the reorder point to at or below it, and resolves the open one when
+// it climbs back above. A quantity that stays on one side changes nothing.
+func crossReorderPointTx(ctx context.Context, tx *sql.Tx, warehouseID, productID uint, reorderPoint *int32, before, after int32) error {
+	if reorderPoint == nil {
+		return nil
+	}
+	rp := *reorderPoint
+	switch {
+	case before > rp && after <= rp:
+		return raiseStockAlertTx(ctx, tx, warehouseID, productID, rp, after)
+	case before <= rp && after > rp:
+		return resolveStockAlertsTx(ctx, tx, warehouseID, productID)
+	}
+	return nil
+}
+
+// checkCapacityTx checks an increase against the weight and volume capacity
+// of the warehouse. Over capacity it fails with ErrCapacityExceeded under
+// the reject policy, or returns a warning under the warn policy. Stock count
+// adjustments only ever warn: they book stock that is physically there.
+//
+// Under the reject policy the caller holds the warehouse lock taken by
+// lockCapacityTx, so the load read here includes every increase committed
+// before it and none can commit until this transaction ends.
+func checkCapacityTx(ctx context.Context, tx *sql.Tx, change domain.StockChange) (string, error) {
+	var maxWeight, maxVolume sql.NullFloat64
+	var policy string
+	err := tx.QueryRowContext(ctx, `SELECT max_weight_kg, max_volume_m3, capacity_policy FROM warehouses WHERE id = $1`,
+		change.WarehouseID).Scan(&maxWeight, &maxVolume, &policy)
+	if err != nil {
+		return "", fmt.Errorf("failed to get warehouse capacity: %w", err)
+	}
+	if !maxWeight.Valid && !maxVolume.Valid {
+		return "", nil
 	}
 
-	if currentQty < qtyToDecrease {
-		return fmt.Errorf("not enough stock for product_id=%d in warehouse_id=%d", productID, warehouseID)
+	var unitWeight, unitVolume float64
+	err = tx.QueryRowContext(ctx, `SELECT COALESCE(weight, 0), COALESCE(volume, 0) FROM products WHERE id = $1`,
+		change.ProductID).Scan(&unitWeight, &unitVolume)
+	if err != nil {
+		return "", fmt.Errorf("failed to get product size: %w", err)
+	}
+	if unitWeight == 0 && unitVolume == 0 {
+		return "", nil
 	}
 
-	newQty := currentQty - qtyToDecrease
-	queryUpdate := `UPDATE warehouse_stock SET quantity = $1, updated_at = NOW() WHERE warehouse_id = $2 AND product_id = $3`
-	_, err = tx.ExecContext(ctx, queryUpdate, newQty, warehouseID, productID)
+	weight, volume, err := warehouseLoad(ctx, tx, change.WarehouseID)
 	if err != nil {
-		return fmt.Errorf("failed to update stock: %w", err)
+		return "", err
 	}
+	weight += unitWeight * float64(change.Delta)
+	volume += unitVolume * float64(change.Delta)
 
+	var over string
+	switch {
+	case maxWeight.Valid && unitWeight > 0 && weight > maxWeight.Float64:
+		over = fmt.Sprintf("weight would be %.2f of %.2f kg", weight, maxWeight.Float64)
+	case maxVolume.Valid && unitVolume > 0 && volume > maxVolume.Float64:
+		over = fmt.Sprintf("volume would be %.3f of %.3f m3", volume, maxVolume.Float64)
+	default:
+		return "", nil
+	}
+	if policy == domain.CapacityPolicyReject && change.ReferenceType != domain.MovementRefStockCount {
+		return "", fmt.Errorf("%w: warehouse_id=%d %s", ErrCapacityExceeded, change.WarehouseID, over)
+	}
+	return fmt.Sprintf("warehouse over capacity: %s", over), nil
+}
+
+// lockCapacityTx locks the warehouses that reject increases over a capacity
+// limit, so concurrent increases into one warehouse are checked one after
+// the other. Warehouses without a limit or with the warn policy are not
+// locked.
+//
+// The lock has to be taken before any stock row of the warehouse: a
+// transaction holding it only ever waits for stock rows, and one holding
+// stock rows must not then wait for it. Transactions that change several
+// rows therefore lock all their warehouses first; locking one again later
+// in the same transaction does not wait. FOR NO KEY UPDATE leaves inserts
+// of rows referencing the warehouse unblocked.
+func lockCapacityTx(ctx context.Context, tx *sql.Tx, warehouseIDs ...uint) error {
+	if len(warehouseIDs) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(warehouseIDs))
+	for i, id := range warehouseIDs {
+		ids[i] = int64(id)
+	}
+	query := `
+		SELECT id FROM warehouses
+		WHERE id = ANY($1) AND capacity_policy = 'reject'
+		  AND (max_weight_kg IS NOT NULL OR max_volume_m3 IS NOT NULL)
+		ORDER BY id
+		FOR NO KEY UPDATE
+	`
+	if _, err := tx.ExecContext(ctx, query, pq.Array(ids)); err != nil {
+		return fmt.Errorf("failed to lock warehouse capacity: %w", err)
+	}
 	return nil
 }
+
+// lockedStock is the part of a warehouse_stock row read under FOR UPDATE.
+type lockedStock struct {
+	Quantity     int32
+	ReorderPoint *int32
+	Version      int32
+}
+
+func lockStock(ctx context.Context, tx *sql.Tx, warehouseID, productID uint) (lockedStock, error) {
+	querySelect := `SELECT quantity, reorder_point, version FROM warehouse_stock WHERE warehouse_id = $1 AND product_id = $2 FOR UPDATE`
+	var s lockedStock
+	err := tx.QueryRowContext(ctx, querySelect, warehouseID, productID).Scan(&s.Quantity, &s.ReorderPoint, &s.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return s, fmt.Errorf("%w: warehouse_id=%d product_id=%d", ErrWarehouseStockNotFound, warehouseID, productID)
+	}
+	if err != nil {
+		return s, fmt.Errorf("failed to get current stock: %w", err)
+	}
+	return s, nil
+}
+
+func insertMovement(ctx context.Context, tx *sql.Tx, change domain.StockChange, quantityAfter int32) (*domain.StockMovement, error) {
+	m := &domain.StockMovement{
+		WarehouseID:   change.WarehouseID,
+		ProductID:     change.ProductID,
+		Delta:         change.Delta,
+		QuantityAfter: quantityAfter,
+		Reason:        change.Reason,
+	}
+	if change.ReferenceType != "" {
+		m.ReferenceType = &change.ReferenceType
+	}
+	if change.ReferenceID != 0 {
+		m.ReferenceID = &change.ReferenceID
+	}
+	if change.ActorID != 0 {
+		m.ActorID = &change.ActorID
+	}
+	if change.Note != "" {
+		m.Note = &change.Note
+	}
+
+	query := `
+		INSERT INTO stock_movements
+			(warehouse_id, product_id, delta, quantity_after, reason, reference_type, reference_id, actor_id, note, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW())
+		RETURNING id, created_at
+	`
+	err := tx.QueryRowContext(ctx, query, m.WarehouseID, m.ProductID, m.Delta, m.QuantityAfter, m.Reason,
+		m.ReferenceType, m.ReferenceID, m.ActorID, m.Note).Scan(&m.ID, &m.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record stock movement: %w", err)
+	}
+	return m, nil
+}
+
+func (r *warehouseStockRepo) ListMovements(ctx context.Context, filter domain.StockMovementFilter) ([]domain.StockMovement, error) {
+	query := `
+		SELECT id, warehouse_id, product_id, delta, quantity_after, reason,
+		       reference_type, reference_id, actor_id, note, created_at
+		FROM stock_movements
+		WHERE warehouse_id = $1
+		  AND ($2 = 0 OR product_id = $2)
+		  AND ($3 = 0 OR id < $3)
+		ORDER BY id DESC
+		LIMIT $4
+	`
+	rows, err := r.db.QueryContext(ctx, query, filter.WarehouseID, filter.ProductID, filter.BeforeID, filter.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stock movements: %w", err)
+	}
+	defer rows.Close()
+
+	movements := []domain.StockMovement{}
+	for rows.Next() {
+		var m domain.StockMovement
+		if err := rows.Scan(&m.ID, &m.WarehouseID, &m.ProductID, &m.Delta, &m.QuantityAfter, &m.Reason,
+			&m.ReferenceType, &m.ReferenceID, &m.ActorID, &m.Note, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan stock movement: %w", err)
+		}
+		movements = append(movements, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating stock movements: %w", err)
+	}
+	return movements, nil
+}
+
+func (r *warehouseStockRepo) RebuildFromLedger(ctx context.Context, apply bool) ([]domain.StockDiscrepancy, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if apply {
+		// block stock changes while quantities are recomputed
+		if _, err := tx.ExecContext(ctx, `LOCK TABLE warehouse_stock IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+			return nil, fmt.Errorf("failed to lock warehouse stock: %w", err)
+		}
+	}
+
+	query := `
+		WITH ledger AS (
+			SELECT warehouse_id, product_id, SUM(delta)::int AS quantity
+			FROM stock_movements
+			GROUP BY warehouse_id, product_id
+		)
+		SELECT COALESCE(s.warehouse_id, l.warehouse_id), COALESCE(s.product_id, l.product_id),
+		       COALESCE(s.quantity, 0), COALESCE(l.quantity, 0)
+		FROM warehouse_stock s
+		FULL OUTER JOIN ledger l ON l.warehouse_id = s.warehouse_id AND l.product_id = s.product_id
+		WHERE COALESCE(s.quantity, 0) <> COALESCE(l.quantity, 0)
+		ORDER BY 1, 2
+	`
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare stock with ledger: %w", err)
+	}
+	discrepancies := []domain.StockDiscrepancy{}
+	for rows.Next() {
+		var d domain.StockDiscrepancy
+		if err := rows.Scan(&d.WarehouseID, &d.ProductID, &d.Quantity, &d.LedgerQuantity); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan stock discrepancy: %w", err)
+		}
+		discrepancies = append(discrepancies, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating stock discrepancies: %w", err)
+	}
+
+	if !apply || len(discrepancies) == 0 {
+		return discrepancies, nil
+	}
+
+	for _, d := range discrepancies {
+		if err := r.EnsureStockRowTx(ctx, tx, d.WarehouseID, d.ProductID); err != nil {
+			return nil, err
+		}
+		var reorderPoint *int32
+		err := tx.QueryRowContext(ctx, `
+			UPDATE warehouse_stock SET quantity = $1, version = version + 1, updated_at = NOW()
+			WHERE warehouse_id = $2 AND product_id = $3
+			RETURNING reorder_point
+		`, d.LedgerQuantity, d.WarehouseID, d.ProductID).Scan(&reorderPoint)
+		if err != nil {
+			return nil, fmt.Errorf("failed to rebuild warehouse stock: %w", err)
+		}
+		// the corrected quantity gets the alert it would have had
+		err = crossReorderPointTx(ctx, tx, d.WarehouseID, d.ProductID, reorderPoint, d.Quantity, d.LedgerQuantity)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit stock rebuild: %w", err)
+	}
+	return discrepancies, nil
+}
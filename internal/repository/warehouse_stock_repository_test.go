@@ -7,9 +7,25 @@ import (
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 )
 
+// expectNoCapacityLimit expects the capacity check of a stock increase on a
+// warehouse without a weight or volume limit.
+func expectNoCapacityLimit(mock sqlmock.Sqlmock, warehouseID uint) {
+	mock.ExpectQuery("SELECT max_weight_kg, max_volume_m3, capacity_policy FROM warehouses").
+		WithArgs(warehouseID).
+		WillReturnRows(sqlmock.NewRows([]string{"max_weight_kg", "max_volume_m3", "capacity_policy"}).AddRow(nil, nil, domain.CapacityPolicyReject))
+}
+
+// expectCapacityLock expects lockCapacityTx for the given warehouses.
+func expectCapacityLock(mock sqlmock.Sqlmock, warehouseIDs ...int64) {
+	mock.ExpectExec("SELECT id FROM warehouses (.+) FOR NO KEY UPDATE").
+		WithArgs(pq.Array(warehouseIDs)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
 func TestWarehouseStockRepository(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	assert.NoError(t, err)
@@ -18,6 +34,20 @@ func TestWarehouseStockRepository(t *testing.T) {
 	repo := NewWarehouseStockRepository(db)
 	ctx := context.Background()
 
+	stockColumns := []string{"id", "warehouse_id", "product_id", "quantity", "reorder_point", "version", "created_at", "updated_at"}
+	lotColumns := []string{"id", "batch_number", "expiry_date", "quantity", "expired"}
+	locationColumns := []string{"location_id", "code", "quantity"}
+	// every decrease locks the lots and locations of the row; these tests hold
+	// neither
+	expectNoLots := func() {
+		mock.ExpectQuery("SELECT (.+) FROM stock_lots (.+) FOR UPDATE").
+			WithArgs(uint(1), uint(1)).
+			WillReturnRows(sqlmock.NewRows(lotColumns))
+		mock.ExpectQuery("SELECT (.+) FROM location_stock (.+) FOR UPDATE").
+			WithArgs(uint(1), uint(1)).
+			WillReturnRows(sqlmock.NewRows(locationColumns))
+	}
+
 	t.Run("Create", func(t *testing.T) {
 		stock := &domain.WarehouseStock{
 			WarehouseID: 1,
@@ -25,22 +55,107 @@ func TestWarehouseStockRepository(t *testing.T) {
 			Quantity:    10,
 		}
 
-		mock.ExpectQuery("INSERT INTO warehouse_stock").
-			WithArgs(stock.WarehouseID, stock.ProductID, stock.Quantity).
+		mock.ExpectBegin()
+		expectCapacityLock(mock, 1)
+		mock.ExpectQuery("INSERT INTO warehouse_stock .* ON CONFLICT \\(warehouse_id, product_id\\) DO NOTHING").
+			WithArgs(stock.WarehouseID, stock.ProductID, nil).
 			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectQuery("SELECT quantity, reorder_point, version FROM warehouse_stock WHERE warehouse_id = \\$1 AND product_id = \\$2 FOR UPDATE").
+			WithArgs(uint(1), uint(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"quantity", "reorder_point", "version"}).AddRow(0, nil, 1))
+		expectNoCapacityLimit(mock, 1)
+		mock.ExpectExec("UPDATE warehouse_stock SET quantity = \\$1").
+			WithArgs(int32(10), uint(1), uint(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("INSERT INTO stock_movements").
+			WithArgs(uint(1), uint(1), int32(10), int32(10), domain.MovementReasonRestock, nil, nil, sqlmock.AnyArg(), nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+		mock.ExpectQuery("SELECT id, warehouse_id, product_id, quantity, reorder_point, version, created_at, updated_at FROM warehouse_stock WHERE warehouse_id = \\$1 AND product_id = \\$2").
+			WithArgs(uint(1), uint(1)).
+			WillReturnRows(sqlmock.NewRows(stockColumns).AddRow(1, 1, 1, 10, nil, 2, time.Now(), time.Now()))
+		mock.ExpectCommit()
 
-		err := repo.Create(ctx, stock)
+		created, err := repo.Create(ctx, stock, domain.StockUpsertAdd, domain.StockReference{ActorID: 7})
 		assert.NoError(t, err)
+		assert.True(t, created)
 		assert.Equal(t, uint(1), stock.ID)
+		assert.Equal(t, int32(10), stock.Quantity)
+	})
+
+	t.Run("Create_AddsToExistingRow", func(t *testing.T) {
+		stock := &domain.WarehouseStock{WarehouseID: 1, ProductID: 1, Quantity: 5}
+
+		mock.ExpectBegin()
+		expectCapacityLock(mock, 1)
+		mock.ExpectQuery("INSERT INTO warehouse_stock").
+			WithArgs(uint(1), uint(1), nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery("SELECT quantity, reorder_point, version FROM warehouse_stock WHERE warehouse_id = \\$1 AND product_id = \\$2 FOR UPDATE").
+			WithArgs(uint(1), uint(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"quantity", "reorder_point", "version"}).AddRow(10, nil, 2))
+		expectNoCapacityLimit(mock, 1)
+		mock.ExpectExec("UPDATE warehouse_stock SET quantity = \\$1").
+			WithArgs(int32(15), uint(1), uint(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("INSERT INTO stock_movements").
+			WithArgs(uint(1), uint(1), int32(5), int32(15), domain.MovementReasonRestock, nil, nil, nil, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(2, time.Now()))
+		mock.ExpectQuery("SELECT id, warehouse_id, product_id, quantity, reorder_point, version, created_at, updated_at FROM warehouse_stock").
+			WithArgs(uint(1), uint(1)).
+			WillReturnRows(sqlmock.NewRows(stockColumns).AddRow(1, 1, 1, 15, nil, 3, time.Now(), time.Now()))
+		mock.ExpectCommit()
+
+		created, err := repo.Create(ctx, stock, domain.StockUpsertAdd, domain.StockReference{})
+		assert.NoError(t, err)
+		assert.False(t, created)
+		assert.Equal(t, int32(15), stock.Quantity)
+	})
+
+	t.Run("Create_ReplacesExistingRow", func(t *testing.T) {
+		rp := int32(5)
+		stock := &domain.WarehouseStock{WarehouseID: 1, ProductID: 1, Quantity: 4, ReorderPoint: &rp}
+
+		mock.ExpectBegin()
+		expectCapacityLock(mock, 1)
+		mock.ExpectQuery("INSERT INTO warehouse_stock").
+			WithArgs(uint(1), uint(1), &rp).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery("SELECT quantity, reorder_point, version FROM warehouse_stock WHERE warehouse_id = \\$1 AND product_id = \\$2 FOR UPDATE").
+			WithArgs(uint(1), uint(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"quantity", "reorder_point", "version"}).AddRow(15, nil, 3))
+		mock.ExpectExec("UPDATE warehouse_stock SET reorder_point = \\$1").
+			WithArgs(&rp, uint(1), uint(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		expectNoLots()
+		mock.ExpectExec("UPDATE warehouse_stock SET quantity = \\$1").
+			WithArgs(int32(4), uint(1), uint(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("INSERT INTO stock_movements").
+			WithArgs(uint(1), uint(1), int32(-11), int32(4), domain.MovementReasonAdjustment, nil, nil, nil, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(3, time.Now()))
+		mock.ExpectExec("INSERT INTO stock_alerts").
+			WithArgs(uint(1), uint(1), int32(5), int32(4)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery("SELECT id, warehouse_id, product_id, quantity, reorder_point, version, created_at, updated_at FROM warehouse_stock").
+			WithArgs(uint(1), uint(1)).
+			WillReturnRows(sqlmock.NewRows(stockColumns).AddRow(1, 1, 1, 4, 5, 5, time.Now(), time.Now()))
+		mock.ExpectCommit()
+
+		created, err := repo.Create(ctx, stock, domain.StockUpsertReplace, domain.StockReference{})
+		assert.NoError(t, err)
+		assert.False(t, created)
+		assert.Equal(t, int32(4), stock.Quantity)
+		assert.Equal(t, int32(5), stock.Version)
+		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
 	t.Run("GetAll", func(t *testing.T) {
 		now := time.Now()
-		rows := sqlmock.NewRows([]string{"id", "warehouse_id", "product_id", "quantity", "created_at", "updated_at"}).
-			AddRow(1, 1, 1, 10, now, now).
-			AddRow(2, 1, 2, 5, now, now)
+		rows := sqlmock.NewRows([]string{"id", "warehouse_id", "product_id", "quantity", "reorder_point", "version", "created_at", "updated_at"}).
+			AddRow(1, 1, 1, 10, nil, 1, now, now).
+			AddRow(2, 1, 2, 5, 3, 1, now, now)
 
-		mock.ExpectQuery("SELECT id, warehouse_id, product_id, quantity, created_at, updated_at FROM warehouse_stock").
+		mock.ExpectQuery("SELECT id, warehouse_id, product_id, quantity, reorder_point, version, created_at, updated_at FROM warehouse_stock").
 			WillReturnRows(rows)
 
 		stocks, err := repo.GetAll(ctx)
@@ -50,10 +165,10 @@ func TestWarehouseStockRepository(t *testing.T) {
 
 	t.Run("GetByWarehouseID", func(t *testing.T) {
 		now := time.Now()
-		rows := sqlmock.NewRows([]string{"id", "warehouse_id", "product_id", "quantity", "created_at", "updated_at"}).
-			AddRow(1, 1, 1, 10, now, now)
+		rows := sqlmock.NewRows([]string{"id", "warehouse_id", "product_id", "quantity", "reorder_point", "version", "created_at", "updated_at"}).
+			AddRow(1, 1, 1, 10, nil, 1, now, now)
 
-		mock.ExpectQuery("SELECT id, warehouse_id, product_id, quantity, created_at, updated_at FROM warehouse_stock WHERE warehouse_id = \\$1").
+		mock.ExpectQuery("SELECT id, warehouse_id, product_id, quantity, reorder_point, version, created_at, updated_at FROM warehouse_stock WHERE warehouse_id = \\$1").
 			WithArgs(1).
 			WillReturnRows(rows)
 
@@ -63,39 +178,501 @@ func TestWarehouseStockRepository(t *testing.T) {
 		assert.Equal(t, uint(1), stocks[0].ID)
 	})
 
-	t.Run("UpdateQuantity", func(t *testing.T) {
+	t.Run("GetByWarehouseAndProduct", func(t *testing.T) {
+		now := time.Now()
+		rows := sqlmock.NewRows([]string{"id", "warehouse_id", "product_id", "quantity", "reorder_point", "version", "created_at", "updated_at"}).
+			AddRow(3, 1, 2, 7, nil, 1, now, now)
+
+		mock.ExpectQuery("SELECT id, warehouse_id, product_id, quantity, reorder_point, version, created_at, updated_at FROM warehouse_stock WHERE warehouse_id = \\$1 AND product_id = \\$2").
+			WithArgs(1, 2).
+			WillReturnRows(rows)
+
+		stock, err := repo.GetByWarehouseAndProduct(ctx, 1, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, int32(7), stock.Quantity)
+	})
+
+	t.Run("GetByWarehouseAndProduct_NotFound", func(t *testing.T) {
+		mock.ExpectQuery("SELECT id, warehouse_id, product_id, quantity, reorder_point, version, created_at, updated_at FROM warehouse_stock WHERE warehouse_id = \\$1 AND product_id = \\$2").
+			WithArgs(1, 99).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "warehouse_id", "product_id", "quantity", "reorder_point", "version", "created_at", "updated_at"}))
+
+		stock, err := repo.GetByWarehouseAndProduct(ctx, 1, 99)
+		assert.Nil(t, stock)
+		assert.ErrorIs(t, err, ErrWarehouseStockNotFound)
+	})
+
+	t.Run("GetAvailability", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"warehouse_id", "name", "location", "quantity", "reserved"}).
+			AddRow(1, "Gudang Jakarta", "Jakarta", 10, 4).
+			AddRow(2, "Gudang Bandung", "Bandung", 3, 5)
+		mock.ExpectQuery("SELECT s.warehouse_id, w.name, w.location, s.quantity, COALESCE\\(d.reserved, 0\\) FROM warehouse_stock s").
+			WithArgs(uint(7)).
+			WillReturnRows(rows)
+
+		availability, err := repo.GetAvailability(ctx, 7)
+		assert.NoError(t, err)
+		assert.Len(t, availability, 2)
+		assert.Equal(t, int32(6), availability[0].Available)
+		assert.Equal(t, int32(0), availability[1].Available)
+		assert.Equal(t, "Gudang Bandung", availability[1].WarehouseName)
+	})
+
+	t.Run("Stocktake", func(t *testing.T) {
+		mock.ExpectBegin()
+		expectCapacityLock(mock, 1)
+		mock.ExpectQuery("SELECT quantity, reorder_point, version FROM warehouse_stock WHERE warehouse_id = \\$1 AND product_id = \\$2 FOR UPDATE").
+			WithArgs(1, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"quantity", "reorder_point", "version"}).AddRow(12, nil, 1))
+		expectNoCapacityLimit(mock, 1)
 		mock.ExpectExec("UPDATE warehouse_stock SET quantity").
 			WithArgs(20, 1, 1).
 			WillReturnResult(sqlmock.NewResult(0, 1)) // 1 row affected
+		mock.ExpectQuery("INSERT INTO stock_movements").
+			WithArgs(uint(1), uint(1), int32(8), int32(20), domain.MovementReasonAdjustment, nil, nil, nil, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(2, time.Now()))
+		mock.ExpectCommit()
+
+		expected := int32(12)
+		result, err := repo.Stocktake(ctx, 1, 1, 20, domain.StockExpectation{Quantity: &expected}, domain.StockReference{})
+		assert.NoError(t, err)
+		assert.Equal(t, int32(12), result.QuantityBefore)
+		assert.Equal(t, int32(2), result.Version)
+		assert.Equal(t, uint64(2), result.Movement.ID)
+	})
+
+	t.Run("Stocktake_Changed", func(t *testing.T) {
+		mock.ExpectBegin()
+		expectCapacityLock(mock, 1)
+		mock.ExpectQuery("SELECT quantity, reorder_point, version FROM warehouse_stock WHERE warehouse_id = \\$1 AND product_id = \\$2 FOR UPDATE").
+			WithArgs(1, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"quantity", "reorder_point", "version"}).AddRow(9, nil, 1))
+		mock.ExpectRollback()
+
+		expected := int32(12)
+		result, err := repo.Stocktake(ctx, 1, 1, 20, domain.StockExpectation{Quantity: &expected}, domain.StockReference{})
+		assert.ErrorIs(t, err, ErrStockChanged)
+		assert.Equal(t, int32(9), result.QuantityBefore)
+	})
+
+	t.Run("Stocktake_VersionConflict", func(t *testing.T) {
+		mock.ExpectBegin()
+		expectCapacityLock(mock, 1)
+		mock.ExpectQuery("SELECT quantity, reorder_point, version FROM warehouse_stock WHERE warehouse_id = \\$1 AND product_id = \\$2 FOR UPDATE").
+			WithArgs(1, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"quantity", "reorder_point", "version"}).AddRow(12, nil, 5))
+		mock.ExpectRollback()
+
+		result, err := repo.Stocktake(ctx, 1, 1, 20, domain.StockExpectation{Version: 4}, domain.StockReference{})
+		assert.ErrorIs(t, err, ErrVersionConflict)
+		assert.Equal(t, int32(5), result.Version)
+	})
+
+	t.Run("ApplyChange", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT quantity, reorder_point, version FROM warehouse_stock WHERE warehouse_id = \\$1 AND product_id = \\$2 FOR UPDATE").
+			WithArgs(1, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"quantity", "reorder_point", "version"}).AddRow(20, nil, 1))
+		expectNoLots()
+		mock.ExpectExec("UPDATE warehouse_stock SET quantity").
+			WithArgs(15, 1, 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("INSERT INTO stock_movements").
+			WithArgs(uint(1), uint(1), int32(-5), int32(15), domain.MovementReasonAdjustment, nil, nil, nil, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(5, time.Now()))
+		mock.ExpectCommit()
 
-		err := repo.UpdateQuantity(ctx, 1, 1, 20)
+		result, err := repo.ApplyChange(ctx, domain.StockChange{
+			WarehouseID:    1,
+			ProductID:      1,
+			Delta:          -5,
+			StockReference: domain.StockReference{Reason: domain.MovementReasonAdjustment},
+		})
 		assert.NoError(t, err)
+		assert.Equal(t, int32(15), result.QuantityAfter)
 	})
 
 	t.Run("Delete", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT warehouse_id, product_id, quantity FROM warehouse_stock WHERE id = \\$1 FOR UPDATE").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"warehouse_id", "product_id", "quantity"}).AddRow(1, 1, 20))
+		mock.ExpectQuery("INSERT INTO stock_movements").
+			WithArgs(uint(1), uint(1), int32(-20), int32(0), domain.MovementReasonAdjustment, nil, nil, nil, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(3, time.Now()))
 		mock.ExpectExec("DELETE FROM warehouse_stock WHERE id = \\$1").
 			WithArgs(1).
 			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE stock_alerts SET status = 'resolved'").
+			WithArgs(uint(1), uint(1)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
 
-		err := repo.Delete(ctx, 1)
+		err := repo.Delete(ctx, 1, domain.StockReference{})
 		assert.NoError(t, err)
 	})
 
 	t.Run("SafeDecreaseQuantity", func(t *testing.T) {
+		mock.ExpectBegin()
 		tx, err := db.Begin()
 		assert.NoError(t, err)
 
-		mock.ExpectQuery("SELECT quantity FROM warehouse_stock WHERE warehouse_id = \\$1 AND product_id = \\$2 FOR UPDATE").
+		mock.ExpectQuery("SELECT quantity, reorder_point, version FROM warehouse_stock WHERE warehouse_id = \\$1 AND product_id = \\$2 FOR UPDATE").
 			WithArgs(1, 1).
-			WillReturnRows(sqlmock.NewRows([]string{"quantity"}).AddRow(15))
+			WillReturnRows(sqlmock.NewRows([]string{"quantity", "reorder_point", "version"}).AddRow(15, nil, 1))
+		// 4 expired units are skipped; 3 come from the next lot, 2 from untracked stock
+		mock.ExpectQuery("SELECT (.+) FROM stock_lots (.+) ORDER BY expiry_date NULLS LAST, received_at, id FOR UPDATE").
+			WithArgs(uint(1), uint(1)).
+			WillReturnRows(sqlmock.NewRows(lotColumns).
+				AddRow(7, "B-OLD", time.Now().AddDate(0, 0, -2), 4, true).
+				AddRow(8, "B-NEW", time.Now().AddDate(0, 0, 20), 3, false))
+		mock.ExpectExec("UPDATE stock_lots SET quantity = quantity - \\$1").
+			WithArgs(int32(3), uint(8)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		// a sale is picked from bins first: 4 from A-01, the last one is unassigned
+		mock.ExpectQuery("SELECT (.+) FROM location_stock (.+) FOR UPDATE").
+			WithArgs(uint(1), uint(1)).
+			WillReturnRows(sqlmock.NewRows(locationColumns).AddRow(2, "A-01", 4))
+		mock.ExpectExec("UPDATE location_stock SET quantity = quantity - \\$1").
+			WithArgs(int32(4), uint(2), uint(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
 
 		mock.ExpectExec("UPDATE warehouse_stock SET quantity").
 			WithArgs(10, 1, 1).
 			WillReturnResult(sqlmock.NewResult(0, 1))
 
-		err = repo.SafeDecreaseQuantity(ctx, tx, 1, 1, 5)
+		mock.ExpectQuery("INSERT INTO stock_movements").
+			WithArgs(uint(1), uint(1), int32(-5), int32(10), domain.MovementReasonSale, "order", uint(42), uint(3), nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(4, time.Now()))
+
+		ref := domain.StockReference{ReferenceType: domain.MovementRefOrder, ReferenceID: 42, ActorID: 3}
+		result, err := repo.SafeDecreaseQuantity(ctx, tx, 1, 1, 5, ref)
+		assert.NoError(t, err)
+		assert.Equal(t, int32(10), result.QuantityAfter)
+		if assert.Len(t, result.Lots, 1) {
+			assert.Equal(t, "B-NEW", result.Lots[0].BatchNumber)
+			assert.Equal(t, int32(3), result.Lots[0].Quantity)
+		}
+		if assert.Len(t, result.Picks, 1) {
+			assert.Equal(t, "A-01", result.Picks[0].LocationCode)
+			assert.Equal(t, int32(4), result.Picks[0].Quantity)
+		}
+
+		mock.ExpectRollback()
+		_ = tx.Rollback()
+	})
+
+	t.Run("SafeDecreaseQuantity_Insufficient", func(t *testing.T) {
+		mock.ExpectBegin()
+		tx, err := db.Begin()
+		assert.NoError(t, err)
+
+		mock.ExpectQuery("SELECT quantity, reorder_point, version FROM warehouse_stock WHERE warehouse_id = \\$1 AND product_id = \\$2 FOR UPDATE").
+			WithArgs(1, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"quantity", "reorder_point", "version"}).AddRow(2, nil, 1))
+
+		_, err = repo.SafeDecreaseQuantity(ctx, tx, 1, 1, 5, domain.StockReference{})
+		assert.ErrorIs(t, err, ErrInsufficientStock)
+
+		mock.ExpectRollback()
+		_ = tx.Rollback()
+	})
+
+	t.Run("SafeDecreaseQuantity_OnlyExpiredLeft", func(t *testing.T) {
+		mock.ExpectBegin()
+		tx, err := db.Begin()
 		assert.NoError(t, err)
 
+		mock.ExpectQuery("SELECT quantity, reorder_point, version FROM warehouse_stock WHERE warehouse_id = \\$1 AND product_id = \\$2 FOR UPDATE").
+			WithArgs(1, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"quantity", "reorder_point", "version"}).AddRow(5, nil, 1))
+		mock.ExpectQuery("SELECT (.+) FROM stock_lots").
+			WithArgs(uint(1), uint(1)).
+			WillReturnRows(sqlmock.NewRows(lotColumns).AddRow(7, "B-OLD", time.Now().AddDate(0, 0, -2), 4, true))
+
+		_, err = repo.SafeDecreaseQuantity(ctx, tx, 1, 1, 5, domain.StockReference{})
+		assert.ErrorIs(t, err, ErrInsufficientStock)
+
+		mock.ExpectRollback()
 		_ = tx.Rollback()
 	})
+
+	t.Run("ApplyChange_RaisesLowStockAlert", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT quantity, reorder_point, version FROM warehouse_stock WHERE warehouse_id = \\$1 AND product_id = \\$2 FOR UPDATE").
+			WithArgs(1, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"quantity", "reorder_point", "version"}).AddRow(12, 10, 1))
+		expectNoLots()
+		mock.ExpectExec("UPDATE warehouse_stock SET quantity").
+			WithArgs(8, 1, 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("INSERT INTO stock_movements").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(6, time.Now()))
+		mock.ExpectExec("INSERT INTO stock_alerts (.+) ON CONFLICT").
+			WithArgs(uint(1), uint(1), int32(10), int32(8)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		_, err := repo.ApplyChange(ctx, domain.StockChange{
+			WarehouseID:    1,
+			ProductID:      1,
+			Delta:          -4,
+			StockReference: domain.StockReference{Reason: domain.MovementReasonSale},
+		})
+		assert.NoError(t, err)
+	})
+
+	t.Run("ApplyChange_BelowThresholdNoNewAlert", func(t *testing.T) {
+		// sudah di bawah reorder point: alert yang terbuka tidak diduplikasi
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT quantity, reorder_point, version FROM warehouse_stock WHERE warehouse_id = \\$1 AND product_id = \\$2 FOR UPDATE").
+			WithArgs(1, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"quantity", "reorder_point", "version"}).AddRow(8, 10, 1))
+		expectNoLots()
+		mock.ExpectExec("UPDATE warehouse_stock SET quantity").
+			WithArgs(6, 1, 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("INSERT INTO stock_movements").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(7, time.Now()))
+		mock.ExpectCommit()
+
+		_, err := repo.ApplyChange(ctx, domain.StockChange{
+			WarehouseID:    1,
+			ProductID:      1,
+			Delta:          -2,
+			StockReference: domain.StockReference{Reason: domain.MovementReasonSale},
+		})
+		assert.NoError(t, err)
+	})
+
+	t.Run("ApplyChange_ReplenishResolvesAlert", func(t *testing.T) {
+		mock.ExpectBegin()
+		expectCapacityLock(mock, 1)
+		mock.ExpectQuery("SELECT quantity, reorder_point, version FROM warehouse_stock WHERE warehouse_id = \\$1 AND product_id = \\$2 FOR UPDATE").
+			WithArgs(1, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"quantity", "reorder_point", "version"}).AddRow(6, 10, 1))
+		expectNoCapacityLimit(mock, 1)
+		mock.ExpectExec("UPDATE warehouse_stock SET quantity").
+			WithArgs(26, 1, 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("INSERT INTO stock_movements").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(8, time.Now()))
+		mock.ExpectExec("UPDATE stock_alerts SET status = 'resolved'").
+			WithArgs(uint(1), uint(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		_, err := repo.ApplyChange(ctx, domain.StockChange{
+			WarehouseID:    1,
+			ProductID:      1,
+			Delta:          20,
+			StockReference: domain.StockReference{Reason: domain.MovementReasonRestock},
+		})
+		assert.NoError(t, err)
+	})
+
+	t.Run("ApplyChange_OverCapacityRejected", func(t *testing.T) {
+		mock.ExpectBegin()
+		expectCapacityLock(mock, 1)
+		mock.ExpectQuery("SELECT quantity, reorder_point, version FROM warehouse_stock WHERE warehouse_id = \\$1 AND product_id = \\$2 FOR UPDATE").
+			WithArgs(1, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"quantity", "reorder_point", "version"}).AddRow(10, nil, 1))
+		mock.ExpectQuery("SELECT max_weight_kg, max_volume_m3, capacity_policy FROM warehouses").
+			WithArgs(uint(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"max_weight_kg", "max_volume_m3", "capacity_policy"}).AddRow(100, nil, domain.CapacityPolicyReject))
+		mock.ExpectQuery("SELECT COALESCE\\(weight, 0\\), COALESCE\\(volume, 0\\) FROM products").
+			WithArgs(uint(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"weight", "volume"}).AddRow(2.5, 0))
+		// 95 kg sudah ada, 4 × 2.5 kg lagi melewati batas 100 kg
+		mock.ExpectQuery("SELECT COALESCE\\(SUM\\(s.quantity \\* p.weight\\), 0\\)").
+			WithArgs(uint(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"weight", "volume"}).AddRow(95, 0))
+		mock.ExpectRollback()
+
+		_, err := repo.ApplyChange(ctx, domain.StockChange{
+			WarehouseID:    1,
+			ProductID:      1,
+			Delta:          4,
+			StockReference: domain.StockReference{Reason: domain.MovementReasonRestock},
+		})
+		assert.ErrorIs(t, err, ErrCapacityExceeded)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("ApplyChange_OverCapacityWarns", func(t *testing.T) {
+		mock.ExpectBegin()
+		expectCapacityLock(mock, 1)
+		mock.ExpectQuery("SELECT quantity, reorder_point, version FROM warehouse_stock WHERE warehouse_id = \\$1 AND product_id = \\$2 FOR UPDATE").
+			WithArgs(1, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"quantity", "reorder_point", "version"}).AddRow(10, nil, 1))
+		mock.ExpectQuery("SELECT max_weight_kg, max_volume_m3, capacity_policy FROM warehouses").
+			WithArgs(uint(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"max_weight_kg", "max_volume_m3", "capacity_policy"}).AddRow(nil, 2, domain.CapacityPolicyWarn))
+		mock.ExpectQuery("SELECT COALESCE\\(weight, 0\\), COALESCE\\(volume, 0\\) FROM products").
+			WithArgs(uint(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"weight", "volume"}).AddRow(0, 0.1))
+		mock.ExpectQuery("SELECT COALESCE\\(SUM\\(s.quantity \\* p.weight\\), 0\\)").
+			WithArgs(uint(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"weight", "volume"}).AddRow(0, 1.5))
+		mock.ExpectExec("UPDATE warehouse_stock SET quantity").
+			WithArgs(16, 1, 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("INSERT INTO stock_movements").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(9, time.Now()))
+		mock.ExpectCommit()
+
+		result, err := repo.ApplyChange(ctx, domain.StockChange{
+			WarehouseID:    1,
+			ProductID:      1,
+			Delta:          6,
+			StockReference: domain.StockReference{Reason: domain.MovementReasonRestock},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "warehouse over capacity: volume would be 2.100 of 2.000 m3", result.CapacityWarning)
+	})
+
+	t.Run("ApplyChange_StockCountOverCapacityWarns", func(t *testing.T) {
+		// a count books stock that is on the shelf: no lock, only a warning
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT quantity, reorder_point, version FROM warehouse_stock WHERE warehouse_id = \\$1 AND product_id = \\$2 FOR UPDATE").
+			WithArgs(1, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"quantity", "reorder_point", "version"}).AddRow(10, nil, 1))
+		mock.ExpectQuery("SELECT max_weight_kg, max_volume_m3, capacity_policy FROM warehouses").
+			WithArgs(uint(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"max_weight_kg", "max_volume_m3", "capacity_policy"}).AddRow(100, nil, domain.CapacityPolicyReject))
+		mock.ExpectQuery("SELECT COALESCE\\(weight, 0\\), COALESCE\\(volume, 0\\) FROM products").
+			WithArgs(uint(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"weight", "volume"}).AddRow(2.5, 0))
+		mock.ExpectQuery("SELECT COALESCE\\(SUM\\(s.quantity \\* p.weight\\), 0\\)").
+			WithArgs(uint(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"weight", "volume"}).AddRow(95, 0))
+		mock.ExpectExec("UPDATE warehouse_stock SET quantity").
+			WithArgs(14, 1, 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("INSERT INTO stock_movements").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(11, time.Now()))
+		mock.ExpectCommit()
+
+		result, err := repo.ApplyChange(ctx, domain.StockChange{
+			WarehouseID: 1,
+			ProductID:   1,
+			Delta:       4,
+			StockReference: domain.StockReference{
+				Reason:        domain.MovementReasonAdjustment,
+				ReferenceType: domain.MovementRefStockCount,
+				ReferenceID:   3,
+			},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "warehouse over capacity: weight would be 105.00 of 100.00 kg", result.CapacityWarning)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("SetQuantityTx", func(t *testing.T) {
+		mock.ExpectBegin()
+		tx, err := db.Begin()
+		assert.NoError(t, err)
+
+		expectCapacityLock(mock, 2)
+		mock.ExpectQuery("SELECT quantity, reorder_point, version FROM warehouse_stock WHERE warehouse_id = \\$1 AND product_id = \\$2 FOR UPDATE").
+			WithArgs(2, 3).
+			WillReturnRows(sqlmock.NewRows([]string{"quantity", "reorder_point", "version"}).AddRow(7, nil, 1))
+		expectNoCapacityLimit(mock, 2)
+		mock.ExpectExec("UPDATE warehouse_stock SET quantity").
+			WithArgs(12, 2, 3).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("INSERT INTO stock_movements").
+			WithArgs(uint(2), uint(3), int32(5), int32(12), domain.MovementReasonAdjustment, nil, nil, nil, "stock import").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(10, time.Now()))
+
+		result, err := repo.SetQuantityTx(ctx, tx, 2, 3, 12, domain.StockReference{Note: "stock import"})
+		assert.NoError(t, err)
+		assert.Equal(t, int32(7), result.QuantityBefore)
+
+		mock.ExpectCommit()
+		assert.NoError(t, tx.Commit())
+	})
+
+	t.Run("GetQuantities", func(t *testing.T) {
+		mock.ExpectQuery("SELECT (.+) FROM warehouse_stock s JOIN unnest").
+			WillReturnRows(sqlmock.NewRows([]string{"warehouse_id", "product_id", "quantity"}).AddRow(1, 1, 15))
+
+		quantities, err := repo.GetQuantities(ctx, []domain.StockKey{{WarehouseID: 1, ProductID: 1}, {WarehouseID: 1, ProductID: 9}})
+		assert.NoError(t, err)
+		assert.Equal(t, map[domain.StockKey]int32{{WarehouseID: 1, ProductID: 1}: 15}, quantities)
+	})
+
+	t.Run("ListMovements", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"id", "warehouse_id", "product_id", "delta", "quantity_after", "reason",
+			"reference_type", "reference_id", "actor_id", "note", "created_at"}).
+			AddRow(9, 1, 1, -5, 10, "sale", "order", 42, 3, nil, time.Now()).
+			AddRow(8, 1, 1, 15, 15, "restock", nil, nil, nil, nil, time.Now())
+
+		mock.ExpectQuery("SELECT (.+) FROM stock_movements").
+			WithArgs(uint(1), uint(1), uint64(0), 2).
+			WillReturnRows(rows)
+
+		movements, err := repo.ListMovements(ctx, domain.StockMovementFilter{WarehouseID: 1, ProductID: 1, Limit: 2})
+		assert.NoError(t, err)
+		assert.Len(t, movements, 2)
+		assert.Equal(t, "order", *movements[0].ReferenceType)
+		assert.Nil(t, movements[1].ReferenceID)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWarehouseStockRepository_RebuildFromLedger(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewWarehouseStockRepository(db)
+	ctx := context.Background()
+	discrepancyColumns := []string{"warehouse_id", "product_id", "quantity", "ledger_quantity"}
+
+	t.Run("ReportOnly", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("WITH ledger AS (.+) FULL OUTER JOIN ledger").
+			WillReturnRows(sqlmock.NewRows(discrepancyColumns).AddRow(1, 2, 20, 5))
+		mock.ExpectRollback()
+
+		discrepancies, err := repo.RebuildFromLedger(ctx, false)
+		assert.NoError(t, err)
+		assert.Equal(t, []domain.StockDiscrepancy{{WarehouseID: 1, ProductID: 2, Quantity: 20, LedgerQuantity: 5}}, discrepancies)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("ApplyRaisesAndResolvesAlerts", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("LOCK TABLE warehouse_stock").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("WITH ledger AS (.+) FULL OUTER JOIN ledger").
+			WillReturnRows(sqlmock.NewRows(discrepancyColumns).
+				AddRow(1, 2, 20, 5). // drops below its reorder point of 10
+				AddRow(1, 3, 4, 30). // climbs back above it
+				AddRow(2, 2, 8, 6))  // stays below: the open alert is kept
+		expectRebuild := func(warehouseID, productID uint, ledger int32) {
+			mock.ExpectExec("INSERT INTO warehouse_stock (.+) ON CONFLICT").
+				WithArgs(warehouseID, productID).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery("UPDATE warehouse_stock SET quantity = \\$1(.+)RETURNING reorder_point").
+				WithArgs(ledger, warehouseID, productID).
+				WillReturnRows(sqlmock.NewRows([]string{"reorder_point"}).AddRow(10))
+		}
+		expectRebuild(1, 2, 5)
+		mock.ExpectExec("INSERT INTO stock_alerts (.+) ON CONFLICT").
+			WithArgs(uint(1), uint(2), int32(10), int32(5)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		expectRebuild(1, 3, 30)
+		mock.ExpectExec("UPDATE stock_alerts SET status = 'resolved'").
+			WithArgs(uint(1), uint(3)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		expectRebuild(2, 2, 6)
+		mock.ExpectCommit()
+
+		discrepancies, err := repo.RebuildFromLedger(ctx, true)
+		assert.NoError(t, err)
+		assert.Len(t, discrepancies, 3)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
 }
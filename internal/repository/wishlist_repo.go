@@ -0,0 +1,209 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+)
+
+var (
+	// ErrWishlistNotFound is returned when a wishlist lookup matches no row.
+	ErrWishlistNotFound = errors.New("wishlist not found")
+	// ErrWishlistItemNotFound is returned when a wishlist item lookup matches no row.
+	ErrWishlistItemNotFound = errors.New("wishlist item not found")
+	// ErrWishlistNameTaken is returned when the user already has a wishlist with that name.
+	ErrWishlistNameTaken = errors.New("wishlist name already used")
+)
+
+type WishlistRepository interface {
+	Create(ctx context.Context, wishlist *domain.Wishlist) error
+	GetByID(ctx context.Context, id uint) (*domain.Wishlist, error)
+	GetByUserID(ctx context.Context, userID uint) ([]domain.Wishlist, error)
+	GetByShareToken(ctx context.Context, token string) (*domain.Wishlist, error)
+	Rename(ctx context.Context, id uint, name string) error
+	SetShareToken(ctx context.Context, id uint, token *string) error
+	Delete(ctx context.Context, id uint) error
+
+	// AddItem stores product in the wishlist. Adding a product that is already
+	// listed is not an error: the existing row is returned and inserted is false.
+	AddItem(ctx context.Context, item *domain.WishlistItem) (inserted bool, err error)
+	GetItemByID(ctx context.Context, id uint) (*domain.WishlistItem, error)
+	GetItems(ctx context.Context, wishlistID uint) ([]domain.WishlistItem, error)
+	DeleteItem(ctx context.Context, id uint) error
+}
+
+type wishlistRepo struct {
+	db *sql.DB
+}
+
+func NewWishlistRepository(db *sql.DB) WishlistRepository {
+	return &wishlistRepo{db: db}
+}
+
+const wishlistColumns = `id, user_id, name, share_token, created_at, updated_at`
+
+func (r *wishlistRepo) Create(ctx context.Context, wishlist *domain.Wishlist) error {
+	query := `
+		INSERT INTO wishlists (user_id, name, created_at, updated_at)
+		VALUES ($1, $2, NOW(), NOW())
+		RETURNING id, created_at, updated_at
+	`
+	err := r.db.QueryRowContext(ctx, query, wishlist.UserID, wishlist.Name).
+		Scan(&wishlist.ID, &wishlist.CreatedAt, &wishlist.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrWishlistNameTaken
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create wishlist: %w", err)
+	}
+	return nil
+}
+
+func (r *wishlistRepo) GetByID(ctx context.Context, id uint) (*domain.Wishlist, error) {
+	query := `SELECT ` + wishlistColumns + ` FROM wishlists WHERE id = $1`
+	return r.scanOne(r.db.QueryRowContext(ctx, query, id))
+}
+
+func (r *wishlistRepo) GetByShareToken(ctx context.Context, token string) (*domain.Wishlist, error) {
+	query := `SELECT ` + wishlistColumns + ` FROM wishlists WHERE share_token = $1`
+	return r.scanOne(r.db.QueryRowContext(ctx, query, token))
+}
+
+func (r *wishlistRepo) GetByUserID(ctx context.Context, userID uint) ([]domain.Wishlist, error) {
+	query := `SELECT ` + wishlistColumns + ` FROM wishlists WHERE user_id = $1 ORDER BY created_at ASC`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query wishlists: %w", err)
+	}
+	defer rows.Close()
+
+	wishlists := []domain.Wishlist{}
+	for rows.Next() {
+		var w domain.Wishlist
+		if err := rows.Scan(&w.ID, &w.UserID, &w.Name, &w.ShareToken, &w.CreatedAt, &w.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan wishlist: %w", err)
+		}
+		wishlists = append(wishlists, w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating wishlists: %w", err)
+	}
+	return wishlists, nil
+}
+
+func (r *wishlistRepo) Rename(ctx context.Context, id uint, name string) error {
+	query := `UPDATE wishlists SET name = $1, updated_at = NOW() WHERE id = $2`
+	res, err := r.db.ExecContext(ctx, query, name, id)
+	if isUniqueViolation(err) {
+		return ErrWishlistNameTaken
+	}
+	if err != nil {
+		return fmt.Errorf("failed to rename wishlist: %w", err)
+	}
+	return expectAffected(res, ErrWishlistNotFound)
+}
+
+func (r *wishlistRepo) SetShareToken(ctx context.Context, id uint, token *string) error {
+	query := `UPDATE wishlists SET share_token = $1, updated_at = NOW() WHERE id = $2`
+	res, err := r.db.ExecContext(ctx, query, token, id)
+	if err != nil {
+		return fmt.Errorf("failed to update wishlist share token: %w", err)
+	}
+	return expectAffected(res, ErrWishlistNotFound)
+}
+
+func (r *wishlistRepo) Delete(ctx context.Context, id uint) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM wishlists WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete wishlist: %w", err)
+	}
+	return expectAffected(res, ErrWishlistNotFound)
+}
+
+func (r *wishlistRepo) AddItem(ctx context.Context, item *domain.WishlistItem) (bool, error) {
+	// DO UPDATE (bukan DO NOTHING) supaya RETURNING tetap mengembalikan baris lama;
+	// xmax = 0 hanya benar untuk baris yang baru di-insert.
+	query := `
+		INSERT INTO wishlist_items (wishlist_id, product_id, created_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (wishlist_id, product_id) DO UPDATE SET wishlist_id = EXCLUDED.wishlist_id
+		RETURNING id, created_at, (xmax = 0) AS inserted
+	`
+	var inserted bool
+	err := r.db.QueryRowContext(ctx, query, item.WishlistID, item.ProductID).
+		Scan(&item.ID, &item.CreatedAt, &inserted)
+	if err != nil {
+		return false, fmt.Errorf("failed to add wishlist item: %w", err)
+	}
+	return inserted, nil
+}
+
+func (r *wishlistRepo) GetItemByID(ctx context.Context, id uint) (*domain.WishlistItem, error) {
+	query := `SELECT id, wishlist_id, product_id, created_at FROM wishlist_items WHERE id = $1`
+	var item domain.WishlistItem
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&item.ID, &item.WishlistID, &item.ProductID, &item.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWishlistItemNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query wishlist item: %w", err)
+	}
+	return &item, nil
+}
+
+func (r *wishlistRepo) GetItems(ctx context.Context, wishlistID uint) ([]domain.WishlistItem, error) {
+	query := `SELECT id, wishlist_id, product_id, created_at FROM wishlist_items WHERE wishlist_id = $1 ORDER BY created_at ASC`
+	rows, err := r.db.QueryContext(ctx, query, wishlistID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query wishlist items: %w", err)
+	}
+	defer rows.Close()
+
+	items := []domain.WishlistItem{}
+	for rows.Next() {
+		var item domain.WishlistItem
+		if err := rows.Scan(&item.ID, &item.WishlistID, &item.ProductID, &item.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan wishlist item: %w", err)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating wishlist items: %w", err)
+	}
+	return items, nil
+}
+
+func (r *wishlistRepo) DeleteItem(ctx context.Context, id uint) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM wishlist_items WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete wishlist item: %w", err)
+	}
+	return expectAffected(res, ErrWishlistItemNotFound)
+}
+
+func (r *wishlistRepo) scanOne(row *sql.Row) (*domain.Wishlist, error) {
+	var w domain.Wishlist
+	err := row.Scan(&w.ID, &w.UserID, &w.Name, &w.ShareToken, &w.CreatedAt, &w.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWishlistNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query wishlist: %w", err)
+	}
+	return &w, nil
+}
+
+// expectAffected returns notFound when res touched no rows.
+func expectAffected(res sql.Result, notFound error) error {
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("could not check affected rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		return notFound
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWishlistRepository_Create(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := NewWishlistRepository(db)
+	ctx := context.Background()
+	now := time.Now()
+
+	t.Run("Success", func(t *testing.T) {
+		w := &domain.Wishlist{UserID: 1, Name: "Birthday"}
+		mock.ExpectQuery("INSERT INTO wishlists").
+			WithArgs(w.UserID, w.Name).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(4, now, now))
+
+		err := repo.Create(ctx, w)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(4), w.ID)
+	})
+
+	t.Run("DuplicateName", func(t *testing.T) {
+		w := &domain.Wishlist{UserID: 1, Name: "Birthday"}
+		mock.ExpectQuery("INSERT INTO wishlists").
+			WithArgs(w.UserID, w.Name).
+			WillReturnError(&pq.Error{Code: "23505"})
+
+		err := repo.Create(ctx, w)
+		assert.ErrorIs(t, err, ErrWishlistNameTaken)
+	})
+}
+
+func TestWishlistRepository_AddItem(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := NewWishlistRepository(db)
+	ctx := context.Background()
+	now := time.Now()
+
+	t.Run("NewItem", func(t *testing.T) {
+		item := &domain.WishlistItem{WishlistID: 4, ProductID: 2}
+		mock.ExpectQuery("INSERT INTO wishlist_items (.+) ON CONFLICT").
+			WithArgs(item.WishlistID, item.ProductID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "inserted"}).AddRow(11, now, true))
+
+		inserted, err := repo.AddItem(ctx, item)
+		assert.NoError(t, err)
+		assert.True(t, inserted)
+		assert.Equal(t, uint(11), item.ID)
+	})
+
+	t.Run("AlreadyListed", func(t *testing.T) {
+		item := &domain.WishlistItem{WishlistID: 4, ProductID: 2}
+		mock.ExpectQuery("INSERT INTO wishlist_items (.+) ON CONFLICT").
+			WithArgs(item.WishlistID, item.ProductID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "inserted"}).AddRow(11, now, false))
+
+		inserted, err := repo.AddItem(ctx, item)
+		assert.NoError(t, err)
+		assert.False(t, inserted)
+		assert.Equal(t, uint(11), item.ID)
+	})
+}
+
+func TestWishlistRepository_GetByShareToken(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := NewWishlistRepository(db)
+	ctx := context.Background()
+
+	mock.ExpectQuery("SELECT (.+) FROM wishlists WHERE share_token =").
+		WithArgs("missing").
+		WillReturnError(sql.ErrNoRows)
+
+	w, err := repo.GetByShareToken(ctx, "missing")
+	assert.Nil(t, w)
+	assert.ErrorIs(t, err, ErrWishlistNotFound)
+}
@@ -0,0 +1,203 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/repository"
+	"github.com/ifs21014-itdel/concurrent-order-processor/pkg/notifier"
+)
+
+// AbandonedCartOptions configures the abandoned cart job.
+type AbandonedCartOptions struct {
+	// AbandonAfter is how long a cart must be idle before it counts as abandoned.
+	AbandonAfter time.Duration
+	// CheckInterval is how often the job runs.
+	CheckInterval time.Duration
+	// MaxReminders caps the reminders sent for one cart, over all the times
+	// it was abandoned.
+	MaxReminders int
+	// ReminderGap is the minimum time between two reminders for the same cart.
+	ReminderGap time.Duration
+}
+
+// AbandonedCartRunResult summarises one pass of the job.
+type AbandonedCartRunResult struct {
+	Detected      int `json:"detected"`
+	RemindersSent int `json:"reminders_sent"`
+	Recovered     int `json:"recovered"`
+	Failed        int `json:"failed"`
+}
+
+type AbandonedCartUsecase struct {
+	repo     repository.CartAbandonmentRepository
+	userRepo repository.UserRepository
+	notifier notifier.Notifier
+	opts     AbandonedCartOptions
+}
+
+func NewAbandonedCartUsecase(
+	repo repository.CartAbandonmentRepository,
+	userRepo repository.UserRepository,
+	n notifier.Notifier,
+	opts AbandonedCartOptions,
+) *AbandonedCartUsecase {
+	if opts.AbandonAfter <= 0 {
+		opts.AbandonAfter = 24 * time.Hour
+	}
+	if opts.CheckInterval <= 0 {
+		opts.CheckInterval = time.Hour
+	}
+	if opts.MaxReminders < 0 {
+		opts.MaxReminders = 0
+	}
+	if opts.ReminderGap <= 0 {
+		opts.ReminderGap = opts.AbandonAfter
+	}
+	return &AbandonedCartUsecase{
+		repo:     repo,
+		userRepo: userRepo,
+		notifier: n,
+		opts:     opts,
+	}
+}
+
+// Run executes the job every CheckInterval until ctx is cancelled.
+func (u *AbandonedCartUsecase) Run(ctx context.Context) {
+	log.Printf("[JOB] Abandoned cart job started (idle=%s interval=%s max_reminders=%d)",
+		u.opts.AbandonAfter, u.opts.CheckInterval, u.opts.MaxReminders)
+
+	ticker := time.NewTicker(u.opts.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := u.RunOnce(ctx); err != nil {
+			log.Printf("[ERROR] Abandoned cart job failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("[JOB] Abandoned cart job stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce performs one pass: it closes recovered abandonments, sends due
+// reminders for the open ones and records newly abandoned carts.
+func (u *AbandonedCartUsecase) RunOnce(ctx context.Context) (*AbandonedCartRunResult, error) {
+	now := time.Now()
+	result := &AbandonedCartRunResult{}
+
+	open, err := u.repo.ListOpen(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range open {
+		// any cart activity or a new order after detection counts as a recovery
+		if a.OrderedSince || a.CurrentActivityAt.After(a.LastActivityAt) {
+			if err := u.repo.MarkRecovered(ctx, a.ID, now); err != nil {
+				log.Printf("[ERROR] Failed to mark cart %d recovered: %v", a.CartID, err)
+				result.Failed++
+				continue
+			}
+			result.Recovered++
+			continue
+		}
+		// an emptied cart has nothing left to remind about
+		if a.HasItems && u.reminderDue(a.CartAbandonment, a.CartRemindersSent, now) {
+			u.remind(ctx, a.CartAbandonment, now, result)
+		}
+	}
+
+	stale, err := u.repo.FindStaleCarts(ctx, now.Add(-u.opts.AbandonAfter))
+	if err != nil {
+		return nil, err
+	}
+	for _, cart := range stale {
+		a := &domain.CartAbandonment{
+			CartID:         cart.CartID,
+			UserID:         cart.UserID,
+			LastActivityAt: cart.LastActivityAt,
+		}
+		if err := u.repo.Create(ctx, a); err != nil {
+			log.Printf("[ERROR] Failed to record abandoned cart %d: %v", cart.CartID, err)
+			result.Failed++
+			continue
+		}
+		result.Detected++
+		if cart.RemindersSent < u.opts.MaxReminders {
+			u.remind(ctx, *a, now, result)
+		}
+	}
+
+	log.Printf("[JOB] Abandoned carts: detected=%d reminded=%d recovered=%d failed=%d",
+		result.Detected, result.RemindersSent, result.Recovered, result.Failed)
+	return result, nil
+}
+
+// Report returns abandonment and recovery figures for carts since the given time.
+func (u *AbandonedCartUsecase) Report(ctx context.Context, since time.Time) (*domain.CartAbandonmentStats, error) {
+	stats, err := u.repo.Stats(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+	if stats.CartsCreated > 0 {
+		stats.AbandonmentRate = float64(stats.CartsAbandoned) / float64(stats.CartsCreated)
+	}
+	if stats.Abandoned > 0 {
+		stats.RecoveryRate = float64(stats.Recovered) / float64(stats.Abandoned)
+	}
+	return stats, nil
+}
+
+// reminderDue decides on a reminder for a; cartReminders is what the cart got
+// over all its abandonments, a's own included.
+func (u *AbandonedCartUsecase) reminderDue(a domain.CartAbandonment, cartReminders int, now time.Time) bool {
+	if cartReminders >= u.opts.MaxReminders {
+		return false
+	}
+	if a.LastRemindedAt == nil {
+		return true
+	}
+	return now.Sub(*a.LastRemindedAt) >= u.opts.ReminderGap
+}
+
+func (u *AbandonedCartUsecase) remind(ctx context.Context, a domain.CartAbandonment, now time.Time, result *AbandonedCartRunResult) {
+	user, err := u.userRepo.FindByID(a.UserID)
+	if err != nil || user == nil {
+		log.Printf("[ERROR] Failed to load user %d for cart reminder: %v", a.UserID, err)
+		result.Failed++
+		return
+	}
+
+	msg := notifier.Message{
+		To:      user.Email,
+		Subject: "You left something in your cart",
+		Body: fmt.Sprintf("Hi %s,\n\nYou still have items waiting in your cart (cart #%d). "+
+			"Come back and complete your order before they sell out.\n", user.Name, a.CartID),
+		Tags: map[string]string{
+			"type":           "abandoned_cart",
+			"cart_id":        strconv.FormatUint(uint64(a.CartID), 10),
+			"abandonment_id": strconv.FormatUint(uint64(a.ID), 10),
+			"reminder":       strconv.Itoa(a.RemindersSent + 1),
+		},
+	}
+	if err := u.notifier.Notify(ctx, msg); err != nil {
+		log.Printf("[ERROR] Failed to send reminder for cart %d: %v", a.CartID, err)
+		result.Failed++
+		return
+	}
+
+	if err := u.repo.MarkReminded(ctx, a.ID, now); err != nil {
+		log.Printf("[ERROR] Failed to record reminder for cart %d: %v", a.CartID, err)
+		result.Failed++
+		return
+	}
+	result.RemindersSent++
+}
@@ -0,0 +1,149 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/repository"
+	"github.com/ifs21014-itdel/concurrent-order-processor/pkg/notifier"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeAbandonmentRepo keeps abandonments in memory for carts whose last
+// activity the test sets directly; every cart has items and nobody orders.
+type fakeAbandonmentRepo struct {
+	repository.CartAbandonmentRepository
+	activity map[uint]time.Time
+	rows     []domain.CartAbandonment
+}
+
+func (f *fakeAbandonmentRepo) cartReminders(cartID uint) int {
+	n := 0
+	for _, r := range f.rows {
+		if r.CartID == cartID {
+			n += r.RemindersSent
+		}
+	}
+	return n
+}
+
+func (f *fakeAbandonmentRepo) FindStaleCarts(ctx context.Context, inactiveSince time.Time) ([]domain.StaleCart, error) {
+	var stale []domain.StaleCart
+	for cartID, at := range f.activity {
+		if !at.Before(inactiveSince) {
+			continue
+		}
+		tracked := false
+		for _, r := range f.rows {
+			if r.CartID == cartID && (r.RecoveredAt == nil || !r.LastActivityAt.Before(at)) {
+				tracked = true
+			}
+		}
+		if !tracked {
+			stale = append(stale, domain.StaleCart{CartID: cartID, UserID: 1, ItemCount: 1,
+				LastActivityAt: at, RemindersSent: f.cartReminders(cartID)})
+		}
+	}
+	return stale, nil
+}
+
+func (f *fakeAbandonmentRepo) Create(ctx context.Context, a *domain.CartAbandonment) error {
+	a.ID = uint(len(f.rows) + 1)
+	a.DetectedAt = time.Now()
+	f.rows = append(f.rows, *a)
+	return nil
+}
+
+func (f *fakeAbandonmentRepo) ListOpen(ctx context.Context) ([]domain.OpenCartAbandonment, error) {
+	var open []domain.OpenCartAbandonment
+	for _, r := range f.rows {
+		if r.RecoveredAt == nil {
+			open = append(open, domain.OpenCartAbandonment{CartAbandonment: r,
+				CartRemindersSent: f.cartReminders(r.CartID), CurrentActivityAt: f.activity[r.CartID], HasItems: true})
+		}
+	}
+	return open, nil
+}
+
+func (f *fakeAbandonmentRepo) MarkReminded(ctx context.Context, id uint, at time.Time) error {
+	f.rows[id-1].RemindersSent++
+	f.rows[id-1].LastRemindedAt = &at
+	return nil
+}
+
+func (f *fakeAbandonmentRepo) MarkRecovered(ctx context.Context, id uint, at time.Time) error {
+	f.rows[id-1].RecoveredAt = &at
+	return nil
+}
+
+type fakeUserRepo struct {
+	repository.UserRepository
+}
+
+func (fakeUserRepo) FindByID(id uint) (*domain.User, error) {
+	return &domain.User{ID: id, Email: "user@example.com"}, nil
+}
+
+type countingNotifier struct{ sent int }
+
+func (n *countingNotifier) Notify(ctx context.Context, msg notifier.Message) error {
+	n.sent++
+	return nil
+}
+
+func TestAbandonedCartUsecase_ReminderCapPerCart(t *testing.T) {
+	now := time.Now()
+	repo := &fakeAbandonmentRepo{activity: map[uint]time.Time{3: now.Add(-3 * time.Hour)}}
+	n := &countingNotifier{}
+	u := NewAbandonedCartUsecase(repo, fakeUserRepo{}, n, AbandonedCartOptions{
+		AbandonAfter: time.Hour,
+		MaxReminders: 2,
+		ReminderGap:  time.Nanosecond,
+	})
+	ctx := context.Background()
+
+	// terdeteksi dan diingatkan, lalu diingatkan sekali lagi sampai batas
+	for i := 0; i < 3; i++ {
+		_, err := u.RunOnce(ctx)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 2, n.sent)
+
+	// cart disentuh lalu ditinggal lagi: abandonment baru, tapi tanpa reminder
+	repo.activity[3] = now.Add(-2 * time.Hour)
+	result, err := u.RunOnce(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Recovered)
+	assert.Equal(t, 1, result.Detected)
+	assert.Equal(t, 0, result.RemindersSent)
+
+	result, err = u.RunOnce(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, result.RemindersSent)
+	assert.Equal(t, 2, n.sent)
+	assert.Len(t, repo.rows, 2)
+}
+
+func TestAbandonedCartUsecase_Report(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	u := NewAbandonedCartUsecase(repository.NewCartAbandonmentRepository(db), nil, nil, AbandonedCartOptions{})
+	since := time.Now().Add(-30 * 24 * time.Hour)
+
+	// 9 dari 40 cart baru pernah ditinggal; 12 abandonment terdeteksi karena
+	// sebagian cart ditinggal lagi setelah pulih, dan itu tidak menaikkan rate
+	mock.ExpectQuery("SELECT (.+) FROM cart_abandonments WHERE detected_at >=").
+		WithArgs(since).
+		WillReturnRows(sqlmock.NewRows([]string{"carts", "carts_abandoned", "abandoned", "recovered", "recovered_after", "reminders"}).
+			AddRow(40, 9, 12, 3, 2, 20))
+
+	stats, err := u.Report(context.Background(), since)
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.225, stats.AbandonmentRate, 1e-9)
+	assert.InDelta(t, 0.25, stats.RecoveryRate, 1e-9)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,43 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/repository"
+)
+
+// AddressUsecase mengelola alamat kirim user. Koordinatnya dipakai order
+// routing untuk memilih gudang terdekat.
+type AddressUsecase struct {
+	repo repository.AddressRepository
+}
+
+func NewAddressUsecase(repo repository.AddressRepository) *AddressUsecase {
+	return &AddressUsecase{repo: repo}
+}
+
+func (u *AddressUsecase) CreateAddress(ctx context.Context, address *domain.CustomerAddress) error {
+	address.Address = strings.TrimSpace(address.Address)
+	if address.Address == "" {
+		return errors.New("address cannot be empty")
+	}
+	if !address.Point().Valid() {
+		return errors.New("latitude must be within [-90, 90] and longitude within [-180, 180]")
+	}
+	return u.repo.Create(ctx, address)
+}
+
+func (u *AddressUsecase) GetAddresses(ctx context.Context, userID uint) ([]domain.CustomerAddress, error) {
+	return u.repo.GetByUserID(ctx, userID)
+}
+
+func (u *AddressUsecase) GetAddress(ctx context.Context, userID, id uint) (*domain.CustomerAddress, error) {
+	return u.repo.GetByID(ctx, id, userID)
+}
+
+func (u *AddressUsecase) DeleteAddress(ctx context.Context, userID, id uint) error {
+	return u.repo.Delete(ctx, id, userID)
+}
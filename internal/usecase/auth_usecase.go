@@ -86,6 +86,16 @@ func (a *AuthUsecase) GenerateTOTPForUser(userID uint, issuer string) (string, s
 	return key, uri, nil
 }
 
+// IsAdmin melihat role user di database, bukan di token, supaya role yang
+// dicabut langsung berlaku.
+func (a *AuthUsecase) IsAdmin(userID uint) (bool, error) {
+	u, err := a.repo.FindByID(userID)
+	if err != nil || u == nil {
+		return false, errors.New("user not found")
+	}
+	return u.Role == domain.UserRoleAdmin, nil
+}
+
 func (a *AuthUsecase) VerifyAndEnableTOTP(userID uint, code string) (bool, error) {
 	u, err := a.repo.FindByID(userID)
 	if err != nil || u == nil {
@@ -2,6 +2,7 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"sync"
@@ -9,83 +10,177 @@ import (
 	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
 	"github.com/ifs21014-itdel/concurrent-order-processor/internal/repository"
 	"github.com/ifs21014-itdel/concurrent-order-processor/internal/utils"
+	"github.com/redis/go-redis/v9"
 )
 
+var (
+	// ErrCartForbidden is returned when a user touches a cart they do not own.
+	ErrCartForbidden = errors.New("cart does not belong to this user")
+	// ErrInvalidCartInput wraps cart and cart item validation failures.
+	ErrInvalidCartInput = errors.New("invalid cart input")
+)
+
+// CartItemQuantityUpdate is one entry of a batch quantity update.
+type CartItemQuantityUpdate struct {
+	ID       uint  `json:"id"`
+	Quantity int32 `json:"quantity"`
+}
+
+// CartItemUpdateResult reports the outcome of a single item in a batch update.
+type CartItemUpdateResult struct {
+	ID      uint             `json:"id"`
+	Success bool             `json:"success"`
+	Item    *domain.CartItem `json:"item,omitempty"`
+	Error   string           `json:"error,omitempty"`
+}
+
 // CartItemUsecase - Jika masih diperlukan untuk operasi spesifik cart item
 type CartItemUsecase struct {
-	repo repository.CartItemRepository
+	repo     repository.CartItemRepository
+	cartRepo repository.CartRepository
+	cache    *redis.Client
 }
 
-func NewCartItemUsecase(repo repository.CartItemRepository) *CartItemUsecase {
-	return &CartItemUsecase{repo: repo}
+func NewCartItemUsecase(repo repository.CartItemRepository, cartRepo repository.CartRepository, cache *redis.Client) *CartItemUsecase {
+	return &CartItemUsecase{
+		repo:     repo,
+		cartRepo: cartRepo,
+		cache:    cache,
+	}
 }
 
 func (u *CartItemUsecase) UpdateCartItem(ctx context.Context, item *domain.CartItem) error {
 	if item.ID == 0 {
-		return fmt.Errorf("invalid item ID")
+		return fmt.Errorf("%w: invalid item ID", ErrInvalidCartInput)
 	}
 	if item.Quantity <= 0 {
-		return fmt.Errorf("quantity must be greater than zero")
+		return fmt.Errorf("%w: quantity must be greater than zero", ErrInvalidCartInput)
 	}
 	if item.SubTotal < 0 {
-		return fmt.Errorf("subtotal cannot be negative")
+		return fmt.Errorf("%w: subtotal cannot be negative", ErrInvalidCartInput)
 	}
 	return u.repo.UpdateCartItem(ctx, item)
 }
 
+// UpdateItemQuantity changes the quantity of a cart item owned by userID.
+// The sub total is rescaled with the unit price the item was added at.
+func (u *CartItemUsecase) UpdateItemQuantity(ctx context.Context, userID, itemID uint, quantity int32) (*domain.CartItem, error) {
+	if quantity <= 0 {
+		return nil, fmt.Errorf("%w: quantity must be greater than zero", ErrInvalidCartInput)
+	}
+
+	item, err := u.getOwnedItem(ctx, userID, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	unitPrice := 0.0
+	if item.Quantity > 0 {
+		unitPrice = item.SubTotal / float64(item.Quantity)
+	}
+	item.Quantity = quantity
+	item.SubTotal = unitPrice * float64(quantity)
+
+	if err := u.UpdateCartItem(ctx, item); err != nil {
+		return nil, err
+	}
+
+	invalidateUserCartCache(ctx, u.cache, userID)
+	return item, nil
+}
+
 func (u *CartItemUsecase) GetCartItemsByCartID(ctx context.Context, cartID uint) ([]domain.CartItem, error) {
 	if cartID == 0 {
-		return nil, fmt.Errorf("invalid cart ID")
+		return nil, fmt.Errorf("%w: invalid cart ID", ErrInvalidCartInput)
 	}
 	return u.repo.GetCartItemsByCartID(ctx, cartID)
 }
 
-func (u *CartItemUsecase) ClearCart(ctx context.Context, cartID uint) error {
+// ClearCart removes every item from a cart owned by userID.
+func (u *CartItemUsecase) ClearCart(ctx context.Context, userID, cartID uint) error {
 	if cartID == 0 {
-		return fmt.Errorf("invalid cart ID")
+		return fmt.Errorf("%w: invalid cart ID", ErrInvalidCartInput)
+	}
+	if _, err := u.getOwnedCart(ctx, userID, cartID); err != nil {
+		return err
+	}
+	if err := u.repo.ClearCart(ctx, cartID); err != nil {
+		return fmt.Errorf("failed to clear cart: %w", err)
 	}
-	return u.repo.ClearCart(ctx, cartID)
+
+	invalidateUserCartCache(ctx, u.cache, userID)
+	return nil
 }
 
-// ConcurrentUpdateItems - Batch update cart items secara concurrent
-func (u *CartItemUsecase) ConcurrentUpdateItems(ctx context.Context, updates []domain.CartItem) error {
+// ConcurrentUpdateItems - Batch update cart items secara concurrent.
+// Setiap item dilaporkan hasilnya sendiri, urutan result mengikuti urutan input.
+func (u *CartItemUsecase) ConcurrentUpdateItems(ctx context.Context, userID uint, updates []CartItemQuantityUpdate) ([]CartItemUpdateResult, error) {
 	if len(updates) == 0 {
-		return fmt.Errorf("no items to update")
+		return nil, fmt.Errorf("%w: no items to update", ErrInvalidCartInput)
 	}
 
+	results := make([]CartItemUpdateResult, len(updates))
 	var wg sync.WaitGroup
-	errChan := make(chan error, len(updates))
 
-	for _, item := range updates {
+	for i, update := range updates {
 		wg.Add(1)
-		localItem := item
+		i, update := i, update
 
-		go utils.SafeGoroutine(fmt.Sprintf("Do Update Item: %d", localItem.ID), func() {
+		go utils.SafeGoroutine(fmt.Sprintf("Do Update Item: %d", update.ID), func() {
 			defer wg.Done()
 
-			if err := u.UpdateCartItem(ctx, &localItem); err != nil {
-				errChan <- fmt.Errorf("failed to update cart item ID %d: %w", localItem.ID, err)
-				log.Printf("[ERROR] Failed to update cart item ID %d: %v", localItem.ID, err)
+			results[i] = CartItemUpdateResult{ID: update.ID}
+			item, err := u.UpdateItemQuantity(ctx, userID, update.ID, update.Quantity)
+			if err != nil {
+				results[i].Error = err.Error()
+				log.Printf("[ERROR] Failed to update cart item ID %d: %v", update.ID, err)
 				return
 			}
 
-			log.Printf("[OK] Cart item ID %d has been successfully updated", localItem.ID)
+			results[i].Success = true
+			results[i].Item = item
+			log.Printf("[OK] Cart item ID %d has been successfully updated", update.ID)
 		})
 	}
 
 	wg.Wait()
-	close(errChan)
 
-	// Collect errors if any
-	var errors []error
-	for err := range errChan {
-		errors = append(errors, err)
+	failed := 0
+	for i := range results {
+		if !results[i].Success {
+			failed++
+			if results[i].Error == "" {
+				// SafeGoroutine recovered a panic before the result was filled in
+				results[i].ID = updates[i].ID
+				results[i].Error = "internal error while updating cart item"
+			}
+		}
 	}
+	log.Printf("[DONE] %d of %d cart items updated successfully", len(updates)-failed, len(updates))
+	return results, nil
+}
 
-	if len(errors) > 0 {
-		return fmt.Errorf("some cart items failed to update: %d errors occurred", len(errors))
+func (u *CartItemUsecase) getOwnedCart(ctx context.Context, userID, cartID uint) (*domain.Cart, error) {
+	cart, err := u.cartRepo.GetCartByID(ctx, cartID)
+	if err != nil {
+		return nil, err
+	}
+	if cart.UserID != userID {
+		return nil, ErrCartForbidden
 	}
+	return cart, nil
+}
 
-	log.Printf("[DONE] All %d cart items updated successfully", len(updates))
-	return nil
+func (u *CartItemUsecase) getOwnedItem(ctx context.Context, userID, itemID uint) (*domain.CartItem, error) {
+	if itemID == 0 {
+		return nil, fmt.Errorf("%w: invalid item ID", ErrInvalidCartInput)
+	}
+	item, err := u.repo.GetCartItemByID(ctx, itemID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := u.getOwnedCart(ctx, userID, item.CartID); err != nil {
+		return nil, err
+	}
+	return item, nil
 }
@@ -0,0 +1,24 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCartItemUsecase_InvalidInput(t *testing.T) {
+	u := NewCartItemUsecase(nil, nil, nil)
+	ctx := context.Background()
+
+	_, err := u.UpdateItemQuantity(ctx, 1, 5, 0)
+	assert.ErrorIs(t, err, ErrInvalidCartInput)
+
+	_, err = u.UpdateItemQuantity(ctx, 1, 0, 2)
+	assert.ErrorIs(t, err, ErrInvalidCartInput)
+
+	assert.ErrorIs(t, u.ClearCart(ctx, 1, 0), ErrInvalidCartInput)
+
+	_, err = u.ConcurrentUpdateItems(ctx, 1, nil)
+	assert.ErrorIs(t, err, ErrInvalidCartInput)
+}
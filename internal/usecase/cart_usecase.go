@@ -2,8 +2,8 @@ package usecase
 
 import (
 	"context"
-	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -22,21 +22,30 @@ type AddItemToCartRequest struct {
 }
 
 type CartWithItemsResponse struct {
-	Cart  domain.Cart       `json:"cart"`
-	Items []domain.CartItem `json:"items"`
+	Cart   domain.Cart       `json:"cart"`
+	Items  []domain.CartItem `json:"items"`
+	Totals *PriceQuote       `json:"totals,omitempty"`
 }
 
 type CartUsecase struct {
 	cartRepo     repository.CartRepository
 	cartItemRepo repository.CartItemRepository
+	pricing      *PricingUsecase
 	cache        *redis.Client
+	// readCache is false when carts already live in Redis: caching the
+	// assembled response on top of that only adds invalidation misses.
+	readCache bool
 }
 
-func NewCartUsecase(cartRepo repository.CartRepository, cartItemRepo repository.CartItemRepository, cache *redis.Client) *CartUsecase {
+// NewCartUsecase builds the cart usecase on top of the configured storage
+// (see repository.NewCartStorage).
+func NewCartUsecase(storage *repository.CartStorage, pricing *PricingUsecase, cache *redis.Client) *CartUsecase {
 	return &CartUsecase{
-		cartRepo:     cartRepo,
-		cartItemRepo: cartItemRepo,
+		cartRepo:     storage.Carts,
+		cartItemRepo: storage.Items,
+		pricing:      pricing,
 		cache:        cache,
+		readCache:    storage.Kind != repository.CartStorageRedis,
 	}
 }
 
@@ -108,14 +117,34 @@ func (u *CartUsecase) DeleteCartItem(ctx context.Context, id uint, userID uint)
 	return nil
 }
 
-// GetCartsWithItemsByUserID - Get all carts with items for a user (cached)
+// GetCartsWithItemsByUserID - Get all carts with items for a user, each with
+// server-computed totals. Only carts and items are cached; totals are always
+// computed from current prices.
 func (u *CartUsecase) GetCartsWithItemsByUserID(ctx context.Context, userID uint) ([]CartWithItemsResponse, error) {
 	if err := u.validateID(userID, "user"); err != nil {
 		return nil, err
 	}
 
+	carts, err := u.loadCartsWithItems(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range carts {
+		quote, err := u.pricing.QuoteCart(ctx, &carts[i].Cart, carts[i].Items)
+		if err != nil {
+			return nil, fmt.Errorf("failed to price cart ID %d: %w", carts[i].Cart.ID, err)
+		}
+		carts[i].Totals = quote
+	}
+	return carts, nil
+}
+
+// loadCartsWithItems - Get all carts with items for a user (cached)
+func (u *CartUsecase) loadCartsWithItems(ctx context.Context, userID uint) ([]CartWithItemsResponse, error) {
+
 	cacheKey := fmt.Sprintf("%s%d", cartsByUserKeyPrefix, userID)
-	if u.cache != nil {
+	if u.cache != nil && u.readCache {
 		cached, err := u.cache.Get(ctx, cacheKey).Result()
 		if err == nil {
 			var carts []CartWithItemsResponse
@@ -151,7 +180,7 @@ func (u *CartUsecase) GetCartsWithItemsByUserID(ctx context.Context, userID uint
 	}
 
 	// Cache the result
-	if u.cache != nil {
+	if u.cache != nil && u.readCache {
 		if data, err := json.Marshal(result); err == nil {
 			if err := u.cache.Set(ctx, cacheKey, data, cartTTL).Err(); err != nil {
 				log.Printf("⚠️ [CACHE SET ERROR] Failed to save cart data to Redis: %v\n", err)
@@ -167,7 +196,7 @@ func (u *CartUsecase) GetCartsWithItemsByUserID(ctx context.Context, userID uint
 // getOrCreateCart - Get existing cart or create a new one
 func (u *CartUsecase) getOrCreateCart(ctx context.Context, userID, warehouseID uint) (*domain.Cart, error) {
 	cart, err := u.cartRepo.FindByUserAndWarehouse(ctx, userID, warehouseID)
-	if err != nil && err != sql.ErrNoRows {
+	if err != nil && !errors.Is(err, repository.ErrCartNotFound) {
 		return nil, err
 	}
 
@@ -193,29 +222,35 @@ func (u *CartUsecase) getOrCreateCart(ctx context.Context, userID, warehouseID u
 
 // Helper to invalidate user cart cache
 func (u *CartUsecase) invalidateUserCartCache(ctx context.Context, userID uint) {
-	if u.cache == nil {
+	invalidateUserCartCache(ctx, u.cache, userID)
+}
+
+// invalidateUserCartCache drops the cached cart list of a user. Shared by every
+// usecase that mutates carts or cart items.
+func invalidateUserCartCache(ctx context.Context, cache *redis.Client, userID uint) {
+	if cache == nil {
 		return
 	}
 	cacheKey := fmt.Sprintf("%s%d", cartsByUserKeyPrefix, userID)
-	u.cache.Del(ctx, cacheKey)
+	cache.Del(ctx, cacheKey)
 }
 
 // Validation methods
 func (u *CartUsecase) validateAddItemRequest(req AddItemToCartRequest) error {
 	if req.UserID == 0 {
-		return fmt.Errorf("user ID is required")
+		return fmt.Errorf("%w: user ID is required", ErrInvalidCartInput)
 	}
 	if req.WarehouseID == 0 {
-		return fmt.Errorf("warehouse ID is required")
+		return fmt.Errorf("%w: warehouse ID is required", ErrInvalidCartInput)
 	}
 	if req.ProductID == 0 {
-		return fmt.Errorf("product ID is required")
+		return fmt.Errorf("%w: product ID is required", ErrInvalidCartInput)
 	}
 	if req.Quantity <= 0 {
-		return fmt.Errorf("quantity must be greater than zero")
+		return fmt.Errorf("%w: quantity must be greater than zero", ErrInvalidCartInput)
 	}
 	if req.SubTotal < 0 {
-		return fmt.Errorf("subtotal cannot be negative")
+		return fmt.Errorf("%w: subtotal cannot be negative", ErrInvalidCartInput)
 	}
 	return nil
 }
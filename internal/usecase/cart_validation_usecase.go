@@ -0,0 +1,185 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/repository"
+)
+
+// CartLineStatus is the outcome of revalidating one cart line.
+type CartLineStatus string
+
+const (
+	CartLineOK                CartLineStatus = "ok"
+	CartLinePriceChanged      CartLineStatus = "price_changed"
+	CartLineInsufficientStock CartLineStatus = "insufficient_stock"
+	CartLineProductRemoved    CartLineStatus = "product_removed"
+)
+
+// priceTolerance absorbs rounding when comparing stored and current unit prices.
+const priceTolerance = 0.005
+
+// CartLineValidation describes the state of one cart item against current data.
+type CartLineValidation struct {
+	CartItemID        uint           `json:"cart_item_id"`
+	ProductID         uint           `json:"product_id"`
+	Quantity          int32          `json:"quantity"`
+	Status            CartLineStatus `json:"status"`
+	CartUnitPrice     float64        `json:"cart_unit_price"`
+	CurrentUnitPrice  *float64       `json:"current_unit_price,omitempty"`
+	AvailableQuantity *int32         `json:"available_quantity,omitempty"`
+}
+
+// CartValidationResult is the outcome of revalidating a whole cart.
+// Valid is false when at least one line cannot be ordered as is; a price
+// change alone is reported but does not make the cart invalid.
+type CartValidationResult struct {
+	CartID      uint                 `json:"cart_id"`
+	WarehouseID uint                 `json:"warehouse_id"`
+	Valid       bool                 `json:"valid"`
+	Lines       []CartLineValidation `json:"lines"`
+}
+
+// CartValidationError is returned when an order is refused because the cart
+// did not pass validation. It carries the full result for the client.
+type CartValidationError struct {
+	Result *CartValidationResult
+}
+
+func (e *CartValidationError) Error() string {
+	return fmt.Sprintf("cart %d is no longer valid: some items are out of stock or removed", e.Result.CartID)
+}
+
+type CartValidationUsecase struct {
+	cartRepo           repository.CartRepository
+	cartItemRepo       repository.CartItemRepository
+	productRepo        repository.ProductRepository
+	warehouseStockRepo repository.WarehouseStockRepository
+}
+
+func NewCartValidationUsecase(
+	cartRepo repository.CartRepository,
+	cartItemRepo repository.CartItemRepository,
+	productRepo repository.ProductRepository,
+	warehouseStockRepo repository.WarehouseStockRepository,
+) *CartValidationUsecase {
+	return &CartValidationUsecase{
+		cartRepo:           cartRepo,
+		cartItemRepo:       cartItemRepo,
+		productRepo:        productRepo,
+		warehouseStockRepo: warehouseStockRepo,
+	}
+}
+
+// ValidateCart checks every line of a cart owned by userID against current
+// product prices and the stock of the cart's warehouse.
+func (u *CartValidationUsecase) ValidateCart(ctx context.Context, userID, cartID uint) (*CartValidationResult, error) {
+	if cartID == 0 {
+		return nil, fmt.Errorf("%w: invalid cart ID", ErrInvalidCartInput)
+	}
+
+	cart, err := u.cartRepo.GetCartByID(ctx, cartID)
+	if err != nil {
+		return nil, err
+	}
+	if cart.UserID != userID {
+		return nil, ErrCartForbidden
+	}
+
+	items, err := u.cartItemRepo.GetCartItemsByCartID(ctx, cart.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cart items: %w", err)
+	}
+
+	return u.validateItems(ctx, cart, items, true)
+}
+
+// validateItems checks prices and removed products. With checkStock the
+// stock of the cart's warehouse is checked too; routed orders skip it
+// because they are not shipped from the cart's warehouse alone.
+func (u *CartValidationUsecase) validateItems(ctx context.Context, cart *domain.Cart, items []domain.CartItem, checkStock bool) (*CartValidationResult, error) {
+	result := &CartValidationResult{
+		CartID:      cart.ID,
+		WarehouseID: cart.WarehouseID,
+		Valid:       true,
+		Lines:       make([]CartLineValidation, 0, len(items)),
+	}
+
+	// the same product can appear on several lines, stock must cover all of them
+	requested := make(map[uint]int32)
+	for _, item := range items {
+		requested[item.ProductID] += item.Quantity
+	}
+
+	products := make(map[uint]*domain.Product)
+	available := make(map[uint]int32)
+
+	for _, item := range items {
+		line := CartLineValidation{
+			CartItemID: item.ID,
+			ProductID:  item.ProductID,
+			Quantity:   item.Quantity,
+			Status:     CartLineOK,
+		}
+		if item.Quantity > 0 {
+			line.CartUnitPrice = item.SubTotal / float64(item.Quantity)
+		}
+
+		product, ok := products[item.ProductID]
+		if !ok {
+			found, err := u.productRepo.FindById(ctx, item.ProductID)
+			if err != nil && !errors.Is(err, repository.ErrProductNotFound) {
+				return nil, fmt.Errorf("failed to get product ID %d: %w", item.ProductID, err)
+			}
+			product = found
+			products[item.ProductID] = product
+		}
+
+		if product == nil {
+			line.Status = CartLineProductRemoved
+			result.Valid = false
+			result.Lines = append(result.Lines, line)
+			continue
+		}
+		price := product.Price
+		line.CurrentUnitPrice = &price
+
+		if !checkStock {
+			if math.Abs(line.CartUnitPrice-product.Price) > priceTolerance {
+				line.Status = CartLinePriceChanged
+			}
+			result.Lines = append(result.Lines, line)
+			continue
+		}
+
+		qty, ok := available[item.ProductID]
+		if !ok {
+			stock, err := u.warehouseStockRepo.GetByWarehouseAndProduct(ctx, cart.WarehouseID, item.ProductID)
+			switch {
+			case errors.Is(err, repository.ErrWarehouseStockNotFound):
+				qty = 0
+			case err != nil:
+				return nil, fmt.Errorf("failed to get stock for product ID %d: %w", item.ProductID, err)
+			default:
+				qty = stock.Quantity
+			}
+			available[item.ProductID] = qty
+		}
+
+		if qty < requested[item.ProductID] {
+			line.Status = CartLineInsufficientStock
+			line.AvailableQuantity = &qty
+			result.Valid = false
+		} else if math.Abs(line.CartUnitPrice-product.Price) > priceTolerance {
+			line.Status = CartLinePriceChanged
+		}
+
+		result.Lines = append(result.Lines, line)
+	}
+
+	return result, nil
+}
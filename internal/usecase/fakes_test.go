@@ -0,0 +1,95 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/repository"
+)
+
+// fakeWarehouseRepo serves warehouses from a map; the other methods of the
+// interface are not used by these tests and panic.
+type fakeWarehouseRepo struct {
+	repository.WarehouseRepository
+	warehouses map[uint]*domain.Warehouse
+}
+
+func (f *fakeWarehouseRepo) GetById(ctx context.Context, id uint) (*domain.Warehouse, error) {
+	w, ok := f.warehouses[id]
+	if !ok {
+		return nil, errors.New("warehouse not found")
+	}
+	return w, nil
+}
+
+// fakeProductRepo knows which products exist; the other methods panic.
+type fakeProductRepo struct {
+	repository.ProductRepository
+	products map[uint]*domain.Product
+}
+
+func (f *fakeProductRepo) FindById(ctx context.Context, id uint) (*domain.Product, error) {
+	p, ok := f.products[id]
+	if !ok {
+		return nil, repository.ErrProductNotFound
+	}
+	return p, nil
+}
+
+// fakeTransferRepo keeps drafts in memory and books their lines as reserved
+// on the stock repo, like the draft subquery of GetAvailability does.
+type fakeTransferRepo struct {
+	repository.StockTransferRepository
+	stock     *countingStockRepo
+	transfers map[uint]*domain.StockTransfer
+}
+
+func (f *fakeTransferRepo) Create(ctx context.Context, transfer *domain.StockTransfer) error {
+	transfer.ID = uint(len(f.transfers) + 1)
+	transfer.Status = domain.TransferStatusDraft
+	f.transfers[transfer.ID] = transfer
+	f.reserve(transfer, 1)
+	return nil
+}
+
+func (f *fakeTransferRepo) GetByID(ctx context.Context, id uint) (*domain.StockTransfer, error) {
+	t, ok := f.transfers[id]
+	if !ok {
+		return nil, repository.ErrTransferNotFound
+	}
+	return t, nil
+}
+
+func (f *fakeTransferRepo) DeleteDraft(ctx context.Context, id uint) error {
+	f.reserve(f.transfers[id], -1)
+	delete(f.transfers, id)
+	return nil
+}
+
+func (f *fakeTransferRepo) reserve(transfer *domain.StockTransfer, sign int32) {
+	f.stock.mu.Lock()
+	defer f.stock.mu.Unlock()
+	if f.stock.reserved == nil {
+		f.stock.reserved = map[domain.StockKey]int32{}
+	}
+	for _, l := range transfer.Lines {
+		f.stock.reserved[domain.StockKey{WarehouseID: transfer.SourceWarehouseID, ProductID: l.ProductID}] += sign * l.Quantity
+	}
+}
+
+type fakeReplenishmentRepo struct {
+	demand    []domain.DailyDemand
+	positions []domain.StockPosition
+	from, to  time.Time
+}
+
+func (f *fakeReplenishmentRepo) DailyDemand(ctx context.Context, warehouseID uint, from, to time.Time) ([]domain.DailyDemand, error) {
+	f.from, f.to = from, to
+	return f.demand, nil
+}
+
+func (f *fakeReplenishmentRepo) StockPositions(ctx context.Context, warehouseID uint) ([]domain.StockPosition, error) {
+	return f.positions, nil
+}
@@ -0,0 +1,304 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/repository"
+	"github.com/ifs21014-itdel/concurrent-order-processor/pkg/jwt"
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrGuestCartUnavailable is returned when guest carts are used without Redis.
+var ErrGuestCartUnavailable = errors.New("guest cart is not available")
+
+// Guest carts live in a Redis hash per guest: carts:guest:<guestID>, with one
+// field per "<warehouseID>:<productID>" line.
+const (
+	guestCartKeyPrefix = "carts:guest:"
+	guestCartTTL       = 7 * 24 * time.Hour
+)
+
+// GuestCartItem is a single line of a guest cart.
+type GuestCartItem struct {
+	WarehouseID uint    `json:"warehouse_id"`
+	ProductID   uint    `json:"product_id"`
+	Quantity    int32   `json:"quantity"`
+	SubTotal    float64 `json:"sub_total"`
+}
+
+// GuestCartResponse groups guest cart lines by warehouse, mirroring user carts.
+type GuestCartResponse struct {
+	WarehouseID uint            `json:"warehouse_id"`
+	Items       []GuestCartItem `json:"items"`
+}
+
+// GuestCartMergeResult summarises what happened to each guest line on login.
+type GuestCartMergeResult struct {
+	Added   int `json:"added"`
+	Updated int `json:"updated"`
+	Kept    int `json:"kept"`
+}
+
+type GuestCartUsecase struct {
+	cartUC      *CartUsecase
+	productRepo repository.ProductRepository
+	cache       *redis.Client
+}
+
+func NewGuestCartUsecase(cartUC *CartUsecase, productRepo repository.ProductRepository, cache *redis.Client) *GuestCartUsecase {
+	return &GuestCartUsecase{
+		cartUC:      cartUC,
+		productRepo: productRepo,
+		cache:       cache,
+	}
+}
+
+// guestCartAddScript merges one line into a guest cart in a single step, so
+// concurrent adds of the same product all count. KEYS[1] is the cart hash;
+// ARGV is field, warehouse ID, product ID, quantity to add, unit price and
+// TTL in milliseconds. The sub total is always quantity × unit price.
+var guestCartAddScript = redis.NewScript(`
+local quantity = tonumber(ARGV[4])
+local raw = redis.call('HGET', KEYS[1], ARGV[1])
+if raw then
+	local ok, existing = pcall(cjson.decode, raw)
+	if ok and type(existing) == 'table' and tonumber(existing.quantity) then
+		quantity = quantity + tonumber(existing.quantity)
+	end
+end
+local line = {
+	warehouse_id = tonumber(ARGV[2]),
+	product_id = tonumber(ARGV[3]),
+	quantity = quantity,
+	sub_total = quantity * tonumber(ARGV[5]),
+}
+redis.call('HSET', KEYS[1], ARGV[1], cjson.encode(line))
+redis.call('PEXPIRE', KEYS[1], ARGV[6])
+return quantity
+`)
+
+// AddItem adds a line to the guest cart identified by token. When token is
+// empty a new guest cart is started; the (possibly new) token is returned.
+// Adding a product that is already in the cart increases its quantity. The
+// sub total comes from the product price, never from the request.
+func (u *GuestCartUsecase) AddItem(ctx context.Context, token string, req AddItemToCartRequest) (string, error) {
+	if u.cache == nil {
+		return "", ErrGuestCartUnavailable
+	}
+	if err := validateGuestItemRequest(req); err != nil {
+		return "", err
+	}
+
+	if token == "" {
+		newToken, err := u.newToken()
+		if err != nil {
+			return "", err
+		}
+		token = newToken
+	}
+	guestID, err := u.parseToken(token)
+	if err != nil {
+		return "", err
+	}
+
+	product, err := u.productRepo.FindById(ctx, req.ProductID)
+	if err != nil {
+		if errors.Is(err, repository.ErrProductNotFound) {
+			return "", fmt.Errorf("product %d not found", req.ProductID)
+		}
+		return "", err
+	}
+
+	err = guestCartAddScript.Run(ctx, u.cache,
+		[]string{guestCartKeyPrefix + guestID},
+		guestCartField(req.WarehouseID, req.ProductID),
+		req.WarehouseID, req.ProductID, req.Quantity,
+		strconv.FormatFloat(product.Price, 'f', -1, 64),
+		guestCartTTL.Milliseconds(),
+	).Err()
+	if err != nil {
+		return "", fmt.Errorf("failed to save guest cart: %w", err)
+	}
+
+	log.Printf("[OK] Added product %d to guest cart %s", req.ProductID, guestID)
+	return token, nil
+}
+
+// GetCart returns the guest cart lines grouped by warehouse.
+func (u *GuestCartUsecase) GetCart(ctx context.Context, token string) ([]GuestCartResponse, error) {
+	guestID, err := u.guestID(token)
+	if err != nil {
+		return nil, err
+	}
+
+	lines, err := u.loadLines(ctx, guestID)
+	if err != nil {
+		return nil, err
+	}
+
+	byWarehouse := make(map[uint][]GuestCartItem)
+	for _, line := range lines {
+		byWarehouse[line.WarehouseID] = append(byWarehouse[line.WarehouseID], line)
+	}
+
+	result := make([]GuestCartResponse, 0, len(byWarehouse))
+	for warehouseID, items := range byWarehouse {
+		result = append(result, GuestCartResponse{WarehouseID: warehouseID, Items: items})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].WarehouseID < result[j].WarehouseID })
+	return result, nil
+}
+
+// RemoveItem deletes a single product line from the guest cart.
+func (u *GuestCartUsecase) RemoveItem(ctx context.Context, token string, warehouseID, productID uint) error {
+	guestID, err := u.guestID(token)
+	if err != nil {
+		return err
+	}
+
+	removed, err := u.cache.HDel(ctx, guestCartKeyPrefix+guestID, guestCartField(warehouseID, productID)).Result()
+	if err != nil {
+		return fmt.Errorf("failed to remove guest cart item: %w", err)
+	}
+	if removed == 0 {
+		return errors.New("guest cart item not found")
+	}
+	return nil
+}
+
+// MergeIntoUser moves the guest cart into the carts of userID, one cart per
+// warehouse. Quantity conflicts are resolved by keeping the larger quantity:
+// when the guest line has more units it replaces the user's line, otherwise
+// the user's line is kept untouched. The guest cart is deleted afterwards.
+func (u *GuestCartUsecase) MergeIntoUser(ctx context.Context, token string, userID uint) (*GuestCartMergeResult, error) {
+	guestID, err := u.guestID(token)
+	if err != nil {
+		return nil, err
+	}
+
+	lines, err := u.loadLines(ctx, guestID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &GuestCartMergeResult{}
+	for _, line := range lines {
+		cart, err := u.cartUC.getOrCreateCart(ctx, userID, line.WarehouseID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get or create cart: %w", err)
+		}
+
+		items, err := u.cartUC.cartItemRepo.GetCartItemsByCartID(ctx, cart.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get cart items for cart ID %d: %w", cart.ID, err)
+		}
+
+		var existing *domain.CartItem
+		for i := range items {
+			if items[i].ProductID == line.ProductID {
+				existing = &items[i]
+				break
+			}
+		}
+
+		switch {
+		case existing == nil:
+			if err := u.cartUC.cartItemRepo.AddCartItem(ctx, &domain.CartItem{
+				CartID:    cart.ID,
+				ProductID: line.ProductID,
+				Quantity:  line.Quantity,
+				SubTotal:  line.SubTotal,
+			}); err != nil {
+				return nil, fmt.Errorf("failed to add cart item: %w", err)
+			}
+			result.Added++
+		case line.Quantity > existing.Quantity:
+			existing.Quantity = line.Quantity
+			existing.SubTotal = line.SubTotal
+			if err := u.cartUC.cartItemRepo.UpdateCartItem(ctx, existing); err != nil {
+				return nil, fmt.Errorf("failed to update cart item: %w", err)
+			}
+			result.Updated++
+		default:
+			result.Kept++
+		}
+	}
+
+	if err := u.cache.Del(ctx, guestCartKeyPrefix+guestID).Err(); err != nil {
+		log.Printf("⚠️ Failed to delete merged guest cart %s: %v", guestID, err)
+	}
+	invalidateUserCartCache(ctx, u.cache, userID)
+
+	log.Printf("[OK] Merged guest cart %s into user %d (added=%d updated=%d kept=%d)",
+		guestID, userID, result.Added, result.Updated, result.Kept)
+	return result, nil
+}
+
+func (u *GuestCartUsecase) loadLines(ctx context.Context, guestID string) ([]GuestCartItem, error) {
+	raw, err := u.cache.HGetAll(ctx, guestCartKeyPrefix+guestID).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read guest cart: %w", err)
+	}
+
+	lines := make([]GuestCartItem, 0, len(raw))
+	for field, value := range raw {
+		var line GuestCartItem
+		if err := json.Unmarshal([]byte(value), &line); err != nil {
+			log.Printf("[WARN] Skipping malformed guest cart line %s: %v", field, err)
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// guestID validates that guest carts are usable and returns the guest behind token.
+func (u *GuestCartUsecase) guestID(token string) (string, error) {
+	if u.cache == nil {
+		return "", ErrGuestCartUnavailable
+	}
+	if token == "" {
+		return "", errors.New("cart token is required")
+	}
+	return u.parseToken(token)
+}
+
+func (u *GuestCartUsecase) newToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate guest ID: %w", err)
+	}
+	return jwt.GenerateCartToken(hex.EncodeToString(buf), os.Getenv("JWT_SECRET"), guestCartTTL)
+}
+
+func (u *GuestCartUsecase) parseToken(token string) (string, error) {
+	return jwt.ParseCartToken(token, os.Getenv("JWT_SECRET"))
+}
+
+func guestCartField(warehouseID, productID uint) string {
+	return fmt.Sprintf("%d:%d", warehouseID, productID)
+}
+
+func validateGuestItemRequest(req AddItemToCartRequest) error {
+	if req.WarehouseID == 0 {
+		return fmt.Errorf("warehouse ID is required")
+	}
+	if req.ProductID == 0 {
+		return fmt.Errorf("product ID is required")
+	}
+	if req.Quantity <= 0 {
+		return fmt.Errorf("quantity must be greater than zero")
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package usecase
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGuestCartUsecase_AddItem(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	mr := miniredis.RunT(t)
+	cache := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer cache.Close()
+
+	products := &fakeProductRepo{products: map[uint]*domain.Product{5: {ID: 5, Price: 12.5}}}
+	u := NewGuestCartUsecase(nil, products, cache)
+	ctx := context.Background()
+
+	// sub_total dari client diabaikan
+	token, err := u.AddItem(ctx, "", AddItemToCartRequest{WarehouseID: 1, ProductID: 5, Quantity: 1, SubTotal: 0.01})
+	assert.NoError(t, err)
+
+	t.Run("ConcurrentAddsAllCount", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := u.AddItem(ctx, token, AddItemToCartRequest{WarehouseID: 1, ProductID: 5, Quantity: 2})
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+
+		cart, err := u.GetCart(ctx, token)
+		assert.NoError(t, err)
+		assert.Len(t, cart, 1)
+		assert.Equal(t, []GuestCartItem{{WarehouseID: 1, ProductID: 5, Quantity: 41, SubTotal: 512.5}}, cart[0].Items)
+	})
+
+	t.Run("UnknownProduct", func(t *testing.T) {
+		_, err := u.AddItem(ctx, token, AddItemToCartRequest{WarehouseID: 1, ProductID: 9, Quantity: 1})
+		assert.Error(t, err)
+
+		cart, err := u.GetCart(ctx, token)
+		assert.NoError(t, err)
+		assert.Len(t, cart[0].Items, 1)
+	})
+}
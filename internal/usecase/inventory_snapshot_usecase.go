@@ -0,0 +1,156 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/repository"
+)
+
+// ErrInvalidInventoryQuery dikembalikan untuk filter snapshot/riwayat yang tidak valid.
+var ErrInvalidInventoryQuery = errors.New("invalid inventory query")
+
+// InventorySnapshotOptions configures the daily snapshot job.
+type InventorySnapshotOptions struct {
+	// CheckInterval is how often the job checks whether today's snapshot
+	// has been taken.
+	CheckInterval time.Duration
+}
+
+// InventorySnapshotUsecase mengambil snapshot stok (harian lewat job, atau
+// manual) dan menjawab stok serta nilainya pada waktu tertentu.
+type InventorySnapshotUsecase struct {
+	repo repository.InventorySnapshotRepository
+	opts InventorySnapshotOptions
+}
+
+func NewInventorySnapshotUsecase(repo repository.InventorySnapshotRepository, opts InventorySnapshotOptions) *InventorySnapshotUsecase {
+	if opts.CheckInterval <= 0 {
+		opts.CheckInterval = time.Hour
+	}
+	return &InventorySnapshotUsecase{repo: repo, opts: opts}
+}
+
+// Run takes the scheduled snapshot once a day, checking every CheckInterval,
+// until ctx is cancelled. The first snapshot of a day is taken at the first
+// check after midnight, or at startup if the day has none yet.
+func (u *InventorySnapshotUsecase) Run(ctx context.Context) {
+	log.Printf("[JOB] Inventory snapshot job started (interval=%s)", u.opts.CheckInterval)
+
+	ticker := time.NewTicker(u.opts.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := u.RunOnce(ctx); err != nil {
+			log.Printf("[ERROR] Inventory snapshot job failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("[JOB] Inventory snapshot job stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce takes today's scheduled snapshot if it is still missing. It returns
+// nil when there was nothing to do.
+func (u *InventorySnapshotUsecase) RunOnce(ctx context.Context) (*domain.InventorySnapshot, error) {
+	latest, err := u.repo.LatestTakenAt(ctx, domain.InventorySnapshotScheduled)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if latest != nil && sameDay(*latest, now) {
+		return nil, nil
+	}
+
+	snapshot, err := u.repo.Create(ctx, domain.InventorySnapshotScheduled, nil)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[JOB] Inventory snapshot %d taken (%d lines)", snapshot.ID, snapshot.LineCount)
+	return snapshot, nil
+}
+
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
+
+// Take mengambil snapshot manual, misalnya tepat saat tutup buku.
+func (u *InventorySnapshotUsecase) Take(ctx context.Context, userID uint) (*domain.InventorySnapshot, error) {
+	return u.repo.Create(ctx, domain.InventorySnapshotManual, &userID)
+}
+
+func (u *InventorySnapshotUsecase) Get(ctx context.Context, id uint) (*domain.InventorySnapshot, error) {
+	return u.repo.GetByID(ctx, id)
+}
+
+// List mengembalikan snapshot terbaru dulu; limit 0 berarti 30, maksimal 365.
+func (u *InventorySnapshotUsecase) List(ctx context.Context, kind string, limit int) ([]domain.InventorySnapshot, error) {
+	switch kind {
+	case "", domain.InventorySnapshotScheduled, domain.InventorySnapshotManual:
+	default:
+		return nil, fmt.Errorf("%w: unknown kind %q", ErrInvalidInventoryQuery, kind)
+	}
+	if limit < 0 || limit > 365 {
+		return nil, fmt.Errorf("%w: limit must be between 1 and 365", ErrInvalidInventoryQuery)
+	}
+	if limit == 0 {
+		limit = 30
+	}
+	return u.repo.List(ctx, kind, limit)
+}
+
+// Levels mengembalikan stok per gudang/produk pada waktu at. Jika gudang dan
+// produk sama-sama diisi, hasilnya selalu satu baris, quantity 0 jika kosong.
+func (u *InventorySnapshotUsecase) Levels(ctx context.Context, at time.Time, warehouseID, productID uint) ([]domain.InventoryLevel, error) {
+	// the future looks like now
+	if now := time.Now(); at.After(now) {
+		at = now
+	}
+	levels, err := u.repo.LevelsAt(ctx, at, warehouseID, productID)
+	if err != nil {
+		return nil, err
+	}
+	if len(levels) == 0 && warehouseID != 0 && productID != 0 {
+		levels = append(levels, domain.InventoryLevel{WarehouseID: warehouseID, ProductID: productID})
+	}
+	return levels, nil
+}
+
+// Valuation menilai stok pada waktu at dengan harga produk yang berlaku saat
+// itu, per gudang dan total.
+func (u *InventorySnapshotUsecase) Valuation(ctx context.Context, at time.Time, warehouseID uint) (*domain.InventoryValuation, error) {
+	// the future looks like now
+	if now := time.Now(); at.After(now) {
+		at = now
+	}
+	levels, err := u.repo.LevelsAt(ctx, at, warehouseID, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &domain.InventoryValuation{At: at, Warehouses: []domain.WarehouseValuation{}, Lines: levels}
+	for i := range levels {
+		l := &levels[i]
+		l.Value = roundMoney(l.Value)
+		// levels come ordered by warehouse, so a new warehouse starts a new group
+		if n := len(report.Warehouses); n == 0 || report.Warehouses[n-1].WarehouseID != l.WarehouseID {
+			report.Warehouses = append(report.Warehouses, domain.WarehouseValuation{WarehouseID: l.WarehouseID, WarehouseName: l.WarehouseName})
+		}
+		w := &report.Warehouses[len(report.Warehouses)-1]
+		w.Quantity += int64(l.Quantity)
+		w.Value = roundMoney(w.Value + l.Value)
+		report.TotalQuantity += int64(l.Quantity)
+		report.TotalValue = roundMoney(report.TotalValue + l.Value)
+	}
+	return report, nil
+}
@@ -0,0 +1,161 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+)
+
+// ErrUnroutableOrder dikembalikan kalau tidak ada gudang, atau gabungan gudang
+// kalau split diizinkan, yang punya stok cukup untuk pesanan.
+var ErrUnroutableOrder = errors.New("no warehouse can fulfil the order")
+
+// fulfilmentPlan adalah hasil routing: dari gudang mana setiap produk dikirim,
+// dan penjelasan yang disimpan di pesanan.
+type fulfilmentPlan struct {
+	allocations []domain.OrderAllocation
+	decision    string
+	warehouses  map[uint]domain.Warehouse
+}
+
+type routingCandidate struct {
+	warehouse domain.Warehouse
+	distance  float64
+}
+
+func (c routingCandidate) String() string {
+	return fmt.Sprintf("%s (#%d, %.1f km)", c.warehouse.Name, c.warehouse.ID, c.distance)
+}
+
+// routeOrder memilih gudang untuk demand (product ID -> quantity) yang dikirim
+// ke destination. Stok dibaca tanpa lock; kalau berubah sebelum transaksi
+// pesanan, pengurangan stok gagal dan pesanan bisa dicoba lagi.
+func (o *OrderUsecase) routeOrder(ctx context.Context, destination domain.GeoPoint, demand map[uint]int32, singleShipment bool) (*fulfilmentPlan, error) {
+	warehouses, err := o.warehouseRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get warehouses: %w", err)
+	}
+
+	stock := make(map[domain.StockKey]int32)
+	for productID := range demand {
+		rows, err := o.warehouseStockRepo.GetByProductID(ctx, productID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get stock for product ID %d: %w", productID, err)
+		}
+		for _, s := range rows {
+			stock[domain.StockKey{WarehouseID: s.WarehouseID, ProductID: s.ProductID}] = s.Quantity
+		}
+	}
+
+	return planFulfilment(destination, warehouses, stock, demand, singleShipment)
+}
+
+// planFulfilment mengirim semua dari gudang terdekat yang punya semua produk.
+// Kalau tidak ada dan singleShipment false, setiap produk diambil dari gudang
+// terdekat dulu sampai quantity-nya terpenuhi (split fulfilment). Gudang
+// tanpa koordinat tidak dipertimbangkan.
+func planFulfilment(destination domain.GeoPoint, warehouses []domain.Warehouse, stock map[domain.StockKey]int32, demand map[uint]int32, singleShipment bool) (*fulfilmentPlan, error) {
+	plan := &fulfilmentPlan{warehouses: make(map[uint]domain.Warehouse, len(warehouses))}
+	candidates := make([]routingCandidate, 0, len(warehouses))
+	skipped := 0
+	for _, w := range warehouses {
+		plan.warehouses[w.ID] = w
+		point, ok := w.Point()
+		if !ok {
+			skipped++
+			continue
+		}
+		candidates = append(candidates, routingCandidate{warehouse: w, distance: destination.DistanceKm(point)})
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("%w: no warehouse has coordinates", ErrUnroutableOrder)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].warehouse.ID < candidates[j].warehouse.ID
+	})
+
+	products := make([]uint, 0, len(demand))
+	for productID := range demand {
+		products = append(products, productID)
+	}
+	sort.Slice(products, func(i, j int) bool { return products[i] < products[j] })
+
+	note := ""
+	if skipped > 0 {
+		note = fmt.Sprintf("; %d warehouse(s) without coordinates not considered", skipped)
+	}
+
+	var lacking []string
+	for _, c := range candidates {
+		if !coversDemand(c.warehouse.ID, stock, demand) {
+			lacking = append(lacking, c.String())
+			continue
+		}
+		for _, productID := range products {
+			plan.allocations = append(plan.allocations, newAllocation(c, productID, demand[productID]))
+		}
+		plan.decision = "single shipment from the nearest warehouse with every item in stock: " + c.String()
+		if len(lacking) > 0 {
+			plan.decision += "; nearer warehouses lacked stock: " + strings.Join(lacking, ", ")
+		}
+		plan.decision += note
+		return plan, nil
+	}
+
+	if singleShipment {
+		return nil, fmt.Errorf("%w: no single warehouse has every item in stock and a single shipment was requested", ErrUnroutableOrder)
+	}
+
+	var parts []string
+	used := make(map[uint]bool)
+	for _, productID := range products {
+		remaining := demand[productID]
+		for _, c := range candidates {
+			if remaining == 0 {
+				break
+			}
+			available := stock[domain.StockKey{WarehouseID: c.warehouse.ID, ProductID: productID}]
+			if available <= 0 {
+				continue
+			}
+			take := min(available, remaining)
+			plan.allocations = append(plan.allocations, newAllocation(c, productID, take))
+			parts = append(parts, fmt.Sprintf("product %d x%d from %s", productID, take, c))
+			used[c.warehouse.ID] = true
+			remaining -= take
+		}
+		if remaining > 0 {
+			return nil, fmt.Errorf("%w: product %d is short by %d across all warehouses", ErrUnroutableOrder, productID, remaining)
+		}
+	}
+	plan.decision = fmt.Sprintf("no single warehouse has every item in stock; split across %d warehouses, nearest first: %s",
+		len(used), strings.Join(parts, "; ")) + note
+	return plan, nil
+}
+
+func coversDemand(warehouseID uint, stock map[domain.StockKey]int32, demand map[uint]int32) bool {
+	for productID, qty := range demand {
+		if stock[domain.StockKey{WarehouseID: warehouseID, ProductID: productID}] < qty {
+			return false
+		}
+	}
+	return true
+}
+
+func newAllocation(c routingCandidate, productID uint, quantity int32) domain.OrderAllocation {
+	distance := math.Round(c.distance*100) / 100
+	return domain.OrderAllocation{
+		WarehouseID: c.warehouse.ID,
+		ProductID:   productID,
+		Quantity:    quantity,
+		DistanceKm:  &distance,
+	}
+}
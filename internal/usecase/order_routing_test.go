@@ -0,0 +1,152 @@
+package usecase
+
+import (
+	"testing"
+
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func coords(lat, lon float64) (*float64, *float64) { return &lat, &lon }
+
+func routingWarehouses() []domain.Warehouse {
+	jktLat, jktLon := coords(-6.2088, 106.8456)
+	bdgLat, bdgLon := coords(-6.9175, 107.6191)
+	sbyLat, sbyLon := coords(-7.2575, 112.7521)
+	return []domain.Warehouse{
+		{ID: 3, Name: "Surabaya", Latitude: sbyLat, Longitude: sbyLon},
+		{ID: 1, Name: "Jakarta", Latitude: jktLat, Longitude: jktLon},
+		{ID: 4, Name: "No coordinates"},
+		{ID: 2, Name: "Bandung", Latitude: bdgLat, Longitude: bdgLon},
+	}
+}
+
+// allocation is an OrderAllocation without the distance.
+type allocation struct {
+	warehouseID, productID uint
+	quantity               int32
+}
+
+func allocationsOf(plan *fulfilmentPlan) []allocation {
+	out := make([]allocation, len(plan.allocations))
+	for i, a := range plan.allocations {
+		out[i] = allocation{a.WarehouseID, a.ProductID, a.Quantity}
+	}
+	return out
+}
+
+func TestPlanFulfilment(t *testing.T) {
+	// a customer in central Jakarta: Jakarta, then Bandung, then Surabaya
+	destination := domain.GeoPoint{Latitude: -6.2, Longitude: 106.82}
+	key := func(w, p uint) domain.StockKey { return domain.StockKey{WarehouseID: w, ProductID: p} }
+
+	tests := []struct {
+		name           string
+		stock          map[domain.StockKey]int32
+		demand         map[uint]int32
+		singleShipment bool
+		want           []allocation
+		wantDecision   string
+		wantErr        string
+	}{
+		{
+			name:         "nearest warehouse with every item wins",
+			stock:        map[domain.StockKey]int32{key(1, 1): 5, key(1, 2): 5, key(2, 1): 10, key(2, 2): 10},
+			demand:       map[uint]int32{1: 2, 2: 3},
+			want:         []allocation{{1, 1, 2}, {1, 2, 3}},
+			wantDecision: "single shipment from the nearest warehouse with every item in stock: Jakarta",
+		},
+		{
+			name:         "nearer warehouse lacking an item is passed over",
+			stock:        map[domain.StockKey]int32{key(1, 1): 1, key(1, 2): 5, key(3, 1): 10, key(3, 2): 10},
+			demand:       map[uint]int32{1: 2, 2: 3},
+			want:         []allocation{{3, 1, 2}, {3, 2, 3}},
+			wantDecision: "nearer warehouses lacked stock: Jakarta",
+		},
+		{
+			name:         "split takes each product nearest first",
+			stock:        map[domain.StockKey]int32{key(1, 1): 2, key(2, 1): 1, key(3, 2): 5},
+			demand:       map[uint]int32{1: 3, 2: 2},
+			want:         []allocation{{1, 1, 2}, {2, 1, 1}, {3, 2, 2}},
+			wantDecision: "split across 3 warehouses",
+		},
+		{
+			name:           "single shipment refuses a split",
+			stock:          map[domain.StockKey]int32{key(1, 1): 2, key(2, 2): 2},
+			demand:         map[uint]int32{1: 2, 2: 2},
+			singleShipment: true,
+			wantErr:        "a single shipment was requested",
+		},
+		{
+			name:         "warehouses without coordinates are skipped",
+			stock:        map[domain.StockKey]int32{key(4, 1): 100, key(2, 1): 5},
+			demand:       map[uint]int32{1: 5},
+			want:         []allocation{{2, 1, 5}},
+			wantDecision: "1 warehouse(s) without coordinates not considered",
+		},
+		{
+			name:    "stock without coordinates does not count",
+			stock:   map[domain.StockKey]int32{key(4, 1): 100, key(2, 1): 1},
+			demand:  map[uint]int32{1: 5},
+			wantErr: "product 1 is short by 4",
+		},
+		{
+			name:    "not enough stock in total",
+			stock:   map[domain.StockKey]int32{key(1, 1): 2, key(2, 1): 2, key(3, 2): 1},
+			demand:  map[uint]int32{1: 5, 2: 1},
+			wantErr: "product 1 is short by 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := planFulfilment(destination, routingWarehouses(), tt.stock, tt.demand, tt.singleShipment)
+			if tt.wantErr != "" {
+				assert.ErrorIs(t, err, ErrUnroutableOrder)
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, allocationsOf(plan))
+			assert.Contains(t, plan.decision, tt.wantDecision)
+			for _, a := range plan.allocations {
+				assert.NotNil(t, a.DistanceKm)
+			}
+		})
+	}
+
+	t.Run("no warehouse has coordinates", func(t *testing.T) {
+		_, err := planFulfilment(destination, []domain.Warehouse{{ID: 4}}, nil, map[uint]int32{1: 1}, false)
+		assert.ErrorIs(t, err, ErrUnroutableOrder)
+	})
+}
+
+func TestPricingUsecase_RepriceShipping(t *testing.T) {
+	p := NewPricingUsecase(nil, nil, PricingOptions{TaxRate: 0.11})
+	quote := &PriceQuote{
+		Lines: []PricedLine{
+			{ProductID: 1, Quantity: 2, LineTotal: 60000, Weight: 4},
+			{ProductID: 2, Quantity: 1, LineTotal: 40000, Weight: 3},
+			{ProductID: 3, Quantity: 1, Weight: 50, Unavailable: true},
+		},
+		Subtotal:     100000,
+		ShippingCost: 99999,
+		Tax:          11000,
+	}
+	warehouses := map[uint]domain.Warehouse{
+		1: {ID: 1, ShippingBaseCost: 10000, ShippingCostPerKg: 2000},
+		2: {ID: 2, ShippingBaseCost: 8000, ShippingCostPerKg: 2500},
+		3: {ID: 3, ShippingBaseCost: 50000, ShippingCostPerKg: 1},
+	}
+	allocations := []domain.OrderAllocation{
+		{WarehouseID: 1, ProductID: 1, Quantity: 1},
+		{WarehouseID: 2, ProductID: 1, Quantity: 1},
+		{WarehouseID: 2, ProductID: 2, Quantity: 1},
+	}
+
+	p.RepriceShipping(quote, allocations, warehouses)
+
+	// Jakarta ships 2 kg: 10000 + 2×2000; Bandung 5 kg: 8000 + 5×2500.
+	// Warehouse 3 ships nothing and charges nothing.
+	assert.Equal(t, 34500.0, quote.ShippingCost)
+	assert.Equal(t, 145500.0, quote.Total)
+}
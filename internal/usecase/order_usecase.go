@@ -3,16 +3,24 @@ package usecase
 import (
 	"context"
 	"fmt"
+	"slices"
+	"sort"
 
 	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
 	"github.com/ifs21014-itdel/concurrent-order-processor/internal/repository"
+	"github.com/redis/go-redis/v9"
 )
 
 type CreateOrderRequest struct {
-	UserID       uint
-	CartID       uint
-	Status       string
-	ShippingCost float64
+	UserID uint
+	CartID uint
+	Status string
+	// AddressID, kalau diisi, membuat pesanan dirutekan ke gudang terdekat
+	// dari alamat itu yang punya stok, bukan ke gudang cart.
+	AddressID uint
+	// SingleShipment menolak split fulfilment: pesanan gagal kalau tidak ada
+	// satu gudang pun yang punya semua item.
+	SingleShipment bool
 }
 
 type OrderWithItemsResponse struct {
@@ -26,6 +34,12 @@ type OrderUsecase struct {
 	cartRepo           repository.CartRepository
 	cartItemRepo       repository.CartItemRepository
 	warehouseStockRepo repository.WarehouseStockRepository
+	warehouseRepo      repository.WarehouseRepository
+	addressRepo        repository.AddressRepository
+	cartValidator      *CartValidationUsecase
+	pricing            *PricingUsecase
+	// cache hanya dipakai untuk membuang availability produk yang terjual; boleh nil
+	cache *redis.Client
 }
 
 func NewOrderUsecase(
@@ -34,6 +48,11 @@ func NewOrderUsecase(
 	cartRepo repository.CartRepository,
 	cartItemRepo repository.CartItemRepository,
 	warehouseStockRepo repository.WarehouseStockRepository,
+	warehouseRepo repository.WarehouseRepository,
+	addressRepo repository.AddressRepository,
+	cartValidator *CartValidationUsecase,
+	pricing *PricingUsecase,
+	cache *redis.Client,
 ) *OrderUsecase {
 	return &OrderUsecase{
 		orderRepo:          orderRepo,
@@ -41,65 +60,174 @@ func NewOrderUsecase(
 		cartRepo:           cartRepo,
 		cartItemRepo:       cartItemRepo,
 		warehouseStockRepo: warehouseStockRepo,
+		warehouseRepo:      warehouseRepo,
+		addressRepo:        addressRepo,
+		cartValidator:      cartValidator,
+		pricing:            pricing,
+		cache:              cache,
 	}
 }
 
-func (o *OrderUsecase) CreateOrder(ctx context.Context, req CreateOrderRequest) error {
+// CreateOrder membuat pesanan dari cart dan mengurangi stok. Tanpa alamat,
+// semua item dikirim dari gudang cart; dengan alamat, pesanan dirutekan lewat
+// planFulfilment dan keputusannya disimpan di routing_decision.
+func (o *OrderUsecase) CreateOrder(ctx context.Context, req CreateOrderRequest) (*domain.Order, error) {
 	if err := o.validateCreateOrderRequest(req); err != nil {
-		return err
+		return nil, err
+	}
+
+	cart, err := o.cartRepo.GetCartByID(ctx, req.CartID)
+	if err != nil {
+		return nil, err
+	}
+	if cart.UserID != req.UserID {
+		return nil, ErrCartForbidden
+	}
+
+	var address *domain.CustomerAddress
+	if req.AddressID != 0 {
+		address, err = o.addressRepo.GetByID(ctx, req.AddressID, req.UserID)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	cartItems, err := o.cartItemRepo.GetCartItemsByCartID(ctx, req.CartID)
 	if err != nil {
-		return fmt.Errorf("failed to get cart items: %w", err)
+		return nil, fmt.Errorf("failed to get cart items: %w", err)
 	}
 	if len(cartItems) == 0 {
-		return fmt.Errorf("cart is empty")
+		return nil, fmt.Errorf("cart is empty")
 	}
 
-	tx, err := o.orderRepo.BeginTx(ctx)
+	// Revalidate before touching stock so the client gets a per-line report
+	// instead of a bare "not enough stock" from inside the transaction.
+	// Routed orders check stock while planning instead.
+	validation, err := o.cartValidator.validateItems(ctx, cart, cartItems, address == nil)
 	if err != nil {
-		return fmt.Errorf("failed to start transaction: %w", err)
+		return nil, fmt.Errorf("failed to validate cart: %w", err)
+	}
+	if !validation.Valid {
+		return nil, &CartValidationError{Result: validation}
+	}
+
+	// Same quote as the cart preview, so the order charges what the cart showed.
+	quote, err := o.pricing.QuoteCart(ctx, cart, cartItems)
+	if err != nil {
+		return nil, fmt.Errorf("failed to price cart: %w", err)
+	}
+
+	demand := make(map[uint]int32)
+	for _, line := range quote.Lines {
+		demand[line.ProductID] += line.Quantity
 	}
-	defer tx.Rollback()
 
-	var totalPrice float64
-	for _, item := range cartItems {
-		totalPrice += item.SubTotal
+	var allocations []domain.OrderAllocation
+	var decision string
+	if address != nil {
+		plan, err := o.routeOrder(ctx, address.Point(), demand, req.SingleShipment)
+		if err != nil {
+			return nil, err
+		}
+		allocations, decision = plan.allocations, plan.decision
+		o.pricing.RepriceShipping(quote, allocations, plan.warehouses)
+	} else {
+		for productID, qty := range demand {
+			allocations = append(allocations, domain.OrderAllocation{
+				WarehouseID: cart.WarehouseID,
+				ProductID:   productID,
+				Quantity:    qty,
+			})
+		}
+		sort.Slice(allocations, func(i, j int) bool { return allocations[i].ProductID < allocations[j].ProductID })
+		decision = fmt.Sprintf("shipped from the cart's warehouse #%d; no delivery address given", cart.WarehouseID)
 	}
 
+	tx, err := o.orderRepo.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	order := &domain.Order{
-		UserID:       req.UserID,
-		Status:       req.Status,
-		TotalPrice:   totalPrice,
-		ShippingCost: req.ShippingCost,
+		UserID:          req.UserID,
+		Status:          req.Status,
+		TotalPrice:      quote.Subtotal,
+		ShippingCost:    quote.ShippingCost,
+		TaxAmount:       quote.Tax,
+		RoutingDecision: &decision,
+	}
+	if address != nil {
+		order.AddressID = &address.ID
 	}
 
 	if err := o.orderRepo.CreateOrderTx(ctx, tx, order); err != nil {
-		return fmt.Errorf("failed to create order: %w", err)
+		return nil, fmt.Errorf("failed to create order: %w", err)
 	}
 
-	for _, cartItem := range cartItems {
-		warehouseID := uint(1)
-		if err := o.orderItemRepo.CreateOrderItemTx(ctx, tx, &domain.OrderItem{
+	// lots picked for a product are recorded against its first order item
+	itemIDs := make(map[uint]uint)
+	for _, line := range quote.Lines {
+		item := &domain.OrderItem{
 			OrderID:   order.ID,
-			ProductID: cartItem.ProductID,
-			Quantity:  cartItem.Quantity,
-			SubTotal:  cartItem.SubTotal,
-		}); err != nil {
-			return fmt.Errorf("failed to create order item: %w", err)
+			ProductID: line.ProductID,
+			Quantity:  line.Quantity,
+			SubTotal:  line.LineTotal,
+		}
+		if err := o.orderItemRepo.CreateOrderItemTx(ctx, tx, item); err != nil {
+			return nil, fmt.Errorf("failed to create order item: %w", err)
 		}
+		if _, ok := itemIDs[line.ProductID]; !ok {
+			itemIDs[line.ProductID] = item.ID
+		}
+	}
 
-		if err := o.warehouseStockRepo.SafeDecreaseQuantity(ctx, tx, warehouseID, cartItem.ProductID, cartItem.Quantity); err != nil {
-			return fmt.Errorf("failed to decrease stock: %w", err)
+	ref := domain.StockReference{
+		Reason:        domain.MovementReasonSale,
+		ReferenceType: domain.MovementRefOrder,
+		ReferenceID:   order.ID,
+		ActorID:       req.UserID,
+	}
+	// Kunci baris stok berurutan menurut (warehouse_id, product_id), sama
+	// seperti AtomicAdjust, supaya dua pesanan split tidak saling deadlock.
+	locking := slices.Clone(allocations)
+	sort.Slice(locking, func(i, j int) bool {
+		if locking[i].WarehouseID != locking[j].WarehouseID {
+			return locking[i].WarehouseID < locking[j].WarehouseID
 		}
+		return locking[i].ProductID < locking[j].ProductID
+	})
+	for _, a := range locking {
+		result, err := o.warehouseStockRepo.SafeDecreaseQuantity(ctx, tx, a.WarehouseID, a.ProductID, a.Quantity, ref)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrease stock: %w", err)
+		}
+		if err := o.orderItemRepo.CreateLotsTx(ctx, tx, itemIDs[a.ProductID], result.Lots); err != nil {
+			return nil, err
+		}
+		picks := pickList(itemIDs[a.ProductID], a, result.Picks)
+		if err := o.orderItemRepo.CreatePicksTx(ctx, tx, picks); err != nil {
+			return nil, err
+		}
+		order.Picks = append(order.Picks, picks...)
+	}
+
+	if err := o.orderRepo.CreateAllocationsTx(ctx, tx, order.ID, allocations); err != nil {
+		return nil, err
 	}
 
 	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	return nil
+	productIDs := make([]uint, 0, len(demand))
+	for productID := range demand {
+		productIDs = append(productIDs, productID)
+	}
+	invalidateAvailabilityCache(ctx, o.cache, productIDs...)
+
+	order.Allocations = allocations
+	return order, nil
 }
 
 func (o *OrderUsecase) DeleteOrder(ctx context.Context, id uint) error {
@@ -114,7 +242,7 @@ func (o *OrderUsecase) GetOrderByUserId(ctx context.Context, userID uint) ([]dom
 	if err != nil {
 		return nil, fmt.Errorf("failed to get orders: %w", err)
 	}
-	return orders, nil
+	return o.attachAllocations(ctx, orders)
 }
 
 func (o *OrderUsecase) GetOrderByUserIdAndStatus(ctx context.Context, userID uint, status string) ([]domain.Order, error) {
@@ -126,18 +254,78 @@ func (o *OrderUsecase) GetOrderByUserIdAndStatus(ctx context.Context, userID uin
 	if err != nil {
 		return nil, fmt.Errorf("failed to get orders by status: %w", err)
 	}
+	return o.attachAllocations(ctx, orders)
+}
+
+// GetOrderPicks mengembalikan pick list pesanan milik userID: lokasi (bin)
+// tempat setiap item diambil di setiap gudang.
+func (o *OrderUsecase) GetOrderPicks(ctx context.Context, userID, orderID uint) ([]domain.OrderItemPick, error) {
+	return o.orderItemRepo.GetPicksByOrderID(ctx, orderID, userID)
+}
+
+// pickList mengubah lokasi yang diambil untuk satu alokasi menjadi baris
+// pick list; sisa yang tidak berasal dari lokasi mana pun diambil dari stok
+// unassigned dan dicatat tanpa lokasi.
+func pickList(orderItemID uint, a domain.OrderAllocation, taken []domain.LocationPick) []domain.OrderItemPick {
+	picks := make([]domain.OrderItemPick, 0, len(taken)+1)
+	remaining := a.Quantity
+	for _, t := range taken {
+		locationID, code := t.LocationID, t.LocationCode
+		picks = append(picks, domain.OrderItemPick{
+			OrderItemID:  orderItemID,
+			ProductID:    a.ProductID,
+			WarehouseID:  a.WarehouseID,
+			LocationID:   &locationID,
+			LocationCode: &code,
+			Quantity:     t.Quantity,
+		})
+		remaining -= t.Quantity
+	}
+	if remaining > 0 {
+		picks = append(picks, domain.OrderItemPick{
+			OrderItemID: orderItemID,
+			ProductID:   a.ProductID,
+			WarehouseID: a.WarehouseID,
+			Quantity:    remaining,
+		})
+	}
+	return picks
+}
+
+// GetOrderLots mengembalikan lot asal setiap item pesanan milik userID,
+// misalnya untuk menelusuri pesanan yang terkena recall batch.
+func (o *OrderUsecase) GetOrderLots(ctx context.Context, userID, orderID uint) ([]domain.OrderItemLot, error) {
+	return o.orderItemRepo.GetLotsByOrderID(ctx, orderID, userID)
+}
+
+// attachAllocations mengisi gudang asal setiap pesanan.
+func (o *OrderUsecase) attachAllocations(ctx context.Context, orders []domain.Order) ([]domain.Order, error) {
+	ids := make([]uint, len(orders))
+	for i, order := range orders {
+		ids[i] = order.ID
+	}
+	allocations, err := o.orderRepo.GetAllocations(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range orders {
+		orders[i].Allocations = allocations[orders[i].ID]
+	}
 	return orders, nil
 }
 
-func (o *OrderUsecase) UpdateOrderStatus(ctx context.Context, id uint, status string) error {
+// UpdateOrderStatus mengubah status pesanan dan mengembalikan versi barunya.
+// version 0 berarti tanpa cek konflik.
+func (o *OrderUsecase) UpdateOrderStatus(ctx context.Context, id uint, status string, version int32) (int32, error) {
 	if err := o.validateStatus(status); err != nil {
-		return err
+		return 0, err
 	}
 
-	if err := o.orderRepo.UpdateOrderStatus(ctx, id, status); err != nil {
-		return err
+	newVersion, err := o.orderRepo.UpdateOrderStatus(ctx, id, status, version)
+	if err != nil {
+		return 0, err
 	}
-	return nil
+	return newVersion, nil
 }
 
 func (o *OrderUsecase) validateCreateOrderRequest(req CreateOrderRequest) error {
@@ -153,9 +341,6 @@ func (o *OrderUsecase) validateCreateOrderRequest(req CreateOrderRequest) error
 	if err := o.validateStatus(req.Status); err != nil {
 		return err
 	}
-	if req.ShippingCost < 0 {
-		return fmt.Errorf("shipping cost cannot be negative")
-	}
 	return nil
 }
 
@@ -0,0 +1,151 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/repository"
+)
+
+// PricingOptions configures the pricing rules shared by carts and orders.
+type PricingOptions struct {
+	// TaxRate is applied to the item subtotal, e.g. 0.11 for 11%.
+	TaxRate float64
+}
+
+// PricedLine is one cart item priced at the product's current price.
+type PricedLine struct {
+	CartItemID uint    `json:"cart_item_id"`
+	ProductID  uint    `json:"product_id"`
+	Quantity   int32   `json:"quantity"`
+	UnitPrice  float64 `json:"unit_price"`
+	LineTotal  float64 `json:"line_total"`
+	Weight     float64 `json:"weight"`
+	// Unavailable is set when the product no longer exists; the line is
+	// left out of every total.
+	Unavailable bool `json:"unavailable,omitempty"`
+}
+
+// PriceQuote holds the server-computed totals of a cart. Order creation uses
+// the same quote, so what the cart shows is what the order charges.
+type PriceQuote struct {
+	Lines        []PricedLine `json:"lines"`
+	Subtotal     float64      `json:"subtotal"`
+	TotalWeight  float64      `json:"total_weight"`
+	ShippingCost float64      `json:"shipping_cost"`
+	TaxRate      float64      `json:"tax_rate"`
+	Tax          float64      `json:"tax"`
+	Total        float64      `json:"total"`
+}
+
+type PricingUsecase struct {
+	productRepo   repository.ProductRepository
+	warehouseRepo repository.WarehouseRepository
+	opts          PricingOptions
+}
+
+func NewPricingUsecase(
+	productRepo repository.ProductRepository,
+	warehouseRepo repository.WarehouseRepository,
+	opts PricingOptions,
+) *PricingUsecase {
+	if opts.TaxRate < 0 {
+		opts.TaxRate = 0
+	}
+	return &PricingUsecase{
+		productRepo:   productRepo,
+		warehouseRepo: warehouseRepo,
+		opts:          opts,
+	}
+}
+
+// QuoteCart prices the items of a cart. Line totals use the current product
+// price, not the sub total stored when the item was added. Shipping is the
+// warehouse's base cost plus its per-kg rate times the total weight; products
+// without a weight count as weightless.
+func (p *PricingUsecase) QuoteCart(ctx context.Context, cart *domain.Cart, items []domain.CartItem) (*PriceQuote, error) {
+	quote := &PriceQuote{
+		Lines:   make([]PricedLine, 0, len(items)),
+		TaxRate: p.opts.TaxRate,
+	}
+
+	products := make(map[uint]*domain.Product)
+	for _, item := range items {
+		line := PricedLine{
+			CartItemID: item.ID,
+			ProductID:  item.ProductID,
+			Quantity:   item.Quantity,
+		}
+
+		product, ok := products[item.ProductID]
+		if !ok {
+			found, err := p.productRepo.FindById(ctx, item.ProductID)
+			if err != nil && !errors.Is(err, repository.ErrProductNotFound) {
+				return nil, fmt.Errorf("failed to get product ID %d: %w", item.ProductID, err)
+			}
+			product = found
+			products[item.ProductID] = product
+		}
+		if product == nil {
+			line.Unavailable = true
+			quote.Lines = append(quote.Lines, line)
+			continue
+		}
+
+		line.UnitPrice = product.Price
+		line.LineTotal = roundMoney(product.Price * float64(item.Quantity))
+		if product.Weight != nil {
+			line.Weight = *product.Weight * float64(item.Quantity)
+		}
+
+		quote.Subtotal += line.LineTotal
+		quote.TotalWeight += line.Weight
+		quote.Lines = append(quote.Lines, line)
+	}
+
+	if quote.Subtotal > 0 {
+		warehouse, err := p.warehouseRepo.GetById(ctx, cart.WarehouseID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get warehouse ID %d: %w", cart.WarehouseID, err)
+		}
+		quote.ShippingCost = roundMoney(warehouse.ShippingBaseCost + warehouse.ShippingCostPerKg*quote.TotalWeight)
+	}
+
+	quote.Subtotal = roundMoney(quote.Subtotal)
+	quote.Tax = roundMoney(quote.Subtotal * p.opts.TaxRate)
+	quote.Total = roundMoney(quote.Subtotal + quote.ShippingCost + quote.Tax)
+	return quote, nil
+}
+
+// RepriceShipping replaces the shipping of quote for an order routed to
+// several warehouses: each warehouse that ships part of it charges its base
+// cost plus its per-kg rate for the weight it ships.
+func (p *PricingUsecase) RepriceShipping(quote *PriceQuote, allocations []domain.OrderAllocation, warehouses map[uint]domain.Warehouse) {
+	unitWeight := make(map[uint]float64)
+	for _, line := range quote.Lines {
+		if line.Quantity > 0 && !line.Unavailable {
+			unitWeight[line.ProductID] = line.Weight / float64(line.Quantity)
+		}
+	}
+
+	weights := make(map[uint]float64)
+	for _, a := range allocations {
+		weights[a.WarehouseID] += unitWeight[a.ProductID] * float64(a.Quantity)
+	}
+
+	shipping := 0.0
+	for id, weight := range weights {
+		w := warehouses[id]
+		shipping += w.ShippingBaseCost + w.ShippingCostPerKg*weight
+	}
+	quote.ShippingCost = roundMoney(shipping)
+	quote.Total = roundMoney(quote.Subtotal + quote.ShippingCost + quote.Tax)
+}
+
+// roundMoney rounds to two decimals, matching the NUMERIC(12,2) columns.
+func roundMoney(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
@@ -14,18 +15,21 @@ import (
 )
 
 type ProductUsecase struct {
-	repo  repository.ProductRepository
-	cache *redis.Client
+	repo      repository.ProductRepository
+	stockRepo repository.WarehouseStockRepository
+	cache     *redis.Client
 }
 
-func NewProductUsecase(repo repository.ProductRepository, cache *redis.Client) *ProductUsecase {
+func NewProductUsecase(repo repository.ProductRepository, stockRepo repository.WarehouseStockRepository, cache *redis.Client) *ProductUsecase {
 	return &ProductUsecase{
-		repo:  repo,
-		cache: cache,
+		repo:      repo,
+		stockRepo: stockRepo,
+		cache:     cache,
 	}
 }
 
-// Cache keys
+// Cache keys. A cached product carries a stale stock; it is replaced by the
+// current total on every read (see withCurrentStock).
 const (
 	productByIDPrefix   = "product:id:"
 	productByNamePrefix = "product:name:"
@@ -38,6 +42,10 @@ func (u *ProductUsecase) CreateProduct(ctx context.Context, product *domain.Prod
 	if err == nil && existing != nil {
 		return errors.New("product already exists")
 	}
+	normalizeSKU(product)
+	if product.Volume != nil && *product.Volume < 0 {
+		return errors.New("volume cannot be negative")
+	}
 
 	err = u.repo.Create(ctx, product)
 	if err != nil {
@@ -53,6 +61,10 @@ func (u *ProductUsecase) UpdateProduct(ctx context.Context, product *domain.Prod
 	if product.ID == 0 {
 		return errors.New("product ID is required")
 	}
+	normalizeSKU(product)
+	if product.Volume != nil && *product.Volume < 0 {
+		return errors.New("volume cannot be negative")
+	}
 
 	err := u.repo.Update(ctx, product)
 	if err != nil {
@@ -64,6 +76,19 @@ func (u *ProductUsecase) UpdateProduct(ctx context.Context, product *domain.Prod
 	return nil
 }
 
+// normalizeSKU trims the SKU; an empty SKU is stored as NULL.
+func normalizeSKU(product *domain.Product) {
+	if product.SKU == nil {
+		return
+	}
+	sku := strings.TrimSpace(*product.SKU)
+	if sku == "" {
+		product.SKU = nil
+		return
+	}
+	product.SKU = &sku
+}
+
 func (u *ProductUsecase) DeleteProduct(ctx context.Context, id int64) error {
 	if id <= 0 {
 		return errors.New("invalid product ID")
@@ -96,6 +121,9 @@ func (u *ProductUsecase) GetProductByName(ctx context.Context, name string) (*do
 		if err == nil {
 			var product domain.Product
 			if json.Unmarshal([]byte(cachedData), &product) == nil {
+				if err := u.withCurrentStock(ctx, []*domain.Product{&product}); err != nil {
+					return nil, err
+				}
 				return &product, nil
 			}
 		}
@@ -124,6 +152,9 @@ func (u *ProductUsecase) GetProductById(ctx context.Context, id uint) (*domain.P
 		if err == nil {
 			var product domain.Product
 			if json.Unmarshal([]byte(cachedData), &product) == nil {
+				if err := u.withCurrentStock(ctx, []*domain.Product{&product}); err != nil {
+					return nil, err
+				}
 				return &product, nil
 			}
 		}
@@ -151,6 +182,13 @@ func (u *ProductUsecase) GetAllProducts(ctx context.Context) ([]domain.Product,
 			var products []domain.Product
 			if json.Unmarshal([]byte(cachedData), &products) == nil {
 				log.Println("✅ [CACHE HIT] Products retrieved from Redis")
+				refs := make([]*domain.Product, len(products))
+				for i := range products {
+					refs[i] = &products[i]
+				}
+				if err := u.withCurrentStock(ctx, refs); err != nil {
+					return nil, err
+				}
 				return products, nil
 			}
 		} else {
@@ -179,6 +217,37 @@ func (u *ProductUsecase) GetAllProducts(ctx context.Context) ([]domain.Product,
 	return products, nil
 }
 
+// withCurrentStock replaces the stock of cached products with the current
+// warehouse totals. Stock changes do not touch the product cache.
+func (u *ProductUsecase) withCurrentStock(ctx context.Context, products []*domain.Product) error {
+	ids := make([]uint, len(products))
+	for i, p := range products {
+		ids[i] = p.ID
+	}
+	totals, err := u.repo.StockTotals(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, p := range products {
+		p.Stock = totals[p.ID]
+	}
+	return nil
+}
+
+// ReconcileStock checks the warehouse quantities that product stock is summed
+// from against the movement ledger. With apply set the quantities are
+// rebuilt from the ledger.
+func (u *ProductUsecase) ReconcileStock(ctx context.Context, apply bool) ([]domain.StockDiscrepancy, error) {
+	discrepancies, err := u.stockRepo.RebuildFromLedger(ctx, apply)
+	if err != nil {
+		return nil, err
+	}
+	if apply && len(discrepancies) > 0 {
+		invalidateAllAvailabilityCache(ctx, u.cache)
+	}
+	return discrepancies, nil
+}
+
 func (u *ProductUsecase) cacheProduct(ctx context.Context, product *domain.Product) {
 	if u.cache == nil {
 		return
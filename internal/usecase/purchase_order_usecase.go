@@ -0,0 +1,329 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/repository"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	// ErrInvalidPurchaseOrder dikembalikan untuk input purchase order yang tidak valid.
+	ErrInvalidPurchaseOrder = errors.New("invalid purchase order")
+	// ErrPurchaseOrderStatus dikembalikan kalau aksi tidak boleh untuk status purchase order saat ini.
+	ErrPurchaseOrderStatus = errors.New("action not allowed for purchase order status")
+)
+
+// PurchaseOrderLineRequest adalah satu produk yang dipesan ke supplier.
+type PurchaseOrderLineRequest struct {
+	ProductID uint    `json:"product_id" binding:"required"`
+	Quantity  int32   `json:"quantity" binding:"required"`
+	UnitCost  float64 `json:"unit_cost"`
+}
+
+type CreatePurchaseOrderRequest struct {
+	SupplierID  uint                       `json:"supplier_id" binding:"required"`
+	WarehouseID uint                       `json:"warehouse_id" binding:"required"`
+	Note        string                     `json:"note"`
+	Lines       []PurchaseOrderLineRequest `json:"lines" binding:"required"`
+}
+
+// ReceivePurchaseLineRequest mencatat barang yang datang untuk satu line.
+// LandedUnitCost kosong berarti sama dengan unit cost di purchase order.
+// Dengan BatchNumber barang masuk ke lot itu (ExpiryDate YYYY-MM-DD, boleh
+// kosong); tanpa BatchNumber masuk sebagai stok tanpa lot.
+type ReceivePurchaseLineRequest struct {
+	LineID         uint     `json:"line_id" binding:"required"`
+	Quantity       int32    `json:"quantity" binding:"required"`
+	LandedUnitCost *float64 `json:"landed_unit_cost"`
+	BatchNumber    string   `json:"batch_number"`
+	ExpiryDate     *string  `json:"expiry_date"`
+}
+
+type ReceivePurchaseOrderRequest struct {
+	Lines []ReceivePurchaseLineRequest `json:"lines" binding:"required"`
+}
+
+type PurchaseOrderUsecase struct {
+	repo               repository.PurchaseOrderRepository
+	supplierRepo       repository.SupplierRepository
+	warehouseRepo      repository.WarehouseRepository
+	productRepo        repository.ProductRepository
+	warehouseStockRepo repository.WarehouseStockRepository
+	stockLotRepo       repository.StockLotRepository
+	// cache hanya dipakai untuk membuang availability produk yang berubah; boleh nil
+	cache *redis.Client
+}
+
+func NewPurchaseOrderUsecase(
+	repo repository.PurchaseOrderRepository,
+	supplierRepo repository.SupplierRepository,
+	warehouseRepo repository.WarehouseRepository,
+	productRepo repository.ProductRepository,
+	warehouseStockRepo repository.WarehouseStockRepository,
+	stockLotRepo repository.StockLotRepository,
+	cache *redis.Client,
+) *PurchaseOrderUsecase {
+	return &PurchaseOrderUsecase{
+		repo:               repo,
+		supplierRepo:       supplierRepo,
+		warehouseRepo:      warehouseRepo,
+		productRepo:        productRepo,
+		warehouseStockRepo: warehouseStockRepo,
+		stockLotRepo:       stockLotRepo,
+		cache:              cache,
+	}
+}
+
+// Create membuat purchase order berstatus draft.
+func (u *PurchaseOrderUsecase) Create(ctx context.Context, actorID uint, req CreatePurchaseOrderRequest) (*domain.PurchaseOrder, error) {
+	if len(req.Lines) == 0 {
+		return nil, fmt.Errorf("%w: at least one line is required", ErrInvalidPurchaseOrder)
+	}
+	if _, err := u.supplierRepo.GetByID(ctx, req.SupplierID); err != nil {
+		if errors.Is(err, repository.ErrSupplierNotFound) {
+			return nil, fmt.Errorf("%w: supplier %d not found", ErrInvalidPurchaseOrder, req.SupplierID)
+		}
+		return nil, err
+	}
+	if _, err := u.warehouseRepo.GetById(ctx, req.WarehouseID); err != nil {
+		return nil, fmt.Errorf("%w: warehouse %d not found", ErrInvalidPurchaseOrder, req.WarehouseID)
+	}
+
+	po := &domain.PurchaseOrder{
+		SupplierID:  req.SupplierID,
+		WarehouseID: req.WarehouseID,
+		CreatedBy:   &actorID,
+		Lines:       make([]domain.PurchaseOrderLine, 0, len(req.Lines)),
+	}
+	if req.Note != "" {
+		po.Note = &req.Note
+	}
+
+	seen := make(map[uint]bool, len(req.Lines))
+	for _, l := range req.Lines {
+		if l.Quantity <= 0 {
+			return nil, fmt.Errorf("%w: quantity for product %d must be greater than zero", ErrInvalidPurchaseOrder, l.ProductID)
+		}
+		if l.UnitCost < 0 {
+			return nil, fmt.Errorf("%w: unit cost for product %d cannot be negative", ErrInvalidPurchaseOrder, l.ProductID)
+		}
+		if seen[l.ProductID] {
+			return nil, fmt.Errorf("%w: product %d listed twice", ErrInvalidPurchaseOrder, l.ProductID)
+		}
+		seen[l.ProductID] = true
+		if _, err := u.productRepo.FindById(ctx, l.ProductID); err != nil {
+			return nil, fmt.Errorf("%w: product %d not found", ErrInvalidPurchaseOrder, l.ProductID)
+		}
+		po.Lines = append(po.Lines, domain.PurchaseOrderLine{ProductID: l.ProductID, Quantity: l.Quantity, UnitCost: l.UnitCost})
+	}
+
+	if err := u.repo.Create(ctx, po); err != nil {
+		return nil, err
+	}
+	return po, nil
+}
+
+func (u *PurchaseOrderUsecase) Get(ctx context.Context, id uint) (*domain.PurchaseOrder, error) {
+	return u.repo.GetByID(ctx, id)
+}
+
+func (u *PurchaseOrderUsecase) List(ctx context.Context, supplierID, warehouseID uint, status string) ([]domain.PurchaseOrder, error) {
+	switch status {
+	case "", domain.PurchaseOrderDraft, domain.PurchaseOrderSent,
+		domain.PurchaseOrderPartiallyReceived, domain.PurchaseOrderReceived:
+	default:
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidPurchaseOrder, status)
+	}
+	return u.repo.List(ctx, supplierID, warehouseID, status)
+}
+
+func (u *PurchaseOrderUsecase) Receipts(ctx context.Context, id uint) ([]domain.PurchaseReceipt, error) {
+	if _, err := u.repo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	return u.repo.ListReceipts(ctx, id)
+}
+
+// Delete hanya untuk draft; purchase order yang sudah dikirim tetap disimpan.
+func (u *PurchaseOrderUsecase) Delete(ctx context.Context, id uint) error {
+	po, err := u.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if po.Status != domain.PurchaseOrderDraft {
+		return fmt.Errorf("%w: only draft purchase orders can be deleted", ErrPurchaseOrderStatus)
+	}
+	return u.repo.DeleteDraft(ctx, id)
+}
+
+// Send menandai draft sebagai sudah dikirim ke supplier.
+func (u *PurchaseOrderUsecase) Send(ctx context.Context, id uint) (*domain.PurchaseOrder, error) {
+	tx, err := u.repo.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	po, err := u.repo.GetByIDForUpdateTx(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if po.Status != domain.PurchaseOrderDraft {
+		return nil, fmt.Errorf("%w: purchase order is %s", ErrPurchaseOrderStatus, po.Status)
+	}
+	if err := u.repo.UpdateStatusTx(ctx, tx, id, domain.PurchaseOrderSent); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit purchase order status: %w", err)
+	}
+	return u.repo.GetByID(ctx, id)
+}
+
+// Receive menambah stok gudang tujuan untuk setiap line yang diterima dan
+// mencatat landed cost-nya. Penerimaan boleh sebagian; purchase order menjadi
+// received setelah semua quantity diterima.
+func (u *PurchaseOrderUsecase) Receive(ctx context.Context, actorID uint, id uint, req ReceivePurchaseOrderRequest) (*domain.PurchaseOrder, error) {
+	if len(req.Lines) == 0 {
+		return nil, fmt.Errorf("%w: at least one line is required", ErrInvalidPurchaseOrder)
+	}
+
+	tx, err := u.repo.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	po, err := u.repo.GetByIDForUpdateTx(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if po.Status != domain.PurchaseOrderSent && po.Status != domain.PurchaseOrderPartiallyReceived {
+		return nil, fmt.Errorf("%w: purchase order is %s", ErrPurchaseOrderStatus, po.Status)
+	}
+	// gudang dikunci sebelum baris stok mana pun, lihat LockCapacityTx
+	if err := u.warehouseStockRepo.LockCapacityTx(ctx, tx, []uint{po.WarehouseID}); err != nil {
+		return nil, err
+	}
+
+	lines := make(map[uint]*domain.PurchaseOrderLine, len(po.Lines))
+	for i := range po.Lines {
+		lines[po.Lines[i].ID] = &po.Lines[i]
+	}
+	// baris stok dikunci urut product, sama seperti saat membuat order,
+	// supaya dua penerimaan untuk gudang yang sama tidak saling deadlock
+	received := make([]ReceivePurchaseLineRequest, len(req.Lines))
+	copy(received, req.Lines)
+	for _, r := range received {
+		if _, ok := lines[r.LineID]; !ok {
+			return nil, fmt.Errorf("%w: line %d", repository.ErrPurchaseOrderLineNotFound, r.LineID)
+		}
+	}
+	sort.SliceStable(received, func(i, j int) bool {
+		return lines[received[i].LineID].ProductID < lines[received[j].LineID].ProductID
+	})
+
+	ref := domain.StockReference{
+		Reason:        domain.MovementReasonRestock,
+		ReferenceType: domain.MovementRefPurchaseOrder,
+		ReferenceID:   po.ID,
+		ActorID:       actorID,
+	}
+	for _, r := range received {
+		line := lines[r.LineID]
+		if r.Quantity <= 0 {
+			return nil, fmt.Errorf("%w: quantity for line %d must be greater than zero", ErrInvalidPurchaseOrder, r.LineID)
+		}
+		if r.Quantity > line.Outstanding() {
+			return nil, fmt.Errorf("%w: line %d has only %d outstanding", ErrInvalidPurchaseOrder, r.LineID, line.Outstanding())
+		}
+		landed := line.UnitCost
+		if r.LandedUnitCost != nil {
+			if *r.LandedUnitCost < 0 {
+				return nil, fmt.Errorf("%w: landed unit cost for line %d cannot be negative", ErrInvalidPurchaseOrder, r.LineID)
+			}
+			landed = *r.LandedUnitCost
+		}
+		batch := strings.TrimSpace(r.BatchNumber)
+		var expiry *time.Time
+		if r.ExpiryDate != nil && *r.ExpiryDate != "" {
+			if batch == "" {
+				return nil, fmt.Errorf("%w: expiry date for line %d needs a batch number", ErrInvalidPurchaseOrder, r.LineID)
+			}
+			t, err := time.Parse(time.DateOnly, *r.ExpiryDate)
+			if err != nil {
+				return nil, fmt.Errorf("%w: expiry_date for line %d must be YYYY-MM-DD", ErrInvalidPurchaseOrder, r.LineID)
+			}
+			expiry = &t
+		}
+
+		if err := u.warehouseStockRepo.EnsureStockRowTx(ctx, tx, po.WarehouseID, line.ProductID); err != nil {
+			return nil, err
+		}
+		_, err := u.warehouseStockRepo.ApplyChangeTx(ctx, tx, domain.StockChange{
+			WarehouseID:    po.WarehouseID,
+			ProductID:      line.ProductID,
+			Delta:          r.Quantity,
+			StockReference: ref,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to receive product %d: %w", line.ProductID, err)
+		}
+
+		receipt := &domain.PurchaseReceipt{
+			PurchaseOrderLineID: line.ID,
+			ProductID:           line.ProductID,
+			Quantity:            r.Quantity,
+			LandedUnitCost:      landed,
+			ReceivedBy:          &actorID,
+		}
+		if batch != "" {
+			lot := &domain.StockLot{
+				WarehouseID: po.WarehouseID,
+				ProductID:   line.ProductID,
+				BatchNumber: batch,
+				ExpiryDate:  expiry,
+				Quantity:    r.Quantity,
+			}
+			if err := u.stockLotRepo.ReceiveTx(ctx, tx, lot); err != nil {
+				return nil, fmt.Errorf("failed to receive product %d: %w", line.ProductID, err)
+			}
+			receipt.BatchNumber = &batch
+			receipt.ExpiryDate = expiry
+		}
+		if err := u.repo.RecordReceiptTx(ctx, tx, receipt); err != nil {
+			return nil, err
+		}
+		line.ReceivedQuantity += r.Quantity
+	}
+
+	status := domain.PurchaseOrderReceived
+	for _, line := range po.Lines {
+		if line.Outstanding() > 0 {
+			status = domain.PurchaseOrderPartiallyReceived
+			break
+		}
+	}
+	if status != po.Status {
+		if err := u.repo.UpdateStatusTx(ctx, tx, id, status); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit purchase receipt: %w", err)
+	}
+	productIDs := make([]uint, len(po.Lines))
+	for i, line := range po.Lines {
+		productIDs[i] = line.ProductID
+	}
+	invalidateAvailabilityCache(ctx, u.cache, productIDs...)
+	return u.repo.GetByID(ctx, id)
+}
@@ -0,0 +1,258 @@
+package usecase
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/repository"
+)
+
+var (
+	// ErrInvalidReplenishment dikembalikan untuk parameter saran pembelian yang tidak valid.
+	ErrInvalidReplenishment = errors.New("invalid replenishment parameters")
+	// ErrReplenishmentWarehouse dikembalikan jika gudangnya tidak ada.
+	ErrReplenishmentWarehouse = errors.New("warehouse not found")
+)
+
+// ReplenishmentParams mengatur perhitungan saran pembelian. Nilai nol memakai
+// default: sma, window 28 hari, alpha 0.3, lead time 7 hari, review 7 hari
+// dan service level 0.95.
+type ReplenishmentParams struct {
+	// Method is sma (moving average) or ses (exponential smoothing).
+	Method string `form:"method"`
+	// WindowDays is how many full days of order history are used.
+	WindowDays int `form:"window_days"`
+	// Alpha is the smoothing factor for ses, in (0, 1].
+	Alpha float64 `form:"alpha"`
+	// LeadTimeDays is how long a purchase takes to arrive.
+	LeadTimeDays int `form:"lead_time_days"`
+	// ReviewDays is how long an order has to last until the next one.
+	ReviewDays int `form:"review_days"`
+	// ServiceLevel is the wanted chance of not running out during the lead
+	// time; it sets the safety stock.
+	ServiceLevel float64 `form:"service_level"`
+}
+
+func (p *ReplenishmentParams) normalize() error {
+	if p.Method == "" {
+		p.Method = domain.DemandMovingAverage
+	}
+	if p.WindowDays == 0 {
+		p.WindowDays = 28
+	}
+	if p.LeadTimeDays == 0 {
+		p.LeadTimeDays = 7
+	}
+	if p.ReviewDays == 0 {
+		p.ReviewDays = 7
+	}
+	if p.ServiceLevel == 0 {
+		p.ServiceLevel = 0.95
+	}
+
+	switch p.Method {
+	case domain.DemandMovingAverage:
+		p.Alpha = 0
+	case domain.DemandExponentialSmoothing:
+		if p.Alpha == 0 {
+			p.Alpha = 0.3
+		}
+		if p.Alpha < 0 || p.Alpha > 1 {
+			return fmt.Errorf("%w: alpha must be within (0, 1]", ErrInvalidReplenishment)
+		}
+	default:
+		return fmt.Errorf("%w: method must be %s or %s", ErrInvalidReplenishment, domain.DemandMovingAverage, domain.DemandExponentialSmoothing)
+	}
+	if p.WindowDays < 1 || p.WindowDays > 365 {
+		return fmt.Errorf("%w: window_days must be between 1 and 365", ErrInvalidReplenishment)
+	}
+	if p.LeadTimeDays < 0 || p.LeadTimeDays > 365 || p.ReviewDays < 0 || p.ReviewDays > 365 {
+		return fmt.Errorf("%w: lead_time_days and review_days must be between 0 and 365", ErrInvalidReplenishment)
+	}
+	if p.ServiceLevel < 0.5 || p.ServiceLevel > 0.999 {
+		return fmt.Errorf("%w: service_level must be between 0.5 and 0.999", ErrInvalidReplenishment)
+	}
+	return nil
+}
+
+// ReplenishmentUsecase menghitung saran pembelian per gudang dari riwayat
+// pesanan: rata-rata permintaan harian, days of cover, safety stock,
+// reorder point dan quantity yang disarankan.
+type ReplenishmentUsecase struct {
+	repo          repository.ReplenishmentRepository
+	warehouseRepo repository.WarehouseRepository
+}
+
+func NewReplenishmentUsecase(repo repository.ReplenishmentRepository, warehouseRepo repository.WarehouseRepository) *ReplenishmentUsecase {
+	return &ReplenishmentUsecase{repo: repo, warehouseRepo: warehouseRepo}
+}
+
+// Suggest computes one suggestion per product of the warehouse, most urgent
+// (fewest days of cover) first.
+//
+// Demand is forecast from the last WindowDays full days, counting days
+// without orders as zero. Safety stock covers demand variation during the
+// lead time: z(service level) × σ(daily demand) × √lead time. A product is
+// reordered when stock on hand plus on order falls to the reorder point
+// (lead time demand + safety stock), up to the target level (demand over
+// lead time and review period + safety stock).
+func (u *ReplenishmentUsecase) Suggest(ctx context.Context, warehouseID uint, params ReplenishmentParams) (*domain.ReplenishmentReport, error) {
+	if err := params.normalize(); err != nil {
+		return nil, err
+	}
+	if _, err := u.warehouseRepo.GetById(ctx, warehouseID); err != nil {
+		return nil, fmt.Errorf("%w: %d", ErrReplenishmentWarehouse, warehouseID)
+	}
+
+	y, m, d := time.Now().Date()
+	to := time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+	from := to.AddDate(0, 0, -params.WindowDays)
+
+	demand, err := u.repo.DailyDemand(ctx, warehouseID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	positions, err := u.repo.StockPositions(ctx, warehouseID)
+	if err != nil {
+		return nil, err
+	}
+
+	// daily series per product, index 0 is the oldest day
+	series := make(map[uint][]float64)
+	for _, dd := range demand {
+		// rounded, a day across a DST change is 23 or 25 hours long
+		day := int(math.Round(time.Date(dd.Day.Year(), dd.Day.Month(), dd.Day.Day(), 0, 0, 0, 0, time.Local).Sub(from).Hours() / 24))
+		if day < 0 || day >= params.WindowDays {
+			continue
+		}
+		if series[dd.ProductID] == nil {
+			series[dd.ProductID] = make([]float64, params.WindowDays)
+		}
+		series[dd.ProductID][day] += float64(dd.Quantity)
+	}
+
+	z := math.Sqrt2 * math.Erfinv(2*params.ServiceLevel-1)
+	report := &domain.ReplenishmentReport{
+		WarehouseID:  warehouseID,
+		Method:       params.Method,
+		WindowDays:   params.WindowDays,
+		Alpha:        params.Alpha,
+		LeadTimeDays: params.LeadTimeDays,
+		ReviewDays:   params.ReviewDays,
+		ServiceLevel: params.ServiceLevel,
+		From:         from,
+		To:           to,
+		Suggestions:  make([]domain.ReplenishmentSuggestion, 0, len(positions)),
+	}
+	for _, p := range positions {
+		s := domain.ReplenishmentSuggestion{StockPosition: p}
+		if xs := series[p.ProductID]; xs != nil {
+			s.AvgDailyDemand = forecastDemand(xs, params)
+			s.DemandStdDev = stdDev(xs)
+		}
+
+		lead := float64(params.LeadTimeDays)
+		s.SafetyStock = int32(math.Ceil(z * s.DemandStdDev * math.Sqrt(lead)))
+		s.ReorderPoint = int32(math.Ceil(s.AvgDailyDemand*lead)) + s.SafetyStock
+		s.TargetLevel = int32(math.Ceil(s.AvgDailyDemand*(lead+float64(params.ReviewDays)))) + s.SafetyStock
+		if position := p.OnHand + p.OnOrder; position <= s.ReorderPoint && s.TargetLevel > position {
+			s.SuggestedQuantity = s.TargetLevel - position
+		}
+		if s.AvgDailyDemand > 0 {
+			cover := round2(float64(max(p.OnHand, 0)) / s.AvgDailyDemand)
+			s.DaysOfCover = &cover
+		}
+		s.AvgDailyDemand = round2(s.AvgDailyDemand)
+		s.DemandStdDev = round2(s.DemandStdDev)
+		report.Suggestions = append(report.Suggestions, s)
+	}
+
+	sort.SliceStable(report.Suggestions, func(i, j int) bool {
+		a, b := report.Suggestions[i].DaysOfCover, report.Suggestions[j].DaysOfCover
+		if a == nil || b == nil {
+			return b == nil && a != nil
+		}
+		return *a < *b
+	})
+	return report, nil
+}
+
+// forecastDemand returns the expected daily demand from the series.
+func forecastDemand(xs []float64, params ReplenishmentParams) float64 {
+	if params.Method == domain.DemandExponentialSmoothing {
+		level := xs[0]
+		for _, x := range xs[1:] {
+			level = params.Alpha*x + (1-params.Alpha)*level
+		}
+		return level
+	}
+	var sum float64
+	for _, x := range xs {
+		sum += x
+	}
+	return sum / float64(len(xs))
+}
+
+func stdDev(xs []float64) float64 {
+	var mean float64
+	for _, x := range xs {
+		mean += x
+	}
+	mean /= float64(len(xs))
+	var sq float64
+	for _, x := range xs {
+		sq += (x - mean) * (x - mean)
+	}
+	return math.Sqrt(sq / float64(len(xs)))
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// WriteCSV menulis saran pembelian sebagai CSV, satu baris per produk.
+// days_of_cover kosong berarti tidak ada permintaan.
+func (u *ReplenishmentUsecase) WriteCSV(w io.Writer, report *domain.ReplenishmentReport) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{
+		"product_id", "sku", "product_name", "on_hand", "on_order", "avg_daily_demand", "demand_std_dev",
+		"days_of_cover", "safety_stock", "reorder_point", "target_level", "suggested_quantity",
+	}); err != nil {
+		return err
+	}
+	for _, s := range report.Suggestions {
+		sku, cover := "", ""
+		if s.SKU != nil {
+			sku = *s.SKU
+		}
+		if s.DaysOfCover != nil {
+			cover = strconv.FormatFloat(*s.DaysOfCover, 'f', 2, 64)
+		}
+		if err := cw.Write([]string{
+			strconv.FormatUint(uint64(s.ProductID), 10),
+			sku,
+			s.ProductName,
+			strconv.FormatInt(int64(s.OnHand), 10),
+			strconv.FormatInt(int64(s.OnOrder), 10),
+			strconv.FormatFloat(s.AvgDailyDemand, 'f', 2, 64),
+			strconv.FormatFloat(s.DemandStdDev, 'f', 2, 64),
+			cover,
+			strconv.FormatInt(int64(s.SafetyStock), 10),
+			strconv.FormatInt(int64(s.ReorderPoint), 10),
+			strconv.FormatInt(int64(s.TargetLevel), 10),
+			strconv.FormatInt(int64(s.SuggestedQuantity), 10),
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
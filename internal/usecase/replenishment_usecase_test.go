@@ -0,0 +1,176 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForecastDemand(t *testing.T) {
+	tests := []struct {
+		name   string
+		series []float64
+		params ReplenishmentParams
+		want   float64
+	}{
+		{"moving average", []float64{1, 2, 3, 4}, ReplenishmentParams{Method: domain.DemandMovingAverage}, 2.5},
+		{"moving average of zero demand", []float64{0, 0, 0}, ReplenishmentParams{Method: domain.DemandMovingAverage}, 0},
+		{"smoothing starts at the oldest day", []float64{4, 0, 2}, ReplenishmentParams{Method: domain.DemandExponentialSmoothing, Alpha: 0.5}, 2},
+		{"smoothing weighs recent days more", []float64{0, 0, 0, 8}, ReplenishmentParams{Method: domain.DemandExponentialSmoothing, Alpha: 0.3}, 2.4},
+		{"alpha 1 is the last day", []float64{1, 5, 3}, ReplenishmentParams{Method: domain.DemandExponentialSmoothing, Alpha: 1}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.want, forecastDemand(tt.series, tt.params), 1e-9)
+		})
+	}
+}
+
+func TestStdDev(t *testing.T) {
+	tests := []struct {
+		name   string
+		series []float64
+		want   float64
+	}{
+		{"constant", []float64{3, 3, 3}, 0},
+		{"population deviation", []float64{2, 4, 4, 4, 5, 5, 7, 9}, 2},
+		{"alternating", []float64{0, 4, 0, 4}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.want, stdDev(tt.series), 1e-9)
+		})
+	}
+}
+
+func TestReplenishmentParams_Normalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  ReplenishmentParams
+		wantErr bool
+	}{
+		{"defaults", ReplenishmentParams{}, false},
+		{"ses default alpha", ReplenishmentParams{Method: domain.DemandExponentialSmoothing}, false},
+		{"unknown method", ReplenishmentParams{Method: "arima"}, true},
+		{"alpha above 1", ReplenishmentParams{Method: domain.DemandExponentialSmoothing, Alpha: 1.5}, true},
+		{"window too long", ReplenishmentParams{WindowDays: 400}, true},
+		{"negative lead time", ReplenishmentParams{LeadTimeDays: -1}, true},
+		{"service level too high", ReplenishmentParams{ServiceLevel: 1}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.params.normalize()
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidReplenishment)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestReplenishmentUsecase_Suggest(t *testing.T) {
+	y, m, d := time.Now().Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+	day := func(i int) time.Time { return today.AddDate(0, 0, i-4) }
+
+	repo := &fakeReplenishmentRepo{
+		demand: []domain.DailyDemand{
+			// product 1 sells 2 a day
+			{ProductID: 1, Day: day(0), Quantity: 2},
+			{ProductID: 1, Day: day(1), Quantity: 2},
+			{ProductID: 1, Day: day(2), Quantity: 2},
+			{ProductID: 1, Day: day(3), Quantity: 2},
+			// today is outside the window
+			{ProductID: 1, Day: day(4), Quantity: 100},
+			// product 2 sells 2 a day on average, σ = 2
+			{ProductID: 2, Day: day(1), Quantity: 4},
+			{ProductID: 2, Day: day(3), Quantity: 4},
+		},
+		positions: []domain.StockPosition{
+			{ProductID: 3, ProductName: "No demand", OnHand: 7},
+			{ProductID: 2, ProductName: "Covered", OnHand: 10},
+			{ProductID: 1, ProductName: "Short", OnHand: 3},
+		},
+	}
+	u := NewReplenishmentUsecase(repo, &fakeWarehouseRepo{warehouses: map[uint]*domain.Warehouse{1: {ID: 1}}})
+
+	report, err := u.Suggest(context.Background(), 1, ReplenishmentParams{WindowDays: 4, LeadTimeDays: 2, ReviewDays: 2, ServiceLevel: 0.95})
+	assert.NoError(t, err)
+	assert.Equal(t, today, repo.to)
+	assert.Equal(t, day(0), repo.from)
+	if !assert.Len(t, report.Suggestions, 3) {
+		return
+	}
+
+	short, covered, none := report.Suggestions[0], report.Suggestions[1], report.Suggestions[2]
+
+	// σ = 0: no safety stock; reorder point 2×2, target 2×(2+2)
+	assert.Equal(t, uint(1), short.ProductID)
+	assert.Equal(t, 2.0, short.AvgDailyDemand)
+	assert.Equal(t, int32(0), short.SafetyStock)
+	assert.Equal(t, int32(4), short.ReorderPoint)
+	assert.Equal(t, int32(8), short.TargetLevel)
+	assert.Equal(t, int32(5), short.SuggestedQuantity)
+	assert.Equal(t, 1.5, *short.DaysOfCover)
+
+	// safety stock ⌈1.645 × 2 × √2⌉ = 5; 10 on hand is above the reorder point 9
+	assert.Equal(t, uint(2), covered.ProductID)
+	assert.Equal(t, 2.0, covered.DemandStdDev)
+	assert.Equal(t, int32(5), covered.SafetyStock)
+	assert.Equal(t, int32(9), covered.ReorderPoint)
+	assert.Equal(t, int32(13), covered.TargetLevel)
+	assert.Equal(t, int32(0), covered.SuggestedQuantity)
+	assert.Equal(t, 5.0, *covered.DaysOfCover)
+
+	assert.Equal(t, uint(3), none.ProductID)
+	assert.Nil(t, none.DaysOfCover)
+	assert.Equal(t, int32(0), none.SuggestedQuantity)
+}
+
+func TestReplenishmentUsecase_SuggestCountsStockOnOrder(t *testing.T) {
+	y, m, d := time.Now().Date()
+	yesterday := time.Date(y, m, d, 0, 0, 0, 0, time.Local).AddDate(0, 0, -1)
+
+	repo := &fakeReplenishmentRepo{
+		demand:    []domain.DailyDemand{{ProductID: 1, Day: yesterday, Quantity: 3}},
+		positions: []domain.StockPosition{{ProductID: 1, OnHand: 1, OnOrder: 2}},
+	}
+	u := NewReplenishmentUsecase(repo, &fakeWarehouseRepo{warehouses: map[uint]*domain.Warehouse{1: {ID: 1}}})
+
+	// 3 a day, lead time 1, review 1: reorder point 3, target 6; position 1 + 2
+	report, err := u.Suggest(context.Background(), 1, ReplenishmentParams{WindowDays: 1, LeadTimeDays: 1, ReviewDays: 1, ServiceLevel: 0.5})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), report.Suggestions[0].SuggestedQuantity)
+}
+
+func TestReplenishmentUsecase_SuggestUnknownWarehouse(t *testing.T) {
+	u := NewReplenishmentUsecase(&fakeReplenishmentRepo{}, &fakeWarehouseRepo{})
+
+	_, err := u.Suggest(context.Background(), 9, ReplenishmentParams{})
+	assert.ErrorIs(t, err, ErrReplenishmentWarehouse)
+}
+
+func TestReplenishmentUsecase_WriteCSV(t *testing.T) {
+	sku := "KB-01"
+	cover := 1.5
+	report := &domain.ReplenishmentReport{Suggestions: []domain.ReplenishmentSuggestion{
+		{
+			StockPosition:  domain.StockPosition{ProductID: 1, ProductName: "Keyboard, mechanical", SKU: &sku, OnHand: 3, OnOrder: 2},
+			AvgDailyDemand: 2, DemandStdDev: 0.5, DaysOfCover: &cover,
+			SafetyStock: 1, ReorderPoint: 5, TargetLevel: 9, SuggestedQuantity: 4,
+		},
+		{StockPosition: domain.StockPosition{ProductID: 2, ProductName: "Mouse", OnHand: 7}},
+	}}
+
+	var buf bytes.Buffer
+	err := NewReplenishmentUsecase(nil, nil).WriteCSV(&buf, report)
+	assert.NoError(t, err)
+	assert.Equal(t, "product_id,sku,product_name,on_hand,on_order,avg_daily_demand,demand_std_dev,days_of_cover,safety_stock,reorder_point,target_level,suggested_quantity\n"+
+		"1,KB-01,\"Keyboard, mechanical\",3,2,2.00,0.50,1.50,1,5,9,4\n"+
+		"2,,Mouse,7,0,0.00,0.00,,0,0,0,0\n", buf.String())
+}
@@ -0,0 +1,147 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/repository"
+	"github.com/ifs21014-itdel/concurrent-order-processor/pkg/notifier"
+)
+
+// StockAlertOptions configures delivery of low-stock alerts.
+type StockAlertOptions struct {
+	// Recipient is put in Message.To, e.g. the purchasing team's address.
+	Recipient string
+	// CheckInterval is how often undelivered alerts are picked up.
+	CheckInterval time.Duration
+	// BatchSize caps the alerts delivered per pass.
+	BatchSize int
+}
+
+// StockAlertRunResult summarises one delivery pass.
+type StockAlertRunResult struct {
+	Delivered int `json:"delivered"`
+	Failed    int `json:"failed"`
+}
+
+// StockAlertUsecase delivers the alerts raised by stock changes. Raising
+// happens inside the stock transaction; delivery happens here, afterwards,
+// so a slow or failing notifier never blocks a sale. Failed deliveries are
+// retried on the next pass.
+type StockAlertUsecase struct {
+	repo          repository.StockAlertRepository
+	warehouseRepo repository.WarehouseRepository
+	productRepo   repository.ProductRepository
+	notifier      notifier.Notifier
+	opts          StockAlertOptions
+}
+
+func NewStockAlertUsecase(
+	repo repository.StockAlertRepository,
+	warehouseRepo repository.WarehouseRepository,
+	productRepo repository.ProductRepository,
+	n notifier.Notifier,
+	opts StockAlertOptions,
+) *StockAlertUsecase {
+	if opts.CheckInterval <= 0 {
+		opts.CheckInterval = time.Minute
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	return &StockAlertUsecase{
+		repo:          repo,
+		warehouseRepo: warehouseRepo,
+		productRepo:   productRepo,
+		notifier:      n,
+		opts:          opts,
+	}
+}
+
+func (u *StockAlertUsecase) List(ctx context.Context, warehouseID uint, status string) ([]domain.StockAlert, error) {
+	switch status {
+	case "", domain.StockAlertOpen, domain.StockAlertResolved:
+	default:
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidStockAlertFilter, status)
+	}
+	return u.repo.List(ctx, warehouseID, status)
+}
+
+// Run delivers pending alerts every CheckInterval until ctx is cancelled.
+func (u *StockAlertUsecase) Run(ctx context.Context) {
+	log.Printf("[JOB] Stock alert job started (interval=%s)", u.opts.CheckInterval)
+
+	ticker := time.NewTicker(u.opts.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := u.DeliverPending(ctx); err != nil {
+			log.Printf("[ERROR] Stock alert job failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("[JOB] Stock alert job stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverPending sends every open alert that has not been delivered yet.
+func (u *StockAlertUsecase) DeliverPending(ctx context.Context) (*StockAlertRunResult, error) {
+	alerts, err := u.repo.ListUnnotified(ctx, u.opts.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &StockAlertRunResult{}
+	for _, a := range alerts {
+		if err := u.notifier.Notify(ctx, u.message(ctx, a)); err != nil {
+			log.Printf("[ERROR] Failed to deliver stock alert %d: %v", a.ID, err)
+			result.Failed++
+			continue
+		}
+		if err := u.repo.MarkNotified(ctx, a.ID, time.Now()); err != nil {
+			log.Printf("[ERROR] Failed to record delivery of stock alert %d: %v", a.ID, err)
+			result.Failed++
+			continue
+		}
+		result.Delivered++
+	}
+
+	if len(alerts) > 0 {
+		log.Printf("[JOB] Stock alerts: delivered=%d failed=%d", result.Delivered, result.Failed)
+	}
+	return result, nil
+}
+
+func (u *StockAlertUsecase) message(ctx context.Context, a domain.StockAlert) notifier.Message {
+	productName := fmt.Sprintf("product #%d", a.ProductID)
+	if p, err := u.productRepo.FindById(ctx, a.ProductID); err == nil && p != nil {
+		productName = p.Name
+	}
+	warehouseName := fmt.Sprintf("warehouse #%d", a.WarehouseID)
+	if w, err := u.warehouseRepo.GetById(ctx, a.WarehouseID); err == nil && w != nil {
+		warehouseName = w.Name
+	}
+
+	return notifier.Message{
+		To:      u.opts.Recipient,
+		Subject: fmt.Sprintf("Low stock: %s at %s", productName, warehouseName),
+		Body: fmt.Sprintf("%s at %s dropped to %d units (reorder point %d) on %s.\n",
+			productName, warehouseName, a.Quantity, a.ReorderPoint, a.CreatedAt.Format(time.RFC3339)),
+		Tags: map[string]string{
+			"type":          "low_stock",
+			"alert_id":      strconv.FormatUint(uint64(a.ID), 10),
+			"warehouse_id":  strconv.FormatUint(uint64(a.WarehouseID), 10),
+			"product_id":    strconv.FormatUint(uint64(a.ProductID), 10),
+			"quantity":      strconv.Itoa(int(a.Quantity)),
+			"reorder_point": strconv.Itoa(int(a.ReorderPoint)),
+		},
+	}
+}
@@ -0,0 +1,90 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/redis/go-redis/v9"
+)
+
+// Cache key dan TTL untuk availability produk. TTL dibuat pendek karena
+// invalidasi terjadi setelah commit: pembaca yang mengisi cache tepat sebelum
+// commit bisa menyimpan angka lama, paling lama selama TTL.
+const (
+	availabilityKeyPrefix = "availability:product:"
+	availabilityTTL       = time.Minute
+)
+
+// ProductAvailability mengembalikan stok produk di setiap gudang beserta
+// totalnya. Hasil disimpan di Redis sampai stok produk itu berubah.
+func (u *WarehouseStockUsecase) ProductAvailability(ctx context.Context, productID uint) (*domain.ProductAvailability, error) {
+	cacheKey := fmt.Sprintf("%s%d", availabilityKeyPrefix, productID)
+	if u.cache != nil {
+		cached, err := u.cache.Get(ctx, cacheKey).Result()
+		if err == nil {
+			var availability domain.ProductAvailability
+			if json.Unmarshal([]byte(cached), &availability) == nil {
+				return &availability, nil
+			}
+		}
+	}
+
+	if _, err := u.productRepo.FindById(ctx, productID); err != nil {
+		return nil, err
+	}
+	warehouses, err := u.repo.GetAvailability(ctx, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stock availability: %w", err)
+	}
+
+	availability := &domain.ProductAvailability{ProductID: productID, Warehouses: warehouses}
+	for _, w := range warehouses {
+		availability.OnHand += w.OnHand
+		availability.Reserved += w.Reserved
+		availability.Available += w.Available
+	}
+
+	if u.cache != nil {
+		if data, err := json.Marshal(availability); err == nil {
+			if err := u.cache.Set(ctx, cacheKey, data, availabilityTTL).Err(); err != nil {
+				log.Printf("failed to cache availability of product %d: %v", productID, err)
+			}
+		}
+	}
+	return availability, nil
+}
+
+// invalidateAvailabilityCache drops the cached availability of the given
+// products. Shared by every usecase that changes stock quantities or draft
+// transfers, and called after the change is committed.
+func invalidateAvailabilityCache(ctx context.Context, cache *redis.Client, productIDs ...uint) {
+	if cache == nil || len(productIDs) == 0 {
+		return
+	}
+	keys := make([]string, len(productIDs))
+	for i, id := range productIDs {
+		keys[i] = fmt.Sprintf("%s%d", availabilityKeyPrefix, id)
+	}
+	if err := cache.Del(ctx, keys...).Err(); err != nil {
+		log.Printf("failed to invalidate availability cache: %v", err)
+	}
+}
+
+// invalidateAllAvailabilityCache drops every cached availability, for changes
+// whose products are not known up front (ledger rebuilds, row deletes).
+func invalidateAllAvailabilityCache(ctx context.Context, cache *redis.Client) {
+	if cache == nil {
+		return
+	}
+	iter := cache.Scan(ctx, 0, availabilityKeyPrefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		cache.Del(ctx, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		log.Printf("failed to invalidate availability cache: %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/repository"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWarehouseStockUsecase_ProductAvailability(t *testing.T) {
+	mr := miniredis.RunT(t)
+	cache := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer cache.Close()
+
+	repo := &countingStockRepo{quantity: map[domain.StockKey]int32{
+		{WarehouseID: 1, ProductID: 5}: 10,
+		{WarehouseID: 2, ProductID: 5}: 4,
+		{WarehouseID: 1, ProductID: 6}: 7,
+	}}
+	products := &fakeProductRepo{products: map[uint]*domain.Product{5: {ID: 5}, 6: {ID: 6}}}
+	u := NewWarehouseStockUsecase(repo, nil, products, cache)
+	ctx := context.Background()
+
+	t.Run("MissLoadsAndCaches", func(t *testing.T) {
+		availability, err := u.ProductAvailability(ctx, 5)
+		assert.NoError(t, err)
+		assert.Equal(t, int32(14), availability.OnHand)
+		assert.Len(t, availability.Warehouses, 2)
+		assert.Equal(t, 1, repo.reads)
+		assert.True(t, mr.Exists("availability:product:5"))
+	})
+
+	t.Run("HitSkipsDatabase", func(t *testing.T) {
+		availability, err := u.ProductAvailability(ctx, 5)
+		assert.NoError(t, err)
+		assert.Equal(t, int32(14), availability.OnHand)
+		assert.Equal(t, 1, repo.reads)
+	})
+
+	t.Run("AdjustDropsOnlyThatProduct", func(t *testing.T) {
+		_, err := u.ProductAvailability(ctx, 6)
+		assert.NoError(t, err)
+		reads := repo.reads
+
+		_, err = u.Adjust(ctx, 7, AdjustStockRequest{WarehouseID: 2, ProductID: 5, Delta: delta(-3)})
+		assert.NoError(t, err)
+		assert.False(t, mr.Exists("availability:product:5"))
+		assert.True(t, mr.Exists("availability:product:6"))
+
+		availability, err := u.ProductAvailability(ctx, 5)
+		assert.NoError(t, err)
+		assert.Equal(t, int32(11), availability.OnHand)
+		assert.Equal(t, reads+1, repo.reads)
+	})
+
+	t.Run("FailedAdjustKeepsEntry", func(t *testing.T) {
+		_, err := u.Adjust(ctx, 7, AdjustStockRequest{WarehouseID: 1, ProductID: 6, Delta: delta(-100)})
+		assert.ErrorIs(t, err, repository.ErrInsufficientStock)
+		assert.True(t, mr.Exists("availability:product:6"))
+	})
+
+	t.Run("ConcurrentAdjustDropsEveryProduct", func(t *testing.T) {
+		_, err := u.ProductAvailability(ctx, 5)
+		assert.NoError(t, err)
+
+		u.ConcurrentAdjust(ctx, 7, []AdjustStockRequest{
+			{WarehouseID: 1, ProductID: 5, Delta: delta(1)},
+			{WarehouseID: 1, ProductID: 6, Delta: delta(1)},
+		})
+		assert.False(t, mr.Exists("availability:product:5"))
+		assert.False(t, mr.Exists("availability:product:6"))
+	})
+
+	t.Run("DraftTransferReservesStock", func(t *testing.T) {
+		warehouses := &fakeWarehouseRepo{warehouses: map[uint]*domain.Warehouse{1: {ID: 1}, 2: {ID: 2}}}
+		transfers := &fakeTransferRepo{stock: repo, transfers: map[uint]*domain.StockTransfer{}}
+		tu := NewStockTransferUsecase(transfers, warehouses, products, repo, nil, cache)
+
+		before, err := u.ProductAvailability(ctx, 5)
+		assert.NoError(t, err)
+
+		transfer, err := tu.Create(ctx, 7, CreateTransferRequest{
+			SourceWarehouseID:      1,
+			DestinationWarehouseID: 2,
+			Lines:                  []TransferLineRequest{{ProductID: 5, Quantity: 4}},
+		})
+		assert.NoError(t, err)
+		assert.False(t, mr.Exists("availability:product:5"))
+
+		after, err := u.ProductAvailability(ctx, 5)
+		assert.NoError(t, err)
+		assert.Equal(t, before.OnHand, after.OnHand)
+		assert.Equal(t, int32(4), after.Reserved)
+		assert.Equal(t, before.Available-4, after.Available)
+
+		assert.NoError(t, tu.Delete(ctx, transfer.ID))
+		assert.False(t, mr.Exists("availability:product:5"))
+
+		restored, err := u.ProductAvailability(ctx, 5)
+		assert.NoError(t, err)
+		assert.Equal(t, int32(0), restored.Reserved)
+		assert.Equal(t, before.Available, restored.Available)
+	})
+
+	t.Run("UnknownProductNotCached", func(t *testing.T) {
+		_, err := u.ProductAvailability(ctx, 99)
+		assert.ErrorIs(t, err, repository.ErrProductNotFound)
+		assert.False(t, mr.Exists("availability:product:99"))
+	})
+}
@@ -0,0 +1,218 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/repository"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	// ErrInvalidStockCount dikembalikan untuk input sesi hitung yang tidak valid.
+	ErrInvalidStockCount = errors.New("invalid stock count")
+	// ErrStockCountStatus dikembalikan kalau sesi sudah tidak open.
+	ErrStockCountStatus = errors.New("action not allowed for stock count status")
+)
+
+// CreateStockCountRequest membuka sesi hitung. ProductIDs kosong berarti
+// seluruh gudang dihitung.
+type CreateStockCountRequest struct {
+	WarehouseID uint   `json:"warehouse_id" binding:"required"`
+	ProductIDs  []uint `json:"product_ids"`
+	Note        string `json:"note"`
+}
+
+// SubmitCountsRequest berisi hasil hitung; boleh dikirim berkali-kali dan
+// hitungan terakhir untuk satu produk yang dipakai.
+type SubmitCountsRequest struct {
+	Counts []domain.StockCountEntry `json:"counts" binding:"required"`
+}
+
+type StockCountUsecase struct {
+	repo          repository.StockCountRepository
+	warehouseRepo repository.WarehouseRepository
+	productRepo   repository.ProductRepository
+	// cache hanya dipakai untuk membuang availability produk yang berubah; boleh nil
+	cache *redis.Client
+}
+
+func NewStockCountUsecase(
+	repo repository.StockCountRepository,
+	warehouseRepo repository.WarehouseRepository,
+	productRepo repository.ProductRepository,
+	cache *redis.Client,
+) *StockCountUsecase {
+	return &StockCountUsecase{
+		repo:          repo,
+		warehouseRepo: warehouseRepo,
+		productRepo:   productRepo,
+		cache:         cache,
+	}
+}
+
+// Create membuka sesi dan menyimpan snapshot quantity yang diharapkan.
+// Penjualan tidak dibekukan; satu gudang hanya boleh punya satu sesi open.
+func (u *StockCountUsecase) Create(ctx context.Context, actorID uint, req CreateStockCountRequest) (*domain.StockCountSession, error) {
+	if req.WarehouseID == 0 {
+		return nil, fmt.Errorf("%w: warehouse is required", ErrInvalidStockCount)
+	}
+	if _, err := u.warehouseRepo.GetById(ctx, req.WarehouseID); err != nil {
+		return nil, fmt.Errorf("%w: warehouse %d not found", ErrInvalidStockCount, req.WarehouseID)
+	}
+
+	seen := make(map[uint]bool, len(req.ProductIDs))
+	for _, id := range req.ProductIDs {
+		if seen[id] {
+			return nil, fmt.Errorf("%w: product %d listed twice", ErrInvalidStockCount, id)
+		}
+		seen[id] = true
+		if _, err := u.productRepo.FindById(ctx, id); err != nil {
+			return nil, fmt.Errorf("%w: product %d not found", ErrInvalidStockCount, id)
+		}
+	}
+
+	session := &domain.StockCountSession{
+		WarehouseID:   req.WarehouseID,
+		FullWarehouse: len(req.ProductIDs) == 0,
+		CreatedBy:     &actorID,
+	}
+	if req.Note != "" {
+		session.Note = &req.Note
+	}
+	if err := u.repo.Create(ctx, session, req.ProductIDs); err != nil {
+		return nil, err
+	}
+	return u.repo.GetByID(ctx, session.ID)
+}
+
+func (u *StockCountUsecase) Get(ctx context.Context, id uint) (*domain.StockCountSession, error) {
+	return u.repo.GetByID(ctx, id)
+}
+
+func (u *StockCountUsecase) List(ctx context.Context, warehouseID uint, status string) ([]domain.StockCountSession, error) {
+	switch status {
+	case "", domain.StockCountOpen, domain.StockCountApproved, domain.StockCountCancelled:
+	default:
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidStockCount, status)
+	}
+	return u.repo.List(ctx, warehouseID, status)
+}
+
+// SubmitCounts mencatat hasil hitung. Quantity sistem saat itu ikut dicatat
+// supaya selisihnya tidak terpengaruh penjualan selama sesi berjalan.
+func (u *StockCountUsecase) SubmitCounts(ctx context.Context, actorID uint, id uint, req SubmitCountsRequest) (*domain.StockCountSession, error) {
+	if len(req.Counts) == 0 {
+		return nil, fmt.Errorf("%w: at least one count is required", ErrInvalidStockCount)
+	}
+
+	tx, err := u.repo.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	session, err := u.repo.GetByIDForUpdateTx(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status != domain.StockCountOpen {
+		return nil, fmt.Errorf("%w: session is %s", ErrStockCountStatus, session.Status)
+	}
+
+	seen := make(map[uint]bool, len(req.Counts))
+	for _, c := range req.Counts {
+		if c.Counted == nil || *c.Counted < 0 {
+			return nil, fmt.Errorf("%w: counted quantity for product %d must be zero or more", ErrInvalidStockCount, c.ProductID)
+		}
+		if seen[c.ProductID] {
+			return nil, fmt.Errorf("%w: product %d listed twice", ErrInvalidStockCount, c.ProductID)
+		}
+		seen[c.ProductID] = true
+		if _, err := u.productRepo.FindById(ctx, c.ProductID); err != nil {
+			return nil, fmt.Errorf("%w: product %d not found", ErrInvalidStockCount, c.ProductID)
+		}
+		if err := u.repo.RecordCountTx(ctx, tx, session, c.ProductID, *c.Counted, actorID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit stock counts: %w", err)
+	}
+	return u.repo.GetByID(ctx, id)
+}
+
+// Approve memposting selisih setiap line yang sudah dihitung sebagai
+// adjustment, semua dalam satu transaksi. Line yang belum dihitung dilewati.
+func (u *StockCountUsecase) Approve(ctx context.Context, actorID uint, id uint) (*domain.StockCountSession, error) {
+	tx, err := u.repo.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	session, err := u.repo.GetByIDForUpdateTx(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status != domain.StockCountOpen {
+		return nil, fmt.Errorf("%w: session is %s", ErrStockCountStatus, session.Status)
+	}
+
+	ref := domain.StockReference{
+		Reason:        domain.MovementReasonAdjustment,
+		ReferenceType: domain.MovementRefStockCount,
+		ReferenceID:   session.ID,
+		ActorID:       actorID,
+		Note:          fmt.Sprintf("stock count #%d", session.ID),
+	}
+	var changed []uint
+	for i := range session.Lines {
+		line := &session.Lines[i]
+		if line.Variance == nil {
+			continue
+		}
+		if _, err := u.repo.PostAdjustmentTx(ctx, tx, session, line, ref); err != nil {
+			return nil, fmt.Errorf("failed to adjust product %d: %w", line.ProductID, err)
+		}
+		if *line.Adjustment != 0 {
+			changed = append(changed, line.ProductID)
+		}
+	}
+
+	if err := u.repo.CloseTx(ctx, tx, id, domain.StockCountApproved, actorID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit stock count approval: %w", err)
+	}
+	invalidateAvailabilityCache(ctx, u.cache, changed...)
+	return u.repo.GetByID(ctx, id)
+}
+
+// Cancel menutup sesi tanpa mengubah stok.
+func (u *StockCountUsecase) Cancel(ctx context.Context, actorID uint, id uint) (*domain.StockCountSession, error) {
+	tx, err := u.repo.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	session, err := u.repo.GetByIDForUpdateTx(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status != domain.StockCountOpen {
+		return nil, fmt.Errorf("%w: session is %s", ErrStockCountStatus, session.Status)
+	}
+	if err := u.repo.CloseTx(ctx, tx, id, domain.StockCountCancelled, actorID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit stock count cancellation: %w", err)
+	}
+	return u.repo.GetByID(ctx, id)
+}
@@ -0,0 +1,120 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/repository"
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrInvalidStockLot dikembalikan untuk input lot yang tidak valid.
+var ErrInvalidStockLot = errors.New("invalid stock lot")
+
+// Batas hari laporan lot yang mendekati kedaluwarsa.
+const (
+	defaultExpiryWindowDays = 30
+	maxExpiryWindowDays     = 365
+)
+
+// ReceiveLotRequest menerima stok ke satu batch. ExpiryDate berformat
+// YYYY-MM-DD dan boleh kosong untuk barang yang tidak kedaluwarsa.
+type ReceiveLotRequest struct {
+	WarehouseID uint    `json:"warehouse_id" binding:"required"`
+	ProductID   uint    `json:"product_id" binding:"required"`
+	BatchNumber string  `json:"batch_number" binding:"required"`
+	ExpiryDate  *string `json:"expiry_date"`
+	Quantity    int32   `json:"quantity" binding:"required"`
+	Note        string  `json:"note"`
+}
+
+// StockLotUsecase mengelola lot (batch) stok dan tanggal kedaluwarsanya.
+// Pengambilan FEFO sendiri terjadi di repository setiap kali stok berkurang.
+type StockLotUsecase struct {
+	repo          repository.StockLotRepository
+	warehouseRepo repository.WarehouseRepository
+	productRepo   repository.ProductRepository
+	// cache hanya dipakai untuk membuang availability produk yang berubah; boleh nil
+	cache *redis.Client
+}
+
+func NewStockLotUsecase(
+	repo repository.StockLotRepository,
+	warehouseRepo repository.WarehouseRepository,
+	productRepo repository.ProductRepository,
+	cache *redis.Client,
+) *StockLotUsecase {
+	return &StockLotUsecase{
+		repo:          repo,
+		warehouseRepo: warehouseRepo,
+		productRepo:   productRepo,
+		cache:         cache,
+	}
+}
+
+// Receive menambah stok ke batch yang diminta. Batch yang sudah ada hanya
+// boleh ditambah dengan tanggal kedaluwarsa yang sama.
+func (u *StockLotUsecase) Receive(ctx context.Context, actorID uint, req ReceiveLotRequest) (*domain.StockLot, *domain.StockChangeResult, error) {
+	lot := &domain.StockLot{
+		WarehouseID: req.WarehouseID,
+		ProductID:   req.ProductID,
+		BatchNumber: strings.TrimSpace(req.BatchNumber),
+		Quantity:    req.Quantity,
+	}
+	if lot.BatchNumber == "" {
+		return nil, nil, fmt.Errorf("%w: batch_number is required", ErrInvalidStockLot)
+	}
+	if lot.Quantity <= 0 {
+		return nil, nil, fmt.Errorf("%w: quantity must be greater than zero", ErrInvalidStockLot)
+	}
+	if req.ExpiryDate != nil && *req.ExpiryDate != "" {
+		expiry, err := time.Parse(time.DateOnly, *req.ExpiryDate)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: expiry_date must be YYYY-MM-DD", ErrInvalidStockLot)
+		}
+		lot.ExpiryDate = &expiry
+	}
+	if _, err := u.warehouseRepo.GetById(ctx, lot.WarehouseID); err != nil {
+		return nil, nil, fmt.Errorf("%w: warehouse %d not found", ErrInvalidStockLot, lot.WarehouseID)
+	}
+	if _, err := u.productRepo.FindById(ctx, lot.ProductID); err != nil {
+		return nil, nil, fmt.Errorf("%w: product %d not found", ErrInvalidStockLot, lot.ProductID)
+	}
+
+	ref := domain.StockReference{ActorID: actorID, Note: req.Note}
+	result, err := u.repo.Receive(ctx, lot, ref)
+	if err != nil {
+		return nil, nil, err
+	}
+	invalidateAvailabilityCache(ctx, u.cache, lot.ProductID)
+	return lot, result, nil
+}
+
+// ListLots mengembalikan lot yang masih berisi, urut FEFO.
+func (u *StockLotUsecase) ListLots(ctx context.Context, warehouseID, productID uint) ([]domain.StockLot, error) {
+	lots, err := u.repo.List(ctx, warehouseID, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stock lots: %w", err)
+	}
+	return lots, nil
+}
+
+// ExpiringLots adalah laporan lot yang kedaluwarsa dalam days hari ke depan,
+// termasuk yang sudah lewat. days 0 memakai 30 hari.
+func (u *StockLotUsecase) ExpiringLots(ctx context.Context, days int, warehouseID uint) ([]domain.ExpiringLot, error) {
+	if days == 0 {
+		days = defaultExpiryWindowDays
+	}
+	if days < 0 || days > maxExpiryWindowDays {
+		return nil, fmt.Errorf("%w: days must be between 1 and %d", ErrInvalidStockLot, maxExpiryWindowDays)
+	}
+	lots, err := u.repo.ListExpiring(ctx, days, warehouseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expiring lots: %w", err)
+	}
+	return lots, nil
+}
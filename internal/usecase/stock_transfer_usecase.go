@@ -0,0 +1,350 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/repository"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	// ErrInvalidTransfer dikembalikan untuk input transfer yang tidak valid.
+	ErrInvalidTransfer = errors.New("invalid stock transfer")
+	// ErrTransferStatus dikembalikan kalau aksi tidak boleh untuk status transfer saat ini.
+	ErrTransferStatus = errors.New("action not allowed for transfer status")
+)
+
+// TransferLineRequest adalah satu produk yang akan dipindahkan.
+type TransferLineRequest struct {
+	ProductID uint  `json:"product_id" binding:"required"`
+	Quantity  int32 `json:"quantity" binding:"required"`
+}
+
+type CreateTransferRequest struct {
+	SourceWarehouseID      uint                  `json:"source_warehouse_id" binding:"required"`
+	DestinationWarehouseID uint                  `json:"destination_warehouse_id" binding:"required"`
+	Note                   string                `json:"note"`
+	Lines                  []TransferLineRequest `json:"lines" binding:"required"`
+}
+
+// ReceiveLineRequest mencatat penerimaan satu line. ReceivedQuantity masuk ke
+// stok gudang tujuan; DiscrepancyQuantity (hilang/rusak) hanya dicatat.
+type ReceiveLineRequest struct {
+	LineID              uint   `json:"line_id" binding:"required"`
+	ReceivedQuantity    int32  `json:"received_quantity"`
+	DiscrepancyQuantity int32  `json:"discrepancy_quantity"`
+	DiscrepancyNote     string `json:"discrepancy_note"`
+}
+
+type ReceiveTransferRequest struct {
+	Lines []ReceiveLineRequest `json:"lines" binding:"required"`
+}
+
+type StockTransferUsecase struct {
+	repo               repository.StockTransferRepository
+	warehouseRepo      repository.WarehouseRepository
+	productRepo        repository.ProductRepository
+	warehouseStockRepo repository.WarehouseStockRepository
+	stockLotRepo       repository.StockLotRepository
+	// cache hanya dipakai untuk membuang availability produk yang berubah; boleh nil
+	cache *redis.Client
+}
+
+func NewStockTransferUsecase(
+	repo repository.StockTransferRepository,
+	warehouseRepo repository.WarehouseRepository,
+	productRepo repository.ProductRepository,
+	warehouseStockRepo repository.WarehouseStockRepository,
+	stockLotRepo repository.StockLotRepository,
+	cache *redis.Client,
+) *StockTransferUsecase {
+	return &StockTransferUsecase{
+		repo:               repo,
+		warehouseRepo:      warehouseRepo,
+		productRepo:        productRepo,
+		warehouseStockRepo: warehouseStockRepo,
+		stockLotRepo:       stockLotRepo,
+		cache:              cache,
+	}
+}
+
+// Create membuat transfer berstatus draft. Stok belum bergerak sampai Dispatch.
+func (u *StockTransferUsecase) Create(ctx context.Context, actorID uint, req CreateTransferRequest) (*domain.StockTransfer, error) {
+	if req.SourceWarehouseID == 0 || req.DestinationWarehouseID == 0 {
+		return nil, fmt.Errorf("%w: source and destination warehouse are required", ErrInvalidTransfer)
+	}
+	if req.SourceWarehouseID == req.DestinationWarehouseID {
+		return nil, fmt.Errorf("%w: source and destination must differ", ErrInvalidTransfer)
+	}
+	if len(req.Lines) == 0 {
+		return nil, fmt.Errorf("%w: at least one line is required", ErrInvalidTransfer)
+	}
+	for _, id := range []uint{req.SourceWarehouseID, req.DestinationWarehouseID} {
+		if _, err := u.warehouseRepo.GetById(ctx, id); err != nil {
+			return nil, fmt.Errorf("%w: warehouse %d not found", ErrInvalidTransfer, id)
+		}
+	}
+
+	transfer := &domain.StockTransfer{
+		SourceWarehouseID:      req.SourceWarehouseID,
+		DestinationWarehouseID: req.DestinationWarehouseID,
+		CreatedBy:              &actorID,
+		Lines:                  make([]domain.StockTransferLine, 0, len(req.Lines)),
+	}
+	if req.Note != "" {
+		transfer.Note = &req.Note
+	}
+
+	seen := make(map[uint]bool, len(req.Lines))
+	for _, l := range req.Lines {
+		if l.Quantity <= 0 {
+			return nil, fmt.Errorf("%w: quantity for product %d must be greater than zero", ErrInvalidTransfer, l.ProductID)
+		}
+		if seen[l.ProductID] {
+			return nil, fmt.Errorf("%w: product %d listed twice", ErrInvalidTransfer, l.ProductID)
+		}
+		seen[l.ProductID] = true
+		if _, err := u.productRepo.FindById(ctx, l.ProductID); err != nil {
+			return nil, fmt.Errorf("%w: product %d not found", ErrInvalidTransfer, l.ProductID)
+		}
+		transfer.Lines = append(transfer.Lines, domain.StockTransferLine{ProductID: l.ProductID, Quantity: l.Quantity})
+	}
+
+	if err := u.repo.Create(ctx, transfer); err != nil {
+		return nil, err
+	}
+	// draft mengurangi stok available gudang asal
+	u.invalidateAvailability(ctx, transfer)
+	return transfer, nil
+}
+
+func (u *StockTransferUsecase) Get(ctx context.Context, id uint) (*domain.StockTransfer, error) {
+	return u.repo.GetByID(ctx, id)
+}
+
+func (u *StockTransferUsecase) List(ctx context.Context, warehouseID uint, status string) ([]domain.StockTransfer, error) {
+	if status != "" && !validTransferStatus(status) {
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidTransfer, status)
+	}
+	return u.repo.List(ctx, warehouseID, status)
+}
+
+// InTransit mengembalikan stok yang sedang dalam perjalanan ke gudang tujuan.
+func (u *StockTransferUsecase) InTransit(ctx context.Context, destinationID uint) ([]domain.InTransitStock, error) {
+	return u.repo.InTransit(ctx, destinationID)
+}
+
+// Delete hanya untuk draft; transfer yang sudah dikirim harus diterima.
+func (u *StockTransferUsecase) Delete(ctx context.Context, id uint) error {
+	transfer, err := u.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if transfer.Status != domain.TransferStatusDraft {
+		return fmt.Errorf("%w: only draft transfers can be deleted", ErrTransferStatus)
+	}
+	if err := u.repo.DeleteDraft(ctx, id); err != nil {
+		return err
+	}
+	u.invalidateAvailability(ctx, transfer)
+	return nil
+}
+
+// Dispatch mengurangi stok gudang asal untuk semua line dalam satu transaksi.
+// Kalau satu line kurang stok, tidak ada yang berubah. Lot yang terambil
+// dicatat di line supaya bisa diterima kembali ke batch yang sama.
+func (u *StockTransferUsecase) Dispatch(ctx context.Context, actorID uint, id uint) (*domain.StockTransfer, error) {
+	tx, err := u.repo.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	transfer, err := u.repo.GetByIDForUpdateTx(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if transfer.Status != domain.TransferStatusDraft {
+		return nil, fmt.Errorf("%w: transfer is %s", ErrTransferStatus, transfer.Status)
+	}
+
+	for _, line := range transfer.Lines {
+		result, err := u.warehouseStockRepo.ApplyChangeTx(ctx, tx, domain.StockChange{
+			WarehouseID:    transfer.SourceWarehouseID,
+			ProductID:      line.ProductID,
+			Delta:          -line.Quantity,
+			StockReference: u.transferRef(actorID, transfer.ID),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to dispatch product %d: %w", line.ProductID, err)
+		}
+		if err := u.repo.RecordLineLotsTx(ctx, tx, line.ID, result.Lots); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := u.repo.UpdateStatusTx(ctx, tx, id, domain.TransferStatusDispatched); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit dispatch: %w", err)
+	}
+	u.invalidateAvailability(ctx, transfer)
+	return u.repo.GetByID(ctx, id)
+}
+
+// MarkInTransit menandai transfer yang sudah dikirim sebagai dalam perjalanan.
+func (u *StockTransferUsecase) MarkInTransit(ctx context.Context, id uint) (*domain.StockTransfer, error) {
+	tx, err := u.repo.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	transfer, err := u.repo.GetByIDForUpdateTx(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if transfer.Status != domain.TransferStatusDispatched {
+		return nil, fmt.Errorf("%w: transfer is %s", ErrTransferStatus, transfer.Status)
+	}
+	if err := u.repo.UpdateStatusTx(ctx, tx, id, domain.TransferStatusInTransit); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transfer status: %w", err)
+	}
+	return u.repo.GetByID(ctx, id)
+}
+
+// Receive menambah stok gudang tujuan untuk quantity yang diterima dan
+// mencatat selisih. Penerimaan boleh sebagian; transfer menjadi received
+// setelah tidak ada quantity yang masih dalam perjalanan. Quantity yang
+// diterima masuk lebih dulu ke lot yang dibawa line (expiry paling awal
+// dulu); sisanya stok tanpa lot, seperti saat diambil dari gudang asal.
+func (u *StockTransferUsecase) Receive(ctx context.Context, actorID uint, id uint, req ReceiveTransferRequest) (*domain.StockTransfer, error) {
+	if len(req.Lines) == 0 {
+		return nil, fmt.Errorf("%w: at least one line is required", ErrInvalidTransfer)
+	}
+
+	tx, err := u.repo.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	transfer, err := u.repo.GetByIDForUpdateTx(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if transfer.Status != domain.TransferStatusDispatched && transfer.Status != domain.TransferStatusInTransit {
+		return nil, fmt.Errorf("%w: transfer is %s", ErrTransferStatus, transfer.Status)
+	}
+	// gudang dikunci sebelum baris stok mana pun, lihat LockCapacityTx
+	if err := u.warehouseStockRepo.LockCapacityTx(ctx, tx, []uint{transfer.DestinationWarehouseID}); err != nil {
+		return nil, err
+	}
+
+	lines := make(map[uint]*domain.StockTransferLine, len(transfer.Lines))
+	for i := range transfer.Lines {
+		lines[transfer.Lines[i].ID] = &transfer.Lines[i]
+	}
+
+	for _, r := range req.Lines {
+		line, ok := lines[r.LineID]
+		if !ok {
+			return nil, fmt.Errorf("%w: line %d", repository.ErrTransferLineNotFound, r.LineID)
+		}
+		if r.ReceivedQuantity < 0 || r.DiscrepancyQuantity < 0 || r.ReceivedQuantity+r.DiscrepancyQuantity == 0 {
+			return nil, fmt.Errorf("%w: line %d needs a positive received or discrepancy quantity", ErrInvalidTransfer, r.LineID)
+		}
+		if r.ReceivedQuantity+r.DiscrepancyQuantity > line.Outstanding() {
+			return nil, fmt.Errorf("%w: line %d has only %d outstanding", ErrInvalidTransfer, r.LineID, line.Outstanding())
+		}
+
+		if r.ReceivedQuantity > 0 {
+			if err := u.warehouseStockRepo.EnsureStockRowTx(ctx, tx, transfer.DestinationWarehouseID, line.ProductID); err != nil {
+				return nil, err
+			}
+			_, err := u.warehouseStockRepo.ApplyChangeTx(ctx, tx, domain.StockChange{
+				WarehouseID:    transfer.DestinationWarehouseID,
+				ProductID:      line.ProductID,
+				Delta:          r.ReceivedQuantity,
+				StockReference: u.transferRef(actorID, transfer.ID),
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to receive product %d: %w", line.ProductID, err)
+			}
+			lots, err := u.repo.ReceiveLineLotsTx(ctx, tx, line.ID, r.ReceivedQuantity)
+			if err != nil {
+				return nil, err
+			}
+			for i := range lots {
+				lots[i].WarehouseID = transfer.DestinationWarehouseID
+				lots[i].ProductID = line.ProductID
+				if err := u.stockLotRepo.ReceiveTx(ctx, tx, &lots[i]); err != nil {
+					return nil, fmt.Errorf("failed to receive product %d: %w", line.ProductID, err)
+				}
+			}
+		}
+
+		var note *string
+		if r.DiscrepancyNote != "" {
+			note = &r.DiscrepancyNote
+		}
+		if err := u.repo.RecordReceiptTx(ctx, tx, line.ID, r.ReceivedQuantity, r.DiscrepancyQuantity, note); err != nil {
+			return nil, err
+		}
+		line.ReceivedQuantity += r.ReceivedQuantity
+		line.DiscrepancyQuantity += r.DiscrepancyQuantity
+	}
+
+	status := domain.TransferStatusReceived
+	for _, line := range transfer.Lines {
+		if line.Outstanding() > 0 {
+			status = domain.TransferStatusInTransit
+			break
+		}
+	}
+	if status != transfer.Status {
+		if err := u.repo.UpdateStatusTx(ctx, tx, id, status); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit receipt: %w", err)
+	}
+	u.invalidateAvailability(ctx, transfer)
+	return u.repo.GetByID(ctx, id)
+}
+
+// invalidateAvailability membuang availability cache semua produk di transfer.
+func (u *StockTransferUsecase) invalidateAvailability(ctx context.Context, transfer *domain.StockTransfer) {
+	productIDs := make([]uint, len(transfer.Lines))
+	for i, line := range transfer.Lines {
+		productIDs[i] = line.ProductID
+	}
+	invalidateAvailabilityCache(ctx, u.cache, productIDs...)
+}
+
+func (u *StockTransferUsecase) transferRef(actorID, transferID uint) domain.StockReference {
+	return domain.StockReference{
+		Reason:        domain.MovementReasonTransfer,
+		ReferenceType: domain.MovementRefTransfer,
+		ReferenceID:   transferID,
+		ActorID:       actorID,
+	}
+}
+
+func validTransferStatus(status string) bool {
+	switch status {
+	case domain.TransferStatusDraft, domain.TransferStatusDispatched,
+		domain.TransferStatusInTransit, domain.TransferStatusReceived:
+		return true
+	}
+	return false
+}
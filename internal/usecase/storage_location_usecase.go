@@ -0,0 +1,104 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/repository"
+)
+
+// ErrInvalidLocation dikembalikan untuk input lokasi yang tidak valid.
+var ErrInvalidLocation = errors.New("invalid storage location")
+
+// CreateLocationRequest membuat satu lokasi. Zone wajib; aisle, shelf dan bin
+// boleh kosong untuk lokasi yang lebih kasar.
+type CreateLocationRequest struct {
+	WarehouseID uint   `json:"warehouse_id" binding:"required"`
+	Zone        string `json:"zone" binding:"required"`
+	Aisle       string `json:"aisle"`
+	Shelf       string `json:"shelf"`
+	Bin         string `json:"bin"`
+}
+
+// StorageLocationUsecase mengelola lokasi (zone/aisle/shelf/bin) di gudang
+// dan perpindahan stok antar lokasi. Quantity gudang tidak berubah di sini;
+// pengambilan dari lokasi saat stok berkurang terjadi di repository.
+type StorageLocationUsecase struct {
+	repo          repository.StorageLocationRepository
+	warehouseRepo repository.WarehouseRepository
+	productRepo   repository.ProductRepository
+}
+
+func NewStorageLocationUsecase(
+	repo repository.StorageLocationRepository,
+	warehouseRepo repository.WarehouseRepository,
+	productRepo repository.ProductRepository,
+) *StorageLocationUsecase {
+	return &StorageLocationUsecase{
+		repo:          repo,
+		warehouseRepo: warehouseRepo,
+		productRepo:   productRepo,
+	}
+}
+
+func (u *StorageLocationUsecase) Create(ctx context.Context, req CreateLocationRequest) (*domain.StorageLocation, error) {
+	location := &domain.StorageLocation{
+		WarehouseID: req.WarehouseID,
+		Zone:        strings.TrimSpace(req.Zone),
+		Aisle:       strings.TrimSpace(req.Aisle),
+		Shelf:       strings.TrimSpace(req.Shelf),
+		Bin:         strings.TrimSpace(req.Bin),
+	}
+	if location.Zone == "" {
+		return nil, fmt.Errorf("%w: zone is required", ErrInvalidLocation)
+	}
+	for _, part := range []string{location.Zone, location.Aisle, location.Shelf, location.Bin} {
+		if len(part) > 20 {
+			return nil, fmt.Errorf("%w: %q is longer than 20 characters", ErrInvalidLocation, part)
+		}
+	}
+	if _, err := u.warehouseRepo.GetById(ctx, req.WarehouseID); err != nil {
+		return nil, fmt.Errorf("%w: warehouse %d not found", ErrInvalidLocation, req.WarehouseID)
+	}
+
+	if err := u.repo.Create(ctx, location); err != nil {
+		return nil, err
+	}
+	return location, nil
+}
+
+func (u *StorageLocationUsecase) List(ctx context.Context, warehouseID uint) ([]domain.StorageLocation, error) {
+	if warehouseID == 0 {
+		return nil, fmt.Errorf("%w: warehouse_id is required", ErrInvalidLocation)
+	}
+	return u.repo.List(ctx, warehouseID)
+}
+
+// Delete hanya untuk lokasi yang sudah kosong.
+func (u *StorageLocationUsecase) Delete(ctx context.Context, id uint) error {
+	return u.repo.Delete(ctx, id)
+}
+
+func (u *StorageLocationUsecase) Stock(ctx context.Context, warehouseID, productID, locationID uint) ([]domain.LocationStock, error) {
+	if warehouseID == 0 {
+		return nil, fmt.Errorf("%w: warehouse_id is required", ErrInvalidLocation)
+	}
+	return u.repo.ListStock(ctx, warehouseID, productID, locationID)
+}
+
+// Move memindahkan stok antar bin, atau put-away dari stok unassigned.
+func (u *StorageLocationUsecase) Move(ctx context.Context, move domain.LocationMove) ([]domain.LocationStock, error) {
+	if move.Quantity <= 0 {
+		return nil, fmt.Errorf("%w: quantity must be greater than zero", ErrInvalidLocation)
+	}
+	if move.FromLocationID == move.ToLocationID {
+		return nil, fmt.Errorf("%w: source and destination location must differ", ErrInvalidLocation)
+	}
+	if _, err := u.productRepo.FindById(ctx, move.ProductID); err != nil {
+		return nil, fmt.Errorf("%w: product %d not found", ErrInvalidLocation, move.ProductID)
+	}
+	return u.repo.Move(ctx, move)
+}
@@ -0,0 +1,57 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/repository"
+)
+
+// ErrInvalidSupplier dikembalikan untuk data supplier yang tidak valid.
+var ErrInvalidSupplier = errors.New("invalid supplier")
+
+type SupplierUsecase struct {
+	repo repository.SupplierRepository
+}
+
+func NewSupplierUsecase(repo repository.SupplierRepository) *SupplierUsecase {
+	return &SupplierUsecase{repo: repo}
+}
+
+func (u *SupplierUsecase) Create(ctx context.Context, supplier *domain.Supplier) error {
+	if err := validateSupplier(supplier); err != nil {
+		return err
+	}
+	return u.repo.Create(ctx, supplier)
+}
+
+func (u *SupplierUsecase) Get(ctx context.Context, id uint) (*domain.Supplier, error) {
+	return u.repo.GetByID(ctx, id)
+}
+
+func (u *SupplierUsecase) List(ctx context.Context) ([]domain.Supplier, error) {
+	return u.repo.GetAll(ctx)
+}
+
+func (u *SupplierUsecase) Update(ctx context.Context, supplier *domain.Supplier) error {
+	if err := validateSupplier(supplier); err != nil {
+		return err
+	}
+	return u.repo.Update(ctx, supplier)
+}
+
+// Delete gagal dengan ErrSupplierInUse kalau supplier sudah punya purchase order.
+func (u *SupplierUsecase) Delete(ctx context.Context, id uint) error {
+	return u.repo.Delete(ctx, id)
+}
+
+func validateSupplier(supplier *domain.Supplier) error {
+	supplier.Name = strings.TrimSpace(supplier.Name)
+	if supplier.Name == "" {
+		return fmt.Errorf("%w: name cannot be empty", ErrInvalidSupplier)
+	}
+	return nil
+}
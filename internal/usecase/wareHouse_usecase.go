@@ -3,6 +3,7 @@ package usecase
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
 	"github.com/ifs21014-itdel/concurrent-order-processor/internal/repository"
@@ -23,6 +24,15 @@ func (u *WarehouseUsecase) CreateWarehouse(ctx context.Context, warehouse *domai
 	if warehouse.Location == "" {
 		return errors.New("warehouse location cannot be empty")
 	}
+	if err := validateShippingRates(warehouse); err != nil {
+		return err
+	}
+	if err := validateCoordinates(warehouse); err != nil {
+		return err
+	}
+	if err := validateCapacity(warehouse); err != nil {
+		return err
+	}
 	return u.repo.Create(ctx, warehouse)
 }
 
@@ -30,6 +40,15 @@ func (u *WarehouseUsecase) UpdateWarehouse(ctx context.Context, warehouse *domai
 	if warehouse.ID == 0 {
 		return errors.New("warehouse ID is required")
 	}
+	if err := validateShippingRates(warehouse); err != nil {
+		return err
+	}
+	if err := validateCoordinates(warehouse); err != nil {
+		return err
+	}
+	if err := validateCapacity(warehouse); err != nil {
+		return err
+	}
 	return u.repo.Update(ctx, warehouse)
 }
 
@@ -47,3 +66,39 @@ func (u *WarehouseUsecase) GetAllWarehouses(ctx context.Context) ([]domain.Wareh
 func (u *WarehouseUsecase) GetWareHouseById(ctx context.Context, id uint) (*domain.Warehouse, error) {
 	return u.repo.GetById(ctx, id)
 }
+
+func validateShippingRates(warehouse *domain.Warehouse) error {
+	if warehouse.ShippingBaseCost < 0 || warehouse.ShippingCostPerKg < 0 {
+		return errors.New("shipping rates cannot be negative")
+	}
+	return nil
+}
+
+// validateCoordinates memastikan lintang dan bujur diisi berpasangan dan ada
+// di rentang yang sah. Gudang tanpa koordinat tetap boleh, tapi tidak ikut
+// routing jarak.
+func validateCoordinates(warehouse *domain.Warehouse) error {
+	if (warehouse.Latitude == nil) != (warehouse.Longitude == nil) {
+		return errors.New("latitude and longitude must be set together")
+	}
+	if point, ok := warehouse.Point(); ok && !point.Valid() {
+		return errors.New("latitude must be within [-90, 90] and longitude within [-180, 180]")
+	}
+	return nil
+}
+
+// validateCapacity memastikan kapasitas, jika diisi, lebih dari nol. Policy
+// kosong berarti reject.
+func validateCapacity(warehouse *domain.Warehouse) error {
+	if (warehouse.MaxWeightKg != nil && *warehouse.MaxWeightKg <= 0) || (warehouse.MaxVolumeM3 != nil && *warehouse.MaxVolumeM3 <= 0) {
+		return errors.New("capacity must be greater than zero, or null for no limit")
+	}
+	switch warehouse.CapacityPolicy {
+	case "":
+		warehouse.CapacityPolicy = domain.CapacityPolicyReject
+	case domain.CapacityPolicyReject, domain.CapacityPolicyWarn:
+	default:
+		return fmt.Errorf("capacity_policy must be %q or %q", domain.CapacityPolicyReject, domain.CapacityPolicyWarn)
+	}
+	return nil
+}
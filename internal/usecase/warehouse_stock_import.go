@@ -0,0 +1,502 @@
+package usecase
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/repository"
+)
+
+// Format file untuk import dan export stok.
+const (
+	StockFormatCSV    = "csv"
+	StockFormatNDJSON = "ndjson"
+)
+
+// Status per baris di laporan import.
+const (
+	StockImportCreated   = "created"
+	StockImportUpdated   = "updated"
+	StockImportUnchanged = "unchanged"
+	StockImportFailed    = "error"
+)
+
+const (
+	defaultStockImportChunkSize = 500
+	maxStockImportChunkSize     = 5000
+	maxNDJSONLineBytes          = 64 * 1024
+)
+
+// ErrInvalidStockImport dikembalikan kalau file tidak bisa dibaca sama sekali
+// (format tidak dikenal, header kurang). Kesalahan per baris masuk laporan.
+var ErrInvalidStockImport = errors.New("invalid stock import")
+
+// StockImportRow adalah satu baris file import: hasil hitung stok satu produk
+// di satu gudang. Produk dirujuk lewat ProductID atau SKU; kalau keduanya
+// diisi harus menunjuk produk yang sama.
+type StockImportRow struct {
+	Line        int
+	WarehouseID uint
+	ProductID   uint
+	SKU         string
+	Quantity    *int32
+	// ParseError diisi kalau baris tidak bisa dibaca
+	ParseError string
+}
+
+// StockImportOptions mengatur ImportStock. Tanpa Apply hanya diff yang dihitung.
+type StockImportOptions struct {
+	Apply     bool
+	ChunkSize int
+	Note      string
+}
+
+// StockImportRowResult adalah hasil satu baris import. CurrentQuantity nil
+// berarti baris stok belum ada dan akan dibuat.
+type StockImportRowResult struct {
+	Line            int    `json:"line"`
+	WarehouseID     uint   `json:"warehouse_id,omitempty"`
+	ProductID       uint   `json:"product_id,omitempty"`
+	SKU             string `json:"sku,omitempty"`
+	CurrentQuantity *int32 `json:"current_quantity,omitempty"`
+	Quantity        *int32 `json:"quantity,omitempty"`
+	Delta           int32  `json:"delta"`
+	Status          string `json:"status"`
+	Error           string `json:"error,omitempty"`
+}
+
+// StockImportReport merangkum import. Applied false berarti dry run.
+type StockImportReport struct {
+	Applied   bool                   `json:"applied"`
+	Total     int                    `json:"total"`
+	Created   int                    `json:"created"`
+	Updated   int                    `json:"updated"`
+	Unchanged int                    `json:"unchanged"`
+	Failed    int                    `json:"failed"`
+	Rows      []StockImportRowResult `json:"rows"`
+}
+
+// ParseStockImport membaca file CSV (dengan header) atau NDJSON. Baris yang
+// rusak tetap dikembalikan dengan ParseError supaya muncul di laporan.
+func ParseStockImport(r io.Reader, format string) ([]StockImportRow, error) {
+	switch format {
+	case StockFormatCSV:
+		return parseStockCSV(r)
+	case StockFormatNDJSON:
+		return parseStockNDJSON(r)
+	default:
+		return nil, fmt.Errorf("%w: unsupported format %q", ErrInvalidStockImport, format)
+	}
+}
+
+func parseStockCSV(r io.Reader) ([]StockImportRow, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: file is empty", ErrInvalidStockImport)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read header: %w", ErrInvalidStockImport, err)
+	}
+	cols := make(map[string]int, len(header))
+	for i, h := range header {
+		// spreadsheet sering menulis BOM di awal file
+		h = strings.TrimPrefix(h, "\ufeff")
+		cols[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	_, hasWarehouse := cols["warehouse_id"]
+	_, hasProduct := cols["product_id"]
+	_, hasSKU := cols["sku"]
+	_, hasQuantity := cols["quantity"]
+	if !hasWarehouse || !(hasProduct || hasSKU) || !hasQuantity {
+		return nil, fmt.Errorf("%w: header needs warehouse_id, product_id or sku, and quantity", ErrInvalidStockImport)
+	}
+
+	var rows []StockImportRow
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				rows = append(rows, StockImportRow{Line: parseErr.StartLine, ParseError: parseErr.Err.Error()})
+				continue
+			}
+			return nil, fmt.Errorf("failed to read csv: %w", err)
+		}
+
+		line, _ := cr.FieldPos(0)
+		field := func(name string) string {
+			i, ok := cols[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		rows = append(rows, parseCSVStockRow(line, field("warehouse_id"), field("product_id"), field("sku"), field("quantity")))
+	}
+	return rows, nil
+}
+
+func parseCSVStockRow(line int, warehouseID, productID, sku, quantity string) StockImportRow {
+	row := StockImportRow{Line: line, SKU: sku}
+
+	id, err := strconv.ParseUint(warehouseID, 10, 32)
+	if err != nil {
+		row.ParseError = "warehouse_id must be a positive integer"
+		return row
+	}
+	row.WarehouseID = uint(id)
+
+	if productID != "" {
+		id, err := strconv.ParseUint(productID, 10, 32)
+		if err != nil {
+			row.ParseError = "product_id must be a positive integer"
+			return row
+		}
+		row.ProductID = uint(id)
+	}
+
+	if quantity != "" {
+		qty, err := strconv.ParseInt(quantity, 10, 32)
+		if err != nil {
+			row.ParseError = "quantity must be an integer"
+			return row
+		}
+		q := int32(qty)
+		row.Quantity = &q
+	}
+	return row
+}
+
+func parseStockNDJSON(r io.Reader) ([]StockImportRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), maxNDJSONLineBytes)
+
+	var rows []StockImportRow
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var in struct {
+			WarehouseID uint   `json:"warehouse_id"`
+			ProductID   uint   `json:"product_id"`
+			SKU         string `json:"sku"`
+			Quantity    *int32 `json:"quantity"`
+		}
+		row := StockImportRow{Line: line}
+		if err := json.Unmarshal([]byte(text), &in); err != nil {
+			row.ParseError = "invalid JSON: " + err.Error()
+		} else {
+			row.WarehouseID = in.WarehouseID
+			row.ProductID = in.ProductID
+			row.SKU = strings.TrimSpace(in.SKU)
+			row.Quantity = in.Quantity
+		}
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read ndjson: %w", err)
+	}
+	return rows, nil
+}
+
+// checkStockImportRow memeriksa isi baris tanpa menyentuh database.
+func checkStockImportRow(row StockImportRow) string {
+	switch {
+	case row.ParseError != "":
+		return row.ParseError
+	case row.WarehouseID == 0:
+		return "warehouse_id is required"
+	case row.ProductID == 0 && row.SKU == "":
+		return "product_id or sku is required"
+	case row.Quantity == nil:
+		return "quantity is required"
+	case *row.Quantity < 0:
+		return "quantity cannot be negative"
+	}
+	return ""
+}
+
+// stockImportItem adalah baris valid yang siap diterapkan.
+type stockImportItem struct {
+	index    int
+	key      domain.StockKey
+	quantity int32
+}
+
+// ImportStock memvalidasi setiap baris lalu menetapkan quantity hasil hitung.
+// Baris yang valid diterapkan per chunk, masing-masing dalam satu transaksi;
+// kalau satu baris gagal, seluruh chunk-nya dibatalkan dan dilaporkan gagal.
+// Tanpa opts.Apply tidak ada yang ditulis (dry run).
+func (u *WarehouseStockUsecase) ImportStock(ctx context.Context, actorID uint, rows []StockImportRow, opts StockImportOptions) (*StockImportReport, error) {
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = defaultStockImportChunkSize
+	}
+	if opts.ChunkSize > maxStockImportChunkSize {
+		opts.ChunkSize = maxStockImportChunkSize
+	}
+
+	results := make([]StockImportRowResult, len(rows))
+	items, err := u.resolveStockImport(ctx, rows, results)
+	if err != nil {
+		return nil, err
+	}
+
+	// urutan kunci yang sama di setiap chunk supaya lock tidak saling menunggu
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].key.WarehouseID != items[j].key.WarehouseID {
+			return items[i].key.WarehouseID < items[j].key.WarehouseID
+		}
+		return items[i].key.ProductID < items[j].key.ProductID
+	})
+
+	for start := 0; start < len(items); start += opts.ChunkSize {
+		end := min(start+opts.ChunkSize, len(items))
+		chunk := items[start:end]
+		if err := u.importStockChunk(ctx, actorID, chunk, results, opts); err != nil {
+			for _, it := range chunk {
+				results[it.index].Status = StockImportFailed
+				results[it.index].Error = "chunk rolled back: " + err.Error()
+			}
+		}
+	}
+
+	report := &StockImportReport{Applied: opts.Apply, Total: len(rows), Rows: results}
+	for _, r := range results {
+		switch r.Status {
+		case StockImportCreated:
+			report.Created++
+		case StockImportUpdated:
+			report.Updated++
+		case StockImportUnchanged:
+			report.Unchanged++
+		default:
+			report.Failed++
+		}
+	}
+	return report, nil
+}
+
+// resolveStockImport mengisi results untuk baris yang tidak valid dan
+// mengembalikan baris sisanya dengan product ID yang sudah diketahui.
+func (u *WarehouseStockUsecase) resolveStockImport(ctx context.Context, rows []StockImportRow, results []StockImportRowResult) ([]stockImportItem, error) {
+	var skus []string
+	for _, row := range rows {
+		if row.SKU != "" {
+			skus = append(skus, row.SKU)
+		}
+	}
+	skuIDs, err := u.productRepo.FindIDsBySKU(ctx, skus)
+	if err != nil {
+		return nil, err
+	}
+
+	warehouses := map[uint]bool{}
+	products := map[uint]bool{}
+	seen := map[domain.StockKey]int{}
+	items := make([]stockImportItem, 0, len(rows))
+
+	for i, row := range rows {
+		res := &results[i]
+		*res = StockImportRowResult{
+			Line:        row.Line,
+			WarehouseID: row.WarehouseID,
+			ProductID:   row.ProductID,
+			SKU:         row.SKU,
+			Quantity:    row.Quantity,
+		}
+		fail := func(msg string) {
+			res.Status = StockImportFailed
+			res.Error = msg
+		}
+
+		if msg := checkStockImportRow(row); msg != "" {
+			fail(msg)
+			continue
+		}
+
+		if row.SKU != "" {
+			id, ok := skuIDs[row.SKU]
+			if !ok {
+				fail(fmt.Sprintf("unknown sku %q", row.SKU))
+				continue
+			}
+			if row.ProductID != 0 && row.ProductID != id {
+				fail(fmt.Sprintf("sku %q belongs to product %d", row.SKU, id))
+				continue
+			}
+			res.ProductID = id
+		} else {
+			exists, ok := products[row.ProductID]
+			if !ok {
+				_, err := u.productRepo.FindById(ctx, row.ProductID)
+				if err != nil && !errors.Is(err, repository.ErrProductNotFound) {
+					return nil, err
+				}
+				exists = err == nil
+				products[row.ProductID] = exists
+			}
+			if !exists {
+				fail(fmt.Sprintf("product %d not found", row.ProductID))
+				continue
+			}
+		}
+
+		exists, ok := warehouses[row.WarehouseID]
+		if !ok {
+			_, err := u.warehouseRepo.GetById(ctx, row.WarehouseID)
+			exists = err == nil
+			warehouses[row.WarehouseID] = exists
+		}
+		if !exists {
+			fail(fmt.Sprintf("warehouse %d not found", row.WarehouseID))
+			continue
+		}
+
+		key := domain.StockKey{WarehouseID: row.WarehouseID, ProductID: res.ProductID}
+		if first, dup := seen[key]; dup {
+			fail(fmt.Sprintf("duplicate of line %d", first))
+			continue
+		}
+		seen[key] = row.Line
+
+		items = append(items, stockImportItem{index: i, key: key, quantity: *row.Quantity})
+	}
+	return items, nil
+}
+
+func (u *WarehouseStockUsecase) importStockChunk(ctx context.Context, actorID uint, chunk []stockImportItem, results []StockImportRowResult, opts StockImportOptions) error {
+	keys := make([]domain.StockKey, len(chunk))
+	for i, it := range chunk {
+		keys[i] = it.key
+	}
+	current, err := u.repo.GetQuantities(ctx, keys)
+	if err != nil {
+		return err
+	}
+
+	for _, it := range chunk {
+		res := &results[it.index]
+		res.Status = StockImportCreated
+		res.Delta = it.quantity
+		if qty, ok := current[it.key]; ok {
+			res.CurrentQuantity = &qty
+			setImportDelta(res, qty, it.quantity)
+		}
+	}
+	if !opts.Apply {
+		return nil
+	}
+
+	tx, err := u.repo.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	note := opts.Note
+	if note == "" {
+		note = "stock import"
+	}
+	ref := domain.StockReference{Reason: domain.MovementReasonAdjustment, ActorID: actorID, Note: note}
+	// semua gudang chunk dikunci sebelum baris stoknya, lihat LockCapacityTx
+	warehouseIDs := make([]uint, 0, len(chunk))
+	for _, it := range chunk {
+		warehouseIDs = append(warehouseIDs, it.key.WarehouseID)
+	}
+	if err := u.repo.LockCapacityTx(ctx, tx, warehouseIDs); err != nil {
+		return err
+	}
+	for _, it := range chunk {
+		res := &results[it.index]
+		if res.CurrentQuantity == nil {
+			if err := u.repo.EnsureStockRowTx(ctx, tx, it.key.WarehouseID, it.key.ProductID); err != nil {
+				return fmt.Errorf("line %d: %w", res.Line, err)
+			}
+		}
+		// quantity dibaca ulang di bawah lock; diff di atas bisa sudah basi
+		result, err := u.repo.SetQuantityTx(ctx, tx, it.key.WarehouseID, it.key.ProductID, it.quantity, ref)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", res.Line, err)
+		}
+		if res.CurrentQuantity != nil {
+			before := result.QuantityBefore
+			res.CurrentQuantity = &before
+			setImportDelta(res, before, it.quantity)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit stock import: %w", err)
+	}
+	productIDs := make([]uint, len(chunk))
+	for i, it := range chunk {
+		productIDs[i] = it.key.ProductID
+	}
+	invalidateAvailabilityCache(ctx, u.cache, productIDs...)
+	return nil
+}
+
+func setImportDelta(res *StockImportRowResult, current, counted int32) {
+	res.Delta = counted - current
+	if res.Delta == 0 {
+		res.Status = StockImportUnchanged
+	} else {
+		res.Status = StockImportUpdated
+	}
+}
+
+// ExportStock menulis stok saat ini ke w baris demi baris, dengan kolom yang
+// sama seperti file import. warehouseID 0 mengekspor semua gudang.
+func (u *WarehouseStockUsecase) ExportStock(ctx context.Context, w io.Writer, format string, warehouseID uint) error {
+	switch format {
+	case StockFormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write([]string{"warehouse_id", "product_id", "sku", "quantity"}); err != nil {
+			return err
+		}
+		err := u.repo.Export(ctx, warehouseID, func(row domain.StockExportRow) error {
+			sku := ""
+			if row.SKU != nil {
+				sku = *row.SKU
+			}
+			return cw.Write([]string{
+				strconv.FormatUint(uint64(row.WarehouseID), 10),
+				strconv.FormatUint(uint64(row.ProductID), 10),
+				sku,
+				strconv.FormatInt(int64(row.Quantity), 10),
+			})
+		})
+		cw.Flush()
+		if err != nil {
+			return err
+		}
+		return cw.Error()
+	case StockFormatNDJSON:
+		enc := json.NewEncoder(w)
+		return u.repo.Export(ctx, warehouseID, func(row domain.StockExportRow) error {
+			return enc.Encode(row)
+		})
+	default:
+		return fmt.Errorf("%w: unsupported format %q", ErrInvalidStockImport, format)
+	}
+}
@@ -2,46 +2,83 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"sort"
 	"sync"
 
-	"github.com/gin-gonic/gin"
 	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
 	"github.com/ifs21014-itdel/concurrent-order-processor/internal/repository"
 	"github.com/ifs21014-itdel/concurrent-order-processor/internal/utils"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	// ErrInvalidMovementFilter dikembalikan kalau warehouse_id tidak diisi.
+	ErrInvalidMovementFilter = errors.New("warehouse_id is required")
+	// ErrInvalidStockAdjustment dikembalikan untuk input adjust/stocktake yang tidak valid.
+	ErrInvalidStockAdjustment = errors.New("invalid stock adjustment")
+	// ErrInvalidStockAlertFilter dikembalikan untuk filter alert yang tidak dikenal.
+	ErrInvalidStockAlertFilter = errors.New("invalid stock alert filter")
 )
 
 type WarehouseStockUsecase struct {
 	repo          repository.WarehouseStockRepository
 	warehouseRepo repository.WarehouseRepository
 	productRepo   repository.ProductRepository
+	// cache menyimpan availability produk; boleh nil
+	cache *redis.Client
 }
 
 func NewWarehouseStockUsecase(
 	repo repository.WarehouseStockRepository,
 	warehouseRepo repository.WarehouseRepository,
 	productRepo repository.ProductRepository,
+	cache *redis.Client,
 ) *WarehouseStockUsecase {
-	return &WarehouseStockUsecase{repo, warehouseRepo, productRepo}
+	return &WarehouseStockUsecase{repo, warehouseRepo, productRepo, cache}
 }
 
-// Semua response dikembalikan dalam bentuk map agar handler tidak perlu mikir
-func (u *WarehouseStockUsecase) Create(ctx context.Context, stock *domain.WarehouseStock) (map[string]interface{}, error) {
-	if err := u.repo.Create(ctx, stock); err != nil {
-		return nil, fmt.Errorf("failed to create warehouse stock: %w", err)
+// Semua response dikembalikan dalam bentuk map agar handler tidak perlu mikir.
+// Create menambah baris stok, atau mengubah baris yang sudah ada sesuai mode
+// (default add); created menandakan baris baru dibuat.
+func (u *WarehouseStockUsecase) Create(ctx context.Context, actorID uint, stock *domain.WarehouseStock, mode domain.StockUpsertMode) (map[string]interface{}, bool, error) {
+	if mode == "" {
+		mode = domain.StockUpsertAdd
+	}
+	if mode != domain.StockUpsertAdd && mode != domain.StockUpsertReplace {
+		return nil, false, fmt.Errorf("%w: mode must be %q or %q", ErrInvalidStockAdjustment, domain.StockUpsertAdd, domain.StockUpsertReplace)
+	}
+	if stock.Quantity < 0 {
+		return nil, false, fmt.Errorf("%w: quantity cannot be negative", ErrInvalidStockAdjustment)
+	}
+	if stock.ReorderPoint != nil && *stock.ReorderPoint < 0 {
+		return nil, false, fmt.Errorf("%w: reorder point cannot be negative", ErrInvalidStockAdjustment)
+	}
+	ref := domain.StockReference{ActorID: actorID}
+	created, err := u.repo.Create(ctx, stock, mode, ref)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create warehouse stock: %w", err)
+	}
+	invalidateAvailabilityCache(ctx, u.cache, stock.ProductID)
+	message := "warehouseStock created successfully"
+	if !created {
+		message = "warehouseStock updated successfully"
 	}
 	return map[string]interface{}{
 		"status":  "success",
-		"message": "warehouseStock created successfully",
+		"message": message,
 		"data":    stock,
-	}, nil
+	}, created, nil
 }
 
-func (u *WarehouseStockUsecase) Delete(ctx context.Context, stockID uint) (map[string]interface{}, error) {
-	if err := u.repo.Delete(ctx, stockID); err != nil {
+func (u *WarehouseStockUsecase) Delete(ctx context.Context, actorID uint, stockID uint) (map[string]interface{}, error) {
+	ref := domain.StockReference{Reason: domain.MovementReasonAdjustment, ActorID: actorID, Note: "stock deleted"}
+	if err := u.repo.Delete(ctx, stockID, ref); err != nil {
 		return nil, fmt.Errorf("failed to delete warehouse stock: %w", err)
 	}
+	invalidateAllAvailabilityCache(ctx, u.cache)
 	return map[string]interface{}{
 		"status":  "success",
 		"message": "warehouseStock deleted successfully",
@@ -69,6 +106,10 @@ func (u *WarehouseStockUsecase) GetWarehouseDetail(ctx context.Context, warehous
 	if err != nil {
 		return nil, fmt.Errorf("failed to get warehouse detail: %w", err)
 	}
+	utilisation, err := u.warehouseRepo.Utilisation(ctx, warehouse)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get warehouse utilisation: %w", err)
+	}
 
 	var result []map[string]interface{}
 	for _, s := range stocks {
@@ -83,47 +124,318 @@ func (u *WarehouseStockUsecase) GetWarehouseDetail(ctx context.Context, warehous
 			"price":  product.Price,
 			"stock":  s.Quantity,
 			"weight": product.Weight,
+			"volume": product.Volume,
 		})
 	}
 
 	return map[string]interface{}{
-		"status":    "success",
-		"warehouse": warehouse,
-		"stocks":    result,
+		"status":      "success",
+		"warehouse":   warehouse,
+		"utilisation": utilisation,
+		"stocks":      result,
 	}, nil
 }
 
-func (u *WarehouseStockUsecase) UpdateQuantity(ctx context.Context, warehouseID uint, productID uint, quantity int32) (map[string]interface{}, error) {
-	if err := u.repo.UpdateQuantity(ctx, warehouseID, productID, quantity); err != nil {
-		return nil, fmt.Errorf("failed to update stock quantity: %w", err)
+// ReorderPointRequest mengatur reorder point; ReorderPoint null menghapusnya.
+// Version (opsional) harus sama dengan versi baris saat ini.
+type ReorderPointRequest struct {
+	WarehouseID  uint   `json:"warehouse_id" binding:"required"`
+	ProductID    uint   `json:"product_id" binding:"required"`
+	ReorderPoint *int32 `json:"reorder_point"`
+	Version      int32  `json:"version"`
+}
+
+// SetReorderPoint mengatur batas stok rendah. Kalau stok saat ini sudah di
+// bawahnya, alert langsung dibuat.
+func (u *WarehouseStockUsecase) SetReorderPoint(ctx context.Context, req ReorderPointRequest) (*domain.WarehouseStock, error) {
+	if req.WarehouseID == 0 || req.ProductID == 0 {
+		return nil, ErrInvalidStockAdjustment
 	}
-	return map[string]interface{}{
-		"status":  "success",
-		"message": fmt.Sprintf("Quantity for stock Product ID %d and Warehouse ID %d updated successfully", warehouseID, productID),
-		"data": gin.H{
-			"Product_id":   productID,
-			"warehouse_id": warehouseID,
-			"quantity":     quantity,
+	if req.ReorderPoint != nil && *req.ReorderPoint < 0 {
+		return nil, fmt.Errorf("%w: reorder point cannot be negative", ErrInvalidStockAdjustment)
+	}
+	stock, err := u.repo.SetReorderPoint(ctx, req.WarehouseID, req.ProductID, req.ReorderPoint, req.Version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set reorder point: %w", err)
+	}
+	return stock, nil
+}
+
+// AdjustStockRequest adalah perubahan relatif: Delta positif menambah stok,
+// negatif mengurangi. Reason boleh restock, adjustment atau return.
+type AdjustStockRequest struct {
+	WarehouseID uint   `json:"warehouse_id" binding:"required"`
+	ProductID   uint   `json:"product_id" binding:"required"`
+	Delta       *int32 `json:"delta" binding:"required"`
+	Reason      string `json:"reason"`
+	Note        string `json:"note"`
+}
+
+// StocktakeRequest menimpa quantity dengan hasil hitung fisik. ExpectedQuantity
+// adalah quantity yang dilihat saat mulai menghitung, Version adalah versi
+// baris yang dibaca; minimal salah satu harus diisi. Kalau sudah berubah,
+// stocktake ditolak supaya perubahan lain tidak hilang.
+type StocktakeRequest struct {
+	WarehouseID      uint   `json:"warehouse_id" binding:"required"`
+	ProductID        uint   `json:"product_id" binding:"required"`
+	ExpectedQuantity *int32 `json:"expected_quantity"`
+	Version          int32  `json:"version"`
+	Quantity         *int32 `json:"quantity" binding:"required"`
+	Note             string `json:"note"`
+}
+
+// StockAdjustResult adalah hasil satu adjustment dalam batch.
+type StockAdjustResult struct {
+	WarehouseID   uint   `json:"warehouse_id"`
+	ProductID     uint   `json:"product_id"`
+	Success       bool   `json:"success"`
+	QuantityAfter *int32 `json:"quantity_after,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+func toStockChange(actorID uint, req AdjustStockRequest) (domain.StockChange, error) {
+	if req.WarehouseID == 0 || req.ProductID == 0 || req.Delta == nil {
+		return domain.StockChange{}, ErrInvalidStockAdjustment
+	}
+	if *req.Delta == 0 {
+		return domain.StockChange{}, fmt.Errorf("%w: delta cannot be zero", ErrInvalidStockAdjustment)
+	}
+	reason := req.Reason
+	switch reason {
+	case "":
+		reason = domain.MovementReasonAdjustment
+	case domain.MovementReasonRestock, domain.MovementReasonAdjustment, domain.MovementReasonReturn:
+	default:
+		// sale dan transfer hanya dicatat oleh order dan transfer
+		return domain.StockChange{}, fmt.Errorf("%w: reason %q is not allowed", ErrInvalidStockAdjustment, reason)
+	}
+	return domain.StockChange{
+		WarehouseID: req.WarehouseID,
+		ProductID:   req.ProductID,
+		Delta:       *req.Delta,
+		StockReference: domain.StockReference{
+			Reason:  reason,
+			ActorID: actorID,
+			Note:    req.Note,
 		},
 	}, nil
 }
 
-func (u *WarehouseStockUsecase) ConcurrentUpdateQuantities(ctx context.Context, updates []domain.WarehouseStock) map[string]interface{} {
+// Adjust menambah atau mengurangi stok secara atomik. Quantity tidak boleh
+// menjadi negatif (repository.ErrInsufficientStock).
+func (u *WarehouseStockUsecase) Adjust(ctx context.Context, actorID uint, req AdjustStockRequest) (*domain.StockChangeResult, error) {
+	change, err := toStockChange(actorID, req)
+	if err != nil {
+		return nil, err
+	}
+	result, err := u.repo.ApplyChange(ctx, change)
+	if err != nil {
+		return nil, fmt.Errorf("failed to adjust stock: %w", err)
+	}
+	invalidateAvailabilityCache(ctx, u.cache, change.ProductID)
+	return result, nil
+}
+
+// Stocktake menimpa quantity dengan hasil hitung, hanya jika quantity saat ini
+// masih sama dengan ExpectedQuantity (repository.ErrStockChanged) dan versinya
+// masih Version (repository.ErrVersionConflict).
+func (u *WarehouseStockUsecase) Stocktake(ctx context.Context, actorID uint, req StocktakeRequest) (*domain.StockChangeResult, error) {
+	if req.WarehouseID == 0 || req.ProductID == 0 || req.Quantity == nil {
+		return nil, ErrInvalidStockAdjustment
+	}
+	if req.ExpectedQuantity == nil && req.Version == 0 {
+		return nil, fmt.Errorf("%w: expected_quantity or version is required", ErrInvalidStockAdjustment)
+	}
+	if *req.Quantity < 0 {
+		return nil, fmt.Errorf("%w: quantity cannot be negative", ErrInvalidStockAdjustment)
+	}
+	ref := domain.StockReference{Reason: domain.MovementReasonAdjustment, ActorID: actorID, Note: req.Note}
+	expect := domain.StockExpectation{Quantity: req.ExpectedQuantity, Version: req.Version}
+	result, err := u.repo.Stocktake(ctx, req.WarehouseID, req.ProductID, *req.Quantity, expect, ref)
+	if err != nil {
+		// result tetap dikembalikan agar client tahu quantity terbaru
+		return result, fmt.Errorf("failed to apply stocktake: %w", err)
+	}
+	invalidateAvailabilityCache(ctx, u.cache, req.ProductID)
+	return result, nil
+}
+
+// maxConcurrentAdjustments membatasi goroutine yang jalan bersamaan di
+// ConcurrentAdjust, supaya batch besar tidak menghabiskan koneksi database.
+const maxConcurrentAdjustments = 8
+
+// ConcurrentAdjust menjalankan beberapa adjustment sekaligus, paling banyak
+// maxConcurrentAdjustments bersamaan. Karena setiap adjustment relatif dan
+// atomik, urutannya tidak mempengaruhi hasil akhir. Adjustment yang gagal
+// tidak membatalkan yang lain; hasilnya dilaporkan per item.
+func (u *WarehouseStockUsecase) ConcurrentAdjust(ctx context.Context, actorID uint, updates []AdjustStockRequest) []StockAdjustResult {
+	results := make([]StockAdjustResult, len(updates))
+	sem := make(chan struct{}, maxConcurrentAdjustments)
 	var wg sync.WaitGroup
-	for _, stock := range updates {
+	for i, req := range updates {
 		wg.Add(1)
-		go utils.SafeGoroutine(fmt.Sprintf("Update stock where warehoseID %d and Product Id %d", stock.WarehouseID, stock.ProductID), func() {
+		sem <- struct{}{}
+		go utils.SafeGoroutine(fmt.Sprintf("Adjust stock where warehoseID %d and Product Id %d", req.WarehouseID, req.ProductID), func() {
+			defer func() { <-sem }()
 			defer wg.Done()
-			if err := u.repo.UpdateQuantity(ctx, stock.WarehouseID, stock.ProductID, stock.Quantity); err != nil {
-				log.Printf("[ERROR] Failed to update stock ID %d: %v", stock.ID, err)
+			res := StockAdjustResult{WarehouseID: req.WarehouseID, ProductID: req.ProductID}
+			result, err := u.Adjust(ctx, actorID, req)
+			if err != nil {
+				log.Printf("[ERROR] Failed to adjust stock warehouse %d product %d: %v", req.WarehouseID, req.ProductID, err)
+				res.Error = err.Error()
 			} else {
-				log.Printf("[OK] Stock ID %d updated successfully", stock.ProductID)
+				res.Success = true
+				res.QuantityAfter = &result.QuantityAfter
 			}
+			results[i] = res
 		})
 	}
 	wg.Wait()
+	return results
+}
+
+// AtomicAdjust menerapkan semua adjustment dalam satu transaksi: semua
+// berhasil atau tidak ada yang diterapkan. Setiap adjustment hanya mengunci
+// baris milik pasangan (warehouse_id, product_id)-nya sendiri: baris stok,
+// lot dan lokasinya; baris products tidak ikut dikunci. Karena itu batch
+// diterapkan berurutan menurut (warehouse_id, product_id), supaya dua batch
+// yang menyentuh baris yang sama selalu mengunci dengan urutan yang sama dan
+// tidak saling deadlock. Gudang yang menerima penambahan dikunci lebih dulu
+// untuk cek kapasitas (LockCapacityTx). Error yang dikembalikan adalah
+// penyebab kegagalan; results tetap berisi status setiap item.
+func (u *WarehouseStockUsecase) AtomicAdjust(ctx context.Context, actorID uint, updates []AdjustStockRequest) ([]StockAdjustResult, error) {
+	results := make([]StockAdjustResult, len(updates))
+	changes := make([]domain.StockChange, len(updates))
+	var firstErr error
+	for i, req := range updates {
+		results[i] = StockAdjustResult{WarehouseID: req.WarehouseID, ProductID: req.ProductID}
+		change, err := toStockChange(actorID, req)
+		if err != nil {
+			results[i].Error = err.Error()
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		changes[i] = change
+	}
+	if firstErr != nil {
+		markNotApplied(results, "batch not applied: another adjustment is invalid")
+		return results, firstErr
+	}
+
+	order := make([]int, len(changes))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		ca, cb := changes[order[a]], changes[order[b]]
+		if ca.WarehouseID != cb.WarehouseID {
+			return ca.WarehouseID < cb.WarehouseID
+		}
+		return ca.ProductID < cb.ProductID
+	})
+
+	tx, err := u.repo.BeginTx(ctx)
+	if err != nil {
+		return results, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var increased []uint
+	for _, change := range changes {
+		if change.Delta > 0 {
+			increased = append(increased, change.WarehouseID)
+		}
+	}
+	if err := u.repo.LockCapacityTx(ctx, tx, increased); err != nil {
+		return results, fmt.Errorf("failed to adjust stock: %w", err)
+	}
+
+	after := make([]int32, len(changes))
+	for _, i := range order {
+		result, err := u.repo.ApplyChangeTx(ctx, tx, changes[i])
+		if err != nil {
+			err = fmt.Errorf("failed to adjust stock: %w", err)
+			results[i].Error = err.Error()
+			markNotApplied(results, "batch rolled back: another adjustment failed")
+			return results, err
+		}
+		after[i] = result.QuantityAfter
+	}
+	if err := tx.Commit(); err != nil {
+		return results, fmt.Errorf("failed to commit stock adjustments: %w", err)
+	}
+	productIDs := make([]uint, len(changes))
+	for i, change := range changes {
+		productIDs[i] = change.ProductID
+	}
+	invalidateAvailabilityCache(ctx, u.cache, productIDs...)
+
+	for i := range results {
+		results[i].Success = true
+		results[i].QuantityAfter = &after[i]
+	}
+	return results, nil
+}
+
+// markNotApplied memberi pesan pada item yang belum punya error.
+func markNotApplied(results []StockAdjustResult, msg string) {
+	for i := range results {
+		if results[i].Error == "" {
+			results[i].Error = msg
+		}
+	}
+}
+
+const (
+	defaultMovementPageSize = 50
+	maxMovementPageSize     = 200
+)
+
+// ListMovements mengembalikan ledger stok terbaru lebih dulu. next_before_id
+// dipakai sebagai cursor halaman berikutnya (nil kalau sudah habis).
+func (u *WarehouseStockUsecase) ListMovements(ctx context.Context, filter domain.StockMovementFilter) (map[string]interface{}, error) {
+	if filter.WarehouseID == 0 {
+		return nil, ErrInvalidMovementFilter
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultMovementPageSize
+	}
+	if filter.Limit > maxMovementPageSize {
+		filter.Limit = maxMovementPageSize
+	}
+
+	movements, err := u.repo.ListMovements(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stock movements: %w", err)
+	}
+
+	var next *uint64
+	if len(movements) == filter.Limit {
+		last := movements[len(movements)-1].ID
+		next = &last
+	}
 	return map[string]interface{}{
-		"status":  "done",
-		"message": "All stock quantities updated successfully",
+		"status":         "success",
+		"data":           movements,
+		"next_before_id": next,
+	}, nil
+}
+
+// RebuildFromLedger melaporkan selisih antara quantity dan ledger; dengan
+// apply=true quantity ditimpa dengan hasil ledger.
+func (u *WarehouseStockUsecase) RebuildFromLedger(ctx context.Context, apply bool) (map[string]interface{}, error) {
+	discrepancies, err := u.repo.RebuildFromLedger(ctx, apply)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rebuild stock from ledger: %w", err)
+	}
+	if apply && len(discrepancies) > 0 {
+		invalidateAllAvailabilityCache(ctx, u.cache)
 	}
+	return map[string]interface{}{
+		"status":  "success",
+		"applied": apply,
+		"data":    discrepancies,
+	}, nil
 }
@@ -0,0 +1,195 @@
+package usecase
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/repository"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingStockRepo applies changes in memory and records how many run at
+// the same time.
+type countingStockRepo struct {
+	repository.WarehouseStockRepository
+	mu        sync.Mutex
+	inFlight  int
+	maxFlight int
+	quantity  map[domain.StockKey]int32
+	// reserved stands in for the draft transfers out of each row
+	reserved map[domain.StockKey]int32
+	reads    int
+}
+
+// GetAvailability reports every row of the product held in memory.
+func (r *countingStockRepo) GetAvailability(ctx context.Context, productID uint) ([]domain.StockAvailability, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reads++
+	availability := []domain.StockAvailability{}
+	for key, qty := range r.quantity {
+		if key.ProductID == productID {
+			reserved := r.reserved[key]
+			availability = append(availability, domain.StockAvailability{
+				WarehouseID: key.WarehouseID,
+				OnHand:      qty,
+				Reserved:    reserved,
+				Available:   max(qty-reserved, 0),
+			})
+		}
+	}
+	return availability, nil
+}
+
+func (r *countingStockRepo) ApplyChange(ctx context.Context, change domain.StockChange) (*domain.StockChangeResult, error) {
+	r.mu.Lock()
+	r.inFlight++
+	r.maxFlight = max(r.maxFlight, r.inFlight)
+	r.mu.Unlock()
+
+	time.Sleep(5 * time.Millisecond)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.inFlight--
+	key := domain.StockKey{WarehouseID: change.WarehouseID, ProductID: change.ProductID}
+	after := r.quantity[key] + change.Delta
+	if after < 0 {
+		return nil, repository.ErrInsufficientStock
+	}
+	r.quantity[key] = after
+	return &domain.StockChangeResult{QuantityAfter: after}, nil
+}
+
+func delta(d int32) *int32 { return &d }
+
+func TestWarehouseStockUsecase_ConcurrentAdjust(t *testing.T) {
+	repo := &countingStockRepo{quantity: map[domain.StockKey]int32{}}
+	u := NewWarehouseStockUsecase(repo, nil, nil, nil)
+
+	updates := make([]AdjustStockRequest, 20)
+	for i := range updates {
+		updates[i] = AdjustStockRequest{WarehouseID: 1, ProductID: uint(i + 1), Delta: delta(5)}
+	}
+	// product 3 would go negative, product 4 is invalid
+	updates[2].Delta = delta(-1)
+	updates[3].Delta = delta(0)
+
+	results := u.ConcurrentAdjust(context.Background(), 7, updates)
+
+	assert.LessOrEqual(t, repo.maxFlight, maxConcurrentAdjustments)
+	assert.Greater(t, repo.maxFlight, 1)
+	for i, res := range results {
+		assert.Equal(t, uint(i+1), res.ProductID)
+		switch i {
+		case 2:
+			assert.False(t, res.Success)
+			assert.Contains(t, res.Error, repository.ErrInsufficientStock.Error())
+		case 3:
+			assert.False(t, res.Success)
+			assert.Contains(t, res.Error, ErrInvalidStockAdjustment.Error())
+		default:
+			assert.True(t, res.Success)
+			assert.Equal(t, int32(5), *res.QuantityAfter)
+		}
+	}
+}
+
+var lockColumns = []string{"quantity", "reorder_point", "version"}
+
+// expectIncrease expects one increase of a row without reorder point in a
+// warehouse without capacity limits.
+func expectIncrease(mock sqlmock.Sqlmock, warehouseID, productID uint, before, delta int32) {
+	expectCapacityLock(mock, int64(warehouseID))
+	mock.ExpectQuery("SELECT quantity, reorder_point, version FROM warehouse_stock (.+) FOR UPDATE").
+		WithArgs(warehouseID, productID).
+		WillReturnRows(sqlmock.NewRows(lockColumns).AddRow(before, nil, 1))
+	mock.ExpectQuery("SELECT max_weight_kg, max_volume_m3, capacity_policy FROM warehouses").
+		WithArgs(warehouseID).
+		WillReturnRows(sqlmock.NewRows([]string{"max_weight_kg", "max_volume_m3", "capacity_policy"}).AddRow(nil, nil, domain.CapacityPolicyReject))
+	mock.ExpectExec("UPDATE warehouse_stock SET quantity").
+		WithArgs(before+delta, warehouseID, productID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("INSERT INTO stock_movements").
+		WithArgs(warehouseID, productID, delta, before+delta, domain.MovementReasonAdjustment, nil, nil, uint(7), nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+}
+
+// expectCapacityLock expects the warehouse lock taken before a capacity check.
+func expectCapacityLock(mock sqlmock.Sqlmock, warehouseIDs ...int64) {
+	mock.ExpectExec("SELECT id FROM warehouses (.+) FOR NO KEY UPDATE").
+		WithArgs(pq.Array(warehouseIDs)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func TestWarehouseStockUsecase_AtomicAdjust(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	u := NewWarehouseStockUsecase(repository.NewWarehouseStockRepository(db), nil, nil, nil)
+	ctx := context.Background()
+
+	t.Run("LocksInWarehouseProductOrder", func(t *testing.T) {
+		// the expectations are ordered: both warehouses are locked for the
+		// capacity check first, then (1,2) before (2,1), whatever the
+		// request order
+		mock.ExpectBegin()
+		expectCapacityLock(mock, 2, 1)
+		expectIncrease(mock, 1, 2, 4, 3)
+		expectIncrease(mock, 2, 1, 0, 5)
+		mock.ExpectCommit()
+
+		results, err := u.AtomicAdjust(ctx, 7, []AdjustStockRequest{
+			{WarehouseID: 2, ProductID: 1, Delta: delta(5)},
+			{WarehouseID: 1, ProductID: 2, Delta: delta(3)},
+		})
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+		// results keep the request order
+		assert.True(t, results[0].Success)
+		assert.Equal(t, int32(5), *results[0].QuantityAfter)
+		assert.True(t, results[1].Success)
+		assert.Equal(t, int32(7), *results[1].QuantityAfter)
+	})
+
+	t.Run("RollsBackWhenOneFails", func(t *testing.T) {
+		mock.ExpectBegin()
+		// only the warehouse receiving an increase is locked
+		expectCapacityLock(mock, 1)
+		expectIncrease(mock, 1, 1, 0, 5)
+		mock.ExpectQuery("SELECT quantity, reorder_point, version FROM warehouse_stock (.+) FOR UPDATE").
+			WithArgs(uint(1), uint(2)).
+			WillReturnRows(sqlmock.NewRows(lockColumns).AddRow(10, nil, 1))
+		mock.ExpectRollback()
+
+		results, err := u.AtomicAdjust(ctx, 7, []AdjustStockRequest{
+			{WarehouseID: 1, ProductID: 2, Delta: delta(-50)},
+			{WarehouseID: 1, ProductID: 1, Delta: delta(5)},
+		})
+		assert.ErrorIs(t, err, repository.ErrInsufficientStock)
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.False(t, results[0].Success)
+		assert.Contains(t, results[0].Error, repository.ErrInsufficientStock.Error())
+		// the increase was applied in the transaction, but rolled back with it
+		assert.False(t, results[1].Success)
+		assert.Nil(t, results[1].QuantityAfter)
+		assert.Equal(t, "batch rolled back: another adjustment failed", results[1].Error)
+	})
+
+	t.Run("InvalidItemAppliesNothing", func(t *testing.T) {
+		results, err := u.AtomicAdjust(ctx, 7, []AdjustStockRequest{
+			{WarehouseID: 1, ProductID: 1, Delta: delta(5)},
+			{WarehouseID: 1, ProductID: 2, Delta: delta(0)},
+		})
+		assert.ErrorIs(t, err, ErrInvalidStockAdjustment)
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, "batch not applied: another adjustment is invalid", results[0].Error)
+		assert.Contains(t, results[1].Error, "delta cannot be zero")
+	})
+}
@@ -0,0 +1,367 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/domain"
+	"github.com/ifs21014-itdel/concurrent-order-processor/internal/repository"
+)
+
+var (
+	// ErrWishlistForbidden is returned when a user touches another user's wishlist.
+	ErrWishlistForbidden = errors.New("wishlist does not belong to user")
+	// ErrProductUnavailable is returned when a warehouse cannot supply the requested quantity.
+	ErrProductUnavailable = errors.New("product is not available in the requested quantity")
+)
+
+// WarehouseAvailability is the stock of one product in one warehouse.
+type WarehouseAvailability struct {
+	WarehouseID   uint   `json:"warehouse_id"`
+	WarehouseName string `json:"warehouse_name"`
+	Quantity      int32  `json:"quantity"`
+}
+
+// WishlistItemDetail is a wishlist item enriched with the product's current
+// price and its availability per warehouse.
+type WishlistItemDetail struct {
+	ID             uint                    `json:"id"`
+	ProductID      uint                    `json:"product_id"`
+	ProductName    string                  `json:"product_name"`
+	CurrentPrice   float64                 `json:"current_price"`
+	TotalAvailable int32                   `json:"total_available"`
+	Availability   []WarehouseAvailability `json:"availability"`
+	AddedAt        time.Time               `json:"added_at"`
+}
+
+type WishlistDetail struct {
+	Wishlist domain.Wishlist      `json:"wishlist"`
+	Items    []WishlistItemDetail `json:"items"`
+}
+
+// MoveToCartRequest selects where a wishlist item lands in the cart.
+type MoveToCartRequest struct {
+	WarehouseID uint  `json:"warehouse_id"`
+	Quantity    int32 `json:"quantity"`
+}
+
+type WishlistUsecase struct {
+	repo               repository.WishlistRepository
+	productRepo        repository.ProductRepository
+	warehouseRepo      repository.WarehouseRepository
+	warehouseStockRepo repository.WarehouseStockRepository
+	cartUC             *CartUsecase
+}
+
+func NewWishlistUsecase(
+	repo repository.WishlistRepository,
+	productRepo repository.ProductRepository,
+	warehouseRepo repository.WarehouseRepository,
+	warehouseStockRepo repository.WarehouseStockRepository,
+	cartUC *CartUsecase,
+) *WishlistUsecase {
+	return &WishlistUsecase{
+		repo:               repo,
+		productRepo:        productRepo,
+		warehouseRepo:      warehouseRepo,
+		warehouseStockRepo: warehouseStockRepo,
+		cartUC:             cartUC,
+	}
+}
+
+func (u *WishlistUsecase) CreateWishlist(ctx context.Context, userID uint, name string) (*domain.Wishlist, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("wishlist name is required")
+	}
+
+	wishlist := &domain.Wishlist{UserID: userID, Name: name}
+	if err := u.repo.Create(ctx, wishlist); err != nil {
+		return nil, err
+	}
+	return wishlist, nil
+}
+
+func (u *WishlistUsecase) GetWishlists(ctx context.Context, userID uint) ([]domain.Wishlist, error) {
+	return u.repo.GetByUserID(ctx, userID)
+}
+
+// GetWishlist returns a wishlist of userID with price and availability per item.
+func (u *WishlistUsecase) GetWishlist(ctx context.Context, userID, wishlistID uint) (*WishlistDetail, error) {
+	wishlist, err := u.getOwnedWishlist(ctx, userID, wishlistID)
+	if err != nil {
+		return nil, err
+	}
+	return u.buildDetail(ctx, wishlist)
+}
+
+// GetSharedWishlist returns a wishlist by its public share token.
+func (u *WishlistUsecase) GetSharedWishlist(ctx context.Context, token string) (*WishlistDetail, error) {
+	if token == "" {
+		return nil, repository.ErrWishlistNotFound
+	}
+	wishlist, err := u.repo.GetByShareToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	return u.buildDetail(ctx, wishlist)
+}
+
+func (u *WishlistUsecase) RenameWishlist(ctx context.Context, userID, wishlistID uint, name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("wishlist name is required")
+	}
+	if _, err := u.getOwnedWishlist(ctx, userID, wishlistID); err != nil {
+		return err
+	}
+	return u.repo.Rename(ctx, wishlistID, name)
+}
+
+func (u *WishlistUsecase) DeleteWishlist(ctx context.Context, userID, wishlistID uint) error {
+	if _, err := u.getOwnedWishlist(ctx, userID, wishlistID); err != nil {
+		return err
+	}
+	return u.repo.Delete(ctx, wishlistID)
+}
+
+// Share makes the wishlist readable through a public token. Sharing an already
+// shared wishlist returns the existing token.
+func (u *WishlistUsecase) Share(ctx context.Context, userID, wishlistID uint) (string, error) {
+	wishlist, err := u.getOwnedWishlist(ctx, userID, wishlistID)
+	if err != nil {
+		return "", err
+	}
+	if wishlist.ShareToken != nil {
+		return *wishlist.ShareToken, nil
+	}
+
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate share token: %w", err)
+	}
+	token := hex.EncodeToString(buf)
+	if err := u.repo.SetShareToken(ctx, wishlistID, &token); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Unshare revokes the public token; old links stop working.
+func (u *WishlistUsecase) Unshare(ctx context.Context, userID, wishlistID uint) error {
+	if _, err := u.getOwnedWishlist(ctx, userID, wishlistID); err != nil {
+		return err
+	}
+	return u.repo.SetShareToken(ctx, wishlistID, nil)
+}
+
+func (u *WishlistUsecase) AddItem(ctx context.Context, userID, wishlistID, productID uint) (*domain.WishlistItem, error) {
+	if productID == 0 {
+		return nil, fmt.Errorf("product ID is required")
+	}
+	if _, err := u.getOwnedWishlist(ctx, userID, wishlistID); err != nil {
+		return nil, err
+	}
+	if _, err := u.productRepo.FindById(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	item := &domain.WishlistItem{WishlistID: wishlistID, ProductID: productID}
+	if _, err := u.repo.AddItem(ctx, item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func (u *WishlistUsecase) RemoveItem(ctx context.Context, userID, wishlistID, itemID uint) error {
+	if _, err := u.getOwnedItem(ctx, userID, wishlistID, itemID); err != nil {
+		return err
+	}
+	return u.repo.DeleteItem(ctx, itemID)
+}
+
+// MoveCartItemToWishlist saves a cart item for later: the product is added to
+// the wishlist and the cart line is removed. If removing the cart line fails,
+// a wishlist entry created by this call is rolled back.
+func (u *WishlistUsecase) MoveCartItemToWishlist(ctx context.Context, userID, cartItemID, wishlistID uint) (*domain.WishlistItem, error) {
+	cartItem, err := u.cartUC.cartItemRepo.GetCartItemByID(ctx, cartItemID)
+	if err != nil {
+		return nil, err
+	}
+	cart, err := u.cartUC.cartRepo.GetCartByID(ctx, cartItem.CartID)
+	if err != nil {
+		return nil, err
+	}
+	if cart.UserID != userID {
+		return nil, ErrCartForbidden
+	}
+	if _, err := u.getOwnedWishlist(ctx, userID, wishlistID); err != nil {
+		return nil, err
+	}
+
+	item := &domain.WishlistItem{WishlistID: wishlistID, ProductID: cartItem.ProductID}
+	inserted, err := u.repo.AddItem(ctx, item)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.cartUC.cartItemRepo.DeleteCartItem(ctx, cartItem.ID); err != nil {
+		if inserted {
+			if rbErr := u.repo.DeleteItem(ctx, item.ID); rbErr != nil {
+				log.Printf("[ERROR] Failed to roll back wishlist item %d: %v", item.ID, rbErr)
+			}
+		}
+		return nil, fmt.Errorf("failed to remove cart item: %w", err)
+	}
+
+	invalidateUserCartCache(ctx, u.cartUC.cache, userID)
+	log.Printf("[OK] Moved cart item %d to wishlist %d", cartItemID, wishlistID)
+	return item, nil
+}
+
+// MoveWishlistItemToCart puts a wishlist item into the user's cart for the
+// chosen warehouse at the current price and removes it from the wishlist.
+// If removing the wishlist entry fails, the new cart line is rolled back.
+func (u *WishlistUsecase) MoveWishlistItemToCart(ctx context.Context, userID, wishlistID, itemID uint, req MoveToCartRequest) (*domain.CartItem, error) {
+	if req.WarehouseID == 0 {
+		return nil, fmt.Errorf("warehouse ID is required")
+	}
+	if req.Quantity < 0 {
+		return nil, fmt.Errorf("quantity must be greater than zero")
+	}
+	if req.Quantity == 0 {
+		req.Quantity = 1
+	}
+
+	item, err := u.getOwnedItem(ctx, userID, wishlistID, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	product, err := u.productRepo.FindById(ctx, item.ProductID)
+	if err != nil {
+		return nil, err
+	}
+
+	stock, err := u.warehouseStockRepo.GetByWarehouseAndProduct(ctx, req.WarehouseID, item.ProductID)
+	if errors.Is(err, repository.ErrWarehouseStockNotFound) {
+		return nil, ErrProductUnavailable
+	}
+	if err != nil {
+		return nil, err
+	}
+	if stock.Quantity < req.Quantity {
+		return nil, ErrProductUnavailable
+	}
+
+	cart, err := u.cartUC.getOrCreateCart(ctx, userID, req.WarehouseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get or create cart: %w", err)
+	}
+
+	cartItem := &domain.CartItem{
+		CartID:    cart.ID,
+		ProductID: item.ProductID,
+		Quantity:  req.Quantity,
+		SubTotal:  product.Price * float64(req.Quantity),
+	}
+	if err := u.cartUC.cartItemRepo.AddCartItem(ctx, cartItem); err != nil {
+		return nil, fmt.Errorf("failed to add cart item: %w", err)
+	}
+
+	if err := u.repo.DeleteItem(ctx, item.ID); err != nil {
+		if rbErr := u.cartUC.cartItemRepo.DeleteCartItem(ctx, cartItem.ID); rbErr != nil {
+			log.Printf("[ERROR] Failed to roll back cart item %d: %v", cartItem.ID, rbErr)
+		}
+		return nil, fmt.Errorf("failed to remove wishlist item: %w", err)
+	}
+
+	invalidateUserCartCache(ctx, u.cartUC.cache, userID)
+	log.Printf("[OK] Moved wishlist item %d to cart ID %d", itemID, cart.ID)
+	return cartItem, nil
+}
+
+func (u *WishlistUsecase) buildDetail(ctx context.Context, wishlist *domain.Wishlist) (*WishlistDetail, error) {
+	items, err := u.repo.GetItems(ctx, wishlist.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	warehouses, err := u.warehouseRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get warehouses: %w", err)
+	}
+	warehouseNames := make(map[uint]string, len(warehouses))
+	for _, w := range warehouses {
+		warehouseNames[w.ID] = w.Name
+	}
+
+	detail := &WishlistDetail{
+		Wishlist: *wishlist,
+		Items:    make([]WishlistItemDetail, 0, len(items)),
+	}
+	for _, item := range items {
+		product, err := u.productRepo.FindById(ctx, item.ProductID)
+		if errors.Is(err, repository.ErrProductNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get product ID %d: %w", item.ProductID, err)
+		}
+
+		stocks, err := u.warehouseStockRepo.GetByProductID(ctx, item.ProductID)
+		if err != nil {
+			return nil, err
+		}
+
+		line := WishlistItemDetail{
+			ID:           item.ID,
+			ProductID:    item.ProductID,
+			ProductName:  product.Name,
+			CurrentPrice: product.Price,
+			Availability: make([]WarehouseAvailability, 0, len(stocks)),
+			AddedAt:      item.CreatedAt,
+		}
+		for _, s := range stocks {
+			line.TotalAvailable += s.Quantity
+			line.Availability = append(line.Availability, WarehouseAvailability{
+				WarehouseID:   s.WarehouseID,
+				WarehouseName: warehouseNames[s.WarehouseID],
+				Quantity:      s.Quantity,
+			})
+		}
+		detail.Items = append(detail.Items, line)
+	}
+	return detail, nil
+}
+
+func (u *WishlistUsecase) getOwnedWishlist(ctx context.Context, userID, wishlistID uint) (*domain.Wishlist, error) {
+	wishlist, err := u.repo.GetByID(ctx, wishlistID)
+	if err != nil {
+		return nil, err
+	}
+	if wishlist.UserID != userID {
+		return nil, ErrWishlistForbidden
+	}
+	return wishlist, nil
+}
+
+func (u *WishlistUsecase) getOwnedItem(ctx context.Context, userID, wishlistID, itemID uint) (*domain.WishlistItem, error) {
+	if _, err := u.getOwnedWishlist(ctx, userID, wishlistID); err != nil {
+		return nil, err
+	}
+	item, err := u.repo.GetItemByID(ctx, itemID)
+	if err != nil {
+		return nil, err
+	}
+	if item.WishlistID != wishlistID {
+		return nil, repository.ErrWishlistItemNotFound
+	}
+	return item, nil
+}
@@ -0,0 +1,26 @@
+CREATE TABLE public.cart_abandonments (
+    id SERIAL PRIMARY KEY,
+    cart_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    last_activity_at TIMESTAMP NOT NULL,
+    detected_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    reminders_sent INTEGER NOT NULL DEFAULT 0 CHECK (reminders_sent >= 0),
+    last_reminded_at TIMESTAMP,
+    recovered_at TIMESTAMP,
+    CONSTRAINT cart_abandonments_cart_id_fkey
+        FOREIGN KEY (cart_id)
+        REFERENCES public.carts(id)
+        ON DELETE CASCADE,
+    CONSTRAINT cart_abandonments_user_id_fkey
+        FOREIGN KEY (user_id)
+        REFERENCES public.users(id)
+        ON DELETE CASCADE
+);
+
+-- at most one open abandonment per cart
+CREATE UNIQUE INDEX cart_abandonments_open_cart_idx
+    ON public.cart_abandonments (cart_id)
+    WHERE recovered_at IS NULL;
+
+CREATE INDEX cart_abandonments_detected_at_idx
+    ON public.cart_abandonments (detected_at);
@@ -0,0 +1,29 @@
+CREATE TABLE public.wishlists (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    share_token VARCHAR(64) UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT wishlists_user_id_name_unique UNIQUE(user_id, name),
+    CONSTRAINT wishlists_user_id_fkey
+        FOREIGN KEY (user_id)
+        REFERENCES public.users(id)
+        ON DELETE CASCADE
+);
+
+CREATE TABLE public.wishlist_items (
+    id SERIAL PRIMARY KEY,
+    wishlist_id INTEGER NOT NULL,
+    product_id INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT wishlist_items_wishlist_id_product_id_unique UNIQUE(wishlist_id, product_id),
+    CONSTRAINT wishlist_items_wishlist_id_fkey
+        FOREIGN KEY (wishlist_id)
+        REFERENCES public.wishlists(id)
+        ON DELETE CASCADE,
+    CONSTRAINT wishlist_items_product_id_fkey
+        FOREIGN KEY (product_id)
+        REFERENCES public.products(id)
+        ON DELETE CASCADE
+);
@@ -0,0 +1,6 @@
+ALTER TABLE public.warehouses
+    ADD COLUMN shipping_base_cost NUMERIC(12,2) NOT NULL DEFAULT 0 CHECK (shipping_base_cost >= 0),
+    ADD COLUMN shipping_cost_per_kg NUMERIC(12,2) NOT NULL DEFAULT 0 CHECK (shipping_cost_per_kg >= 0);
+
+ALTER TABLE public.orders
+    ADD COLUMN tax_amount NUMERIC(12,2) NOT NULL DEFAULT 0;
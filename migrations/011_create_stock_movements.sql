@@ -0,0 +1,41 @@
+-- Append-only ledger of every change to warehouse_stock.quantity.
+-- SUM(delta) per warehouse/product equals the current quantity.
+CREATE TABLE public.stock_movements (
+    id BIGSERIAL PRIMARY KEY,
+    warehouse_id INTEGER NOT NULL,
+    product_id INTEGER NOT NULL,
+    delta INTEGER NOT NULL,
+    quantity_after INTEGER NOT NULL,
+    reason VARCHAR(20) NOT NULL
+        CHECK (reason IN ('sale', 'restock', 'adjustment', 'transfer', 'return')),
+    reference_type VARCHAR(20),
+    reference_id INTEGER,
+    actor_id INTEGER,
+    note TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT stock_movements_warehouse_id_fkey
+        FOREIGN KEY (warehouse_id)
+        REFERENCES public.warehouses(id)
+        ON DELETE CASCADE,
+    CONSTRAINT stock_movements_product_id_fkey
+        FOREIGN KEY (product_id)
+        REFERENCES public.products(id)
+        ON DELETE CASCADE,
+    CONSTRAINT stock_movements_actor_id_fkey
+        FOREIGN KEY (actor_id)
+        REFERENCES public.users(id)
+        ON DELETE SET NULL
+);
+
+CREATE INDEX stock_movements_warehouse_product_idx
+    ON public.stock_movements (warehouse_id, product_id, id DESC);
+
+CREATE INDEX stock_movements_reference_idx
+    ON public.stock_movements (reference_type, reference_id)
+    WHERE reference_id IS NOT NULL;
+
+-- Opening balance, so the ledger explains the quantities that existed before it.
+INSERT INTO public.stock_movements (warehouse_id, product_id, delta, quantity_after, reason, note)
+SELECT warehouse_id, product_id, quantity, quantity, 'adjustment', 'opening balance'
+FROM public.warehouse_stock
+WHERE quantity <> 0;
@@ -0,0 +1,48 @@
+-- Stock moved between warehouses. Dispatch takes the quantities out of the
+-- source, receipts put them into the destination; what is neither received
+-- nor written off as a discrepancy is in transit.
+CREATE TABLE public.stock_transfers (
+    id SERIAL PRIMARY KEY,
+    source_warehouse_id INTEGER NOT NULL,
+    destination_warehouse_id INTEGER NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'draft'
+        CHECK (status IN ('draft', 'dispatched', 'in_transit', 'received')),
+    note TEXT,
+    created_by INTEGER,
+    dispatched_at TIMESTAMP,
+    received_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT stock_transfers_distinct_warehouses CHECK (source_warehouse_id <> destination_warehouse_id),
+    CONSTRAINT stock_transfers_source_fkey
+        FOREIGN KEY (source_warehouse_id)
+        REFERENCES public.warehouses(id),
+    CONSTRAINT stock_transfers_destination_fkey
+        FOREIGN KEY (destination_warehouse_id)
+        REFERENCES public.warehouses(id),
+    CONSTRAINT stock_transfers_created_by_fkey
+        FOREIGN KEY (created_by)
+        REFERENCES public.users(id)
+        ON DELETE SET NULL
+);
+
+CREATE INDEX stock_transfers_status_idx ON public.stock_transfers (status);
+
+CREATE TABLE public.stock_transfer_lines (
+    id SERIAL PRIMARY KEY,
+    transfer_id INTEGER NOT NULL,
+    product_id INTEGER NOT NULL,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    received_quantity INTEGER NOT NULL DEFAULT 0 CHECK (received_quantity >= 0),
+    discrepancy_quantity INTEGER NOT NULL DEFAULT 0 CHECK (discrepancy_quantity >= 0),
+    discrepancy_note TEXT,
+    CONSTRAINT stock_transfer_lines_settled CHECK (received_quantity + discrepancy_quantity <= quantity),
+    CONSTRAINT stock_transfer_lines_transfer_product_unique UNIQUE (transfer_id, product_id),
+    CONSTRAINT stock_transfer_lines_transfer_id_fkey
+        FOREIGN KEY (transfer_id)
+        REFERENCES public.stock_transfers(id)
+        ON DELETE CASCADE,
+    CONSTRAINT stock_transfer_lines_product_id_fkey
+        FOREIGN KEY (product_id)
+        REFERENCES public.products(id)
+);
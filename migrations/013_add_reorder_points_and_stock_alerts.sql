@@ -0,0 +1,35 @@
+-- Reorder point per warehouse/product; NULL means no low-stock alert.
+ALTER TABLE public.warehouse_stock
+    ADD COLUMN reorder_point INTEGER CHECK (reorder_point >= 0);
+
+-- One row per time stock fell to or below its reorder point. At most one
+-- alert per warehouse/product is open; it is resolved when the stock is
+-- replenished above the reorder point. notified_at is set once the alert
+-- has been delivered through the notifier.
+CREATE TABLE public.stock_alerts (
+    id SERIAL PRIMARY KEY,
+    warehouse_id INTEGER NOT NULL,
+    product_id INTEGER NOT NULL,
+    reorder_point INTEGER NOT NULL,
+    quantity INTEGER NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'resolved')),
+    notified_at TIMESTAMP,
+    resolved_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT stock_alerts_warehouse_id_fkey
+        FOREIGN KEY (warehouse_id)
+        REFERENCES public.warehouses(id)
+        ON DELETE CASCADE,
+    CONSTRAINT stock_alerts_product_id_fkey
+        FOREIGN KEY (product_id)
+        REFERENCES public.products(id)
+        ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX stock_alerts_open_unique
+    ON public.stock_alerts (warehouse_id, product_id)
+    WHERE status = 'open';
+
+CREATE INDEX stock_alerts_unnotified_idx
+    ON public.stock_alerts (id)
+    WHERE notified_at IS NULL;
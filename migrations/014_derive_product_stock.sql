@@ -0,0 +1,12 @@
+-- products.stock is no longer written by the product API. It is the sum of
+-- warehouse_stock.quantity over all warehouses, kept up to date by the stock
+-- repository in the same transaction as every stock change.
+UPDATE public.products p
+SET stock = COALESCE((
+    SELECT SUM(s.quantity) FROM public.warehouse_stock s WHERE s.product_id = p.id
+), 0);
+
+ALTER TABLE public.products ALTER COLUMN stock SET DEFAULT 0;
+
+COMMENT ON COLUMN public.products.stock IS
+    'Read-only aggregate: SUM(warehouse_stock.quantity) for the product';
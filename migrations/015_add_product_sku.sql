@@ -0,0 +1,4 @@
+-- Optional stock keeping unit, used to key stock imports and exports
+ALTER TABLE public.products ADD COLUMN sku VARCHAR(64);
+
+CREATE UNIQUE INDEX products_sku_key ON public.products (sku) WHERE sku IS NOT NULL;
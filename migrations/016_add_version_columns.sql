@@ -0,0 +1,7 @@
+-- Row versions for optimistic concurrency control. Every update increments
+-- the version; a client sends back the version it read (If-Match or a
+-- version field) and the update is refused if the row changed meanwhile.
+ALTER TABLE public.products ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE public.warehouses ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE public.warehouse_stock ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE public.orders ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,29 @@
+-- One warehouse_stock row per warehouse/product. Duplicates are merged into
+-- the oldest row: quantities are summed, so products.stock and the ledger
+-- (which is keyed by warehouse/product, not by row) stay correct.
+WITH merged AS (
+    SELECT warehouse_id, product_id,
+           MIN(id) AS keep_id,
+           SUM(quantity) AS quantity,
+           MIN(reorder_point) AS reorder_point,
+           MAX(version) AS version
+    FROM public.warehouse_stock
+    GROUP BY warehouse_id, product_id
+    HAVING COUNT(*) > 1
+)
+UPDATE public.warehouse_stock s
+SET quantity = m.quantity,
+    reorder_point = COALESCE(s.reorder_point, m.reorder_point),
+    version = m.version + 1,
+    updated_at = NOW()
+FROM merged m
+WHERE s.id = m.keep_id;
+
+DELETE FROM public.warehouse_stock s
+USING public.warehouse_stock keep
+WHERE keep.warehouse_id = s.warehouse_id
+  AND keep.product_id = s.product_id
+  AND keep.id < s.id;
+
+ALTER TABLE public.warehouse_stock
+    ADD CONSTRAINT warehouse_stock_warehouse_product_key UNIQUE (warehouse_id, product_id);
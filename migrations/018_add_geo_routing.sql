@@ -0,0 +1,51 @@
+-- Coordinates for warehouses and customer addresses, so an order can be
+-- shipped from the nearest warehouse that has the stock. Warehouses without
+-- coordinates are not considered by the routing.
+ALTER TABLE public.warehouses
+    ADD COLUMN latitude DOUBLE PRECISION CHECK (latitude BETWEEN -90 AND 90),
+    ADD COLUMN longitude DOUBLE PRECISION CHECK (longitude BETWEEN -180 AND 180),
+    ADD CONSTRAINT warehouses_coordinates_pair CHECK ((latitude IS NULL) = (longitude IS NULL));
+
+CREATE TABLE public.customer_addresses (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    label VARCHAR(100),
+    address TEXT NOT NULL,
+    latitude DOUBLE PRECISION NOT NULL CHECK (latitude BETWEEN -90 AND 90),
+    longitude DOUBLE PRECISION NOT NULL CHECK (longitude BETWEEN -180 AND 180),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT customer_addresses_user_id_fkey
+        FOREIGN KEY (user_id)
+        REFERENCES public.users(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX customer_addresses_user_idx ON public.customer_addresses (user_id);
+
+-- routing_decision explains in plain text which warehouses were chosen and why.
+ALTER TABLE public.orders
+    ADD COLUMN address_id INTEGER REFERENCES public.customer_addresses(id) ON DELETE SET NULL,
+    ADD COLUMN routing_decision TEXT;
+
+-- Which warehouse ships how many units of each product of an order. A split
+-- order has several rows for the same product.
+CREATE TABLE public.order_allocations (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL,
+    warehouse_id INTEGER NOT NULL,
+    product_id INTEGER NOT NULL,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    distance_km DOUBLE PRECISION,
+    CONSTRAINT order_allocations_order_id_fkey
+        FOREIGN KEY (order_id)
+        REFERENCES public.orders(id)
+        ON DELETE CASCADE,
+    CONSTRAINT order_allocations_warehouse_id_fkey
+        FOREIGN KEY (warehouse_id)
+        REFERENCES public.warehouses(id),
+    CONSTRAINT order_allocations_product_id_fkey
+        FOREIGN KEY (product_id)
+        REFERENCES public.products(id)
+);
+
+CREATE INDEX order_allocations_order_idx ON public.order_allocations (order_id);
@@ -0,0 +1,46 @@
+-- Lots (batches) of a warehouse/product with their expiry date. The lots are a
+-- breakdown of warehouse_stock.quantity: their quantities never add up to
+-- more than it, and whatever is not in a lot is untracked stock (stock
+-- received before lots existed, or received without a batch number).
+CREATE TABLE public.stock_lots (
+    id SERIAL PRIMARY KEY,
+    warehouse_id INTEGER NOT NULL,
+    product_id INTEGER NOT NULL,
+    batch_number VARCHAR(100) NOT NULL,
+    expiry_date DATE,
+    quantity INTEGER NOT NULL DEFAULT 0 CHECK (quantity >= 0),
+    received_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT stock_lots_batch_key UNIQUE (warehouse_id, product_id, batch_number),
+    CONSTRAINT stock_lots_stock_fkey
+        FOREIGN KEY (warehouse_id, product_id)
+        REFERENCES public.warehouse_stock(warehouse_id, product_id)
+        ON DELETE CASCADE
+);
+
+-- FEFO picking and the expiry report both read lots by expiry date.
+CREATE INDEX stock_lots_fefo_idx ON public.stock_lots (warehouse_id, product_id, expiry_date) WHERE quantity > 0;
+CREATE INDEX stock_lots_expiry_idx ON public.stock_lots (expiry_date) WHERE quantity > 0;
+
+-- Which lots each order item was picked from. Stock taken from untracked
+-- stock has no row here. The batch number and expiry are copied so the
+-- record survives the lot being deleted with its stock row.
+CREATE TABLE public.order_item_lots (
+    id SERIAL PRIMARY KEY,
+    order_item_id INTEGER NOT NULL,
+    lot_id INTEGER,
+    batch_number VARCHAR(100) NOT NULL,
+    expiry_date DATE,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    CONSTRAINT order_item_lots_order_item_id_fkey
+        FOREIGN KEY (order_item_id)
+        REFERENCES public.order_items(id)
+        ON DELETE CASCADE,
+    CONSTRAINT order_item_lots_lot_id_fkey
+        FOREIGN KEY (lot_id)
+        REFERENCES public.stock_lots(id)
+        ON DELETE SET NULL
+);
+
+CREATE INDEX order_item_lots_order_item_idx ON public.order_item_lots (order_item_id);
+CREATE INDEX order_item_lots_lot_idx ON public.order_item_lots (lot_id);
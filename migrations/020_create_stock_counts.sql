@@ -0,0 +1,62 @@
+-- Physical count (stocktake / cycle count) sessions. Opening a session
+-- snapshots the expected quantities; counts can be submitted over several
+-- requests while sales go on; approval posts the variances as adjustments.
+CREATE TABLE public.stock_count_sessions (
+    id SERIAL PRIMARY KEY,
+    warehouse_id INTEGER NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'open'
+        CHECK (status IN ('open', 'approved', 'cancelled')),
+    -- full_warehouse sessions may count products that had no stock row
+    full_warehouse BOOLEAN NOT NULL DEFAULT TRUE,
+    note TEXT,
+    created_by INTEGER,
+    closed_by INTEGER,
+    closed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT stock_count_sessions_warehouse_id_fkey
+        FOREIGN KEY (warehouse_id)
+        REFERENCES public.warehouses(id)
+        ON DELETE CASCADE,
+    CONSTRAINT stock_count_sessions_created_by_fkey
+        FOREIGN KEY (created_by)
+        REFERENCES public.users(id)
+        ON DELETE SET NULL,
+    CONSTRAINT stock_count_sessions_closed_by_fkey
+        FOREIGN KEY (closed_by)
+        REFERENCES public.users(id)
+        ON DELETE SET NULL
+);
+
+-- Two open sessions on one warehouse would post the same variance twice.
+CREATE UNIQUE INDEX stock_count_sessions_one_open_idx
+    ON public.stock_count_sessions (warehouse_id) WHERE status = 'open';
+
+-- expected_quantity is the snapshot taken when the session was opened.
+-- system_quantity is the quantity at the moment the count was submitted, so
+-- counted_quantity - system_quantity is the variance regardless of sales
+-- made between opening the session, counting and approving.
+CREATE TABLE public.stock_count_lines (
+    id SERIAL PRIMARY KEY,
+    session_id INTEGER NOT NULL,
+    product_id INTEGER NOT NULL,
+    expected_quantity INTEGER NOT NULL DEFAULT 0,
+    counted_quantity INTEGER CHECK (counted_quantity >= 0),
+    system_quantity INTEGER,
+    counted_by INTEGER,
+    counted_at TIMESTAMP,
+    adjustment INTEGER,
+    CONSTRAINT stock_count_lines_session_product_key UNIQUE (session_id, product_id),
+    CONSTRAINT stock_count_lines_session_id_fkey
+        FOREIGN KEY (session_id)
+        REFERENCES public.stock_count_sessions(id)
+        ON DELETE CASCADE,
+    CONSTRAINT stock_count_lines_product_id_fkey
+        FOREIGN KEY (product_id)
+        REFERENCES public.products(id)
+        ON DELETE CASCADE,
+    CONSTRAINT stock_count_lines_counted_by_fkey
+        FOREIGN KEY (counted_by)
+        REFERENCES public.users(id)
+        ON DELETE SET NULL
+);
@@ -0,0 +1,80 @@
+-- Suppliers and the purchase orders placed with them. Receiving a purchase
+-- order line raises the warehouse stock (a 'restock' movement referencing the
+-- purchase order) and records what each received unit cost.
+CREATE TABLE public.suppliers (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    email VARCHAR(255),
+    phone VARCHAR(50),
+    address TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE TABLE public.purchase_orders (
+    id SERIAL PRIMARY KEY,
+    supplier_id INTEGER NOT NULL,
+    warehouse_id INTEGER NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'draft'
+        CHECK (status IN ('draft', 'sent', 'partially_received', 'received')),
+    note TEXT,
+    created_by INTEGER,
+    sent_at TIMESTAMP,
+    received_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    -- suppliers and warehouses with purchase orders cannot be deleted
+    CONSTRAINT purchase_orders_supplier_id_fkey
+        FOREIGN KEY (supplier_id)
+        REFERENCES public.suppliers(id),
+    CONSTRAINT purchase_orders_warehouse_id_fkey
+        FOREIGN KEY (warehouse_id)
+        REFERENCES public.warehouses(id),
+    CONSTRAINT purchase_orders_created_by_fkey
+        FOREIGN KEY (created_by)
+        REFERENCES public.users(id)
+        ON DELETE SET NULL
+);
+
+CREATE INDEX purchase_orders_supplier_idx ON public.purchase_orders (supplier_id);
+CREATE INDEX purchase_orders_status_idx ON public.purchase_orders (status);
+
+CREATE TABLE public.purchase_order_lines (
+    id SERIAL PRIMARY KEY,
+    purchase_order_id INTEGER NOT NULL,
+    product_id INTEGER NOT NULL,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    unit_cost NUMERIC(12,2) NOT NULL CHECK (unit_cost >= 0),
+    received_quantity INTEGER NOT NULL DEFAULT 0 CHECK (received_quantity >= 0),
+    CONSTRAINT purchase_order_lines_not_over_received CHECK (received_quantity <= quantity),
+    CONSTRAINT purchase_order_lines_order_product_unique UNIQUE (purchase_order_id, product_id),
+    CONSTRAINT purchase_order_lines_purchase_order_id_fkey
+        FOREIGN KEY (purchase_order_id)
+        REFERENCES public.purchase_orders(id)
+        ON DELETE CASCADE,
+    CONSTRAINT purchase_order_lines_product_id_fkey
+        FOREIGN KEY (product_id)
+        REFERENCES public.products(id)
+);
+
+-- One row per receipt of a line. landed_unit_cost is the ordered unit cost
+-- plus whatever freight, duty etc. the receiver allocated to the unit, so
+-- SUM(quantity * landed_unit_cost) is what the received stock cost.
+CREATE TABLE public.purchase_receipts (
+    id SERIAL PRIMARY KEY,
+    purchase_order_line_id INTEGER NOT NULL,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    landed_unit_cost NUMERIC(12,2) NOT NULL CHECK (landed_unit_cost >= 0),
+    received_by INTEGER,
+    received_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT purchase_receipts_line_id_fkey
+        FOREIGN KEY (purchase_order_line_id)
+        REFERENCES public.purchase_order_lines(id)
+        ON DELETE CASCADE,
+    CONSTRAINT purchase_receipts_received_by_fkey
+        FOREIGN KEY (received_by)
+        REFERENCES public.users(id)
+        ON DELETE SET NULL
+);
+
+CREATE INDEX purchase_receipts_line_idx ON public.purchase_receipts (purchase_order_line_id);
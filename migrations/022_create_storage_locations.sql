@@ -0,0 +1,61 @@
+-- Storage locations (zone / aisle / shelf / bin) inside a warehouse and the
+-- stock held in each. Like lots, location_stock is a breakdown of
+-- warehouse_stock.quantity: the locations of a row never hold more than it,
+-- and the rest is unassigned stock (not put away yet, or never located).
+CREATE TABLE public.storage_locations (
+    id SERIAL PRIMARY KEY,
+    warehouse_id INTEGER NOT NULL,
+    zone VARCHAR(20) NOT NULL,
+    aisle VARCHAR(20) NOT NULL DEFAULT '',
+    shelf VARCHAR(20) NOT NULL DEFAULT '',
+    bin VARCHAR(20) NOT NULL DEFAULT '',
+    -- zone-aisle-shelf-bin without the empty parts, e.g. 'A-03-2-B'
+    code VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT storage_locations_warehouse_code_key UNIQUE (warehouse_id, code),
+    CONSTRAINT storage_locations_warehouse_id_fkey
+        FOREIGN KEY (warehouse_id)
+        REFERENCES public.warehouses(id)
+        ON DELETE CASCADE
+);
+
+CREATE TABLE public.location_stock (
+    location_id INTEGER NOT NULL,
+    warehouse_id INTEGER NOT NULL,
+    product_id INTEGER NOT NULL,
+    quantity INTEGER NOT NULL DEFAULT 0 CHECK (quantity >= 0),
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    PRIMARY KEY (location_id, product_id),
+    -- only empty locations can be deleted; the repository checks that
+    CONSTRAINT location_stock_location_id_fkey
+        FOREIGN KEY (location_id)
+        REFERENCES public.storage_locations(id)
+        ON DELETE CASCADE,
+    CONSTRAINT location_stock_stock_fkey
+        FOREIGN KEY (warehouse_id, product_id)
+        REFERENCES public.warehouse_stock(warehouse_id, product_id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX location_stock_stock_idx ON public.location_stock (warehouse_id, product_id) WHERE quantity > 0;
+
+-- Where each order item is picked from. A NULL location is unassigned stock.
+-- The code is copied so the pick list survives the location being deleted.
+CREATE TABLE public.order_item_picks (
+    id SERIAL PRIMARY KEY,
+    order_item_id INTEGER NOT NULL,
+    warehouse_id INTEGER NOT NULL,
+    location_id INTEGER,
+    location_code VARCHAR(100),
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    CONSTRAINT order_item_picks_order_item_id_fkey
+        FOREIGN KEY (order_item_id)
+        REFERENCES public.order_items(id)
+        ON DELETE CASCADE,
+    CONSTRAINT order_item_picks_location_id_fkey
+        FOREIGN KEY (location_id)
+        REFERENCES public.storage_locations(id)
+        ON DELETE SET NULL
+);
+
+CREATE INDEX order_item_picks_order_item_idx ON public.order_item_picks (order_item_id);
@@ -0,0 +1,50 @@
+-- Point-in-time copies of warehouse_stock, taken daily by the snapshot job
+-- and on demand. Together with stock_movements they answer what the stock
+-- was at any moment: the latest snapshot before it plus the movements since.
+CREATE TABLE public.inventory_snapshots (
+    id SERIAL PRIMARY KEY,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('scheduled', 'manual')),
+    taken_by INTEGER,
+    line_count INTEGER NOT NULL DEFAULT 0,
+    total_quantity BIGINT NOT NULL DEFAULT 0,
+    taken_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT inventory_snapshots_taken_by_fkey
+        FOREIGN KEY (taken_by)
+        REFERENCES public.users(id)
+        ON DELETE SET NULL
+);
+
+CREATE INDEX inventory_snapshots_taken_at_idx ON public.inventory_snapshots (taken_at DESC);
+
+-- No foreign keys to warehouses or products: the snapshot is an audit record
+-- and has to outlive them.
+CREATE TABLE public.inventory_snapshot_lines (
+    snapshot_id INTEGER NOT NULL,
+    warehouse_id INTEGER NOT NULL,
+    product_id INTEGER NOT NULL,
+    quantity INTEGER NOT NULL,
+    unit_price NUMERIC(12,2) NOT NULL,
+    PRIMARY KEY (snapshot_id, warehouse_id, product_id),
+    CONSTRAINT inventory_snapshot_lines_snapshot_id_fkey
+        FOREIGN KEY (snapshot_id)
+        REFERENCES public.inventory_snapshots(id)
+        ON DELETE CASCADE
+);
+
+-- Every price a product has had, for valuations as of a past moment.
+-- Written by the product repository on create and on a price change; like
+-- the snapshot lines it is kept when the product is deleted.
+CREATE TABLE public.product_price_history (
+    id BIGSERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL,
+    price NUMERIC(12,2) NOT NULL,
+    effective_from TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX product_price_history_product_idx
+    ON public.product_price_history (product_id, effective_from DESC);
+
+-- Current prices as the opening entry, effective since the product was created.
+INSERT INTO public.product_price_history (product_id, price, effective_from)
+SELECT id, price, created_at
+FROM public.products;
@@ -0,0 +1,13 @@
+-- Physical capacity of a warehouse by weight and volume. NULL means no
+-- limit. capacity_policy decides what happens to a stock increase that would
+-- go over a limit: 'reject' refuses it, 'warn' books it and returns a warning.
+ALTER TABLE public.warehouses
+    ADD COLUMN max_weight_kg NUMERIC(14,2) CHECK (max_weight_kg > 0),
+    ADD COLUMN max_volume_m3 NUMERIC(14,3) CHECK (max_volume_m3 > 0),
+    ADD COLUMN capacity_policy VARCHAR(10) NOT NULL DEFAULT 'reject'
+        CHECK (capacity_policy IN ('reject', 'warn'));
+
+-- Volume of one unit in cubic metres, next to the weight in kg. Products
+-- without a volume take no space in the capacity check.
+ALTER TABLE public.products
+    ADD COLUMN volume NUMERIC(12,6) CHECK (volume >= 0);
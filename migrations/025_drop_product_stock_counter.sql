@@ -0,0 +1,7 @@
+-- products.stock was a counter moved by every stock change, which made the
+-- product row a lock every sale of the product waited on, in any warehouse.
+-- The total is now computed on read from warehouse_stock instead.
+ALTER TABLE public.products DROP COLUMN stock;
+
+-- The totals are read per product.
+CREATE INDEX warehouse_stock_product_idx ON public.warehouse_stock (product_id);
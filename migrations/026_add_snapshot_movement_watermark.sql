@@ -0,0 +1,12 @@
+-- Snapshots used taken_at to tell which movements they already contain, but
+-- taken_at and the movements' created_at are both transaction-start times,
+-- so a movement committed while a snapshot was taken could land on either
+-- side of it. The snapshot now records the last movement it includes and
+-- history is replayed from the movements after that id.
+ALTER TABLE public.inventory_snapshots
+    ADD COLUMN last_movement_id BIGINT NOT NULL DEFAULT 0;
+
+-- Best guess for the snapshots taken before this migration.
+UPDATE public.inventory_snapshots s
+SET last_movement_id = COALESCE(
+    (SELECT MAX(m.id) FROM public.stock_movements m WHERE m.created_at <= s.taken_at), 0);
@@ -0,0 +1,23 @@
+-- The lots a transfer line took from the source warehouse. Receiving the line
+-- puts the same batches into the destination, so stock does not lose its
+-- batch and expiry on the way. The rest of the line was untracked stock.
+CREATE TABLE public.stock_transfer_line_lots (
+    id SERIAL PRIMARY KEY,
+    transfer_line_id INTEGER NOT NULL,
+    batch_number VARCHAR(100) NOT NULL,
+    expiry_date DATE,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    received_quantity INTEGER NOT NULL DEFAULT 0 CHECK (received_quantity >= 0),
+    CONSTRAINT stock_transfer_line_lots_settled CHECK (received_quantity <= quantity),
+    CONSTRAINT stock_transfer_line_lots_line_id_fkey
+        FOREIGN KEY (transfer_line_id)
+        REFERENCES public.stock_transfer_lines(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX stock_transfer_line_lots_line_idx ON public.stock_transfer_line_lots (transfer_line_id);
+
+-- A purchase receipt can name the batch it brought in.
+ALTER TABLE public.purchase_receipts
+    ADD COLUMN batch_number VARCHAR(100),
+    ADD COLUMN expiry_date DATE;
@@ -151,3 +151,45 @@ func GetUserIDFromContext(c *gin.Context) (uint, error) {
 
 	return id, nil
 }
+
+// guestCartTokenType menandai token yang hanya berlaku untuk guest cart
+const guestCartTokenType = "guest_cart"
+
+// GenerateCartToken membuat token bertanda tangan untuk guest cart dengan guestID sebagai identitas
+func GenerateCartToken(guestID string, secret string, duration time.Duration) (string, error) {
+	claims := jwt.MapClaims{
+		"gid": guestID,
+		"typ": guestCartTokenType,
+		"exp": time.Now().Add(duration).Unix(),
+		"iat": time.Now().Unix(),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(secret))
+}
+
+// ParseCartToken memverifikasi token guest cart dan mengembalikan guestID di dalamnya
+func ParseCartToken(tokenStr string, secret string) (string, error) {
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return []byte(secret), nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("invalid cart token: %w", err)
+	}
+	if !token.Valid {
+		return "", fmt.Errorf("invalid cart token")
+	}
+
+	if typ, _ := claims["typ"].(string); typ != guestCartTokenType {
+		return "", fmt.Errorf("invalid cart token type")
+	}
+	guestID, ok := claims["gid"].(string)
+	if !ok || guestID == "" {
+		return "", fmt.Errorf("invalid cart token claims")
+	}
+	return guestID, nil
+}
@@ -0,0 +1,177 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message adalah notifikasi yang dikirim ke satu penerima
+type Message struct {
+	To      string            `json:"to"`
+	Subject string            `json:"subject"`
+	Body    string            `json:"body"`
+	Tags    map[string]string `json:"tags,omitempty"`
+}
+
+// Notifier mengirim Message lewat satu channel (log, file, email, ...)
+type Notifier interface {
+	Notify(ctx context.Context, msg Message) error
+}
+
+// LogNotifier menulis notifikasi ke log aplikasi, cocok untuk development
+type LogNotifier struct{}
+
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+func (n *LogNotifier) Notify(ctx context.Context, msg Message) error {
+	log.Printf("📨 [NOTIFY] to=%s subject=%q tags=%v\n%s", msg.To, msg.Subject, msg.Tags, msg.Body)
+	return nil
+}
+
+// FileNotifier menambahkan setiap notifikasi sebagai satu baris JSON ke sebuah file
+type FileNotifier struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileNotifier(path string) *FileNotifier {
+	return &FileNotifier{path: path}
+}
+
+func (n *FileNotifier) Notify(ctx context.Context, msg Message) error {
+	line, err := json.Marshal(struct {
+		Message
+		SentAt time.Time `json:"sent_at"`
+	}{msg, time.Now()})
+	if err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	f, err := os.OpenFile(n.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open notification file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write notification: %w", err)
+	}
+	return nil
+}
+
+// SMTPNotifier mengirim notifikasi sebagai email plain text. Untuk lokal bisa
+// diarahkan ke SMTP stand-in seperti MailHog atau Mailpit.
+type SMTPNotifier struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+// NewSMTPNotifier membuat SMTPNotifier; username kosong berarti tanpa auth
+func NewSMTPNotifier(host, port, username, password, from string) *SMTPNotifier {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &SMTPNotifier{
+		addr: host + ":" + port,
+		from: from,
+		auth: auth,
+	}
+}
+
+func (n *SMTPNotifier) Notify(ctx context.Context, msg Message) error {
+	if msg.To == "" {
+		return fmt.Errorf("email recipient is empty")
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", n.from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n\r\n")
+	b.WriteString(msg.Body)
+
+	if err := smtp.SendMail(n.addr, n.auth, n.from, []string{msg.To}, []byte(b.String())); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+// SignatureHeader berisi HMAC-SHA256 (hex) dari body request webhook, dengan
+// secret yang sama di pengirim dan penerima.
+const SignatureHeader = "X-Notifier-Signature"
+
+// WebhookNotifier mengirim setiap Message sebagai JSON lewat HTTP POST.
+type WebhookNotifier struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+// NewWebhookNotifier membuat WebhookNotifier; secret kosong berarti tanpa signature
+func NewWebhookNotifier(url, secret string, timeout time.Duration) *WebhookNotifier {
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	return &WebhookNotifier{
+		url:    url,
+		secret: secret,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(struct {
+		Message
+		SentAt time.Time `json:"sent_at"`
+	}{msg, time.Now()})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.secret != "" {
+		req.Header.Set(SignatureHeader, Sign(n.secret, body))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return nil
+}
+
+// Sign menghitung signature body untuk SignatureHeader.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -30,5 +30,5 @@ func ValidateCode(code, secret string) (bool, error) {
 // Optional helper to get otpauth URI manually
 // otpauth://totp/{issuer}:{account}?secret={secret}&issuer={issuer}
 func KeyUri(issuer, account, secret string) string {
-	return fmt.Sprintf("otpauth://totp/%s:%s?secret=%s&issuer=%s", issuer, account, secret)
+	return fmt.Sprintf("otpauth://totp/%s:%s?secret=%s&issuer=%s", issuer, account, secret, issuer)
 }